@@ -0,0 +1,225 @@
+package circuit_breaker
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// AdminConfig configures AdminHandler.
+//
+// Breakers lists the breakers managed by the handler, e.g. KeyedBreaker.Breakers.
+//
+// Authorize is called before every request. If it returns an error, the request is rejected with 403 Forbidden.
+// If Authorize is nil, all the requests are allowed, so the handler must not be exposed publicly.
+//
+// StreamInterval is the interval of the dashboard updates. If StreamInterval is zero, the dashboard is updated every second.
+//
+// Actor identifies who applies the actions for the audit log, see CircuitBreaker.Apply.
+// If Actor is nil, the user of the basic authentication is used, or the remote address without one.
+type AdminConfig struct {
+	Breakers       func() []*CircuitBreaker
+	Authorize      func(r *http.Request) error
+	StreamInterval time.Duration
+	Actor          func(r *http.Request) string
+}
+
+// adminActions are the actions of AdminHandler, applied by POST /breakers/{name}/{action}
+var adminActions = map[string]Action{
+	"trip":    ActionTrip,
+	"reset":   ActionReset,
+	"disable": ActionDisable,
+	"enable":  ActionEnable,
+}
+
+// protobufContentType is the media type of the protobuf encoding of ExportedState
+const protobufContentType = "application/x-protobuf"
+
+// maxImportSize bounds the body of POST /breakers/{name}/import
+const maxImportSize = 1 << 20
+
+// AdminHandler serves the API to inspect and control the breakers at runtime, e.g. during incidents:
+//
+//	GET  /breakers                  the snapshots of all the breakers, sorted by name
+//	GET  /summary                   the RegistrySnapshot of all the breakers, with the aggregates
+//	GET  /breakers/{name}           the snapshot of the breaker
+//	POST /breakers/{name}/trip      Trip the breaker
+//	POST /breakers/{name}/reset     Reset the breaker
+//	POST /breakers/{name}/disable   Disable the breaker
+//	POST /breakers/{name}/enable    Enable the breaker
+//	GET  /breakers/{name}/export    the ExportedState of the breaker
+//	POST /breakers/{name}/import    Import the ExportedState of the request body
+//	GET  /dashboard                 the dashboard of the breakers, also served at /
+//	GET  /stream                    the server-sent events updating the dashboard
+//
+// The actions respond with the snapshot of the breaker after the action.
+// They are recorded in the audit log of the breaker with the actor, see AdminConfig,
+// and the reason given by the "reason" query or form parameter.
+// The exported states are encoded as JSON, or as protobuf if the Accept or the Content-Type header
+// is application/x-protobuf, e.g. to hand the breakers over to the next deployment.
+// The names of the breakers are path escaped, and may contain slashes, like the names of KeyedBreaker.
+// Errors are responded as {"error": "..."}.
+//
+// The handler can be mounted under a prefix with http.StripPrefix.
+func AdminHandler(cfg AdminConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cfg.Authorize != nil {
+			if err := cfg.Authorize(r); err != nil {
+				writeAdminError(w, http.StatusForbidden, err.Error())
+				return
+			}
+		}
+
+		path := r.URL.EscapedPath()
+		if path == "/" || path == "/dashboard" || path == "/stream" {
+			if r.Method != http.MethodGet {
+				writeAdminError(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+			if path == "/stream" {
+				streamDashboard(w, r, cfg)
+			} else {
+				serveDashboard(w)
+			}
+			return
+		}
+		if path == "/breakers" || path == "/breakers/" || path == "/summary" {
+			if r.Method != http.MethodGet {
+				writeAdminError(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+			snapshot := SnapshotAll(cfg.Breakers()...)
+			if path == "/summary" {
+				writeAdminJSON(w, http.StatusOK, snapshot)
+			} else {
+				writeAdminJSON(w, http.StatusOK, snapshot.Breakers)
+			}
+			return
+		}
+
+		rest := strings.TrimPrefix(path, "/breakers/")
+		if rest == path {
+			writeAdminError(w, http.StatusNotFound, "not found")
+			return
+		}
+
+		action := ""
+		if i := strings.LastIndex(rest, "/"); i >= 0 {
+			if _, ok := adminActions[rest[i+1:]]; ok || rest[i+1:] == "export" || rest[i+1:] == "import" {
+				rest, action = rest[:i], rest[i+1:]
+			}
+		}
+		name, err := url.PathUnescape(rest)
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, "invalid breaker name")
+			return
+		}
+
+		cb := adminLookup(cfg.Breakers(), name)
+		if cb == nil {
+			writeAdminError(w, http.StatusNotFound, "circuit breaker "+name+" not found")
+			return
+		}
+
+		switch action {
+		case "":
+			if r.Method != http.MethodGet {
+				writeAdminError(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+		case "export":
+			if r.Method != http.MethodGet {
+				writeAdminError(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+			writeAdminExport(w, r, cb.Export())
+			return
+		case "import":
+			if r.Method != http.MethodPost {
+				writeAdminError(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+			e, err := readAdminImport(r)
+			if err == nil {
+				err = cb.Import(e)
+			}
+			if err != nil {
+				writeAdminError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		default:
+			if r.Method != http.MethodPost {
+				writeAdminError(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+			if err := cb.Apply(adminActions[action], adminActor(cfg, r), r.FormValue("reason")); err != nil {
+				writeAdminError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		writeAdminJSON(w, http.StatusOK, cb.Snapshot())
+	})
+}
+
+func adminLookup(cbs []*CircuitBreaker, name string) *CircuitBreaker {
+	for _, cb := range cbs {
+		if cb.name == name {
+			return cb
+		}
+	}
+
+	return nil
+}
+
+func adminActor(cfg AdminConfig, r *http.Request) string {
+	if cfg.Actor != nil {
+		return cfg.Actor(r)
+	}
+	if user, _, ok := r.BasicAuth(); ok {
+		return user
+	}
+
+	return r.RemoteAddr
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeAdminExport(w http.ResponseWriter, r *http.Request, e ExportedState) {
+	if r.Header.Get("Accept") != protobufContentType {
+		writeAdminJSON(w, http.StatusOK, e)
+		return
+	}
+
+	w.Header().Set("Content-Type", protobufContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(e.MarshalProto())
+}
+
+// readAdminImport decodes the ExportedState of the request body by its Content-Type
+func readAdminImport(r *http.Request) (ExportedState, error) {
+	var e ExportedState
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxImportSize))
+	if err != nil {
+		return e, err
+	}
+	if r.Header.Get("Content-Type") == protobufContentType {
+		err = e.UnmarshalProto(body)
+	} else {
+		err = json.Unmarshal(body, &e)
+	}
+
+	return e, err
+}
+
+func writeAdminError(w http.ResponseWriter, status int, msg string) {
+	writeAdminJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,156 @@
+package circuit_breaker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func adminRequest(h http.Handler, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestAdminHandler(t *testing.T) {
+	kb := NewKeyedBreaker(KeyedConfig{Config: Config{Name: "hosts"}})
+	kb.Get("a.example.com")
+	kb.Get("b.example.com")
+	h := AdminHandler(AdminConfig{Breakers: kb.Breakers})
+
+	w := adminRequest(h, http.MethodGet, "/breakers")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var snapshots []struct{ Name, State string }
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &snapshots))
+	assert.Equal(t, []struct{ Name, State string }{
+		{Name: "hosts/a.example.com", State: "closed"},
+		{Name: "hosts/b.example.com", State: "closed"},
+	}, snapshots)
+
+	// the names may contain slashes
+	w = adminRequest(h, http.MethodPost, "/breakers/hosts/a.example.com/trip")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"State":"open"`)
+	assert.Equal(t, StateOpen, kb.Get("a.example.com").State())
+
+	w = adminRequest(h, http.MethodPost, "/breakers/hosts%2Fa.example.com/reset")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, StateClosed, kb.Get("a.example.com").State())
+
+	assert.Equal(t, http.StatusOK, adminRequest(h, http.MethodPost, "/breakers/hosts/b.example.com/disable").Code)
+	assert.True(t, kb.Get("b.example.com").Disabled())
+	assert.Contains(t, adminRequest(h, http.MethodGet, "/breakers/hosts/b.example.com").Body.String(), `"Disabled":true`)
+	assert.Equal(t, http.StatusOK, adminRequest(h, http.MethodPost, "/breakers/hosts/b.example.com/enable").Code)
+	assert.False(t, kb.Get("b.example.com").Disabled())
+
+	w = adminRequest(h, http.MethodPost, "/breakers/hosts/c.example.com/trip")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, `{"error":"circuit breaker hosts/c.example.com not found"}`+"\n", w.Body.String())
+	assert.Equal(t, http.StatusMethodNotAllowed, adminRequest(h, http.MethodGet, "/breakers/hosts/a.example.com/trip").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, adminRequest(h, http.MethodPost, "/breakers").Code)
+	assert.Equal(t, http.StatusNotFound, adminRequest(h, http.MethodGet, "/health").Code)
+}
+
+func TestAdminHandlerAuthorize(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "payments"})
+	h := AdminHandler(AdminConfig{
+		Breakers: func() []*CircuitBreaker { return []*CircuitBreaker{cb} },
+		Authorize: func(r *http.Request) error {
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				return errors.New("invalid token")
+			}
+			return nil
+		},
+	})
+
+	w := adminRequest(h, http.MethodPost, "/breakers/payments/trip")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, `{"error":"invalid token"}`+"\n", w.Body.String())
+	assert.Equal(t, StateClosed, cb.State())
+
+	r := httptest.NewRequest(http.MethodPost, "/admin/breakers/payments/trip", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	http.StripPrefix("/admin", h).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestAdminHandlerAudit(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "payments", AuditLogSize: 4})
+	h := AdminHandler(AdminConfig{Breakers: func() []*CircuitBreaker { return []*CircuitBreaker{cb} }})
+
+	r := httptest.NewRequest(http.MethodPost, "/breakers/payments/trip?reason=incident+42", nil)
+	r.SetBasicAuth("alice", "secret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	h = AdminHandler(AdminConfig{
+		Breakers: func() []*CircuitBreaker { return []*CircuitBreaker{cb} },
+		Actor:    func(r *http.Request) string { return r.Header.Get("X-User") },
+	})
+	r = httptest.NewRequest(http.MethodPost, "/breakers/payments/reset", nil)
+	r.Header.Set("X-User", "bob")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	log := cb.AuditLog()
+	if assert.Len(t, log, 2) {
+		assert.Equal(t, ActionTrip, log[0].Action)
+		assert.Equal(t, "alice", log[0].Actor)
+		assert.Equal(t, "incident 42", log[0].Reason)
+		assert.Equal(t, ActionReset, log[1].Action)
+		assert.Equal(t, "bob", log[1].Actor)
+		assert.Equal(t, "", log[1].Reason)
+	}
+}
+
+func TestAdminHandlerExport(t *testing.T) {
+	blue := NewCircuitBreaker(Config{Name: "payments"})
+	blue.Trip()
+	green := NewCircuitBreaker(Config{Name: "payments"})
+	blueAdmin := AdminHandler(AdminConfig{Breakers: func() []*CircuitBreaker { return []*CircuitBreaker{blue} }})
+	greenAdmin := AdminHandler(AdminConfig{Breakers: func() []*CircuitBreaker { return []*CircuitBreaker{green} }})
+
+	w := adminRequest(blueAdmin, http.MethodGet, "/breakers/payments/export")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `"State":"open"`)
+
+	r := httptest.NewRequest(http.MethodPost, "/breakers/payments/import", w.Body)
+	w = httptest.NewRecorder()
+	greenAdmin.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"State":"open"`)
+	assert.Equal(t, StateOpen, green.State())
+
+	// the protobuf encoding
+	green.Reset()
+	r = httptest.NewRequest(http.MethodGet, "/breakers/payments/export", nil)
+	r.Header.Set("Accept", "application/x-protobuf")
+	w = httptest.NewRecorder()
+	blueAdmin.ServeHTTP(w, r)
+	assert.Equal(t, "application/x-protobuf", w.Header().Get("Content-Type"))
+	assert.Equal(t, blue.Export().MarshalProto()[:10], w.Body.Bytes()[:10])
+
+	r = httptest.NewRequest(http.MethodPost, "/breakers/payments/import", bytes.NewReader(w.Body.Bytes()))
+	r.Header.Set("Content-Type", "application/x-protobuf")
+	w = httptest.NewRecorder()
+	greenAdmin.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, StateOpen, green.State())
+
+	r = httptest.NewRequest(http.MethodPost, "/breakers/payments/import", bytes.NewReader([]byte(`{"Version":9}`)))
+	w = httptest.NewRecorder()
+	greenAdmin.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"error":"unsupported circuit breaker export version 9"}`+"\n", w.Body.String())
+	assert.Equal(t, http.StatusMethodNotAllowed, adminRequest(greenAdmin, http.MethodPost, "/breakers/payments/export").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, adminRequest(greenAdmin, http.MethodGet, "/breakers/payments/import").Code)
+}
@@ -0,0 +1,43 @@
+package circuit_breaker
+
+import "context"
+
+// Allow is the two-step form of ExecuteContext for integrations that can't wrap the request in a function,
+// such as hooks split into "before" and "after" callbacks.
+//
+// If the request is admitted, Allow returns done, which must be called exactly once with the error of the request.
+// Otherwise it returns the rejection error and a nil done.
+//
+// While a coalesced half-open probe is in flight, Allow waits for it and then decides again, see Config.CoalesceHalfOpen.
+func (cb *CircuitBreaker) Allow(ctx context.Context) (done func(err error), err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := cb.checkDeadline(ctx); err != nil {
+		return nil, err
+	}
+
+	release := func() {}
+	if cb.bulkhead != nil {
+		if err := cb.bulkhead.acquire(ctx); err != nil {
+			cb.onBulkheadRejection(ctx, err)
+			return nil, err
+		}
+		release = cb.bulkhead.release
+	}
+
+	t, now, err := cb.admitRequest(ctx)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if t.bypass {
+		return func(error) { release() }, nil
+	}
+
+	return func(err error) {
+		defer release()
+		defer t.probe.complete()
+		cb.afterRequest(t, now, err)
+	}, nil
+}
@@ -0,0 +1,83 @@
+package circuit_breaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerAllow(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "two-step circuit breaker",
+		RequestThreshold:       1,
+		MaxConsecutiveFailures: 2,
+	})
+	ctx := context.Background()
+
+	done, err := cb.Allow(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cb.InFlight())
+	done(nil)
+	assert.Equal(t, 0, cb.InFlight())
+	assert.Equal(t, Counts{Requests: 1, TotalSuccesses: 1, ConsecutiveSuccesses: 1}, cb.Counts())
+
+	for i := 0; i < 2; i++ {
+		done, err = cb.Allow(ctx)
+		assert.Nil(t, err)
+		done(errServiceError)
+	}
+	assert.Equal(t, StateOpen, cb.State())
+
+	done, err = cb.Allow(ctx)
+	assert.Nil(t, done)
+	assert.Equal(t, ErrOpenState, err)
+
+	pseudoSleep(cb, DefaultTimeout)
+	done, err = cb.Allow(ctx)
+	assert.Nil(t, err)
+
+	// only one request is allowed in half-open state
+	_, err = cb.Allow(ctx)
+	assert.Equal(t, ErrTooManyRequests, err)
+
+	done(nil)
+	assert.Equal(t, StateClosed, cb.State())
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = cb.Allow(canceled)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestCircuitBreakerAllowCoalesceHalfOpen(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "coalescing circuit breaker",
+		RequestThreshold:       1,
+		MaxConsecutiveFailures: 1,
+		CoalesceHalfOpen:       true,
+	})
+	ctx := context.Background()
+
+	assert.Equal(t, errServiceError, fail(cb))
+	pseudoSleep(cb, DefaultTimeout)
+
+	probe, err := cb.Allow(ctx)
+	assert.Nil(t, err)
+
+	followed := make(chan error)
+	go func() {
+		done, err := cb.Allow(ctx)
+		if done != nil {
+			done(nil)
+		}
+		followed <- err
+	}()
+
+	// the follower is waiting for the probe
+	time.Sleep(10 * time.Millisecond)
+	probe(errServiceError)
+	assert.Equal(t, ErrOpenState, <-followed)
+	assert.Equal(t, StateOpen, cb.State())
+}
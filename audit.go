@@ -0,0 +1,119 @@
+package circuit_breaker
+
+import (
+	"fmt"
+	"time"
+)
+
+// Action is an administrative action on a CircuitBreaker, see Apply.
+type Action string
+
+// Administrative actions.
+const (
+	ActionTrip    Action = "trip"
+	ActionReset   Action = "reset"
+	ActionDisable Action = "disable"
+	ActionEnable  Action = "enable"
+)
+
+// AuditEntry records an administrative action: who applied it, when and why,
+// and the State of the CircuitBreaker before and after it.
+// Actor and Reason are empty when the action was applied by Trip, Reset, Disable or Enable.
+//
+// AuditEntry is also the Event of the action, delivered to SubscribeEvents.
+type AuditEntry struct {
+	Name   string
+	Action Action
+	Actor  string
+	Reason string
+	From   State
+	To     State
+	At     time.Time
+}
+
+func (AuditEntry) isEvent() {}
+
+// auditLog keeps the last administrative actions
+type auditLog struct {
+	size    int
+	entries []AuditEntry
+}
+
+func (a *auditLog) add(entry AuditEntry) {
+	if a.size <= 0 {
+		return
+	}
+
+	if len(a.entries) == a.size {
+		copy(a.entries, a.entries[1:])
+		a.entries = a.entries[:len(a.entries)-1]
+	}
+	a.entries = append(a.entries, entry)
+}
+
+// Apply performs the administrative action on behalf of the actor, e.g. the name of an operator, for the reason.
+// The action is recorded in the audit log, see AuditLog, delivered as an AuditEntry to SubscribeEvents
+// after the state change it caused, and logged at the warn level.
+// Apply returns an error only for an unknown action.
+func (cb *CircuitBreaker) Apply(action Action, actor, reason string) error {
+	now := time.Now()
+
+	cb.mu.Lock()
+	cb.refreshState(now)
+	from := cb.state
+	switch action {
+	case ActionTrip:
+		if cb.state != StateOpen {
+			cb.trip(now, ReasonManualTrip)
+		}
+	case ActionReset:
+		cb.reset(now)
+	case ActionDisable:
+		cb.disabled = true
+	case ActionEnable:
+		cb.disabled = false
+	default:
+		cb.unlock()
+		return fmt.Errorf("unknown circuit breaker action %q", action)
+	}
+
+	entry := AuditEntry{
+		Name:   cb.name,
+		Action: action,
+		Actor:  actor,
+		Reason: reason,
+		From:   from,
+		To:     cb.state,
+		At:     now,
+	}
+	cb.audit.add(entry)
+	listeners := cb.listeners
+	cb.unlock()
+
+	if cb.logger != nil {
+		cb.logAudit(entry)
+	}
+	emit(listeners, entry)
+
+	return nil
+}
+
+// AuditLog returns the last administrative actions applied to the CircuitBreaker, from the oldest to the newest,
+// up to AuditLogSize of them.
+func (cb *CircuitBreaker) AuditLog() []AuditEntry {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	return append([]AuditEntry(nil), cb.audit.entries...)
+}
+
+func (cb *CircuitBreaker) logAudit(entry AuditEntry) {
+	cb.logger.Warn("circuit breaker action applied",
+		"name", cb.name,
+		"action", string(entry.Action),
+		"actor", entry.Actor,
+		"reason", entry.Reason,
+		"from", entry.From.String(),
+		"to", entry.To.String(),
+	)
+}
@@ -0,0 +1,58 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerApply(t *testing.T) {
+	var events []Event
+	cb := NewCircuitBreaker(Config{
+		Name:         "payments",
+		AuditLogSize: 2,
+		OnEvent:      func(e Event) { events = append(events, e) },
+	})
+
+	assert.Nil(t, cb.Apply(ActionTrip, "alice", "incident 42"))
+	assert.Equal(t, StateOpen, cb.State())
+	assert.EqualError(t, cb.Apply("explode", "alice", ""), `unknown circuit breaker action "explode"`)
+
+	// the audit entry follows the state change it caused
+	assert.Len(t, events, 2)
+	assert.Equal(t, ReasonManualTrip, events[0].(StateChanged).Reason)
+	entry := events[1].(AuditEntry)
+	assert.False(t, entry.At.IsZero())
+	entry.At = time.Time{}
+	assert.Equal(t, AuditEntry{
+		Name:   "payments",
+		Action: ActionTrip,
+		Actor:  "alice",
+		Reason: "incident 42",
+		From:   StateClosed,
+		To:     StateOpen,
+	}, entry)
+
+	cb.Disable()
+	cb.Reset()
+	cb.Enable()
+
+	// only the last entries are kept
+	log := cb.AuditLog()
+	if assert.Len(t, log, 2) {
+		assert.Equal(t, ActionReset, log[0].Action)
+		assert.Equal(t, StateOpen, log[0].From)
+		assert.Equal(t, StateClosed, log[0].To)
+		assert.Equal(t, ActionEnable, log[1].Action)
+		assert.Equal(t, "", log[1].Actor)
+	}
+	assert.Len(t, events, 6)
+}
+
+func TestCircuitBreakerAuditLogDisabled(t *testing.T) {
+	cb := NewCircuitBreaker(Config{})
+
+	cb.Trip()
+	assert.Empty(t, cb.AuditLog())
+}
@@ -0,0 +1,76 @@
+package circuit_breaker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultStorageTimeout bounds the calls to the Storage, the Broadcaster and the ProbeElector
+// made by the CircuitBreaker on its own if StorageTimeout is not set.
+const DefaultStorageTimeout = time.Second
+
+// background runs the calls to the Storage, the Broadcaster and the ProbeElector off the request path,
+// one at a time and in the order of the state changes.
+// Its goroutine runs only while there are calls to make, so an idle CircuitBreaker holds none.
+type background struct {
+	mu      sync.Mutex
+	idle    *sync.Cond
+	queue   []func()
+	running bool
+}
+
+// run queues the call, starting the goroutine if it is not running.
+func (b *background) run(fn func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.queue = append(b.queue, fn)
+	if !b.running {
+		b.running = true
+		go b.drain()
+	}
+}
+
+func (b *background) drain() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for len(b.queue) > 0 {
+		fn := b.queue[0]
+		b.queue[0] = nil
+		b.queue = b.queue[1:]
+
+		b.mu.Unlock()
+		fn()
+		b.mu.Lock()
+	}
+	b.running = false
+	if b.idle != nil {
+		b.idle.Broadcast()
+	}
+}
+
+// wait blocks until the queued calls are made.
+func (b *background) wait() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.idle == nil {
+		b.idle = sync.NewCond(&b.mu)
+	}
+	for b.running {
+		b.idle.Wait()
+	}
+}
+
+// WaitBackground blocks until the state changes made so far are stored and published,
+// and the probe lease is resigned, e.g. on shutdown before the process exits.
+func (cb *CircuitBreaker) WaitBackground() {
+	cb.background.wait()
+}
+
+// storageContext returns the context bounding a call made by the CircuitBreaker on its own, see StorageTimeout.
+func (cb *CircuitBreaker) storageContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), cb.storageTimeout)
+}
@@ -0,0 +1,69 @@
+package circuit_breaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowStorage blocks every operation until its context is done
+type slowStorage struct {
+	stored chan StoredState
+}
+
+func (s slowStorage) Load(ctx context.Context, name string) (StoredState, bool, error) {
+	<-ctx.Done()
+	return StoredState{}, false, ctx.Err()
+}
+
+func (s slowStorage) Store(ctx context.Context, name string, state StoredState) error {
+	<-ctx.Done()
+	s.stored <- state
+	return ctx.Err()
+}
+
+func TestCircuitBreakerStorageTimeout(t *testing.T) {
+	logger := &recordingLogger{}
+	storage := slowStorage{stored: make(chan StoredState, 1)}
+
+	start := time.Now()
+	cb := NewCircuitBreaker(Config{
+		Name:                   "slow",
+		MaxConsecutiveFailures: 1,
+		Storage:                storage,
+		StorageTimeout:         50 * time.Millisecond,
+		Logger:                 logger,
+	})
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// the state change is stored in the background, the request doesn't wait for it
+	start = time.Now()
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+	assert.Equal(t, StateOpen, cb.State())
+
+	assert.Equal(t, StateOpen, (<-storage.stored).State)
+	cb.WaitBackground()
+	assert.Contains(t, logger.entries, "WARN circuit breaker storage failed name=slow error=context deadline exceeded")
+}
+
+func TestBackground(t *testing.T) {
+	var b background
+	b.wait()
+
+	var calls []int
+	release := make(chan struct{})
+	b.run(func() { <-release })
+	for i := 0; i < 3; i++ {
+		i := i
+		b.run(func() { calls = append(calls, i) })
+	}
+	close(release)
+	b.wait()
+	assert.Equal(t, []int{0, 1, 2}, calls)
+	assert.False(t, b.running)
+	assert.Empty(t, b.queue)
+}
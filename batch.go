@@ -0,0 +1,71 @@
+package circuit_breaker
+
+// Result is the outcome of a single request of a batch
+type Result struct {
+	Value interface{}
+	Err   error
+}
+
+// BatchPolicy decides whether a batch with partial failures is recorded as a failure
+type BatchPolicy uint32
+
+const (
+	// BatchAnyFailure records the batch as a failure if any request failed
+	BatchAnyFailure BatchPolicy = iota
+	// BatchMajorityFailure records the batch as a failure if more than half of the requests failed
+	BatchMajorityFailure
+	// BatchAllFailures records the batch as a failure only if every request failed
+	BatchAllFailures
+)
+
+// outcome returns the error recorded for the batch: the first failure if the batch failed, nil otherwise
+func (p BatchPolicy) outcome(results []Result) error {
+	var first error
+	failures := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failures++
+			if first == nil {
+				first = r.Err
+			}
+		}
+	}
+
+	switch {
+	case failures == 0:
+		return nil
+	case p == BatchMajorityFailure && failures*2 <= len(results):
+		return nil
+	case p == BatchAllFailures && failures < len(results):
+		return nil
+	default:
+		return first
+	}
+}
+
+// ExecuteBatch runs the requests one after another under a single admission decision,
+// and records the aggregate outcome of the batch as one request according to the BatchPolicy of the Config.
+// If the batch is rejected, every Result holds the rejection error.
+func (cb *CircuitBreaker) ExecuteBatch(reqs []func() (interface{}, error)) []Result {
+	if len(reqs) == 0 {
+		return nil
+	}
+
+	results := make([]Result, len(reqs))
+	admitted := false
+	_, err := cb.Execute(func() (interface{}, error) {
+		admitted = true
+		for i, req := range reqs {
+			results[i].Value, results[i].Err = req()
+		}
+		return nil, cb.batchPolicy.outcome(results)
+	})
+
+	if !admitted {
+		for i := range results {
+			results[i].Err = err
+		}
+	}
+
+	return results
+}
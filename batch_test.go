@@ -0,0 +1,46 @@
+package circuit_breaker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchPolicy(t *testing.T) {
+	errOther := errors.New("other error")
+	partial := []Result{{Err: errServiceError}, {Value: 1}, {Err: errOther}}
+	majority := []Result{{Err: errServiceError}, {Value: 1}, {Value: 2}}
+	all := []Result{{Err: errServiceError}, {Err: errOther}}
+
+	assert.Nil(t, BatchAnyFailure.outcome([]Result{{Value: 1}}))
+	assert.Equal(t, errServiceError, BatchAnyFailure.outcome(majority))
+	assert.Equal(t, errServiceError, BatchMajorityFailure.outcome(partial))
+	assert.Nil(t, BatchMajorityFailure.outcome(majority))
+	assert.Nil(t, BatchAllFailures.outcome(partial))
+	assert.Equal(t, errServiceError, BatchAllFailures.outcome(all))
+}
+
+func TestCircuitBreakerExecuteBatch(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "batch circuit breaker",
+		MaxConsecutiveFailures: 2,
+		BatchPolicy:            BatchMajorityFailure,
+	})
+
+	ok := func() (interface{}, error) { return "ok", nil }
+	bad := func() (interface{}, error) { return nil, errServiceError }
+
+	assert.Nil(t, cb.ExecuteBatch(nil))
+
+	results := cb.ExecuteBatch([]func() (interface{}, error){ok, bad, ok})
+	assert.Equal(t, []Result{{Value: "ok"}, {Err: errServiceError}, {Value: "ok"}}, results)
+	assert.Equal(t, Counts{1, 1, 0, 1, 0}, cb.Counts())
+
+	cb.ExecuteBatch([]func() (interface{}, error){bad, bad, ok})
+	cb.ExecuteBatch([]func() (interface{}, error){bad, bad, bad})
+	assert.Equal(t, StateOpen, cb.State())
+
+	results = cb.ExecuteBatch([]func() (interface{}, error){ok, ok})
+	assert.Equal(t, []Result{{Err: ErrOpenState}, {Err: ErrOpenState}}, results)
+}
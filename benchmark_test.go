@@ -0,0 +1,78 @@
+package circuit_breaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func succeedRequest() (interface{}, error) { return nil, nil }
+
+func failRequest() (interface{}, error) { return nil, errServiceError }
+
+func BenchmarkExecuteClosed(b *testing.B) {
+	cb := NewCircuitBreaker(Config{Name: "benchmark circuit breaker"})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = cb.Execute(succeedRequest)
+	}
+}
+
+func BenchmarkExecuteParallel(b *testing.B) {
+	cb := NewCircuitBreaker(Config{Name: "benchmark circuit breaker"})
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = cb.Execute(succeedRequest)
+		}
+	})
+}
+
+func BenchmarkExecuteOpen(b *testing.B) {
+	cb := NewCircuitBreaker(Config{Name: "benchmark circuit breaker", MaxConsecutiveFailures: 1})
+	_, _ = cb.Execute(failRequest)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = cb.Execute(succeedRequest)
+	}
+}
+
+func BenchmarkStateTransitions(b *testing.B) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "benchmark circuit breaker",
+		RequestThreshold:       1,
+		MaxConsecutiveFailures: 1,
+	})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = cb.Execute(failRequest) // StateClosed -> StateOpen
+		pseudoSleep(cb, DefaultTimeout)
+		_, _ = cb.Execute(succeedRequest) // StateOpen -> StateHalfOpen -> StateClosed
+	}
+}
+
+func TestExecuteSuccessAllocs(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "allocs circuit breaker", WarmupDuration: time.Minute})
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = cb.Execute(succeedRequest)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+func TestExecuteContextSuccessAllocs(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "allocs circuit breaker", RejectInsufficientDeadline: true})
+	ctx := context.Background()
+	req := func(ctx context.Context) (interface{}, error) { return nil, nil }
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = cb.ExecuteContext(ctx, req)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
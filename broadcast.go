@@ -0,0 +1,183 @@
+package circuit_breaker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ReasonBroadcast is the reason of the state changes adopted from the other instances, see BroadcastState.
+const ReasonBroadcast = "broadcast by a peer"
+
+// StateBroadcast is a state change of a breaker announced to the other instances of the service.
+// Origin identifies the instance which changed its state, and State is the state it moved to.
+type StateBroadcast struct {
+	Name   string
+	Origin string
+	State  StoredState
+}
+
+// Broadcaster delivers the state changes of the breakers to all the instances of the service,
+// e.g. by Redis pub/sub, so when one instance trips, the rest of the fleet opens immediately
+// instead of each one burning failures to discover the outage, see BroadcastState.
+type Broadcaster interface {
+	// Publish announces the state change to all the subscribers, including the ones of the publishing instance.
+	Publish(ctx context.Context, b StateBroadcast) error
+	// Subscribe returns the channel delivering the broadcasts of the breaker with the name.
+	// The channel is closed when the broadcaster stops.
+	Subscribe(name string) <-chan StateBroadcast
+}
+
+// BroadcastState publishes the state changes of the CircuitBreaker by the broadcaster,
+// and adopts the state changes published by the other instances, until stop is called or the broadcaster stops.
+//
+// origin identifies the instance, e.g. its host name, and must differ between the instances.
+// The state changes adopted from the peers are not published again.
+// A broadcast is adopted only if it changes the state and is more recent than the last local state change,
+// so the clocks of the instances must be roughly in sync.
+// The state changes are published in the background, bounded by the StorageTimeout of the CircuitBreaker,
+// and the publishing errors are logged by the Logger.
+func BroadcastState(cb *CircuitBreaker, b Broadcaster, origin string) (stop func()) {
+	unsubscribe := cb.subscribe(&listener{change: func(name string, change stateChange) {
+		if change.reason == ReasonBroadcast || change.reason == ReasonSynced {
+			return
+		}
+		msg := StateBroadcast{Name: name, Origin: origin, State: change.stored}
+		cb.background.run(func() {
+			ctx, cancel := cb.storageContext()
+			defer cancel()
+
+			if err := b.Publish(ctx, msg); err != nil && cb.logger != nil {
+				cb.logger.Warn("circuit breaker broadcast failed", "name", name, "error", err)
+			}
+		})
+	}})
+
+	broadcasts := b.Subscribe(cb.name)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case msg, ok := <-broadcasts:
+				if !ok {
+					return
+				}
+				// the broadcast may be received together with stop
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if msg.Origin != origin {
+					cb.adoptBroadcast(msg.State)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			unsubscribe()
+			close(done)
+		})
+	}
+}
+
+// adoptBroadcast moves the CircuitBreaker into the state announced by a peer.
+func (cb *CircuitBreaker) adoptBroadcast(s StoredState) {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	now := time.Now()
+	cb.refreshState(now)
+	if s.State == cb.state || !s.ChangedAt.After(cb.changedAt) {
+		return
+	}
+	cb.adopt(s, ReasonBroadcast, now)
+}
+
+// hubBuffer is the number of the broadcasts a subscriber of the BroadcastHub may fall behind
+const hubBuffer = 16
+
+// BroadcastHub is the Broadcaster within the process, delivering the published broadcasts to the subscribers,
+// e.g. in tests, or by the broadcasters of the remote transports, see Deliver.
+// A slow subscriber loses the oldest of the broadcasts it has not received once they don't fit its buffer,
+// so the broadcasts of one instance don't push out the ones of the others.
+// BroadcastHub is safe for concurrent use.
+type BroadcastHub struct {
+	mu          sync.Mutex
+	subscribers map[string][]chan StateBroadcast
+	closed      bool
+}
+
+var _ Broadcaster = (*BroadcastHub)(nil)
+
+func NewBroadcastHub() *BroadcastHub {
+	return &BroadcastHub{subscribers: make(map[string][]chan StateBroadcast)}
+}
+
+// Publish implements Broadcaster by delivering the broadcast.
+func (h *BroadcastHub) Publish(ctx context.Context, b StateBroadcast) error {
+	h.Deliver(b)
+	return nil
+}
+
+// Subscribe implements Broadcaster.
+func (h *BroadcastHub) Subscribe(name string) <-chan StateBroadcast {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan StateBroadcast, hubBuffer)
+	if h.closed {
+		close(ch)
+		return ch
+	}
+	h.subscribers[name] = append(h.subscribers[name], ch)
+
+	return ch
+}
+
+// Deliver delivers the broadcast to the subscribers of its breaker.
+func (h *BroadcastHub) Deliver(b StateBroadcast) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	for _, ch := range h.subscribers[b.Name] {
+		for {
+			select {
+			case ch <- b:
+			default:
+				// drop the oldest broadcast the subscriber has not received yet
+				select {
+				case <-ch:
+				default:
+				}
+				continue
+			}
+			break
+		}
+	}
+}
+
+// Close stops the hub, closing the channels of the subscribers.
+func (h *BroadcastHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	h.closed = true
+	for _, subscribers := range h.subscribers {
+		for _, ch := range subscribers {
+			close(ch)
+		}
+	}
+	h.subscribers = nil
+}
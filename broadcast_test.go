@@ -0,0 +1,105 @@
+package circuit_breaker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingBroadcaster counts the broadcasts published to the hub
+type countingBroadcaster struct {
+	*BroadcastHub
+
+	mu        sync.Mutex
+	published []StateBroadcast
+}
+
+func (b *countingBroadcaster) Publish(ctx context.Context, msg StateBroadcast) error {
+	b.mu.Lock()
+	b.published = append(b.published, msg)
+	b.mu.Unlock()
+
+	return b.BroadcastHub.Publish(ctx, msg)
+}
+
+func (b *countingBroadcaster) origins() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var origins []string
+	for _, msg := range b.published {
+		origins = append(origins, msg.Origin+": "+msg.State.State.String())
+	}
+	return origins
+}
+
+func TestBroadcastState(t *testing.T) {
+	hub := &countingBroadcaster{BroadcastHub: NewBroadcastHub()}
+	defer hub.Close()
+	cfg := Config{Name: "fleet", MaxConsecutiveFailures: 1, RequestThreshold: 1, Timeout: time.Minute}
+	a := NewCircuitBreaker(cfg)
+	b := NewCircuitBreaker(cfg)
+	stopA := BroadcastState(a, hub, "a")
+	defer stopA()
+	stopB := BroadcastState(b, hub, "b")
+	defer stopB()
+
+	// b opens as soon as a trips
+	assert.Equal(t, errServiceError, fail(a))
+	assert.Eventually(t, func() bool { return b.State() == StateOpen }, time.Second, time.Millisecond)
+	assert.Equal(t, a.OpensAt(), b.OpensAt())
+	assert.Equal(t, ErrOpenState, succeed(b))
+
+	// b recovers first and closes a
+	pseudoSleep(b, time.Minute)
+	assert.Nil(t, succeed(b))
+	assert.Equal(t, StateClosed, b.State())
+	assert.Eventually(t, func() bool { return a.State() == StateClosed }, time.Second, time.Millisecond)
+
+	// the adopted state changes are not published again
+	assert.Equal(t, []string{"a: open", "b: half-open", "b: closed"}, hub.origins())
+
+	// an outdated broadcast is ignored
+	hub.Deliver(StateBroadcast{Name: "fleet", Origin: "c", State: StoredState{State: StateOpen, ChangedAt: time.Now().Add(-time.Hour)}})
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, StateClosed, a.State())
+	assert.Equal(t, StateClosed, b.State())
+
+	// nothing is received after stop
+	stopB()
+	assert.Equal(t, errServiceError, fail(a))
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBroadcastHub(t *testing.T) {
+	hub := NewBroadcastHub()
+	ch := hub.Subscribe("hub")
+	assert.Nil(t, hub.Publish(context.Background(), StateBroadcast{Name: "hub", Origin: "a"}))
+	assert.Nil(t, hub.Publish(context.Background(), StateBroadcast{Name: "hub", Origin: "b"}))
+	hub.Deliver(StateBroadcast{Name: "other"})
+
+	assert.Equal(t, "a", (<-ch).Origin)
+	assert.Equal(t, "b", (<-ch).Origin)
+
+	// a slow subscriber loses the oldest broadcasts
+	for i := 0; i < hubBuffer+2; i++ {
+		hub.Deliver(StateBroadcast{Name: "hub", State: StoredState{Version: uint64(i)}})
+	}
+	assert.Equal(t, uint64(2), (<-ch).State.Version)
+
+	// the pending broadcasts are received before the channel is closed
+	hub.Close()
+	pending := 0
+	for range ch {
+		pending++
+	}
+	assert.Equal(t, hubBuffer-1, pending)
+	_, ok := <-ch
+	assert.False(t, ok)
+	_, ok = <-hub.Subscribe("hub")
+	assert.False(t, ok)
+}
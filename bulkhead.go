@@ -0,0 +1,80 @@
+package circuit_breaker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBulkheadFull is returned when the maximum number of concurrent requests is reached
+// and the request can't wait for a free slot
+var ErrBulkheadFull = errors.New("bulkhead is full")
+
+// Bulkhead caps the number of concurrently executing requests.
+//
+// MaxConcurrent is the maximum number of requests executing at the same time. Zero disables the bulkhead.
+//
+// MaxWaiting is the maximum number of requests waiting for a free slot.
+// If MaxWaiting is zero, the requests over MaxConcurrent are rejected immediately.
+//
+// MaxWait is the maximum time a request waits for a free slot.
+// If MaxWait is zero, the request waits until its context is done.
+type Bulkhead struct {
+	MaxConcurrent uint32
+	MaxWaiting    uint32
+	MaxWait       time.Duration
+}
+
+type bulkhead struct {
+	Bulkhead
+
+	slots   chan struct{}
+	waiting int32
+}
+
+func newBulkhead(cfg Bulkhead) *bulkhead {
+	if cfg.MaxConcurrent == 0 {
+		return nil
+	}
+
+	return &bulkhead{
+		Bulkhead: cfg,
+		slots:    make(chan struct{}, cfg.MaxConcurrent),
+	}
+}
+
+// acquire takes a slot, waiting for it in the queue if allowed
+func (b *bulkhead) acquire(ctx context.Context) error {
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if atomic.AddInt32(&b.waiting, 1) > int32(b.MaxWaiting) {
+		atomic.AddInt32(&b.waiting, -1)
+		return ErrBulkheadFull
+	}
+	defer atomic.AddInt32(&b.waiting, -1)
+
+	var timeout <-chan time.Time
+	if b.MaxWait > 0 {
+		timer := time.NewTimer(b.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-timeout:
+		return ErrBulkheadFull
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *bulkhead) release() {
+	<-b.slots
+}
@@ -0,0 +1,74 @@
+package circuit_breaker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// occupy runs a request holding a bulkhead slot until release is closed
+func occupy(cb *CircuitBreaker, release chan struct{}) {
+	started := make(chan struct{})
+	go func() {
+		_, _ = cb.Execute(func() (interface{}, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+	}()
+	<-started
+}
+
+func TestCircuitBreakerBulkheadReject(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:     "bulkhead circuit breaker",
+		Bulkhead: Bulkhead{MaxConcurrent: 2},
+	})
+
+	release := make(chan struct{})
+	occupy(cb, release)
+	occupy(cb, release)
+
+	assert.Equal(t, ErrBulkheadFull, succeed(cb))
+
+	close(release)
+	assert.Eventually(t, func() bool { return succeed(cb) == nil }, time.Second, time.Millisecond)
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestCircuitBreakerBulkheadQueue(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:     "bulkhead circuit breaker",
+		Bulkhead: Bulkhead{MaxConcurrent: 1, MaxWaiting: 1, MaxWait: 20 * time.Millisecond},
+	})
+
+	release := make(chan struct{})
+	occupy(cb, release)
+
+	// the queued request times out
+	assert.Equal(t, ErrBulkheadFull, succeed(cb))
+
+	// the queued request gets the slot once it is free
+	waited := make(chan error)
+	go func() { waited <- succeed(cb) }()
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&cb.bulkhead.waiting) == 1 }, time.Second, time.Millisecond)
+
+	// the queue is full
+	assert.Equal(t, ErrBulkheadFull, succeed(cb))
+
+	close(release)
+	assert.Nil(t, <-waited)
+
+	// the context is done while waiting
+	cb.bulkhead.MaxWait = 0
+	release = make(chan struct{})
+	defer close(release)
+	occupy(cb, release)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) { return nil, nil })
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
@@ -0,0 +1,69 @@
+package circuit_breaker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+)
+
+// ErrorCategory groups request errors for per-category trip thresholds
+type ErrorCategory string
+
+const (
+	CategoryTimeout     ErrorCategory = "timeout"
+	CategoryConnection  ErrorCategory = "connection"
+	CategoryServer      ErrorCategory = "server"
+	CategoryApplication ErrorCategory = "application"
+)
+
+// DefaultErrorCategorizer detects timeouts, connection errors and the 5xx responses reported by StatusError,
+// any other error is considered an application one.
+func DefaultErrorCategorizer(err error) ErrorCategory {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return CategoryTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return CategoryTimeout
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET):
+		return CategoryConnection
+	case isServerError(err):
+		return CategoryServer
+	default:
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return CategoryConnection
+		}
+		return CategoryApplication
+	}
+}
+
+func isServerError(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode >= 500 && statusErr.StatusCode < 600
+}
+
+// categoryCounts holds the number of failures of every category since the last success
+type categoryCounts map[ErrorCategory]uint32
+
+func (c *categoryCounts) onSuccess() {
+	c.reset()
+}
+
+func (c *categoryCounts) onFailure(category ErrorCategory) {
+	if *c == nil {
+		*c = make(categoryCounts)
+	}
+	(*c)[category]++
+}
+
+func (c *categoryCounts) reset() {
+	*c = nil
+}
+
+// readyToTripCategory reports whether the failures of the category reached its threshold
+func (cb *CircuitBreaker) readyToTripCategory(category ErrorCategory) bool {
+	threshold, ok := cb.categoryThresholds[category]
+	return ok && cb.categoryCounts[category] >= threshold
+}
@@ -0,0 +1,86 @@
+package circuit_breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultErrorCategorizer(t *testing.T) {
+	assert.Equal(t, CategoryTimeout, DefaultErrorCategorizer(context.DeadlineExceeded))
+	assert.Equal(t, CategoryTimeout, DefaultErrorCategorizer(fmt.Errorf("call: %w", context.DeadlineExceeded)))
+	assert.Equal(t, CategoryConnection, DefaultErrorCategorizer(syscall.ECONNREFUSED))
+	assert.Equal(t, CategoryConnection, DefaultErrorCategorizer(&net.OpError{Op: "dial", Err: errors.New("no route")}))
+	assert.Equal(t, CategoryServer, DefaultErrorCategorizer(&StatusError{StatusCode: http.StatusBadGateway}))
+	assert.Equal(t, CategoryServer, DefaultErrorCategorizer(fmt.Errorf("call: %w", &StatusError{StatusCode: 500})))
+	assert.Equal(t, CategoryApplication, DefaultErrorCategorizer(&StatusError{StatusCode: http.StatusTooManyRequests}))
+	assert.Equal(t, CategoryApplication, DefaultErrorCategorizer(errServiceError))
+}
+
+func TestCircuitBreakerCategoryThresholds(t *testing.T) {
+	timeout := func(cb *CircuitBreaker) error {
+		_, err := cb.Execute(func() (interface{}, error) { return nil, context.DeadlineExceeded })
+		return err
+	}
+
+	cb := NewCircuitBreaker(Config{
+		Name: "category circuit breaker",
+		CategoryThresholds: map[ErrorCategory]uint32{
+			CategoryTimeout: 3,
+		},
+	})
+
+	// application errors use the default ReadyToTrip
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, errServiceError, fail(cb))
+	}
+	assert.Equal(t, StateClosed, cb.state)
+	assert.Nil(t, succeed(cb))
+
+	// every category is counted on its own
+	assert.Equal(t, context.DeadlineExceeded, timeout(cb))
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, context.DeadlineExceeded, timeout(cb))
+	assert.Equal(t, categoryCounts{CategoryTimeout: 2, CategoryApplication: 1}, cb.categoryCounts)
+
+	// a success interrupts the sequences
+	assert.Nil(t, succeed(cb))
+	assert.Empty(t, cb.categoryCounts)
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, context.DeadlineExceeded, timeout(cb))
+	}
+	assert.Equal(t, StateClosed, cb.state)
+	assert.Equal(t, context.DeadlineExceeded, timeout(cb))
+	assert.Equal(t, StateOpen, cb.state)
+	assert.Empty(t, cb.categoryCounts)
+}
+
+func TestCircuitBreakerCategoryThresholdsInterleaved(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "interleaved category circuit breaker",
+		MaxConsecutiveFailures: 100,
+		CategoryThresholds: map[ErrorCategory]uint32{
+			CategoryServer: 3,
+		},
+	})
+	serverError := func() error {
+		_, err := cb.Execute(func() (interface{}, error) { return nil, &StatusError{StatusCode: 503} })
+		return err
+	}
+
+	// the interleaved timeouts don't hide the run of 5xx responses
+	for i := 0; i < 2; i++ {
+		assert.NotNil(t, serverError())
+		_, _ = cb.Execute(func() (interface{}, error) { return nil, context.DeadlineExceeded })
+	}
+	assert.Equal(t, StateClosed, cb.State())
+	assert.NotNil(t, serverError())
+	assert.Equal(t, StateOpen, cb.State())
+}
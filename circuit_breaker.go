@@ -1,6 +1,7 @@
 package circuit_breaker
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -15,9 +16,16 @@ const (
 	StateHalfOpen
 )
 
-const (
-	defaultConsecutiveFailures = 5
-	defaultTimeout             = 60 * time.Second
+// The package defaults, see DefaultConfig.
+// They may be changed by the application before creating the breakers, but not concurrently with their use.
+var (
+	// DefaultMaxConsecutiveFailures is the number of consecutive failures after which the CircuitBreaker trips
+	// if neither ReadyToTrip nor MaxConsecutiveFailures is set
+	DefaultMaxConsecutiveFailures uint32 = 6
+	// DefaultTimeout is the period of the open state if Timeout is not set
+	DefaultTimeout = 60 * time.Second
+	// DefaultRequestThreshold is the number of the half-open requests if RequestThreshold is not set
+	DefaultRequestThreshold uint32 = 1
 )
 
 var (
@@ -25,6 +33,10 @@ var (
 	ErrTooManyRequests = errors.New("too many requests")
 	// ErrOpenState is returned when the CB state is open
 	ErrOpenState = errors.New("circuit breaker is open")
+
+	errPanic = errors.New("panic in protected call")
+	// errAbandoned is the outcome of a request whose result is no longer needed, which is not recorded
+	errAbandoned = errors.New("abandoned call")
 )
 
 func (state State) String() string {
@@ -40,6 +52,27 @@ func (state State) String() string {
 	}
 }
 
+// MarshalText encodes the state by its name, e.g. in the JSON snapshots.
+func (state State) MarshalText() ([]byte, error) {
+	return []byte(state.String()), nil
+}
+
+// UnmarshalText decodes the state from its name.
+func (state *State) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "closed":
+		*state = StateClosed
+	case "half-open":
+		*state = StateHalfOpen
+	case "open":
+		*state = StateOpen
+	default:
+		return fmt.Errorf("unknown circuit breaker state %q", text)
+	}
+
+	return nil
+}
+
 type Counts struct {
 	Requests             uint32
 	TotalSuccesses       uint32
@@ -73,128 +106,691 @@ func (c *Counts) reset() {
 }
 
 // RequestThreshold is the maximum number of requests allowed to pass through
-// when the CircuitBreaker is half-opened
+// when the CircuitBreaker is half-opened, DefaultRequestThreshold if it is not set.
 //
 // Timeout is the period of the open state,
 // after which the state of the CircuitBreaker becomes half-open.
 //
 // ReadyToTrip is called with a copy of Counts whenever a request fails in the closed state.
 // If ReadyToTrip returns true, the CircuitBreaker will be placed into the open state.
-// If ReadyToTrip is nil, default ReadyToTrip is used.
-// Default ReadyToTrip returns true when the number of consecutive failures is more than 5.
+// If ReadyToTrip is nil, DefaultReadyToTrip is used.
+//
+// MaxConsecutiveFailures is a shortcut for the most common ReadyToTrip:
+// open after N consecutive failures. It is ignored if ReadyToTrip is set.
+// If neither is set, the CircuitBreaker opens after DefaultMaxConsecutiveFailures consecutive failures.
+//
+// WarmupDuration is the grace period after creation or Reset,
+// during which the CircuitBreaker will not trip regardless of failures.
+//
+// MaintenanceWindows are the planned periods during which the CircuitBreaker
+// is forced open or disabled.
+//
+// CategoryThresholds is the number of failures of an ErrorCategory since the last success
+// after which the CircuitBreaker trips, regardless of ReadyToTrip.
+// Every category is counted on its own, so the failures of other categories don't interrupt its sequence.
+// Errors are categorized by ErrorCategorizer, or by DefaultErrorCategorizer if it is nil.
+//
+// FailureStatusCodes and IgnoreStatusCodes configure the HTTPClassifier used by ExecuteHTTP.
+//
+// RejectInsufficientDeadline makes ExecuteContext reject the requests whose remaining
+// context deadline is shorter than the observed p95 latency of the protected call.
+//
+// BatchPolicy decides how ExecuteBatch records a batch with partial failures.
+//
+// CoalesceHalfOpen makes the callers in the half-open state wait for the single probe request in flight
+// instead of hitting the recovering backend independently. Once the probe is over, each caller is admitted again:
+// it runs its own request if the CircuitBreaker closed, or is rejected if it opened.
+// The waiting callers give up when their context is done.
+//
+// LoadShedding configures the shedding of low priority requests made by ExecuteContext,
+// see WithPriority.
+//
+// Bulkhead caps the number of concurrently executing requests.
+//
+// ConcurrencyLimiter adapts the maximum number of requests in flight, see AIMDLimiter.
+// The requests over the limit are rejected with ErrLimitExceeded.
+//
+// RateLimiter bounds the rate of admitted requests, see TokenBucket.
+// The requests over the rate are rejected with ErrRateLimited.
+//
+// Logger logs the rejected requests, the state changes and the outcomes of the half-open probes, see SlogLogger.
+//
+// HistorySize is the number of the last state changes kept for History. Zero disables the history.
+//
+// RecentErrorsSize is the number of the last distinct errors kept for RecentErrors. Zero disables them.
+//
+// AuditLogSize is the number of the last administrative actions kept for AuditLog. Zero disables the audit log,
+// but the actions are still delivered as events.
+//
+// SlowCallThreshold is the latency above which a request counts as slow in the Stats of the Snapshot.
+// Zero disables the accounting of slow requests.
+//
+// LatencyHistogram records the duration of every completed request, see NewBucketHistogram.
+// Its state is included in the Snapshot.
+//
+// Policy replaces the built-in state machine strategy.
+// If Policy is nil, DefaultPolicy built from ReadyToTrip, MaxConsecutiveFailures,
+// RequestThreshold and Timeout is used.
+//
+// Storage keeps the state of the CircuitBreaker outside of the process, see StoredState.
+// The stored state is adopted on creation and by SyncState, and the state is stored on every state change.
+// The storage errors are logged, and the CircuitBreaker keeps going on its local state.
+// The state changes are stored off the request path, in the background.
+//
+// StorageTimeout bounds every call to the Storage, the Broadcaster of BroadcastState and the ProbeElector
+// made by the CircuitBreaker on its own, e.g. the sync on creation, DefaultStorageTimeout by default.
+// It also bounds the probe elections, made with the context of the request.
+//
+// ProbeElector elects the single instance of the fleet probing the half-open state,
+// for ProbeLease at most, DefaultProbeLease by default. The other instances reject the half-open requests
+// with ErrTooManyRequests and wait for the result of the probes, which they adopt by BroadcastState,
+// or elect another prober once the lease is over. If the election fails, the CircuitBreaker probes on its own.
+//
+// Labels are the dimensions of the CircuitBreaker in metrics, e.g. tier or region, see Snapshot.
+//
+// Critical marks a dependency the service cannot work without, see HealthHandler.
+//
+// OnEvent receives all the events of the CircuitBreaker, see SubscribeEvents.
+//
+// CorrelationID extracts the correlation ID of the requests made by ExecuteContext from their context,
+// e.g. the trace ID of the span, attached to their events and log entries.
+// If CorrelationID is nil, CorrelationIDFromContext is used.
+//
+// Enabled gates the enforcement, e.g. by a feature flag, and is called on every admission.
+// While it returns false, the CircuitBreaker keeps its state machine and statistics,
+// but runs the requests it would reject without accounting, counting them in Totals.Unenforced.
+// The rejections of the Bulkhead and of RejectInsufficientDeadline are still enforced.
+// If Enabled is nil, the rejections are always enforced.
 
 type CircuitBreaker struct {
-	mu               sync.Mutex
-	name             string
-	requestThreshold uint32
-	timeout          time.Duration
-	readyToTrip      func(counts Counts) bool
-	onStateChange    func(name string, from State, to State)
-
-	state     State
-	counts    Counts
-	expiredAt time.Time
+	mu                 sync.Mutex
+	name               string
+	labels             map[string]string
+	critical           bool
+	requestThreshold   uint32
+	policy             Policy
+	onStateChange      func(name string, from State, to State)
+	warmupDuration     time.Duration
+	maintenanceWindows []MaintenanceWindow
+	errorCategorizer   func(err error) ErrorCategory
+	categoryThresholds map[ErrorCategory]uint32
+	httpClassifier     HTTPClassifier
+	correlationID      func(ctx context.Context) string
+	enabled            func() bool
+
+	rejectInsufficientDeadline bool
+	batchPolicy                BatchPolicy
+	coalesceHalfOpen           bool
+	loadShedding               LoadShedding
+	bulkhead                   *bulkhead
+	concurrencyLimiter         ConcurrencyLimiter
+	rateLimiter                RateLimiter
+	logger                     Logger
+	slowCallThreshold          time.Duration
+	latencyHistogram           Histogram
+	storage                    Storage
+	storageTimeout             time.Duration
+	probeElector               ProbeElector
+	probeLease                 time.Duration
+
+	state       State
+	counts      Counts
+	totals      Totals
+	changedAt   time.Time
+	history     history
+	audit       auditLog
+	lastErr     error
+	window      windowStats
+	expiredAt   time.Time
+	warmupUntil time.Time
+	trips       uint32
+	disabled    bool
+
+	generation     uint64
+	inFlight       int
+	drained        chan struct{}
+	categoryCounts categoryCounts
+	latencies      latencySample
+	recentErrors   recentErrors
+	probe          *probeCall
+	shedWindow     *Window
+	sync           storageSync
+	election       probeElection
+	background     background
+
+	listeners []*listener
+	pending   []stateChange
+	spare     []stateChange
+	notifying bool
 }
 
 type Config struct {
 	Name             string
 	RequestThreshold uint32
 	Timeout          time.Duration
+	WarmupDuration   time.Duration
+
+	MaintenanceWindows []MaintenanceWindow
+
+	MaxConsecutiveFailures uint32
+
+	ErrorCategorizer   func(err error) ErrorCategory
+	CategoryThresholds map[ErrorCategory]uint32
+
+	FailureStatusCodes []int
+	IgnoreStatusCodes  []int
+
+	RejectInsufficientDeadline bool
+	BatchPolicy                BatchPolicy
+	CoalesceHalfOpen           bool
+	LoadShedding               LoadShedding
+	Bulkhead                   Bulkhead
+	ConcurrencyLimiter         ConcurrencyLimiter
+	RateLimiter                RateLimiter
+	Logger                     Logger
+	HistorySize                int
+	RecentErrorsSize           int
+	AuditLogSize               int
+	SlowCallThreshold          time.Duration
+	LatencyHistogram           Histogram
 
 	ReadyToTrip   func(counts Counts) bool
 	OnStateChange func(name string, from State, to State)
+	OnEvent       func(e Event)
+	CorrelationID func(ctx context.Context) string
+	Enabled       func() bool
+
+	Policy Policy
+
+	Storage        Storage
+	StorageTimeout time.Duration
+	ProbeElector   ProbeElector
+	ProbeLease     time.Duration
+
+	Labels   map[string]string
+	Critical bool
+}
+
+// DefaultConfig returns the Config with the package defaults spelled out,
+// a starting point to change only the settings which differ:
+// it trips after DefaultMaxConsecutiveFailures consecutive failures, stays open for DefaultTimeout,
+// and closes after DefaultRequestThreshold successful half-open requests.
+func DefaultConfig() Config {
+	return Config{
+		RequestThreshold:       DefaultRequestThreshold,
+		Timeout:                DefaultTimeout,
+		MaxConsecutiveFailures: DefaultMaxConsecutiveFailures,
+	}
 }
 
 func NewCircuitBreaker(cfg Config) *CircuitBreaker {
 	cb := CircuitBreaker{
-		name:             cfg.Name,
-		requestThreshold: cfg.RequestThreshold,
-		timeout:          cfg.Timeout,
-		readyToTrip:      cfg.ReadyToTrip,
-		onStateChange:    cfg.OnStateChange,
-		state:            StateClosed,
-		counts:           Counts{},
+		name:               cfg.Name,
+		labels:             copyLabels(cfg.Labels),
+		critical:           cfg.Critical,
+		requestThreshold:   requestThresholdOf(cfg),
+		policy:             policyOf(cfg),
+		onStateChange:      cfg.OnStateChange,
+		correlationID:      cfg.CorrelationID,
+		enabled:            cfg.Enabled,
+		warmupDuration:     cfg.WarmupDuration,
+		maintenanceWindows: cfg.MaintenanceWindows,
+		errorCategorizer:   cfg.ErrorCategorizer,
+		categoryThresholds: cfg.CategoryThresholds,
+		httpClassifier: HTTPClassifier{
+			FailureStatusCodes: cfg.FailureStatusCodes,
+			IgnoreStatusCodes:  cfg.IgnoreStatusCodes,
+		},
+		rejectInsufficientDeadline: cfg.RejectInsufficientDeadline,
+		batchPolicy:                cfg.BatchPolicy,
+		coalesceHalfOpen:           cfg.CoalesceHalfOpen,
+		loadShedding:               cfg.LoadShedding,
+		bulkhead:                   newBulkhead(cfg.Bulkhead),
+		concurrencyLimiter:         cfg.ConcurrencyLimiter,
+		rateLimiter:                cfg.RateLimiter,
+		logger:                     cfg.Logger,
+		history:                    newHistory(cfg.HistorySize),
+		recentErrors:               recentErrors{size: cfg.RecentErrorsSize},
+		audit:                      auditLog{size: cfg.AuditLogSize},
+		slowCallThreshold:          cfg.SlowCallThreshold,
+		latencyHistogram:           cfg.LatencyHistogram,
+		storage:                    cfg.Storage,
+		storageTimeout:             cfg.StorageTimeout,
+		probeElector:               cfg.ProbeElector,
+		probeLease:                 cfg.ProbeLease,
+		state:                      StateClosed,
+		counts:                     Counts{},
+	}
+
+	if cfg.OnEvent != nil {
+		cb.listeners = []*listener{{event: cfg.OnEvent}}
 	}
 
-	if cb.readyToTrip == nil {
-		cb.readyToTrip = defaultReadyToTrip
+	if cb.loadShedding.enabled() {
+		if cb.loadShedding.MinRequests == 0 {
+			cb.loadShedding.MinRequests = defaultShedMinRequests
+		}
+		if cb.loadShedding.Window == 0 {
+			cb.loadShedding.Window = defaultShedWindow
+		}
+		cb.shedWindow = NewWindow(cb.loadShedding.Window, 0)
+	}
+	if cb.errorCategorizer == nil {
+		cb.errorCategorizer = DefaultErrorCategorizer
+	}
+	if cb.probeLease <= 0 {
+		cb.probeLease = DefaultProbeLease
+	}
+	if cb.storageTimeout <= 0 {
+		cb.storageTimeout = DefaultStorageTimeout
 	}
-	if cb.timeout == 0 {
-		cb.timeout = defaultTimeout
+	cb.changedAt = time.Now()
+	cb.window.start = cb.changedAt
+	cb.startWarmup(cb.changedAt)
+	if cb.storage != nil {
+		ctx, cancel := cb.storageContext()
+		if err := cb.SyncState(ctx); err != nil {
+			cb.onStorageError(err)
+		}
+		cancel()
 	}
 
 	return &cb
 }
 
+// Reset returns the CircuitBreaker to the closed state with cleared counts
+// and restarts the warm-up period. Use Apply to record who reset the breaker and why.
+func (cb *CircuitBreaker) Reset() {
+	_ = cb.Apply(ActionReset, "", "")
+}
+
+func (cb *CircuitBreaker) reset(now time.Time) {
+	cb.expiredAt = time.Time{}
+	cb.trips = 0
+	cb.setState(StateClosed, ReasonReset)
+	cb.newGeneration()
+	cb.startWarmup(now)
+}
+
+// Name returns the name of the CircuitBreaker.
+func (cb *CircuitBreaker) Name() string {
+	return cb.name
+}
+
+// State returns the current state of the CircuitBreaker.
+func (cb *CircuitBreaker) State() State {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	cb.refreshState(time.Now())
+	return cb.state
+}
+
+// RemainingOpenTime returns the time left until the open CircuitBreaker becomes half-open, and zero in the other states.
+func (cb *CircuitBreaker) RemainingOpenTime() time.Duration {
+	return cb.remainingOpenTime(time.Now())
+}
+
+// remainingOpenTime returns the time left until the open breaker becomes half-open, zero otherwise.
+func (cb *CircuitBreaker) remainingOpenTime(now time.Time) time.Duration {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	if cb.state != StateOpen || cb.expiredAt.Before(now) {
+		return 0
+	}
+	return cb.expiredAt.Sub(now)
+}
+
+// OpensAt returns the time at which the open CircuitBreaker becomes half-open, and the zero time in the other states.
+func (cb *CircuitBreaker) OpensAt() time.Time {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	cb.refreshState(time.Now())
+	if cb.state != StateOpen {
+		return time.Time{}
+	}
+	return cb.expiredAt
+}
+
+// Counts returns a copy of the current Counts of the CircuitBreaker.
+func (cb *CircuitBreaker) Counts() Counts {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	cb.refreshState(time.Now())
+	return cb.counts
+}
+
+// Execute runs the request if the CircuitBreaker accepts it.
+// The CircuitBreaker lock is not held while the request is running,
+// so requests may execute concurrently.
+// A panic in the request is recorded as a failure and re-panicked.
 func (cb *CircuitBreaker) Execute(req func() (interface{}, error)) (interface{}, error) {
+	return cb.execute(context.Background(), req)
+}
+
+// execute runs the request on behalf of the caller context, which carries the request options such as Priority.
+func (cb *CircuitBreaker) execute(ctx context.Context, req func() (interface{}, error)) (interface{}, error) {
+	if cb.bulkhead != nil {
+		if err := cb.bulkhead.acquire(ctx); err != nil {
+			cb.onBulkheadRejection(ctx, err)
+			return nil, err
+		}
+		defer cb.bulkhead.release()
+	}
+
+	t, now, err := cb.admitRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if t.bypass {
+		return req()
+	}
+
+	// the outcome is recorded once, even if the state change callbacks panic
+	defer t.probe.complete()
+	returned := false
+	defer func() {
+		if !returned {
+			cb.afterRequest(t, now, errPanic)
+		}
+	}()
+
+	result, err := req()
+	returned = true
+	cb.afterRequest(t, now, err)
+
+	return result, err
+}
+
+// ticket is the admission decision for a request
+type ticket struct {
+	// generation the request belongs to
+	generation uint64
+	// bypass is true when the request must run without accounting
+	bypass bool
+	// probe is the coalesced half-open probe the request leads or follows
+	probe *probeCall
+	// follower is true when the request must wait for the probe before it is admitted again
+	follower bool
+	// elect is true when the prober of the half-open state must be elected before the admission
+	elect bool
+	// correlationID of the request context, see WithCorrelationID
+	correlationID string
+}
+
+// beforeRequest admits the request or returns the rejection error.
+func (cb *CircuitBreaker) beforeRequest(ctx context.Context, now time.Time) (ticket, error) {
+	correlationID := cb.correlationIDOf(ctx)
+	enforced := cb.enabled == nil || cb.enabled()
 
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
+	t, err := cb.admit(ctx, now)
+	if t.elect {
+		t, err = cb.elect(ctx, now)
+	}
+	state := cb.state
+	listeners := cb.listeners
+	var unenforced error
+	if err != nil && !enforced {
+		// the request the CircuitBreaker would reject runs without accounting
+		cb.totals.Unenforced.add(err)
+		unenforced, t, err = err, ticket{bypass: true}, nil
+	}
+	if err != nil {
+		cb.onRejection(err)
+	}
+	cb.unlock()
 
-	if cb.state == StateOpen && cb.expiredAt.Before(time.Now()) {
-		cb.expiredAt = time.Time{}
-		cb.setState(StateHalfOpen)
+	if unenforced != nil && cb.logger != nil {
+		cb.logger.Debug("circuit breaker rejection not enforced", withCorrelationID([]interface{}{
+			"name", cb.name, "state", state.String(), "error", unenforced,
+		}, correlationID)...)
+	}
+	if err != nil {
+		if cb.logger != nil {
+			cb.logger.Debug("circuit breaker rejected request", withCorrelationID([]interface{}{
+				"name", cb.name, "state", state.String(), "error", err,
+			}, correlationID)...)
+		}
+		emit(listeners, CallRejected{Name: cb.name, State: state, Err: err, CorrelationID: correlationID, At: now})
+	}
+	t.correlationID = correlationID
+
+	return t, err
+}
+
+// admit makes the admission decision while the lock is held.
+func (cb *CircuitBreaker) admit(ctx context.Context, now time.Time) (ticket, error) {
+	if cb.disabled {
+		return ticket{bypass: true}, nil
+	}
+	if w, ok := cb.activeMaintenance(now); ok {
+		if w.Mode == MaintenanceDisable {
+			return ticket{bypass: true}, nil
+		}
+		return ticket{}, ErrOpenState
 	}
 
+	cb.refreshState(now)
 	if cb.state == StateOpen {
-		return nil, ErrOpenState
-	} else if cb.state == StateHalfOpen && cb.counts.Requests >= cb.requestThreshold {
-		return nil, ErrTooManyRequests
+		return ticket{}, ErrOpenState
+	}
+	if cb.state == StateHalfOpen && cb.probeElector != nil && !cb.prober() {
+		if cb.needsElection(now) {
+			return ticket{elect: true}, nil
+		}
+		return ticket{}, ErrTooManyRequests
+	}
+	if cb.state == StateHalfOpen && cb.coalesceHalfOpen && cb.probe != nil {
+		return ticket{probe: cb.probe, follower: true}, nil
+	}
+	if cb.state == StateHalfOpen && cb.counts.Requests >= cb.requestThreshold {
+		return ticket{}, ErrTooManyRequests
+	}
+	if cb.state == StateClosed && cb.shedWindow != nil &&
+		cb.loadShedding.shouldShed(PriorityFromContext(ctx), cb.shedWindow.Counts(now)) {
+		return ticket{}, ErrLoadShed
+	}
+	if cb.concurrencyLimiter != nil && cb.inFlight >= cb.concurrencyLimiter.Limit() {
+		return ticket{}, ErrLimitExceeded
+	}
+	if cb.rateLimiter != nil && !cb.rateLimiter.Allow() {
+		return ticket{}, ErrRateLimited
 	}
 	cb.counts.onRequest()
+	cb.totals.Requests++
+	cb.inFlight++
 
-	result, err := req()
+	t := ticket{generation: cb.generation}
+	if cb.state == StateHalfOpen && cb.coalesceHalfOpen {
+		cb.probe = newProbeCall()
+		t.probe = cb.probe
+	}
+
+	return t, nil
+}
+
+// afterRequest records the outcome of the request,
+// unless the CircuitBreaker has moved to another generation since the request was admitted.
+func (cb *CircuitBreaker) afterRequest(t ticket, start time.Time, err error) {
+	probe := false
+	failed := false
+	var state State
+	var end time.Time
+	var listeners []*listener
+	// reported after the lock is released
+	defer func() {
+		if failed {
+			emit(listeners, CallFailed{
+				Name:          cb.name,
+				State:         state,
+				Err:           err,
+				Latency:       end.Sub(start),
+				CorrelationID: t.correlationID,
+				At:            end,
+			})
+		}
+		if probe {
+			cb.onProbe(listeners, err, end.Sub(start), t.correlationID, end)
+		}
+	}()
+
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	end = time.Now()
+	latency := end.Sub(start)
+	if err != errAbandoned {
+		cb.latencies.record(latency)
+		if cb.latencyHistogram != nil {
+			cb.latencyHistogram.Record(latency)
+		}
+		if cb.concurrencyLimiter != nil {
+			cb.concurrencyLimiter.OnSample(latency, cb.inFlight, err != nil)
+		}
+	}
+	cb.onDone()
 
+	if t.probe != nil && cb.probe == t.probe {
+		cb.probe = nil
+	}
+	if err == errAbandoned {
+		return
+	}
 	if err != nil {
-		cb.onFailure(cb.state)
+		cb.totals.Failures++
 	} else {
-		cb.onSuccess(cb.state)
+		cb.totals.Successes++
+	}
+	if t.generation != cb.generation {
+		return
 	}
 
-	return result, err
+	probe = cb.state == StateHalfOpen
+	failed = err != nil
+	state = cb.state
+	listeners = cb.listeners
+	if cb.slowCallThreshold > 0 && latency > cb.slowCallThreshold {
+		cb.window.slowCalls++
+	}
+	cb.policy.OnCall(cb.state, err)
+	if cb.state == StateClosed && cb.shedWindow != nil {
+		cb.shedWindow.Record(end, err == nil)
+	}
+	if err != nil {
+		cb.lastErr = err
+		cb.recentErrors.add(err, end)
+		cb.onFailure(cb.state, err, end)
+	} else {
+		cb.onSuccess(cb.state)
+	}
 }
 
-func defaultReadyToTrip(counts Counts) bool {
-	return counts.ConsecutiveFailures > defaultConsecutiveFailures
+// refreshState moves the CircuitBreaker from the open to the half-open state once the open period is over.
+func (cb *CircuitBreaker) refreshState(now time.Time) {
+	if cb.state == StateOpen && cb.expiredAt.Before(now) {
+		cb.expiredAt = time.Time{}
+		cb.setState(StateHalfOpen, ReasonTimeout)
+	}
 }
 
 func (cb *CircuitBreaker) onSuccess(state State) {
 	switch state {
 	case StateClosed:
 		cb.counts.onSuccess()
+		cb.categoryCounts.onSuccess()
 	case StateHalfOpen:
 		cb.counts.onSuccess()
-		if cb.counts.ConsecutiveSuccesses >= cb.requestThreshold {
-			cb.setState(StateClosed)
+		if cb.policy.ShouldClose(cb.counts) {
+			cb.trips = 0
+			cb.setState(StateClosed, ReasonProbeSucceeded)
 		}
 	}
 }
 
-func (cb *CircuitBreaker) onFailure(state State) {
+func (cb *CircuitBreaker) onFailure(state State, err error, now time.Time) {
 	switch state {
 	case StateClosed:
 		cb.counts.onFailure()
-		if cb.readyToTrip(cb.counts) {
-			cb.expiredAt = time.Now().Add(cb.timeout)
-			cb.setState(StateOpen)
+		var category ErrorCategory
+		if len(cb.categoryThresholds) > 0 {
+			category = cb.errorCategorizer(err)
+			cb.categoryCounts.onFailure(category)
+		}
+		if !cb.inWarmup(now) && (cb.readyToTripCategory(category) || cb.policy.ShouldTrip(cb.counts)) {
+			cb.trip(now, ReasonTripped)
 		}
 	case StateHalfOpen:
-		cb.expiredAt = time.Now().Add(cb.timeout)
-		cb.setState(StateOpen)
+		cb.counts.onFailure()
+		cb.trip(now, ReasonProbeFailed)
+	}
+}
+
+func (cb *CircuitBreaker) trip(now time.Time, reason string) {
+	cb.trips++
+	cb.expiredAt = now.Add(cb.policy.NextOpenDuration(cb.trips))
+	cb.setState(StateOpen, reason)
+}
+
+func (cb *CircuitBreaker) startWarmup(now time.Time) {
+	if cb.warmupDuration > 0 {
+		cb.warmupUntil = now.Add(cb.warmupDuration)
 	}
 }
 
-func (cb *CircuitBreaker) setState(state State) {
+func (cb *CircuitBreaker) inWarmup(now time.Time) bool {
+	return now.Before(cb.warmupUntil)
+}
+
+func (cb *CircuitBreaker) setState(state State, reason string) {
 	if cb.state == state {
 		return
 	}
 
 	prev := cb.state
+	prevChangedAt := cb.changedAt
+	resign := prev == StateHalfOpen && cb.prober()
 	cb.state = state
-
-	if cb.onStateChange != nil {
-		cb.onStateChange(cb.name, prev, state)
+	cb.changedAt = time.Now()
+	cb.totals.onTransition(prev, state, cb.changedAt.Sub(prevChangedAt))
+	cb.history.add(Transition{
+		At:        cb.changedAt,
+		From:      prev,
+		To:        state,
+		Reason:    reason,
+		Counts:    cb.counts,
+		LastError: cb.lastErr,
+	})
+
+	if cb.onStateChange != nil || len(cb.listeners) > 0 || cb.logger != nil || cb.storage != nil || resign {
+		cb.pending = append(cb.pending, stateChange{
+			from:       prev,
+			to:         state,
+			reason:     reason,
+			counts:     cb.counts,
+			at:         cb.changedAt,
+			lastErr:    cb.lastErr,
+			save:       cb.storage != nil && reason != ReasonSynced && reason != ReasonBroadcast,
+			stored:     cb.storedState(),
+			generation: cb.generation + 1,
+			resign:     resign,
+		})
 	}
 
+	cb.newGeneration()
+}
+
+// newGeneration clears the counts and discards the outcomes of the requests in flight.
+func (cb *CircuitBreaker) newGeneration() {
+	cb.generation++
 	cb.counts.reset()
+	cb.lastErr = nil
+	cb.window = windowStats{start: time.Now()}
+	cb.categoryCounts.reset()
+	if cb.shedWindow != nil {
+		cb.shedWindow.Reset()
+	}
 }
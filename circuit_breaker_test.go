@@ -2,6 +2,7 @@ package circuit_breaker
 
 import (
 	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -31,7 +32,7 @@ func TestCircuitBreaker(t *testing.T) {
 		Name:             "test circuit breaker",
 		RequestThreshold: 2,
 		ReadyToTrip: func(counts Counts) bool {
-			return counts.ConsecutiveFailures > defaultConsecutiveFailures
+			return counts.ConsecutiveFailures >= DefaultMaxConsecutiveFailures
 		},
 	})
 
@@ -92,3 +93,190 @@ func TestCircuitBreaker(t *testing.T) {
 	assert.Equal(t, Counts{0, 0, 0, 0, 0}, cb.counts)
 	assert.True(t, cb.expiredAt.IsZero())
 }
+
+func TestCircuitBreakerWarmup(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:           "warmup circuit breaker",
+		WarmupDuration: time.Duration(30) * time.Second,
+	})
+	assert.False(t, cb.warmupUntil.IsZero())
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, errServiceError, fail(cb))
+	}
+	assert.Equal(t, StateClosed, cb.state)
+	assert.Equal(t, Counts{10, 0, 10, 0, 10}, cb.counts)
+
+	// warm-up is over
+	cb.warmupUntil = cb.warmupUntil.Add(time.Duration(-30) * time.Second)
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.state)
+
+	// Reset restarts the warm-up
+	cb.Reset()
+	assert.Equal(t, StateClosed, cb.state)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0}, cb.counts)
+	assert.True(t, cb.expiredAt.IsZero())
+	assert.True(t, cb.inWarmup(time.Now()))
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, errServiceError, fail(cb))
+	}
+	assert.Equal(t, StateClosed, cb.state)
+}
+
+func TestCircuitBreakerConcurrentExecution(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "concurrent circuit breaker"})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err := cb.Execute(func() (interface{}, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+		done <- err
+	}()
+
+	// the lock is not held while the request is running
+	<-started
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, Counts{2, 1, 0, 1, 0}, cb.Counts())
+
+	close(release)
+	assert.Nil(t, <-done)
+	assert.Equal(t, Counts{2, 2, 0, 2, 0}, cb.Counts())
+}
+
+func TestCircuitBreakerStaleGeneration(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "generation circuit breaker"})
+
+	_, err := cb.Execute(func() (interface{}, error) {
+		cb.Reset()
+		return nil, errServiceError
+	})
+
+	// the outcome of a request admitted before Reset is discarded
+	assert.Equal(t, errServiceError, err)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0}, cb.Counts())
+}
+
+func TestCircuitBreakerPanic(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "panic circuit breaker"})
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_, _ = cb.Execute(func() (interface{}, error) { panic("boom") })
+	})
+	assert.Equal(t, Counts{1, 0, 1, 0, 1}, cb.Counts())
+}
+
+func TestStateText(t *testing.T) {
+	for _, state := range []State{StateClosed, StateOpen, StateHalfOpen} {
+		text, err := state.MarshalText()
+		assert.Nil(t, err)
+
+		var decoded State
+		assert.Nil(t, decoded.UnmarshalText(text))
+		assert.Equal(t, state, decoded)
+	}
+
+	var state State
+	assert.NotNil(t, state.UnmarshalText([]byte("ajar")))
+}
+
+func TestRemainingOpenTime(t *testing.T) {
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 1, Timeout: time.Minute})
+	assert.Equal(t, time.Duration(0), cb.RemainingOpenTime())
+	assert.True(t, cb.OpensAt().IsZero())
+
+	before := time.Now()
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Greater(t, cb.RemainingOpenTime(), 59*time.Second)
+	assert.LessOrEqual(t, cb.RemainingOpenTime(), time.Minute)
+	assert.WithinDuration(t, before.Add(time.Minute), cb.OpensAt(), time.Second)
+	assert.Equal(t, cb.OpensAt(), cb.Snapshot().OpensAt)
+
+	cb.Reset()
+	assert.True(t, cb.OpensAt().IsZero())
+	assert.True(t, cb.Snapshot().OpensAt.IsZero())
+}
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	assert.Equal(t, Config{RequestThreshold: 1, Timeout: time.Minute, MaxConsecutiveFailures: 6}, cfg)
+
+	cfg.Name = "defaults"
+	cb := NewCircuitBreaker(cfg)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, errServiceError, fail(cb))
+	}
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+
+	pseudoSleep(cb, DefaultTimeout)
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestCircuitBreakerZeroRequestThreshold(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Timeout: 10 * time.Millisecond, MaxConsecutiveFailures: 1})
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+
+	// the half-open state admits DefaultRequestThreshold probes and closes
+	time.Sleep(20 * time.Millisecond)
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.State())
+
+	// so does the breaker updated with a zero RequestThreshold
+	assert.Nil(t, cb.UpdateConfig(Config{Timeout: 10 * time.Millisecond, MaxConsecutiveFailures: 1}))
+	assert.Equal(t, errServiceError, fail(cb))
+	time.Sleep(20 * time.Millisecond)
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestPackageDefaults(t *testing.T) {
+	defer func(failures uint32, timeout time.Duration) {
+		DefaultMaxConsecutiveFailures, DefaultTimeout = failures, timeout
+	}(DefaultMaxConsecutiveFailures, DefaultTimeout)
+	DefaultMaxConsecutiveFailures = 2
+	DefaultTimeout = time.Second
+
+	cb := NewCircuitBreaker(Config{})
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+	assert.LessOrEqual(t, cb.RemainingOpenTime(), time.Second)
+	assert.True(t, DefaultReadyToTrip(Counts{ConsecutiveFailures: 2}))
+}
+
+func TestCircuitBreakerEnabled(t *testing.T) {
+	var enabled atomic.Bool
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 1, RequestThreshold: 1, Enabled: enabled.Load})
+
+	// the breaker trips, but the requests still run
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+	assert.Nil(t, succeed(cb))
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateOpen, cb.State())
+
+	s := cb.Snapshot()
+	assert.Equal(t, uint64(1), s.Totals.Requests)
+	assert.Equal(t, uint64(0), s.Totals.Rejections)
+	assert.Equal(t, Rejections{Open: 2}, s.Totals.Unenforced)
+
+	// the enforcement is turned on instantly
+	enabled.Store(true)
+	assert.Equal(t, ErrOpenState, succeed(cb))
+
+	// the state machine keeps working while the enforcement is off
+	enabled.Store(false)
+	pseudoSleep(cb, DefaultTimeout)
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.State())
+}
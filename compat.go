@@ -0,0 +1,280 @@
+package circuit_breaker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// forever is the end of the maintenance windows without an end
+var forever = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+// HystrixConfig is the circuit breaker configuration of a Hystrix command,
+// with the names of its properties, e.g. decoded from the configuration of a JVM service.
+// The zero fields take the Hystrix defaults, given in parentheses.
+//
+// RequestVolumeThreshold (20) is the minimum number of requests in the rolling window to trip,
+// and ErrorThresholdPercentage (50) the percentage of failures in the window which trips.
+// The rolling window lasts MetricsRollingStatisticalWindowInMilliseconds (10000)
+// split into MetricsRollingStatisticalWindowBuckets (10).
+//
+// SleepWindowInMilliseconds (5000) is the period of the open state, after which a single probe is let through.
+//
+// MaxConcurrentRequests caps the requests in flight like the semaphore isolation, see Bulkhead. Zero means no limit.
+//
+// ForceOpen rejects all the requests, and ForceClosed lets them all through while keeping the statistics.
+type HystrixConfig struct {
+	RequestVolumeThreshold                        uint32  `json:"requestVolumeThreshold"`
+	ErrorThresholdPercentage                      float64 `json:"errorThresholdPercentage"`
+	SleepWindowInMilliseconds                     int64   `json:"sleepWindowInMilliseconds"`
+	MetricsRollingStatisticalWindowInMilliseconds int64   `json:"metricsRollingStatisticalWindowInMilliseconds"`
+	MetricsRollingStatisticalWindowBuckets        int     `json:"metricsRollingStatisticalWindowBuckets"`
+	MaxConcurrentRequests                         uint32  `json:"maxConcurrentRequests"`
+	ForceOpen                                     bool    `json:"forceOpen"`
+	ForceClosed                                   bool    `json:"forceClosed"`
+}
+
+// Config returns the equivalent Config, or an error if the configuration is invalid.
+// The Config holds the rolling window of its Policy, so it must be used for one breaker only.
+func (h HystrixConfig) Config() (Config, error) {
+	if h.ErrorThresholdPercentage < 0 || h.ErrorThresholdPercentage > 100 {
+		return Config{}, fmt.Errorf("hystrix: errorThresholdPercentage must be in [0, 100], got %v", h.ErrorThresholdPercentage)
+	}
+	if h.SleepWindowInMilliseconds < 0 || h.MetricsRollingStatisticalWindowInMilliseconds < 0 || h.MetricsRollingStatisticalWindowBuckets < 0 {
+		return Config{}, errors.New("hystrix: durations and buckets must not be negative")
+	}
+	if h.ForceOpen && h.ForceClosed {
+		return Config{}, errors.New("hystrix: forceOpen and forceClosed are exclusive")
+	}
+
+	volume := h.RequestVolumeThreshold
+	if volume == 0 {
+		volume = 20
+	}
+	percentage := h.ErrorThresholdPercentage
+	if percentage == 0 {
+		percentage = 50
+	}
+	sleepWindow := time.Duration(h.SleepWindowInMilliseconds) * time.Millisecond
+	if sleepWindow == 0 {
+		sleepWindow = 5 * time.Second
+	}
+	window := time.Duration(h.MetricsRollingStatisticalWindowInMilliseconds) * time.Millisecond
+	if window == 0 {
+		window = 10 * time.Second
+	}
+
+	readyToTrip := TripOnFailureRate(percentage/100, volume)
+	cfg := Config{
+		RequestThreshold: 1,
+		Timeout:          sleepWindow,
+		Bulkhead:         Bulkhead{MaxConcurrent: h.MaxConcurrentRequests},
+		Policy: NewDualWindowPolicy(DualWindowConfig{
+			FastWindow:        window,
+			FastWindowBuckets: h.MetricsRollingStatisticalWindowBuckets,
+			SlowWindow:        window,
+			SlowWindowBuckets: h.MetricsRollingStatisticalWindowBuckets,
+			ReadyToTrip:       func(fast, _ Counts) bool { return readyToTrip(fast) },
+			RequestThreshold:  1,
+			Timeout:           sleepWindow,
+		}),
+	}
+	if h.ForceOpen {
+		cfg.MaintenanceWindows = []MaintenanceWindow{{End: forever, Mode: MaintenanceForceOpen}}
+	}
+	if h.ForceClosed {
+		cfg.Enabled = func() bool { return false }
+	}
+
+	return cfg, nil
+}
+
+// Sliding window types of Resilience4jConfig.
+const (
+	SlidingWindowCountBased = "COUNT_BASED"
+	SlidingWindowTimeBased  = "TIME_BASED"
+)
+
+// Resilience4jConfig is the configuration of a resilience4j circuit breaker instance,
+// with the names of its properties, e.g. decoded from the configuration of a JVM service.
+// The zero fields take the resilience4j defaults, given in parentheses.
+//
+// FailureRateThreshold (50) is the percentage of failures in the sliding window which trips,
+// once there were at least MinimumNumberOfCalls (100) calls in the window.
+// The window holds the last SlidingWindowSize (100) calls if SlidingWindowType is SlidingWindowCountBased (the default),
+// or the calls of the last SlidingWindowSize seconds if it is SlidingWindowTimeBased.
+//
+// WaitDurationInOpenState (60s) is the period of the open state, and PermittedNumberOfCallsInHalfOpenState (10)
+// the number of the half-open calls. Unlike resilience4j, the breaker closes only if all of them succeed.
+//
+// SlowCallDurationThreshold (60s) is the duration of the slow calls, reported by the Snapshot.
+// The slow calls don't trip the breaker, so a SlowCallRateThreshold below 100 is rejected.
+//
+// The durations are decoded from JSON as milliseconds or as strings parsed by time.ParseDuration, e.g. "60s".
+type Resilience4jConfig struct {
+	FailureRateThreshold                  float64       `json:"failureRateThreshold"`
+	SlowCallRateThreshold                 float64       `json:"slowCallRateThreshold"`
+	SlowCallDurationThreshold             time.Duration `json:"slowCallDurationThreshold"`
+	PermittedNumberOfCallsInHalfOpenState uint32        `json:"permittedNumberOfCallsInHalfOpenState"`
+	SlidingWindowType                     string        `json:"slidingWindowType"`
+	SlidingWindowSize                     uint32        `json:"slidingWindowSize"`
+	MinimumNumberOfCalls                  uint32        `json:"minimumNumberOfCalls"`
+	WaitDurationInOpenState               time.Duration `json:"waitDurationInOpenState"`
+}
+
+func (r *Resilience4jConfig) UnmarshalJSON(data []byte) error {
+	type plain Resilience4jConfig
+	var v struct {
+		*plain
+		SlowCallDurationThreshold jvmDuration `json:"slowCallDurationThreshold"`
+		WaitDurationInOpenState   jvmDuration `json:"waitDurationInOpenState"`
+	}
+	v.plain = (*plain)(r)
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	r.SlowCallDurationThreshold = time.Duration(v.SlowCallDurationThreshold)
+	r.WaitDurationInOpenState = time.Duration(v.WaitDurationInOpenState)
+
+	return nil
+}
+
+// Config returns the equivalent Config, or an error if the configuration is invalid or not supported.
+// The Config holds the sliding window of its Policy, so it must be used for one breaker only.
+func (r Resilience4jConfig) Config() (Config, error) {
+	if r.FailureRateThreshold < 0 || r.FailureRateThreshold > 100 {
+		return Config{}, fmt.Errorf("resilience4j: failureRateThreshold must be in [0, 100], got %v", r.FailureRateThreshold)
+	}
+	if r.SlowCallRateThreshold != 0 && r.SlowCallRateThreshold != 100 {
+		return Config{}, fmt.Errorf("resilience4j: slowCallRateThreshold is not supported, got %v", r.SlowCallRateThreshold)
+	}
+	if r.SlowCallDurationThreshold < 0 || r.WaitDurationInOpenState < 0 {
+		return Config{}, errors.New("resilience4j: durations must not be negative")
+	}
+
+	rate := r.FailureRateThreshold
+	if rate == 0 {
+		rate = 50
+	}
+	size := r.SlidingWindowSize
+	if size == 0 {
+		size = 100
+	}
+	minCalls := r.MinimumNumberOfCalls
+	if minCalls == 0 {
+		minCalls = 100
+	}
+	permitted := r.PermittedNumberOfCallsInHalfOpenState
+	if permitted == 0 {
+		permitted = 10
+	}
+	wait := r.WaitDurationInOpenState
+	if wait == 0 {
+		wait = 60 * time.Second
+	}
+	slowCall := r.SlowCallDurationThreshold
+	if slowCall == 0 {
+		slowCall = 60 * time.Second
+	}
+
+	readyToTrip := TripOnFailureRate(rate/100, minCalls)
+	defaults := DefaultPolicy{RequestThreshold: permitted, Timeout: wait}
+	var policy Policy
+	switch strings.ToUpper(r.SlidingWindowType) {
+	case "", SlidingWindowCountBased:
+		policy = &countWindowPolicy{
+			DefaultPolicy: defaults,
+			readyToTrip:   readyToTrip,
+			outcomes:      make([]bool, size),
+		}
+	case SlidingWindowTimeBased:
+		window := time.Duration(size) * time.Second
+		policy = NewDualWindowPolicy(DualWindowConfig{
+			FastWindow:        window,
+			FastWindowBuckets: int(size),
+			SlowWindow:        window,
+			SlowWindowBuckets: int(size),
+			ReadyToTrip:       func(fast, _ Counts) bool { return readyToTrip(fast) },
+			RequestThreshold:  permitted,
+			Timeout:           wait,
+		})
+	default:
+		return Config{}, fmt.Errorf("resilience4j: unknown slidingWindowType %q", r.SlidingWindowType)
+	}
+
+	return Config{
+		RequestThreshold:  permitted,
+		Timeout:           wait,
+		SlowCallThreshold: slowCall,
+		Policy:            policy,
+	}, nil
+}
+
+// countWindowPolicy is a Policy tripping by the outcomes of the last calls in the closed state
+type countWindowPolicy struct {
+	DefaultPolicy
+
+	readyToTrip func(counts Counts) bool
+	// outcomes is the ring of the last outcomes, true for the failures
+	outcomes []bool
+	next     int
+	calls    uint32
+	failures uint32
+}
+
+func (p *countWindowPolicy) OnCall(state State, err error) {
+	if state != StateClosed {
+		return
+	}
+
+	if p.calls == uint32(len(p.outcomes)) {
+		if p.outcomes[p.next] {
+			p.failures--
+		}
+	} else {
+		p.calls++
+	}
+	p.outcomes[p.next] = err != nil
+	if err != nil {
+		p.failures++
+	}
+	p.next = (p.next + 1) % len(p.outcomes)
+}
+
+func (p *countWindowPolicy) ShouldTrip(counts Counts) bool {
+	return p.readyToTrip(Counts{Requests: p.calls, TotalSuccesses: p.calls - p.failures, TotalFailures: p.failures})
+}
+
+func (p *countWindowPolicy) ShouldClose(counts Counts) bool {
+	if !p.DefaultPolicy.ShouldClose(counts) {
+		return false
+	}
+
+	p.next, p.calls, p.failures = 0, 0, 0
+	return true
+}
+
+// jvmDuration is a duration written in the configuration of a JVM service, as milliseconds or as a string like "60s"
+type jvmDuration time.Duration
+
+func (d *jvmDuration) UnmarshalJSON(data []byte) error {
+	var ms int64
+	if err := json.Unmarshal(data, &ms); err == nil {
+		*d = jvmDuration(time.Duration(ms) * time.Millisecond)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = jvmDuration(v)
+
+	return nil
+}
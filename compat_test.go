@@ -0,0 +1,124 @@
+package circuit_breaker
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHystrixConfig(t *testing.T) {
+	var h HystrixConfig
+	assert.Nil(t, json.Unmarshal([]byte(`{
+		"requestVolumeThreshold": 4,
+		"errorThresholdPercentage": 50,
+		"sleepWindowInMilliseconds": 2000,
+		"maxConcurrentRequests": 10
+	}`), &h))
+	cfg, err := h.Config()
+	assert.Nil(t, err)
+	assert.Equal(t, 2*time.Second, cfg.Timeout)
+	assert.Equal(t, uint32(1), cfg.RequestThreshold)
+	assert.Equal(t, uint32(10), cfg.Bulkhead.MaxConcurrent)
+
+	cb := NewCircuitBreaker(cfg)
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Nil(t, succeed(cb))
+	// 3 of 4 requests failed in the rolling window
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+	assert.LessOrEqual(t, cb.RemainingOpenTime(), 2*time.Second)
+
+	cfg, err = HystrixConfig{}.Config()
+	assert.Nil(t, err)
+	assert.Equal(t, 5*time.Second, cfg.Timeout)
+}
+
+func TestHystrixConfigForced(t *testing.T) {
+	cfg, err := HystrixConfig{ForceOpen: true}.Config()
+	assert.Nil(t, err)
+	assert.Equal(t, ErrOpenState, succeed(NewCircuitBreaker(cfg)))
+
+	cfg, err = HystrixConfig{ForceClosed: true, RequestVolumeThreshold: 1}.Config()
+	assert.Nil(t, err)
+	cb := NewCircuitBreaker(cfg)
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+	assert.Nil(t, succeed(cb))
+
+	for _, h := range []HystrixConfig{
+		{ErrorThresholdPercentage: 101},
+		{SleepWindowInMilliseconds: -1},
+		{ForceOpen: true, ForceClosed: true},
+	} {
+		_, err := h.Config()
+		assert.NotNil(t, err)
+	}
+}
+
+func TestResilience4jConfig(t *testing.T) {
+	var r Resilience4jConfig
+	assert.Nil(t, json.Unmarshal([]byte(`{
+		"failureRateThreshold": 50,
+		"slidingWindowSize": 4,
+		"minimumNumberOfCalls": 4,
+		"permittedNumberOfCallsInHalfOpenState": 2,
+		"waitDurationInOpenState": "10s",
+		"slowCallDurationThreshold": 500
+	}`), &r))
+	assert.Equal(t, 10*time.Second, r.WaitDurationInOpenState)
+	assert.Equal(t, 500*time.Millisecond, r.SlowCallDurationThreshold)
+
+	cfg, err := r.Config()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), cfg.RequestThreshold)
+	assert.Equal(t, 10*time.Second, cfg.Timeout)
+	assert.Equal(t, 500*time.Millisecond, cfg.SlowCallThreshold)
+
+	cb := NewCircuitBreaker(cfg)
+	assert.Equal(t, errServiceError, fail(cb))
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, succeed(cb))
+	}
+	// the first failure left the window of the last 4 calls
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+
+	pseudoSleep(cb, 10*time.Second)
+	assert.Nil(t, succeed(cb))
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.State())
+	// the window is cleared when the breaker closes
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestResilience4jConfigTimeBased(t *testing.T) {
+	cfg, err := Resilience4jConfig{SlidingWindowType: "time_based", SlidingWindowSize: 10, MinimumNumberOfCalls: 2}.Config()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(10), cfg.RequestThreshold)
+	assert.Equal(t, time.Minute, cfg.Timeout)
+
+	cb := NewCircuitBreaker(cfg)
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+
+	for _, r := range []Resilience4jConfig{
+		{FailureRateThreshold: 150},
+		{SlowCallRateThreshold: 50},
+		{SlidingWindowType: "SESSION"},
+		{WaitDurationInOpenState: -time.Second},
+	} {
+		_, err := r.Config()
+		assert.NotNil(t, err)
+	}
+
+	var r Resilience4jConfig
+	assert.NotNil(t, json.Unmarshal([]byte(`{"waitDurationInOpenState": "a minute"}`), &r))
+	assert.NotNil(t, json.Unmarshal([]byte(`{"waitDurationInOpenState": true}`), &r))
+}
@@ -0,0 +1,77 @@
+package circuit_breaker
+
+import "context"
+
+// Composite protects a request by several breakers at once, e.g. the breaker of an availability zone
+// and the breaker of a service, admitting it only if all of them allow it.
+//
+// The outcome of an admitted request is attributed to each breaker.
+// A request rejected by one of the breakers is counted as a rejection by it,
+// and only as a request without an outcome by the breakers which admitted it before,
+// unlike nested Execute calls, which count the rejection as a failure of the outer breakers.
+// Composite is safe for concurrent use.
+type Composite struct {
+	breakers []*CircuitBreaker
+}
+
+// NewComposite returns the Composite of the breakers, which are asked for admission in order.
+func NewComposite(cbs ...*CircuitBreaker) *Composite {
+	return &Composite{breakers: append([]*CircuitBreaker(nil), cbs...)}
+}
+
+// Breakers returns the breakers of the Composite.
+func (c *Composite) Breakers() []*CircuitBreaker {
+	return append([]*CircuitBreaker(nil), c.breakers...)
+}
+
+// Execute runs the request if all the breakers accept it.
+func (c *Composite) Execute(req func() (interface{}, error)) (interface{}, error) {
+	return c.ExecuteContext(context.Background(), func(ctx context.Context) (interface{}, error) {
+		return req()
+	})
+}
+
+// ExecuteContext is like Execute, but runs the request with the context, see CircuitBreaker.ExecuteContext.
+// It returns the error of the first breaker rejecting the request.
+func (c *Composite) ExecuteContext(ctx context.Context, req func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	done, err := c.Allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	returned := false
+	defer func() {
+		if !returned {
+			done(errPanic)
+		}
+	}()
+
+	result, err := req(ctx)
+	returned = true
+	done(err)
+
+	return result, err
+}
+
+// Allow is the two-step form of ExecuteContext, see CircuitBreaker.Allow.
+func (c *Composite) Allow(ctx context.Context) (done func(err error), err error) {
+	dones := make([]func(err error), 0, len(c.breakers))
+	for _, cb := range c.breakers {
+		d, err := cb.Allow(ctx)
+		if err != nil {
+			// the breakers which admitted the request don't record an outcome
+			for _, d := range dones {
+				d(errAbandoned)
+			}
+			return nil, err
+		}
+		dones = append(dones, d)
+	}
+
+	return func(err error) {
+		// every breaker records the outcome, even if the callbacks of another one panic
+		for _, d := range dones {
+			defer d(err)
+		}
+	}, nil
+}
@@ -0,0 +1,52 @@
+package circuit_breaker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComposite(t *testing.T) {
+	zone := NewCircuitBreaker(Config{Name: "eu-west-1a", MaxConsecutiveFailures: 3})
+	service := NewCircuitBreaker(Config{Name: "users", MaxConsecutiveFailures: 1})
+	c := NewComposite(zone, service)
+	assert.Equal(t, []*CircuitBreaker{zone, service}, c.Breakers())
+
+	_, err := c.Execute(func() (interface{}, error) { return nil, nil })
+	assert.Nil(t, err)
+	// the outcome is attributed to each breaker
+	_, err = c.Execute(func() (interface{}, error) { return nil, errServiceError })
+	assert.Equal(t, errServiceError, err)
+	assert.Equal(t, Counts{2, 1, 1, 0, 1}, zone.Counts())
+	assert.Equal(t, StateOpen, service.State())
+
+	// the rejection by the service isn't a failure of the zone
+	executed := false
+	_, err = c.ExecuteContext(context.Background(), func(ctx context.Context) (interface{}, error) {
+		executed = true
+		return nil, nil
+	})
+	assert.Equal(t, ErrOpenState, err)
+	assert.False(t, executed)
+	assert.Equal(t, Counts{3, 1, 1, 0, 1}, zone.Counts())
+	assert.Equal(t, uint64(1), service.Snapshot().Totals.Rejections)
+
+	// the zone rejects before asking the service
+	zone.Trip()
+	_, err = c.Execute(func() (interface{}, error) { return nil, nil })
+	assert.Equal(t, ErrOpenState, err)
+	assert.Equal(t, uint64(1), service.Snapshot().Totals.Rejections)
+}
+
+func TestCompositePanic(t *testing.T) {
+	a := NewCircuitBreaker(Config{})
+	b := NewCircuitBreaker(Config{})
+	c := NewComposite(a, b)
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_, _ = c.Execute(func() (interface{}, error) { panic("boom") })
+	})
+	assert.Equal(t, Counts{1, 0, 1, 0, 1}, a.Counts())
+	assert.Equal(t, Counts{1, 0, 1, 0, 1}, b.Counts())
+}
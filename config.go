@@ -0,0 +1,198 @@
+package circuit_breaker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Built-in policies of TripSpec.
+const (
+	// TripConsecutiveFailures trips after Threshold consecutive failures, see TripAfterConsecutiveFailures
+	TripConsecutiveFailures = "consecutive_failures"
+	// TripFailures trips after Threshold failures, see TripAfterFailures
+	TripFailures = "failures"
+	// TripFailureRate trips when the failure rate reaches FailureRate, see TripOnFailureRate
+	TripFailureRate = "failure_rate"
+)
+
+// TripSpec names a built-in ReadyToTrip in a configuration file.
+//
+// Threshold is the number of failures of TripConsecutiveFailures and TripFailures.
+// FailureRate, from 0 to 1, and MinRequests are the parameters of TripFailureRate.
+type TripSpec struct {
+	Policy      string  `json:"policy"`
+	Threshold   uint32  `json:"threshold"`
+	FailureRate float64 `json:"failure_rate"`
+	MinRequests uint32  `json:"min_requests"`
+}
+
+// ReadyToTrip returns the ReadyToTrip of the spec, or an error if the spec is invalid.
+func (s TripSpec) ReadyToTrip() (func(counts Counts) bool, error) {
+	switch s.Policy {
+	case TripConsecutiveFailures, TripFailures:
+		if s.Threshold == 0 {
+			return nil, fmt.Errorf("trip policy %q requires a threshold", s.Policy)
+		}
+		if s.Policy == TripFailures {
+			return TripAfterFailures(s.Threshold), nil
+		}
+		return TripAfterConsecutiveFailures(s.Threshold), nil
+	case TripFailureRate:
+		if s.FailureRate <= 0 || s.FailureRate > 1 {
+			return nil, fmt.Errorf("trip policy %q requires a failure rate in (0, 1], got %v", s.Policy, s.FailureRate)
+		}
+		return TripOnFailureRate(s.FailureRate, s.MinRequests), nil
+	default:
+		return nil, fmt.Errorf("unknown trip policy %q", s.Policy)
+	}
+}
+
+// duration is a time.Duration written in a configuration file as a string parsed by time.ParseDuration, e.g. "30s"
+type duration time.Duration
+
+func (d *duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	if v < 0 {
+		return fmt.Errorf("negative duration %q", text)
+	}
+	*d = duration(v)
+	return nil
+}
+
+func (d duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// configFile is the part of Config which can be written in a configuration file
+type configFile struct {
+	Name                       string            `json:"name"`
+	RequestThreshold           uint32            `json:"request_threshold"`
+	Timeout                    duration          `json:"timeout"`
+	WarmupDuration             duration          `json:"warmup_duration"`
+	MaxConsecutiveFailures     uint32            `json:"max_consecutive_failures"`
+	ReadyToTrip                *TripSpec         `json:"ready_to_trip"`
+	FailureStatusCodes         []int             `json:"failure_status_codes"`
+	IgnoreStatusCodes          []int             `json:"ignore_status_codes"`
+	RejectInsufficientDeadline bool              `json:"reject_insufficient_deadline"`
+	CoalesceHalfOpen           bool              `json:"coalesce_half_open"`
+	LoadShedding               loadSheddingFile  `json:"load_shedding"`
+	Bulkhead                   bulkheadFile      `json:"bulkhead"`
+	HistorySize                int               `json:"history_size"`
+	RecentErrorsSize           int               `json:"recent_errors_size"`
+	AuditLogSize               int               `json:"audit_log_size"`
+	SlowCallThreshold          duration          `json:"slow_call_threshold"`
+	Labels                     map[string]string `json:"labels"`
+	Critical                   bool              `json:"critical"`
+}
+
+type loadSheddingFile struct {
+	LowPriorityFailureRate    float64  `json:"low_priority_failure_rate"`
+	NormalPriorityFailureRate float64  `json:"normal_priority_failure_rate"`
+	MinRequests               uint32   `json:"min_requests"`
+	Window                    duration `json:"window"`
+}
+
+type bulkheadFile struct {
+	MaxConcurrent uint32   `json:"max_concurrent"`
+	MaxWaiting    uint32   `json:"max_waiting"`
+	MaxWait       duration `json:"max_wait"`
+}
+
+// ConfigFromJSON decodes the Config from JSON, e.g. a section of the configuration file of the application:
+//
+//	{
+//	  "name": "payments",
+//	  "timeout": "30s",
+//	  "request_threshold": 1,
+//	  "ready_to_trip": {"policy": "failure_rate", "failure_rate": 0.5, "min_requests": 20},
+//	  "bulkhead": {"max_concurrent": 100, "max_wait": "50ms"},
+//	  "labels": {"tier": "critical"},
+//	  "critical": true
+//	}
+//
+// The fields are the snake case names of the Config fields which don't hold code or state.
+// The durations are strings parsed by time.ParseDuration, and ready_to_trip is a TripSpec.
+// It returns an error for unknown fields and invalid values.
+func ConfigFromJSON(data []byte) (Config, error) {
+	var f configFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return Config{}, fmt.Errorf("circuit breaker config: %w", err)
+	}
+
+	cfg, err := f.config()
+	if err != nil {
+		return Config{}, fmt.Errorf("circuit breaker config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// config validates the configuration file and converts it to Config
+func (f configFile) config() (Config, error) {
+	cfg := Config{
+		Name:                       f.Name,
+		RequestThreshold:           f.RequestThreshold,
+		Timeout:                    time.Duration(f.Timeout),
+		WarmupDuration:             time.Duration(f.WarmupDuration),
+		MaxConsecutiveFailures:     f.MaxConsecutiveFailures,
+		FailureStatusCodes:         f.FailureStatusCodes,
+		IgnoreStatusCodes:          f.IgnoreStatusCodes,
+		RejectInsufficientDeadline: f.RejectInsufficientDeadline,
+		CoalesceHalfOpen:           f.CoalesceHalfOpen,
+		LoadShedding: LoadShedding{
+			LowPriorityFailureRate:    f.LoadShedding.LowPriorityFailureRate,
+			NormalPriorityFailureRate: f.LoadShedding.NormalPriorityFailureRate,
+			MinRequests:               f.LoadShedding.MinRequests,
+			Window:                    time.Duration(f.LoadShedding.Window),
+		},
+		Bulkhead: Bulkhead{
+			MaxConcurrent: f.Bulkhead.MaxConcurrent,
+			MaxWaiting:    f.Bulkhead.MaxWaiting,
+			MaxWait:       time.Duration(f.Bulkhead.MaxWait),
+		},
+		HistorySize:       f.HistorySize,
+		RecentErrorsSize:  f.RecentErrorsSize,
+		AuditLogSize:      f.AuditLogSize,
+		SlowCallThreshold: time.Duration(f.SlowCallThreshold),
+		Labels:            f.Labels,
+		Critical:          f.Critical,
+	}
+
+	if f.ReadyToTrip != nil {
+		if f.MaxConsecutiveFailures > 0 {
+			return Config{}, errors.New("max_consecutive_failures is ignored with ready_to_trip")
+		}
+		readyToTrip, err := f.ReadyToTrip.ReadyToTrip()
+		if err != nil {
+			return Config{}, err
+		}
+		cfg.ReadyToTrip = readyToTrip
+	}
+	for name, rate := range map[string]float64{
+		"low_priority_failure_rate":    f.LoadShedding.LowPriorityFailureRate,
+		"normal_priority_failure_rate": f.LoadShedding.NormalPriorityFailureRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return Config{}, fmt.Errorf("%s must be in [0, 1], got %v", name, rate)
+		}
+	}
+	for name, size := range map[string]int{
+		"history_size":       f.HistorySize,
+		"recent_errors_size": f.RecentErrorsSize,
+		"audit_log_size":     f.AuditLogSize,
+	} {
+		if size < 0 {
+			return Config{}, fmt.Errorf("%s must not be negative, got %d", name, size)
+		}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,67 @@
+package circuit_breaker
+
+import (
+	"path"
+	"sort"
+)
+
+// ConfigMap selects the Config of an endpoint, e.g. a route or an RPC method,
+// so a single integration applies stricter thresholds to critical endpoints and looser ones to batch endpoints.
+//
+// Endpoints are keyed by patterns with the syntax of path.Match, e.g. "/payments/*".
+// An endpoint equal to a key uses its Config, otherwise the longest matching pattern does,
+// the lexically smallest one among the patterns of the same length.
+// The endpoints matching no pattern use Default. A malformed pattern only matches the endpoint equal to it.
+type ConfigMap struct {
+	Default   Config
+	Endpoints map[string]Config
+}
+
+// ConfigFor returns the Config of the endpoint.
+func (m ConfigMap) ConfigFor(endpoint string) Config {
+	if pattern, ok := m.Match(endpoint); ok {
+		return m.Endpoints[pattern]
+	}
+
+	return m.Default
+}
+
+// Match returns the key of Endpoints selecting the endpoint, if any.
+func (m ConfigMap) Match(endpoint string) (string, bool) {
+	return newEndpointMatcher(m.Endpoints).match(endpoint)
+}
+
+// endpointMatcher matches the endpoints against the patterns in the order of precedence
+type endpointMatcher struct {
+	exact    map[string]bool
+	patterns []string
+}
+
+func newEndpointMatcher(endpoints map[string]Config) endpointMatcher {
+	m := endpointMatcher{exact: make(map[string]bool, len(endpoints))}
+	for pattern := range endpoints {
+		m.exact[pattern] = true
+		m.patterns = append(m.patterns, pattern)
+	}
+	sort.Slice(m.patterns, func(i, j int) bool {
+		if len(m.patterns[i]) != len(m.patterns[j]) {
+			return len(m.patterns[i]) > len(m.patterns[j])
+		}
+		return m.patterns[i] < m.patterns[j]
+	})
+
+	return m
+}
+
+func (m endpointMatcher) match(endpoint string) (string, bool) {
+	if m.exact[endpoint] {
+		return endpoint, true
+	}
+	for _, pattern := range m.patterns {
+		if ok, _ := path.Match(pattern, endpoint); ok {
+			return pattern, true
+		}
+	}
+
+	return "", false
+}
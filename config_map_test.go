@@ -0,0 +1,35 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigMap(t *testing.T) {
+	m := ConfigMap{
+		Default: Config{Timeout: time.Minute},
+		Endpoints: map[string]Config{
+			"/pkg.Payments/*":      {Timeout: time.Second},
+			"/pkg.Payments/Refund": {Timeout: 2 * time.Second},
+			"/pkg.*/Export":        {Timeout: time.Hour},
+			"/pkg.*/Expor?":        {Timeout: 2 * time.Hour},
+			"[":                    {Timeout: 3 * time.Hour},
+		},
+	}
+
+	assert.Equal(t, time.Second, m.ConfigFor("/pkg.Payments/Charge").Timeout)
+	assert.Equal(t, 2*time.Second, m.ConfigFor("/pkg.Payments/Refund").Timeout)
+	// the longest pattern wins, then the lexically smallest
+	assert.Equal(t, time.Second, m.ConfigFor("/pkg.Payments/Export").Timeout)
+	assert.Equal(t, 2*time.Hour, m.ConfigFor("/pkg.Reports/Export").Timeout)
+	assert.Equal(t, time.Minute, m.ConfigFor("/pkg.Reports/Create").Timeout)
+	assert.Equal(t, 3*time.Hour, m.ConfigFor("[").Timeout)
+
+	pattern, ok := m.Match("/pkg.Payments/Charge")
+	assert.True(t, ok)
+	assert.Equal(t, "/pkg.Payments/*", pattern)
+	_, ok = m.Match("/pkg.Reports/Create")
+	assert.False(t, ok)
+}
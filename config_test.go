@@ -0,0 +1,69 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigFromJSON(t *testing.T) {
+	cfg, err := ConfigFromJSON([]byte(`{
+		"name": "payments",
+		"timeout": "30s",
+		"request_threshold": 2,
+		"ready_to_trip": {"policy": "failure_rate", "failure_rate": 0.5, "min_requests": 4},
+		"bulkhead": {"max_concurrent": 100, "max_wait": "50ms"},
+		"load_shedding": {"low_priority_failure_rate": 0.2},
+		"history_size": 10,
+		"labels": {"tier": "critical"},
+		"critical": true
+	}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "payments", cfg.Name)
+	assert.Equal(t, 30*time.Second, cfg.Timeout)
+	assert.Equal(t, uint32(2), cfg.RequestThreshold)
+	assert.Equal(t, Bulkhead{MaxConcurrent: 100, MaxWait: 50 * time.Millisecond}, cfg.Bulkhead)
+	assert.Equal(t, 0.2, cfg.LoadShedding.LowPriorityFailureRate)
+	assert.Equal(t, 10, cfg.HistorySize)
+	assert.Equal(t, map[string]string{"tier": "critical"}, cfg.Labels)
+	assert.True(t, cfg.Critical)
+
+	assert.False(t, cfg.ReadyToTrip(Counts{Requests: 3, TotalFailures: 3}))
+	assert.True(t, cfg.ReadyToTrip(Counts{Requests: 4, TotalFailures: 2}))
+	assert.False(t, cfg.ReadyToTrip(Counts{Requests: 5, TotalFailures: 2}))
+}
+
+func TestConfigFromJSONTripPolicies(t *testing.T) {
+	cfg, err := ConfigFromJSON([]byte(`{"ready_to_trip": {"policy": "consecutive_failures", "threshold": 3}}`))
+	assert.Nil(t, err)
+	assert.False(t, cfg.ReadyToTrip(Counts{ConsecutiveFailures: 2, TotalFailures: 5}))
+	assert.True(t, cfg.ReadyToTrip(Counts{ConsecutiveFailures: 3}))
+
+	cfg, err = ConfigFromJSON([]byte(`{"ready_to_trip": {"policy": "failures", "threshold": 3}}`))
+	assert.Nil(t, err)
+	assert.True(t, cfg.ReadyToTrip(Counts{ConsecutiveFailures: 1, TotalFailures: 3}))
+
+	cfg, err = ConfigFromJSON([]byte(`{}`))
+	assert.Nil(t, err)
+	assert.Nil(t, cfg.ReadyToTrip)
+}
+
+func TestConfigFromJSONInvalid(t *testing.T) {
+	for _, data := range []string{
+		`{"timeout": 30}`,
+		`{"timeout": "30 seconds"}`,
+		`{"timeout": "-1s"}`,
+		`{"time_out": "1s"}`,
+		`{"ready_to_trip": {"policy": "sometimes"}}`,
+		`{"ready_to_trip": {"policy": "consecutive_failures"}}`,
+		`{"ready_to_trip": {"policy": "failure_rate", "failure_rate": 1.5}}`,
+		`{"ready_to_trip": {"policy": "failures", "threshold": 3}, "max_consecutive_failures": 3}`,
+		`{"load_shedding": {"normal_priority_failure_rate": -0.1}}`,
+		`{"history_size": -1}`,
+		`[]`,
+	} {
+		_, err := ConfigFromJSON([]byte(data))
+		assert.NotNil(t, err, data)
+	}
+}
@@ -0,0 +1,67 @@
+package circuit_breaker
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	deadlinePercentile = 0.95
+	minLatencySamples  = 10
+)
+
+// ErrInsufficientDeadline is returned when the remaining context deadline
+// is shorter than the observed p95 latency of the protected call
+var ErrInsufficientDeadline = errors.New("insufficient deadline")
+
+// ExecuteContext runs the request with the given context if the CircuitBreaker accepts it.
+// The request is not made if the context is already done.
+// The context may carry the Priority of the request, see WithPriority.
+// If RejectInsufficientDeadline is set, the request is rejected with ErrInsufficientDeadline
+// when the context deadline is too close to complete it.
+func (cb *CircuitBreaker) ExecuteContext(ctx context.Context, req func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := cb.checkDeadline(ctx); err != nil {
+		return nil, err
+	}
+
+	return cb.execute(ctx, func() (interface{}, error) {
+		return req(ctx)
+	})
+}
+
+func (cb *CircuitBreaker) checkDeadline(ctx context.Context) error {
+	if !cb.rejectInsufficientDeadline {
+		return nil
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+
+	cb.mu.Lock()
+	reject := cb.latencies.size >= minLatencySamples &&
+		time.Until(deadline) < cb.latencies.percentile(deadlinePercentile)
+	if reject {
+		cb.onRejection(ErrInsufficientDeadline)
+	}
+	state := cb.state
+	listeners := cb.listeners
+	cb.mu.Unlock()
+
+	if !reject {
+		return nil
+	}
+	emit(listeners, CallRejected{
+		Name:          cb.name,
+		State:         state,
+		Err:           ErrInsufficientDeadline,
+		CorrelationID: cb.correlationIDOf(ctx),
+		At:            time.Now(),
+	})
+
+	return ErrInsufficientDeadline
+}
@@ -0,0 +1,53 @@
+package circuit_breaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerExecuteContext(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                       "context circuit breaker",
+		RejectInsufficientDeadline: true,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) { return "ok", nil })
+	assert.Nil(t, err)
+	assert.Equal(t, "ok", result)
+
+	cancel()
+	_, err = cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) { return "ok", nil })
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, Counts{1, 1, 0, 1, 0}, cb.counts)
+}
+
+func TestCircuitBreakerInsufficientDeadline(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                       "deadline circuit breaker",
+		RejectInsufficientDeadline: true,
+	})
+	for i := 0; i < minLatencySamples; i++ {
+		cb.latencies.record(time.Second)
+	}
+
+	short, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := cb.ExecuteContext(short, func(ctx context.Context) (interface{}, error) { return nil, nil })
+	assert.Equal(t, ErrInsufficientDeadline, err)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0}, cb.counts)
+	assert.Equal(t, Rejections{InsufficientDeadline: 1}, cb.Snapshot().Rejections)
+
+	long, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	_, err = cb.ExecuteContext(long, func(ctx context.Context) (interface{}, error) { return nil, nil })
+	assert.Nil(t, err)
+
+	// no deadline
+	_, err = cb.ExecuteContext(context.Background(), func(ctx context.Context) (interface{}, error) { return nil, nil })
+	assert.Nil(t, err)
+	assert.Equal(t, Counts{2, 2, 0, 2, 0}, cb.counts)
+}
@@ -0,0 +1,18 @@
+module github.com/shirokovnv/circuit_breaker/contrib/bolt
+
+go 1.21
+
+require (
+	github.com/shirokovnv/circuit_breaker v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.8.4
+	go.etcd.io/bbolt v1.3.10
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.15.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/shirokovnv/circuit_breaker => ../..
@@ -0,0 +1,252 @@
+// Package boltbreaker keeps the states and the transition history of circuit breakers in a bbolt database,
+// for single-binary deployments needing durable breakers without external infrastructure.
+package boltbreaker
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shirokovnv/circuit_breaker"
+	bolt "go.etcd.io/bbolt"
+)
+
+// DefaultBucket is the bucket of the breakers if the Bucket of the configuration is empty.
+const DefaultBucket = "circuit_breaker"
+
+// DefaultHistorySize is the number of transitions kept per breaker if the HistorySize of the configuration is zero.
+const DefaultHistorySize = 100
+
+var (
+	statesBucket  = []byte("states")
+	historyBucket = []byte("history")
+)
+
+// Config configures Storage.
+//
+// DB is the open database, which the application closes after the breakers are done with it.
+// Bucket is the top-level bucket of the breakers, DefaultBucket by default.
+//
+// HistorySize is the number of the last transitions kept per breaker by Record, DefaultHistorySize by default.
+//
+// OnError is called with the errors of writing the transitions recorded by Record, which are dropped.
+type Config struct {
+	DB          *bolt.DB
+	Bucket      string
+	HistorySize int
+	OnError     func(err error)
+}
+
+// Storage is the circuit_breaker.CASStorage keeping the states of the breakers in a bbolt database,
+// so they survive the restarts of the process, see circuit_breaker.Config.Storage.
+// It also keeps the transition history of the breakers passed to Record.
+type Storage struct {
+	cfg Config
+}
+
+var _ circuit_breaker.CASStorage = (*Storage)(nil)
+
+// NewStorage creates the Storage.
+func NewStorage(cfg Config) *Storage {
+	if cfg.Bucket == "" {
+		cfg.Bucket = DefaultBucket
+	}
+	if cfg.HistorySize <= 0 {
+		cfg.HistorySize = DefaultHistorySize
+	}
+
+	return &Storage{cfg: cfg}
+}
+
+// Load implements circuit_breaker.Storage.
+func (s *Storage) Load(ctx context.Context, name string) (circuit_breaker.StoredState, bool, error) {
+	var state circuit_breaker.StoredState
+	var ok bool
+	err := s.cfg.DB.View(func(tx *bolt.Tx) error {
+		var err error
+		state, ok, err = s.get(tx, name)
+		return err
+	})
+
+	return state, ok, err
+}
+
+// Store implements circuit_breaker.Storage.
+func (s *Storage) Store(ctx context.Context, name string, state circuit_breaker.StoredState) error {
+	return s.cfg.DB.Update(func(tx *bolt.Tx) error {
+		return s.put(tx, name, state)
+	})
+}
+
+// CompareAndSwap implements circuit_breaker.CASStorage within a read-write transaction.
+func (s *Storage) CompareAndSwap(ctx context.Context, name string, version uint64, state circuit_breaker.StoredState) (bool, error) {
+	swapped := false
+	err := s.cfg.DB.Update(func(tx *bolt.Tx) error {
+		current, _, err := s.get(tx, name)
+		if err != nil || current.Version != version {
+			return err
+		}
+		swapped = true
+		return s.put(tx, name, state)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return swapped, nil
+}
+
+// Record keeps the transitions of the breaker in its history, up to HistorySize of them,
+// until unsubscribe is called. Unlike the History of the breaker, they survive the restarts of the process.
+func (s *Storage) Record(cb *circuit_breaker.CircuitBreaker) (unsubscribe func()) {
+	return cb.SubscribeTransitions(func(name string, t circuit_breaker.Transition) {
+		if err := s.add(name, t); err != nil && s.cfg.OnError != nil {
+			s.cfg.OnError(err)
+		}
+	})
+}
+
+// History returns the recorded transitions of the breaker with the name, from the oldest to the newest.
+// The LastError of the transitions only keeps the message of the original error.
+func (s *Storage) History(name string) ([]circuit_breaker.Transition, error) {
+	var history []circuit_breaker.Transition
+	err := s.cfg.DB.View(func(tx *bolt.Tx) error {
+		b := s.bucket(tx, historyBucket)
+		if b != nil {
+			b = b.Bucket([]byte(name))
+		}
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var r record
+			if err := json.Unmarshal(v, &r); err != nil {
+				return fmt.Errorf("bolt: transition %d of %q: %w", binary.BigEndian.Uint64(k), name, err)
+			}
+			history = append(history, r.transition())
+			return nil
+		})
+	})
+
+	return history, err
+}
+
+// record is a Transition with the error encoded as a string
+type record struct {
+	At        time.Time
+	From      circuit_breaker.State
+	To        circuit_breaker.State
+	Reason    string
+	Counts    circuit_breaker.Counts
+	LastError string `json:",omitempty"`
+}
+
+func (r record) transition() circuit_breaker.Transition {
+	t := circuit_breaker.Transition{At: r.At, From: r.From, To: r.To, Reason: r.Reason, Counts: r.Counts}
+	if r.LastError != "" {
+		t.LastError = errors.New(r.LastError)
+	}
+
+	return t
+}
+
+// add appends the transition to the history of the breaker, removing the oldest ones beyond HistorySize
+func (s *Storage) add(name string, t circuit_breaker.Transition) error {
+	r := record{At: t.At, From: t.From, To: t.To, Reason: t.Reason, Counts: t.Counts}
+	if t.LastError != nil {
+		r.LastError = t.LastError.Error()
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	return s.cfg.DB.Update(func(tx *bolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists([]byte(s.cfg.Bucket))
+		if err != nil {
+			return fmt.Errorf("bolt: bucket %q: %w", s.cfg.Bucket, err)
+		}
+		history, err := root.CreateBucketIfNotExists(historyBucket)
+		if err != nil {
+			return fmt.Errorf("bolt: history bucket: %w", err)
+		}
+		b, err := history.CreateBucketIfNotExists([]byte(name))
+		if err != nil {
+			return fmt.Errorf("bolt: history of %q: %w", name, err)
+		}
+
+		// the keys are big-endian sequence numbers, so the cursor iterates from the oldest
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		if err := b.Put(key, data); err != nil {
+			return err
+		}
+
+		n := 0
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			n++
+		}
+		for k, _ := c.First(); k != nil && n > s.cfg.HistorySize; k, _ = c.First() {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+			n--
+		}
+		return nil
+	})
+}
+
+// get reads the state of the breaker within the transaction
+func (s *Storage) get(tx *bolt.Tx, name string) (circuit_breaker.StoredState, bool, error) {
+	var state circuit_breaker.StoredState
+	b := s.bucket(tx, statesBucket)
+	if b == nil {
+		return state, false, nil
+	}
+	data := b.Get([]byte(name))
+	if data == nil {
+		return state, false, nil
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, false, fmt.Errorf("bolt: state of %q: %w", name, err)
+	}
+
+	return state, true, nil
+}
+
+// put writes the state of the breaker within the transaction
+func (s *Storage) put(tx *bolt.Tx, name string, state circuit_breaker.StoredState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	root, err := tx.CreateBucketIfNotExists([]byte(s.cfg.Bucket))
+	if err != nil {
+		return fmt.Errorf("bolt: bucket %q: %w", s.cfg.Bucket, err)
+	}
+	b, err := root.CreateBucketIfNotExists(statesBucket)
+	if err != nil {
+		return fmt.Errorf("bolt: states bucket: %w", err)
+	}
+
+	return b.Put([]byte(name), data)
+}
+
+// bucket returns the nested bucket of the breakers, which is nil if nothing was written yet
+func (s *Storage) bucket(tx *bolt.Tx, name []byte) *bolt.Bucket {
+	root := tx.Bucket([]byte(s.cfg.Bucket))
+	if root == nil {
+		return nil
+	}
+
+	return root.Bucket(name)
+}
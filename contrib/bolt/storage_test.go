@@ -0,0 +1,117 @@
+package boltbreaker
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+)
+
+func open(t *testing.T, path string) *bolt.DB {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	assert.Nil(t, err)
+	return db
+}
+
+func TestStorage(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "breakers.db")
+	db := open(t, path)
+	storage := NewStorage(Config{DB: db})
+
+	_, ok, err := storage.Load(ctx, "payments")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	cfg := circuit_breaker.Config{Name: "payments", MaxConsecutiveFailures: 1, Timeout: time.Minute, Storage: storage}
+	cb := circuit_breaker.NewCircuitBreaker(cfg)
+	_, err = cb.Execute(func() (interface{}, error) { return nil, errors.New("unavailable") })
+	assert.NotNil(t, err)
+	assert.Equal(t, circuit_breaker.StateOpen, cb.State())
+	cb.WaitBackground()
+	assert.Nil(t, db.Close())
+
+	// the restarted process restores the open state
+	db = open(t, path)
+	defer db.Close()
+	cfg.Storage = NewStorage(Config{DB: db})
+	restarted := circuit_breaker.NewCircuitBreaker(cfg)
+	assert.Equal(t, circuit_breaker.StateOpen, restarted.State())
+	assert.Equal(t, cb.OpensAt().UnixNano(), restarted.OpensAt().UnixNano())
+
+	state, ok, err := cfg.Storage.Load(ctx, "payments")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	swapped, err := cfg.Storage.(*Storage).CompareAndSwap(ctx, "payments", state.Version-1, state)
+	assert.Nil(t, err)
+	assert.False(t, swapped)
+	state.Version++
+	swapped, err = cfg.Storage.(*Storage).CompareAndSwap(ctx, "payments", state.Version-1, state)
+	assert.Nil(t, err)
+	assert.True(t, swapped)
+}
+
+func TestStorageHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breakers.db")
+	db := open(t, path)
+	storage := NewStorage(Config{DB: db, Bucket: "breakers", HistorySize: 2})
+
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "payments", MaxConsecutiveFailures: 1})
+	unsubscribe := storage.Record(cb)
+	_, _ = cb.Execute(func() (interface{}, error) { return nil, errors.New("unavailable") })
+	cb.Reset()
+	cb.Trip()
+	unsubscribe()
+	cb.Reset()
+	assert.Nil(t, db.Close())
+
+	db = open(t, path)
+	defer db.Close()
+	storage = NewStorage(Config{DB: db, Bucket: "breakers"})
+	history, err := storage.History("payments")
+	assert.Nil(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, circuit_breaker.ReasonReset, history[0].Reason)
+	assert.Equal(t, circuit_breaker.StateOpen, history[1].To)
+	assert.Equal(t, circuit_breaker.ReasonManualTrip, history[1].Reason)
+
+	history, err = storage.History("search")
+	assert.Nil(t, err)
+	assert.Empty(t, history)
+}
+
+func TestStorageHistoryLastError(t *testing.T) {
+	db := open(t, filepath.Join(t.TempDir(), "breakers.db"))
+	defer db.Close()
+	storage := NewStorage(Config{DB: db})
+
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "payments", MaxConsecutiveFailures: 1})
+	defer storage.Record(cb)()
+	_, _ = cb.Execute(func() (interface{}, error) { return nil, errors.New("unavailable") })
+
+	history, err := storage.History("payments")
+	assert.Nil(t, err)
+	assert.Len(t, history, 1)
+	assert.Equal(t, circuit_breaker.ReasonTripped, history[0].Reason)
+	assert.EqualError(t, history[0].LastError, "unavailable")
+	assert.Equal(t, uint32(1), history[0].Counts.ConsecutiveFailures)
+}
+
+func TestStorageErrors(t *testing.T) {
+	db := open(t, filepath.Join(t.TempDir(), "breakers.db"))
+	var errs []error
+	storage := NewStorage(Config{DB: db, OnError: func(err error) { errs = append(errs, err) }})
+	assert.Nil(t, db.Close())
+
+	_, _, err := storage.Load(context.Background(), "payments")
+	assert.ErrorIs(t, err, bolt.ErrDatabaseNotOpen)
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "payments"})
+	storage.Record(cb)
+	cb.Trip()
+	assert.Len(t, errs, 1)
+}
@@ -0,0 +1,55 @@
+// Package chibreaker protects chi routes with a circuit breaker per route pattern.
+package chibreaker
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+// Config configures the middleware.
+//
+// Breakers holds a breaker per route, keyed by the method and the route pattern (e.g. "GET /users/{id}"),
+// sharing the configuration of the KeyedBreaker. Requests not matching any route are not protected.
+//
+// Skipper, Classifier and OnReject have the same meaning as in circuit_breaker.MiddlewareConfig.
+type Config struct {
+	Breakers   *circuit_breaker.KeyedBreaker
+	Skipper    func(r *http.Request) bool
+	Classifier circuit_breaker.HTTPClassifier
+	OnReject   func(w http.ResponseWriter, r *http.Request, cb *circuit_breaker.CircuitBreaker, err error)
+}
+
+// Middleware creates the middleware. It can be mounted with Use at any level of the router.
+func Middleware(cfg Config) func(next http.Handler) http.Handler {
+	return circuit_breaker.Middleware(circuit_breaker.MiddlewareConfig{
+		Breaker: func(r *http.Request) *circuit_breaker.CircuitBreaker {
+			pattern := RoutePattern(r)
+			if pattern == "" {
+				return nil
+			}
+			return cfg.Breakers.Get(r.Method + " " + pattern)
+		},
+		Skipper:    cfg.Skipper,
+		Classifier: cfg.Classifier,
+		OnReject:   cfg.OnReject,
+	})
+}
+
+// RoutePattern returns the full route pattern matching the request, or "" if there is none.
+// Unlike chi.RouteContext(ctx).RoutePattern(), it is complete before the routing is done,
+// i.e. inside the middlewares.
+func RoutePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return ""
+	}
+
+	path := r.URL.RawPath
+	if path == "" {
+		path = r.URL.Path
+	}
+
+	return rctx.Routes.Find(chi.NewRouteContext(), r.Method, path)
+}
@@ -0,0 +1,61 @@
+package chibreaker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(h http.Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	kb := circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{MaxConsecutiveFailures: 1},
+	})
+
+	r := chi.NewRouter()
+	r.Use(Middleware(Config{Breakers: kb}))
+	r.Get("/ping", func(w http.ResponseWriter, r *http.Request) {})
+	r.Route("/reports", func(r chi.Router) {
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		})
+	})
+
+	assert.Equal(t, http.StatusBadGateway, serve(r, "/reports/1").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, serve(r, "/reports/2").Code)
+	assert.Equal(t, http.StatusOK, serve(r, "/ping").Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, "/unknown").Code)
+	assert.ElementsMatch(t, []string{"GET /reports/{id}", "GET /ping"}, kb.Keys())
+}
+
+func TestMiddlewareOnReject(t *testing.T) {
+	kb := circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{MaxConsecutiveFailures: 1},
+	})
+
+	r := chi.NewRouter()
+	r.Use(Middleware(Config{
+		Breakers: kb,
+		OnReject: func(w http.ResponseWriter, r *http.Request, cb *circuit_breaker.CircuitBreaker, err error) {
+			w.Header().Set("X-Breaker", cb.Name())
+			w.WriteHeader(http.StatusTooManyRequests)
+		},
+	}))
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, serve(r, "/users/1").Code)
+	w := serve(r, "/users/2")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "GET /users/{id}", w.Header().Get("X-Breaker"))
+}
@@ -0,0 +1,41 @@
+// Package connectbreaker protects connect-go RPCs with circuit breakers.
+package connectbreaker
+
+import "connectrpc.com/connect"
+
+// DefaultFailureCodes are the codes signalling that the peer is unhealthy.
+// Codes like NotFound or InvalidArgument are caused by the request and don't count as failures.
+var DefaultFailureCodes = []connect.Code{
+	connect.CodeUnavailable,
+	connect.CodeDeadlineExceeded,
+	connect.CodeResourceExhausted,
+	connect.CodeInternal,
+	connect.CodeUnknown,
+}
+
+// Classifier maps the error of an RPC to the outcome recorded by the breaker.
+// If FailureCodes is empty, DefaultFailureCodes are used.
+type Classifier struct {
+	FailureCodes []connect.Code
+}
+
+// Classify returns err if it is a failure, and nil otherwise.
+func (c Classifier) Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	failureCodes := c.FailureCodes
+	if len(failureCodes) == 0 {
+		failureCodes = DefaultFailureCodes
+	}
+
+	code := connect.CodeOf(err)
+	for _, failure := range failureCodes {
+		if code == failure {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,24 @@
+package connectbreaker
+
+import (
+	"errors"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifier(t *testing.T) {
+	unavailable := connect.NewError(connect.CodeUnavailable, errors.New("unavailable"))
+	notFound := connect.NewError(connect.CodeNotFound, errors.New("not found"))
+	plain := errors.New("plain")
+
+	assert.Nil(t, Classifier{}.Classify(nil))
+	assert.Equal(t, unavailable, Classifier{}.Classify(unavailable))
+	assert.Nil(t, Classifier{}.Classify(notFound))
+	assert.Equal(t, plain, Classifier{}.Classify(plain))
+
+	c := Classifier{FailureCodes: []connect.Code{connect.CodeNotFound}}
+	assert.Nil(t, c.Classify(unavailable))
+	assert.Equal(t, notFound, c.Classify(notFound))
+}
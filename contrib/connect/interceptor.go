@@ -0,0 +1,100 @@
+package connectbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+// Config configures the interceptor.
+//
+// Breaker selects the breaker protecting the RPC by its procedure ("/pkg.Service/Method").
+// If Breaker returns nil, the RPC is not protected.
+//
+// Classifier maps the error of the RPC to its outcome.
+type Config struct {
+	Breaker    func(procedure string) *circuit_breaker.CircuitBreaker
+	Classifier Classifier
+}
+
+// Interceptor protects unary RPCs with circuit breakers. Streaming RPCs pass through.
+//
+// Mounted on a client, it fails rejected RPCs with connect.CodeUnavailable.
+// Mounted on a handler, it sheds inbound RPCs with connect.CodeResourceExhausted,
+// the same error mapping as the grpc-go integration.
+type Interceptor struct {
+	cfg Config
+}
+
+var _ connect.Interceptor = (*Interceptor)(nil)
+
+// New creates the interceptor protecting all the RPCs with a single breaker.
+func New(cb *circuit_breaker.CircuitBreaker) *Interceptor {
+	return NewInterceptor(Config{
+		Breaker: func(procedure string) *circuit_breaker.CircuitBreaker { return cb },
+	})
+}
+
+// PerProcedure creates the interceptor protecting each procedure with its own breaker.
+func PerProcedure(kb *circuit_breaker.KeyedBreaker) *Interceptor {
+	return NewInterceptor(Config{Breaker: kb.Get})
+}
+
+// NewInterceptor creates the interceptor.
+func NewInterceptor(cfg Config) *Interceptor {
+	return &Interceptor{cfg: cfg}
+}
+
+// WrapUnary implements connect.Interceptor.
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		procedure := req.Spec().Procedure
+		cb := i.cfg.Breaker(procedure)
+		if cb == nil {
+			return next(ctx, req)
+		}
+
+		handled := false
+		var resp connect.AnyResponse
+		var callErr error
+		_, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+			handled = true
+			resp, callErr = next(ctx, req)
+			return nil, i.cfg.Classifier.Classify(callErr)
+		})
+		if !handled {
+			code := connect.CodeResourceExhausted
+			if req.Spec().IsClient {
+				code = connect.CodeUnavailable
+			}
+			return nil, rejection(code, cb, procedure, err)
+		}
+
+		return resp, callErr
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor.
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler implements connect.Interceptor.
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+// rejection converts the rejection error of the breaker into a connect error
+func rejection(code connect.Code, cb *circuit_breaker.CircuitBreaker, procedure string, err error) error {
+	if errors.Is(err, context.Canceled) {
+		return connect.NewError(connect.CodeCanceled, err)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return connect.NewError(connect.CodeDeadlineExceeded, err)
+	}
+
+	return connect.NewError(code, fmt.Errorf("%s rejected by circuit breaker %q: %w", procedure, cb.Name(), err))
+}
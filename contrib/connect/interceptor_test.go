@@ -0,0 +1,90 @@
+package connectbreaker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const procedure = "/reports.Reports/GetReport"
+
+// serve starts a server answering the procedure with the errors in order, then with success
+func serve(t *testing.T, errs []error, opts ...connect.HandlerOption) (*httptest.Server, *int) {
+	calls := 0
+	mux := http.NewServeMux()
+	mux.Handle(procedure, connect.NewUnaryHandler(procedure,
+		func(ctx context.Context, req *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+			calls++
+			if calls <= len(errs) {
+				return nil, errs[calls-1]
+			}
+			return connect.NewResponse(&emptypb.Empty{}), nil
+		}, opts...))
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func TestClientInterceptor(t *testing.T) {
+	unavailable := connect.NewError(connect.CodeUnavailable, errors.New("unavailable"))
+	notFound := connect.NewError(connect.CodeNotFound, errors.New("not found"))
+	server, calls := serve(t, []error{notFound, unavailable, unavailable})
+
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "reports", MaxConsecutiveFailures: 2})
+	client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+procedure,
+		connect.WithInterceptors(New(cb)))
+	ctx := context.Background()
+
+	for _, code := range []connect.Code{connect.CodeNotFound, connect.CodeUnavailable, connect.CodeUnavailable} {
+		_, err := client.CallUnary(ctx, connect.NewRequest(&emptypb.Empty{}))
+		assert.Equal(t, code, connect.CodeOf(err))
+	}
+	assert.Equal(t, circuit_breaker.StateOpen, cb.State())
+
+	_, err := client.CallUnary(ctx, connect.NewRequest(&emptypb.Empty{}))
+	assert.Equal(t, connect.CodeUnavailable, connect.CodeOf(err))
+	assert.ErrorIs(t, err, circuit_breaker.ErrOpenState)
+	assert.Equal(t, 3, *calls)
+}
+
+func TestHandlerInterceptor(t *testing.T) {
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "storage", MaxConsecutiveFailures: 1})
+	internal := connect.NewError(connect.CodeInternal, errors.New("storage failed"))
+	server, calls := serve(t, []error{internal}, connect.WithInterceptors(New(cb)))
+
+	client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+procedure)
+	ctx := context.Background()
+
+	_, err := client.CallUnary(ctx, connect.NewRequest(&emptypb.Empty{}))
+	assert.Equal(t, connect.CodeInternal, connect.CodeOf(err))
+
+	_, err = client.CallUnary(ctx, connect.NewRequest(&emptypb.Empty{}))
+	assert.Equal(t, connect.CodeResourceExhausted, connect.CodeOf(err))
+	assert.Contains(t, err.Error(), `rejected by circuit breaker "storage"`)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestPerProcedure(t *testing.T) {
+	kb := circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{MaxConsecutiveFailures: 1},
+	})
+	server, _ := serve(t, []error{connect.NewError(connect.CodeUnavailable, errors.New("unavailable"))})
+
+	client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+procedure,
+		connect.WithInterceptors(PerProcedure(kb)))
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	assert.Equal(t, connect.CodeUnavailable, connect.CodeOf(err))
+
+	cb, ok := kb.Lookup(procedure)
+	assert.True(t, ok)
+	assert.Equal(t, circuit_breaker.StateOpen, cb.State())
+}
@@ -0,0 +1,162 @@
+// Package consulbreaker provides the configurations of circuit breakers from the Consul KV store.
+package consulbreaker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+const defaultRetryInterval = 5 * time.Second
+
+// Config configures Provider.
+//
+// Client is the Consul client. Prefix is the KV prefix of the configurations, e.g. "circuit_breakers/":
+// the key of a breaker is the prefix followed by its name, and its value is the JSON of circuit_breaker.ConfigFromJSON.
+//
+// Fallback are the local configurations of the breakers, delivered until the store is reachable,
+// and again when the key of a breaker is deleted.
+// While the store is unavailable, the breakers keep their last configurations.
+//
+// RetryInterval is the delay before querying the store again after an error, 5 seconds by default.
+//
+// OnError is called with the errors of the store and the invalid configurations, which are skipped.
+type Config struct {
+	Client        *api.Client
+	Prefix        string
+	Fallback      map[string]circuit_breaker.Config
+	RetryInterval time.Duration
+	OnError       func(err error)
+}
+
+// Provider is the circuit_breaker.ConfigProvider watching the configurations in the Consul KV store
+// by blocking queries, so the updates propagate to the fleet within seconds, see circuit_breaker.WatchConfig.
+type Provider struct {
+	cfg    Config
+	feed   *circuit_breaker.ConfigFeed
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+var _ circuit_breaker.ConfigProvider = (*Provider)(nil)
+
+// New creates the Provider and starts watching the store until Close.
+func New(cfg Config) *Provider {
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = defaultRetryInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Provider{
+		cfg:    cfg,
+		feed:   circuit_breaker.NewConfigFeed(),
+		cancel: cancel,
+		done:   make(chan struct{}),
+		values: make(map[string][]byte),
+	}
+	fallback := make(map[string]circuit_breaker.Config, len(cfg.Fallback))
+	for name, c := range cfg.Fallback {
+		c.Name = name
+		fallback[name] = c
+		p.feed.Set(name, c)
+	}
+	p.cfg.Fallback = fallback
+	go p.watch(ctx)
+
+	return p
+}
+
+// Watch implements circuit_breaker.ConfigProvider.
+func (p *Provider) Watch(name string) <-chan circuit_breaker.Config {
+	return p.feed.Watch(name)
+}
+
+// Close stops watching the store, closing the channels of the watchers.
+func (p *Provider) Close() {
+	p.cancel()
+	<-p.done
+	p.feed.Close()
+}
+
+func (p *Provider) watch(ctx context.Context) {
+	defer close(p.done)
+
+	kv := p.cfg.Client.KV()
+	var index uint64
+	for {
+		opts := (&api.QueryOptions{WaitIndex: index}).WithContext(ctx)
+		pairs, meta, err := kv.List(p.cfg.Prefix, opts)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			p.onError(fmt.Errorf("consul: list %q: %w", p.cfg.Prefix, err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.cfg.RetryInterval):
+			}
+			continue
+		}
+
+		// the index goes backwards when the store is restored, see the Consul blocking queries
+		if meta.LastIndex < index {
+			index = 0
+		} else {
+			index = meta.LastIndex
+		}
+		p.apply(pairs)
+	}
+}
+
+// apply delivers the configurations of the keys which changed
+func (p *Provider) apply(pairs api.KVPairs) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	seen := make(map[string]bool, len(pairs))
+	for _, pair := range pairs {
+		name := strings.TrimPrefix(pair.Key, p.cfg.Prefix)
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		seen[name] = true
+		if prev, ok := p.values[name]; ok && bytes.Equal(prev, pair.Value) {
+			continue
+		}
+		p.values[name] = pair.Value
+
+		cfg, err := circuit_breaker.ConfigFromJSON(pair.Value)
+		if err != nil {
+			p.onError(fmt.Errorf("consul: key %q: %w", pair.Key, err))
+			continue
+		}
+		cfg.Name = name
+		p.feed.Set(name, cfg)
+	}
+
+	for name := range p.values {
+		if seen[name] {
+			continue
+		}
+		delete(p.values, name)
+		if fallback, ok := p.cfg.Fallback[name]; ok {
+			p.feed.Set(name, fallback)
+		}
+	}
+}
+
+func (p *Provider) onError(err error) {
+	if p.cfg.OnError != nil {
+		p.cfg.OnError(err)
+	}
+}
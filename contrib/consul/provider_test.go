@@ -0,0 +1,168 @@
+package consulbreaker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeKV serves the blocking queries of the Consul KV store
+type fakeKV struct {
+	mu      sync.Mutex
+	index   uint64
+	pairs   map[string]string
+	changed chan struct{}
+	down    bool
+}
+
+func newFakeKV() *fakeKV {
+	return &fakeKV{index: 1, pairs: make(map[string]string), changed: make(chan struct{})}
+}
+
+func (kv *fakeKV) set(key, value string) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.pairs[key] = value
+	kv.bump()
+}
+
+func (kv *fakeKV) delete(key string) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	delete(kv.pairs, key)
+	kv.bump()
+}
+
+func (kv *fakeKV) setDown(down bool) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.down = down
+}
+
+func (kv *fakeKV) bump() {
+	kv.index++
+	close(kv.changed)
+	kv.changed = make(chan struct{})
+}
+
+func (kv *fakeKV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	prefix := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	wait, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+
+	kv.mu.Lock()
+	if kv.index == wait {
+		changed := kv.changed
+		kv.mu.Unlock()
+		select {
+		case <-changed:
+		case <-time.After(50 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		kv.mu.Lock()
+	}
+	defer kv.mu.Unlock()
+
+	if kv.down {
+		http.Error(w, "no cluster leader", http.StatusInternalServerError)
+		return
+	}
+	var pairs api.KVPairs
+	for key, value := range kv.pairs {
+		if strings.HasPrefix(key, prefix) {
+			pairs = append(pairs, &api.KVPair{Key: key, Value: []byte(value), ModifyIndex: kv.index})
+		}
+	}
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(kv.index, 10))
+	if len(pairs) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(pairs)
+}
+
+func TestProvider(t *testing.T) {
+	kv := newFakeKV()
+	kv.setDown(true)
+	srv := httptest.NewServer(kv)
+	defer srv.Close()
+
+	client, err := api.NewClient(&api.Config{Address: srv.URL})
+	assert.Nil(t, err)
+	var errs []error
+	var errsMu sync.Mutex
+	p := New(Config{
+		Client:        client,
+		Prefix:        "circuit_breakers/",
+		Fallback:      map[string]circuit_breaker.Config{"payments": {MaxConsecutiveFailures: 5}},
+		RetryInterval: 10 * time.Millisecond,
+		OnError: func(err error) {
+			errsMu.Lock()
+			defer errsMu.Unlock()
+			errs = append(errs, err)
+		},
+	})
+	defer p.Close()
+
+	// the fallback is delivered while the store is unavailable
+	updates := p.Watch("payments")
+	cfg := <-updates
+	assert.Equal(t, "payments", cfg.Name)
+	assert.Equal(t, uint32(5), cfg.MaxConsecutiveFailures)
+	assert.Eventually(t, func() bool {
+		errsMu.Lock()
+		defer errsMu.Unlock()
+		return len(errs) > 0
+	}, time.Second, time.Millisecond)
+
+	kv.set("circuit_breakers/payments", `{"max_consecutive_failures": 2, "timeout": "10s"}`)
+	kv.setDown(false)
+	cfg = <-updates
+	assert.Equal(t, "payments", cfg.Name)
+	assert.Equal(t, uint32(2), cfg.MaxConsecutiveFailures)
+	assert.Equal(t, 10*time.Second, cfg.Timeout)
+
+	// the invalid configurations are skipped
+	kv.set("circuit_breakers/payments", `{"timeout": 10}`)
+	kv.set("circuit_breakers/payments", `{"max_consecutive_failures": 3}`)
+	cfg = <-updates
+	assert.Equal(t, uint32(3), cfg.MaxConsecutiveFailures)
+
+	// the fallback is restored when the key is deleted
+	kv.delete("circuit_breakers/payments")
+	cfg = <-updates
+	assert.Equal(t, uint32(5), cfg.MaxConsecutiveFailures)
+}
+
+func TestProviderWatchConfig(t *testing.T) {
+	kv := newFakeKV()
+	kv.set("circuit_breakers/search", `{"max_consecutive_failures": 1}`)
+	srv := httptest.NewServer(kv)
+	defer srv.Close()
+
+	client, err := api.NewClient(&api.Config{Address: srv.URL})
+	assert.Nil(t, err)
+	p := New(Config{Client: client, Prefix: "circuit_breakers/"})
+
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "search", MaxConsecutiveFailures: 100})
+	stop := circuit_breaker.WatchConfig(cb, p)
+	defer stop()
+
+	assert.Eventually(t, func() bool {
+		_, _ = cb.Execute(func() (interface{}, error) { return nil, assert.AnError })
+		return cb.State() == circuit_breaker.StateOpen
+	}, time.Second, time.Millisecond)
+
+	p.Close()
+	_, ok := <-p.Watch("search")
+	assert.False(t, ok)
+}
@@ -0,0 +1,95 @@
+// Package echobreaker protects Echo handlers with circuit breakers,
+// with the same semantics as circuit_breaker.Middleware.
+package echobreaker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+// Config configures the middleware.
+//
+// Breaker selects the breaker protecting the request, e.g. per route with PerRoute.
+// If Breaker returns nil, the request is not protected.
+//
+// Skipper excludes requests from the protection.
+//
+// Classifier maps the status code of the response to the request outcome.
+// The zero value treats 5xx and 429 as failures.
+//
+// OnReject handles a request rejected by the breaker.
+// If OnReject is nil, circuit_breaker.WriteRejection responds with 503 and Retry-After.
+type Config struct {
+	Breaker    func(c echo.Context) *circuit_breaker.CircuitBreaker
+	Skipper    func(c echo.Context) bool
+	Classifier circuit_breaker.HTTPClassifier
+	OnReject   func(c echo.Context, cb *circuit_breaker.CircuitBreaker, err error) error
+}
+
+// Middleware protects all the routes with a single breaker.
+func Middleware(cb *circuit_breaker.CircuitBreaker) echo.MiddlewareFunc {
+	return New(Config{
+		Breaker: func(c echo.Context) *circuit_breaker.CircuitBreaker { return cb },
+	})
+}
+
+// PerRoute selects the breaker by the method and the route pattern (e.g. "GET /users/:id").
+func PerRoute(kb *circuit_breaker.KeyedBreaker) func(c echo.Context) *circuit_breaker.CircuitBreaker {
+	return func(c echo.Context) *circuit_breaker.CircuitBreaker {
+		return kb.Get(c.Request().Method + " " + c.Path())
+	}
+}
+
+// New creates the middleware.
+func New(cfg Config) echo.MiddlewareFunc {
+	if cfg.OnReject == nil {
+		cfg.OnReject = defaultOnReject
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if cfg.Skipper != nil && cfg.Skipper(c) {
+				return next(c)
+			}
+			cb := cfg.Breaker(c)
+			if cb == nil {
+				return next(c)
+			}
+
+			handled := false
+			var handlerErr error
+			_, err := cb.ExecuteContext(c.Request().Context(), func(ctx context.Context) (interface{}, error) {
+				handled = true
+				handlerErr = next(c)
+				return nil, cfg.Classifier.ClassifyStatus(status(c, handlerErr))
+			})
+			if !handled {
+				return cfg.OnReject(c, cb, err)
+			}
+
+			return handlerErr
+		}
+	}
+}
+
+// status returns the status code the response will have once the handler error is handled by Echo
+func status(c echo.Context, err error) int {
+	if err == nil {
+		return c.Response().Status
+	}
+
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+	return http.StatusInternalServerError
+}
+
+func defaultOnReject(c echo.Context, cb *circuit_breaker.CircuitBreaker, err error) error {
+	circuit_breaker.WriteRejection(c.Response(), cb, err)
+	return nil
+}
@@ -0,0 +1,57 @@
+package echobreaker
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(e *echo.Echo, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{MaxConsecutiveFailures: 2})
+	e := echo.New()
+	e.Use(Middleware(cb))
+	e.GET("/fail", func(c echo.Context) error { return errors.New("boom") })
+	e.GET("/unavailable", func(c echo.Context) error { return echo.NewHTTPError(http.StatusServiceUnavailable) })
+	e.GET("/ok", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
+
+	assert.Equal(t, http.StatusOK, serve(e, "/ok").Code)
+	assert.Equal(t, http.StatusInternalServerError, serve(e, "/fail").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, serve(e, "/unavailable").Code)
+	assert.Equal(t, circuit_breaker.StateOpen, cb.State())
+
+	w := serve(e, "/ok")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+}
+
+func TestPerRouteWithSkipper(t *testing.T) {
+	kb := circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{MaxConsecutiveFailures: 1},
+	})
+	e := echo.New()
+	e.Use(New(Config{
+		Breaker: PerRoute(kb),
+		Skipper: func(c echo.Context) bool { return c.Path() == "/healthz" },
+	}))
+	e.GET("/reports/:id", func(c echo.Context) error { return c.NoContent(http.StatusBadGateway) })
+	e.GET("/healthz", func(c echo.Context) error { return c.NoContent(http.StatusInternalServerError) })
+	e.GET("/ping", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	assert.Equal(t, http.StatusBadGateway, serve(e, "/reports/1").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, serve(e, "/reports/2").Code)
+	assert.Equal(t, http.StatusOK, serve(e, "/ping").Code)
+	assert.Equal(t, http.StatusInternalServerError, serve(e, "/healthz").Code)
+	assert.Equal(t, http.StatusInternalServerError, serve(e, "/healthz").Code)
+	assert.ElementsMatch(t, []string{"GET /reports/:id", "GET /ping"}, kb.Keys())
+}
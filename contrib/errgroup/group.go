@@ -0,0 +1,53 @@
+// Package errgroupbreaker guards the branches of an errgroup.Group with circuit breakers.
+package errgroupbreaker
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"golang.org/x/sync/errgroup"
+)
+
+// Go calls fn in a new goroutine of the group if the breaker accepts it.
+//
+// ctx is the context of the group, as returned by errgroup.WithContext.
+// A rejection is returned from the branch as is, e.g. circuit_breaker.ErrOpenState,
+// so it fails the group and cancels the other branches like any other error.
+// Branches failing because the group has already been cancelled are not counted as failures,
+// so one rejected or failed branch doesn't trip the breakers of its siblings.
+// Branches started after the group has been cancelled are not run.
+func Go(ctx context.Context, g *errgroup.Group, cb *circuit_breaker.CircuitBreaker, fn func(ctx context.Context) error) {
+	g.Go(branch(ctx, cb, fn))
+}
+
+// TryGo is like Go, but calls fn only if the number of active goroutines of the group is below its limit,
+// see errgroup.Group.TryGo. It reports whether the branch was started.
+func TryGo(ctx context.Context, g *errgroup.Group, cb *circuit_breaker.CircuitBreaker, fn func(ctx context.Context) error) bool {
+	return g.TryGo(branch(ctx, cb, fn))
+}
+
+func branch(ctx context.Context, cb *circuit_breaker.CircuitBreaker, fn func(ctx context.Context) error) func() error {
+	return func() error {
+		handled := false
+		var fnErr error
+		_, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+			handled = true
+			fnErr = fn(ctx)
+			if fnErr != nil && ctx.Err() != nil && errors.Is(fnErr, ctx.Err()) {
+				// cancelled by the group, not a failure of the dependency
+				return nil, nil
+			}
+			return nil, fnErr
+		})
+		if !handled {
+			if err == nil {
+				// a branch which didn't run is a rejection, so it must fail the group
+				err = circuit_breaker.ErrTooManyRequests
+			}
+			return err
+		}
+
+		return fnErr
+	}
+}
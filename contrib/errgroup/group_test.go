@@ -0,0 +1,61 @@
+package errgroupbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestGo(t *testing.T) {
+	reports := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{MaxConsecutiveFailures: 1})
+	users := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{MaxConsecutiveFailures: 1})
+	errUnavailable := errors.New("unavailable")
+
+	g, ctx := errgroup.WithContext(context.Background())
+	Go(ctx, g, reports, func(ctx context.Context) error {
+		return errUnavailable
+	})
+	Go(ctx, g, users, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.Equal(t, errUnavailable, g.Wait())
+
+	assert.Equal(t, circuit_breaker.StateOpen, reports.State())
+	// the cancelled sibling is not counted as a failure
+	assert.Equal(t, circuit_breaker.StateClosed, users.State())
+	assert.Equal(t, uint32(0), users.Counts().TotalFailures)
+
+	calls := 0
+	g, ctx = errgroup.WithContext(context.Background())
+	Go(ctx, g, reports, func(ctx context.Context) error {
+		calls++
+		return nil
+	})
+	assert.Equal(t, circuit_breaker.ErrOpenState, g.Wait())
+	assert.Equal(t, 0, calls)
+}
+
+func TestTryGo(t *testing.T) {
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{})
+
+	var g errgroup.Group
+	g.SetLimit(1)
+	release := make(chan struct{})
+
+	assert.True(t, TryGo(context.Background(), &g, cb, func(ctx context.Context) error {
+		<-release
+		return nil
+	}))
+	assert.False(t, TryGo(context.Background(), &g, cb, func(ctx context.Context) error {
+		return nil
+	}))
+
+	close(release)
+	assert.Nil(t, g.Wait())
+	assert.Equal(t, uint32(1), cb.Counts().TotalSuccesses)
+}
@@ -0,0 +1,247 @@
+package etcdbreaker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// StorageConfig configures Storage.
+//
+// Client is the etcd client. Prefix is the prefix of the states, e.g. "/circuit_breakers/states/":
+// the key of a breaker is the prefix followed by its name. It must differ from the prefix of the Provider.
+//
+// Lease grants the leases of the states, usually the same *clientv3.Client as Client.
+// If Lease is set and TTL is positive, a state expires TTL after it was last stored,
+// e.g. so the states of the removed breakers don't pile up. TTL must be longer than the open periods of the breakers.
+type StorageConfig struct {
+	Client Client
+	Lease  clientv3.Lease
+	Prefix string
+	TTL    time.Duration
+}
+
+// Storage is the circuit_breaker.CASStorage keeping the states of the breakers in etcd,
+// so the fleet shares them and a restarted instance restores them, see circuit_breaker.Config.Storage.
+type Storage struct {
+	cfg StorageConfig
+}
+
+var _ circuit_breaker.CASStorage = (*Storage)(nil)
+
+// NewStorage creates the Storage.
+func NewStorage(cfg StorageConfig) *Storage {
+	return &Storage{cfg: cfg}
+}
+
+// Load implements circuit_breaker.Storage.
+func (s *Storage) Load(ctx context.Context, name string) (circuit_breaker.StoredState, bool, error) {
+	state, kv, err := s.get(ctx, name)
+	return state, kv != nil, err
+}
+
+// Store implements circuit_breaker.Storage.
+func (s *Storage) Store(ctx context.Context, name string, state circuit_breaker.StoredState) error {
+	key := s.cfg.Prefix + name
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	opts, err := leaseOptions(ctx, s.cfg.Lease, s.cfg.TTL)
+	if err != nil {
+		return err
+	}
+	if _, err := s.cfg.Client.Put(ctx, key, string(data), opts...); err != nil {
+		return fmt.Errorf("etcd: put %q: %w", key, err)
+	}
+
+	return nil
+}
+
+// CompareAndSwap implements circuit_breaker.CASStorage by a transaction
+// on the revision of the key holding the expected version.
+func (s *Storage) CompareAndSwap(ctx context.Context, name string, version uint64, state circuit_breaker.StoredState) (bool, error) {
+	key := s.cfg.Prefix + name
+	current, kv, err := s.get(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	if current.Version != version {
+		return false, nil
+	}
+
+	// the key must not change between the read and the write
+	cmp := clientv3.Compare(clientv3.CreateRevision(key), "=", 0)
+	if kv != nil {
+		cmp = clientv3.Compare(clientv3.ModRevision(key), "=", kv.ModRevision)
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return false, err
+	}
+	opts, err := leaseOptions(ctx, s.cfg.Lease, s.cfg.TTL)
+	if err != nil {
+		return false, err
+	}
+	resp, err := s.cfg.Client.Txn(ctx).If(cmp).Then(clientv3.OpPut(key, string(data), opts...)).Commit()
+	if err != nil {
+		return false, fmt.Errorf("etcd: txn %q: %w", key, err)
+	}
+
+	return resp.Succeeded, nil
+}
+
+// get reads the state of the breaker and the key holding it, which is nil if there is none
+func (s *Storage) get(ctx context.Context, name string) (circuit_breaker.StoredState, *mvccpb.KeyValue, error) {
+	key := s.cfg.Prefix + name
+	resp, err := s.cfg.Client.Get(ctx, key)
+	if err != nil {
+		return circuit_breaker.StoredState{}, nil, fmt.Errorf("etcd: get %q: %w", key, err)
+	}
+	if len(resp.Kvs) == 0 {
+		return circuit_breaker.StoredState{}, nil, nil
+	}
+
+	var state circuit_breaker.StoredState
+	if err := json.Unmarshal(resp.Kvs[0].Value, &state); err != nil {
+		return circuit_breaker.StoredState{}, nil, fmt.Errorf("etcd: key %q: %w", key, err)
+	}
+	return state, resp.Kvs[0], nil
+}
+
+// BroadcasterConfig configures Broadcaster.
+//
+// Client is the etcd client. Prefix is the prefix of the broadcasts, e.g. "/circuit_breakers/broadcasts/":
+// the latest broadcast of a breaker is kept in the key of the prefix followed by its name.
+//
+// Lease and TTL expire the broadcasts like the states of Storage, see StorageConfig.
+//
+// RetryInterval is the delay before watching the broadcasts again after an error, 5 seconds by default.
+//
+// OnError is called with the errors of the watch and the broadcasts which can't be decoded, which are skipped.
+type BroadcasterConfig struct {
+	Client        Client
+	Lease         clientv3.Lease
+	Prefix        string
+	TTL           time.Duration
+	RetryInterval time.Duration
+	OnError       func(err error)
+}
+
+// Broadcaster is the circuit_breaker.Broadcaster publishing the state changes of the breakers
+// to etcd and watching the ones of the fleet, see circuit_breaker.BroadcastState.
+// The broadcasts published while the watch is restarting are missed.
+type Broadcaster struct {
+	cfg    BroadcasterConfig
+	hub    *circuit_breaker.BroadcastHub
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+var _ circuit_breaker.Broadcaster = (*Broadcaster)(nil)
+
+// NewBroadcaster creates the Broadcaster and starts watching the broadcasts until Close.
+func NewBroadcaster(cfg BroadcasterConfig) *Broadcaster {
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = defaultRetryInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &Broadcaster{
+		cfg:    cfg,
+		hub:    circuit_breaker.NewBroadcastHub(),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go b.watch(ctx)
+
+	return b
+}
+
+// Publish implements circuit_breaker.Broadcaster.
+func (b *Broadcaster) Publish(ctx context.Context, msg circuit_breaker.StateBroadcast) error {
+	key := b.cfg.Prefix + msg.Name
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	opts, err := leaseOptions(ctx, b.cfg.Lease, b.cfg.TTL)
+	if err != nil {
+		return err
+	}
+	if _, err := b.cfg.Client.Put(ctx, key, string(data), opts...); err != nil {
+		return fmt.Errorf("etcd: put %q: %w", key, err)
+	}
+
+	return nil
+}
+
+// Subscribe implements circuit_breaker.Broadcaster.
+func (b *Broadcaster) Subscribe(name string) <-chan circuit_breaker.StateBroadcast {
+	return b.hub.Subscribe(name)
+}
+
+// Close stops watching the broadcasts, closing the channels of the subscribers.
+func (b *Broadcaster) Close() {
+	b.cancel()
+	<-b.done
+	b.hub.Close()
+}
+
+func (b *Broadcaster) watch(ctx context.Context) {
+	defer close(b.done)
+
+	for {
+		updates := b.cfg.Client.Watch(clientv3.WithRequireLeader(ctx), b.cfg.Prefix, clientv3.WithPrefix())
+		for wr := range updates {
+			if err := wr.Err(); err != nil {
+				b.onError(fmt.Errorf("etcd: watch %q: %w", b.cfg.Prefix, err))
+				break
+			}
+			for _, ev := range wr.Events {
+				if ev.Type == mvccpb.PUT {
+					b.deliver(ev.Kv)
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(b.cfg.RetryInterval):
+		}
+	}
+}
+
+func (b *Broadcaster) deliver(kv *mvccpb.KeyValue) {
+	var msg circuit_breaker.StateBroadcast
+	if err := json.Unmarshal(kv.Value, &msg); err != nil {
+		b.onError(fmt.Errorf("etcd: key %q: %w", kv.Key, err))
+		return
+	}
+	b.hub.Deliver(msg)
+}
+
+func (b *Broadcaster) onError(err error) {
+	if b.cfg.OnError != nil {
+		b.cfg.OnError(err)
+	}
+}
+
+// leaseOptions grants the lease of a key expiring after ttl, if any
+func leaseOptions(ctx context.Context, lease clientv3.Lease, ttl time.Duration) ([]clientv3.OpOption, error) {
+	if lease == nil || ttl <= 0 {
+		return nil, nil
+	}
+
+	resp, err := lease.Grant(ctx, int64(math.Ceil(ttl.Seconds())))
+	if err != nil {
+		return nil, fmt.Errorf("etcd: grant lease: %w", err)
+	}
+	return []clientv3.OpOption{clientv3.WithLease(resp.ID)}, nil
+}
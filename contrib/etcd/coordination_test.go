@@ -0,0 +1,122 @@
+package etcdbreaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// fakeLease records the TTLs of the granted leases
+type fakeLease struct {
+	clientv3.Lease
+
+	mu   sync.Mutex
+	ttls []int64
+	err  error
+}
+
+func (l *fakeLease) Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err != nil {
+		return nil, l.err
+	}
+	l.ttls = append(l.ttls, ttl)
+	return &clientv3.LeaseGrantResponse{ID: clientv3.LeaseID(len(l.ttls)), TTL: ttl}, nil
+}
+
+func TestStorage(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeClient()
+	lease := &fakeLease{}
+	storage := NewStorage(StorageConfig{Client: client, Lease: lease, Prefix: "/states/", TTL: 90 * time.Second})
+	cfg := circuit_breaker.Config{Name: "payments", MaxConsecutiveFailures: 1, Timeout: time.Minute, Storage: storage}
+
+	cb := circuit_breaker.NewCircuitBreaker(cfg)
+	_, _ = cb.Execute(func() (interface{}, error) { return nil, assert.AnError })
+	cb.WaitBackground()
+	state, ok, err := storage.Load(ctx, "payments")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, circuit_breaker.StateOpen, state.State)
+	assert.Equal(t, uint64(1), state.Version)
+	assert.Equal(t, []int64{90}, lease.ttls)
+
+	// a restarted instance restores the open state
+	restarted := circuit_breaker.NewCircuitBreaker(cfg)
+	assert.Equal(t, circuit_breaker.StateOpen, restarted.State())
+	assert.Equal(t, cb.OpensAt().UnixNano(), restarted.OpensAt().UnixNano())
+
+	// the swaps of an outdated version fail
+	swapped, err := storage.CompareAndSwap(ctx, "payments", 0, circuit_breaker.StoredState{Version: 1})
+	assert.Nil(t, err)
+	assert.False(t, swapped)
+	swapped, err = storage.CompareAndSwap(ctx, "payments", 1, circuit_breaker.StoredState{Version: 2})
+	assert.Nil(t, err)
+	assert.True(t, swapped)
+	swapped, err = storage.CompareAndSwap(ctx, "search", 0, circuit_breaker.StoredState{Version: 1})
+	assert.Nil(t, err)
+	assert.True(t, swapped)
+
+	_, ok, err = storage.Load(ctx, "missing")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	client.set("/states/invalid", "{")
+	_, _, err = storage.Load(ctx, "invalid")
+	assert.NotNil(t, err)
+	lease.err = errors.New("etcdserver: too many leases")
+	assert.NotNil(t, storage.Store(ctx, "payments", circuit_breaker.StoredState{}))
+	client.setDown(true)
+	_, _, err = storage.Load(ctx, "payments")
+	assert.NotNil(t, err)
+}
+
+func TestBroadcaster(t *testing.T) {
+	client := newFakeClient()
+	errs := make(chan error, 10)
+	b := NewBroadcaster(BroadcasterConfig{
+		Client:        client,
+		Prefix:        "/broadcasts/",
+		RetryInterval: 10 * time.Millisecond,
+		OnError:       func(err error) { errs <- err },
+	})
+	defer b.Close()
+
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "payments", MaxConsecutiveFailures: 1, Timeout: time.Minute})
+	stop := circuit_breaker.BroadcastState(cb, b, "a")
+	defer stop()
+	watch := <-client.watches
+
+	// the state changes are published to the key of the breaker
+	peer := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "payments", MaxConsecutiveFailures: 1, Timeout: time.Minute})
+	stopPeer := circuit_breaker.BroadcastState(peer, b, "b")
+	defer stopPeer()
+	_, _ = peer.Execute(func() (interface{}, error) { return nil, assert.AnError })
+	peer.WaitBackground()
+	client.mu.Lock()
+	published := client.kvs["/broadcasts/payments"]
+	client.mu.Unlock()
+	assert.Contains(t, published, `"Origin":"b"`)
+
+	watch <- event(mvccpb.PUT, "/broadcasts/payments", published, 2)
+	assert.Eventually(t, func() bool {
+		return cb.State() == circuit_breaker.StateOpen
+	}, time.Second, time.Millisecond)
+
+	watch <- event(mvccpb.PUT, "/broadcasts/payments", "{", 3)
+	assert.NotNil(t, <-errs)
+
+	// the watch is restarted after an error
+	watch <- clientv3.WatchResponse{CompactRevision: 3}
+	assert.NotNil(t, <-errs)
+	<-client.watches
+}
@@ -0,0 +1,203 @@
+// Package etcdbreaker provides the configurations of circuit breakers from etcd,
+// and coordinates the breakers of the fleet by the states and the broadcasts kept in etcd.
+package etcdbreaker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const defaultRetryInterval = 5 * time.Second
+
+// Client is the part of *clientv3.Client used by Provider.
+type Client interface {
+	clientv3.KV
+	clientv3.Watcher
+}
+
+// Config configures Provider.
+//
+// Client is the etcd client. Prefix is the prefix of the configurations, e.g. "/circuit_breakers/":
+// the key of a breaker is the prefix followed by its name, and its value is the JSON of circuit_breaker.ConfigFromJSON.
+//
+// Fallback are the local configurations of the breakers, delivered until the store is reachable,
+// and again when the key of a breaker is deleted.
+// While the store is unavailable, the breakers keep their last configurations.
+//
+// RetryInterval is the delay before reading the store again after an error, 5 seconds by default.
+//
+// OnError is called with the errors of the store and the invalid configurations, which are skipped.
+type Config struct {
+	Client        Client
+	Prefix        string
+	Fallback      map[string]circuit_breaker.Config
+	RetryInterval time.Duration
+	OnError       func(err error)
+}
+
+// Provider is the circuit_breaker.ConfigProvider watching the configurations in etcd,
+// so the updates propagate to the fleet within seconds, see circuit_breaker.WatchConfig.
+type Provider struct {
+	cfg    Config
+	feed   *circuit_breaker.ConfigFeed
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu        sync.Mutex
+	revisions map[string]int64
+}
+
+var _ circuit_breaker.ConfigProvider = (*Provider)(nil)
+
+// New creates the Provider and starts watching the store until Close.
+func New(cfg Config) *Provider {
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = defaultRetryInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Provider{
+		cfg:       cfg,
+		feed:      circuit_breaker.NewConfigFeed(),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		revisions: make(map[string]int64),
+	}
+	fallback := make(map[string]circuit_breaker.Config, len(cfg.Fallback))
+	for name, c := range cfg.Fallback {
+		c.Name = name
+		fallback[name] = c
+		p.feed.Set(name, c)
+	}
+	p.cfg.Fallback = fallback
+	go p.watch(ctx)
+
+	return p
+}
+
+// Watch implements circuit_breaker.ConfigProvider.
+func (p *Provider) Watch(name string) <-chan circuit_breaker.Config {
+	return p.feed.Watch(name)
+}
+
+// Close stops watching the store, closing the channels of the watchers.
+func (p *Provider) Close() {
+	p.cancel()
+	<-p.done
+	p.feed.Close()
+}
+
+// watch reads all the configurations and then watches their changes,
+// starting over after the errors, e.g. when the watched revision is compacted
+func (p *Provider) watch(ctx context.Context) {
+	defer close(p.done)
+
+	for {
+		if err := p.sync(ctx); err != nil && ctx.Err() == nil {
+			p.onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.cfg.RetryInterval):
+		}
+	}
+}
+
+func (p *Provider) sync(ctx context.Context) error {
+	resp, err := p.cfg.Client.Get(ctx, p.cfg.Prefix, clientv3.WithPrefix())
+	if err != nil {
+		return fmt.Errorf("etcd: get %q: %w", p.cfg.Prefix, err)
+	}
+	p.reset(resp.Kvs)
+
+	updates := p.cfg.Client.Watch(clientv3.WithRequireLeader(ctx), p.cfg.Prefix,
+		clientv3.WithPrefix(), clientv3.WithRev(resp.Header.Revision+1))
+	for wr := range updates {
+		if err := wr.Err(); err != nil {
+			return fmt.Errorf("etcd: watch %q: %w", p.cfg.Prefix, err)
+		}
+		for _, ev := range wr.Events {
+			if ev.Type == mvccpb.DELETE {
+				p.remove(string(ev.Kv.Key))
+			} else {
+				p.put(ev.Kv)
+			}
+		}
+	}
+
+	return ctx.Err()
+}
+
+// reset delivers the configurations read from the store, restoring the fallbacks of the deleted keys
+func (p *Provider) reset(kvs []*mvccpb.KeyValue) {
+	present := make(map[string]bool, len(kvs))
+	for _, kv := range kvs {
+		present[string(kv.Key)] = true
+		p.put(kv)
+	}
+
+	p.mu.Lock()
+	var deleted []string
+	for name := range p.revisions {
+		if !present[p.cfg.Prefix+name] {
+			deleted = append(deleted, p.cfg.Prefix+name)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, key := range deleted {
+		p.remove(key)
+	}
+}
+
+func (p *Provider) put(kv *mvccpb.KeyValue) {
+	name := strings.TrimPrefix(string(kv.Key), p.cfg.Prefix)
+	if name == "" {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.revisions[name] == kv.ModRevision {
+		return
+	}
+	p.revisions[name] = kv.ModRevision
+
+	cfg, err := circuit_breaker.ConfigFromJSON(kv.Value)
+	if err != nil {
+		p.onError(fmt.Errorf("etcd: key %q: %w", kv.Key, err))
+		return
+	}
+	cfg.Name = name
+	p.feed.Set(name, cfg)
+}
+
+func (p *Provider) remove(key string) {
+	name := strings.TrimPrefix(key, p.cfg.Prefix)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.revisions[name]; !ok {
+		return
+	}
+	delete(p.revisions, name)
+	if fallback, ok := p.cfg.Fallback[name]; ok {
+		p.feed.Set(name, fallback)
+	}
+}
+
+func (p *Provider) onError(err error) {
+	if p.cfg.OnError != nil {
+		p.cfg.OnError(err)
+	}
+}
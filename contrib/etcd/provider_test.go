@@ -0,0 +1,221 @@
+package etcdbreaker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// fakeClient serves the reads and the writes from a map and the watches from a channel
+type fakeClient struct {
+	clientv3.KV
+	clientv3.Watcher
+
+	mu        sync.Mutex
+	kvs       map[string]string
+	revisions map[string]int64
+	revision  int64
+	down      bool
+	watches   chan chan clientv3.WatchResponse
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		kvs:       make(map[string]string),
+		revisions: make(map[string]int64),
+		revision:  1,
+		watches:   make(chan chan clientv3.WatchResponse, 10),
+	}
+}
+
+func (c *fakeClient) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.down {
+		return nil, errors.New("etcdserver: no leader")
+	}
+	resp := &clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: c.revision}}
+	for k, v := range c.kvs {
+		if strings.HasPrefix(k, key) {
+			resp.Kvs = append(resp.Kvs, &mvccpb.KeyValue{Key: []byte(k), Value: []byte(v), ModRevision: c.revisions[k]})
+		}
+	}
+	return resp, nil
+}
+
+func (c *fakeClient) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.down {
+		return nil, errors.New("etcdserver: no leader")
+	}
+	c.put(key, val)
+	return &clientv3.PutResponse{}, nil
+}
+
+func (c *fakeClient) Txn(ctx context.Context) clientv3.Txn {
+	return &fakeTxn{c: c}
+}
+
+func (c *fakeClient) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	ch := make(chan clientv3.WatchResponse)
+	c.watches <- ch
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch
+}
+
+func (c *fakeClient) setDown(down bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.down = down
+}
+
+func (c *fakeClient) set(key, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.put(key, value)
+}
+
+func (c *fakeClient) put(key, value string) {
+	c.revision++
+	c.kvs[key] = value
+	c.revisions[key] = c.revision
+}
+
+// fakeTxn supports the comparisons of the revisions and the puts
+type fakeTxn struct {
+	c   *fakeClient
+	cmp []clientv3.Cmp
+	ops []clientv3.Op
+}
+
+func (t *fakeTxn) If(cs ...clientv3.Cmp) clientv3.Txn {
+	t.cmp = append(t.cmp, cs...)
+	return t
+}
+
+func (t *fakeTxn) Then(ops ...clientv3.Op) clientv3.Txn {
+	t.ops = append(t.ops, ops...)
+	return t
+}
+
+func (t *fakeTxn) Else(ops ...clientv3.Op) clientv3.Txn {
+	return t
+}
+
+func (t *fakeTxn) Commit() (*clientv3.TxnResponse, error) {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+
+	if t.c.down {
+		return nil, errors.New("etcdserver: no leader")
+	}
+	for _, cmp := range t.cmp {
+		revision := t.c.revisions[string(cmp.Key)]
+		switch target := cmp.TargetUnion.(type) {
+		case *etcdserverpb.Compare_CreateRevision:
+			if (revision == 0) != (target.CreateRevision == 0) {
+				return &clientv3.TxnResponse{}, nil
+			}
+		case *etcdserverpb.Compare_ModRevision:
+			if revision != target.ModRevision {
+				return &clientv3.TxnResponse{}, nil
+			}
+		}
+	}
+	for _, op := range t.ops {
+		t.c.put(string(op.KeyBytes()), string(op.ValueBytes()))
+	}
+	return &clientv3.TxnResponse{Succeeded: true}, nil
+}
+
+func event(typ mvccpb.Event_EventType, key, value string, revision int64) clientv3.WatchResponse {
+	return clientv3.WatchResponse{Events: []*clientv3.Event{{
+		Type: typ,
+		Kv:   &mvccpb.KeyValue{Key: []byte(key), Value: []byte(value), ModRevision: revision},
+	}}}
+}
+
+func TestProvider(t *testing.T) {
+	client := newFakeClient()
+	client.setDown(true)
+	errs := make(chan error, 10)
+	p := New(Config{
+		Client:        client,
+		Prefix:        "/circuit_breakers/",
+		Fallback:      map[string]circuit_breaker.Config{"payments": {MaxConsecutiveFailures: 5}},
+		RetryInterval: 10 * time.Millisecond,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+	defer p.Close()
+
+	// the fallback is delivered while the store is unavailable
+	updates := p.Watch("payments")
+	cfg := <-updates
+	assert.Equal(t, "payments", cfg.Name)
+	assert.Equal(t, uint32(5), cfg.MaxConsecutiveFailures)
+	assert.NotNil(t, <-errs)
+
+	client.set("/circuit_breakers/payments", `{"max_consecutive_failures": 2, "timeout": "10s"}`)
+	client.setDown(false)
+	cfg = <-updates
+	assert.Equal(t, uint32(2), cfg.MaxConsecutiveFailures)
+	assert.Equal(t, 10*time.Second, cfg.Timeout)
+
+	watch := <-client.watches
+	watch <- event(mvccpb.PUT, "/circuit_breakers/payments", `{"timeout": 10}`, 3)
+	assert.NotNil(t, <-errs)
+	watch <- event(mvccpb.PUT, "/circuit_breakers/payments", `{"max_consecutive_failures": 3}`, 4)
+	cfg = <-updates
+	assert.Equal(t, uint32(3), cfg.MaxConsecutiveFailures)
+
+	// the fallback is restored when the key is deleted
+	watch <- event(mvccpb.DELETE, "/circuit_breakers/payments", "", 5)
+	cfg = <-updates
+	assert.Equal(t, uint32(5), cfg.MaxConsecutiveFailures)
+
+	// the configurations are read again after the watch fails
+	client.set("/circuit_breakers/payments", `{"max_consecutive_failures": 4}`)
+	watch <- clientv3.WatchResponse{CompactRevision: 5}
+	assert.NotNil(t, <-errs)
+	cfg = <-updates
+	assert.Equal(t, uint32(4), cfg.MaxConsecutiveFailures)
+}
+
+func TestProviderWatchConfig(t *testing.T) {
+	client := newFakeClient()
+	client.set("/circuit_breakers/search", `{"max_consecutive_failures": 1}`)
+	p := New(Config{Client: client, Prefix: "/circuit_breakers/"})
+
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "search", MaxConsecutiveFailures: 100})
+	stop := circuit_breaker.WatchConfig(cb, p)
+	defer stop()
+
+	assert.Eventually(t, func() bool {
+		_, _ = cb.Execute(func() (interface{}, error) { return nil, assert.AnError })
+		return cb.State() == circuit_breaker.StateOpen
+	}, time.Second, time.Millisecond)
+
+	p.Close()
+	_, ok := <-p.Watch("search")
+	assert.False(t, ok)
+}
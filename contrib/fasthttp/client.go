@@ -0,0 +1,73 @@
+// Package fasthttpbreaker protects fasthttp clients with circuit breakers.
+package fasthttpbreaker
+
+import (
+	"time"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/valyala/fasthttp"
+)
+
+// Doer is the request part of *fasthttp.Client and *fasthttp.HostClient.
+type Doer interface {
+	Do(req *fasthttp.Request, resp *fasthttp.Response) error
+	DoTimeout(req *fasthttp.Request, resp *fasthttp.Response, timeout time.Duration) error
+}
+
+var (
+	_ Doer = (*fasthttp.Client)(nil)
+	_ Doer = (*fasthttp.HostClient)(nil)
+)
+
+// Client maintains an independent breaker per destination host, the fasthttp counterpart of circuit_breaker.Transport.
+// Requests rejected by the breaker fail with its rejection error, e.g. circuit_breaker.ErrOpenState.
+type Client struct {
+	// Client is the underlying client.
+	Client Doer
+	// Breakers holds a breaker per host, created lazily.
+	Breakers *circuit_breaker.KeyedBreaker
+	// Classifier maps the status code of the response to the outcome of the request.
+	// The zero value treats 5xx and 429 as failures.
+	Classifier circuit_breaker.HTTPClassifier
+}
+
+// NewClient creates a Client with a breaker per destination host.
+func NewClient(client Doer, cfg circuit_breaker.KeyedConfig) *Client {
+	return &Client{
+		Client:   client,
+		Breakers: circuit_breaker.NewKeyedBreaker(cfg),
+	}
+}
+
+// Do performs the request, see fasthttp.Client.Do.
+func (c *Client) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	return c.execute(req, resp, func() error {
+		return c.Client.Do(req, resp)
+	})
+}
+
+// DoTimeout performs the request waiting for the response at most for the timeout, see fasthttp.Client.DoTimeout.
+func (c *Client) DoTimeout(req *fasthttp.Request, resp *fasthttp.Response, timeout time.Duration) error {
+	return c.execute(req, resp, func() error {
+		return c.Client.DoTimeout(req, resp, timeout)
+	})
+}
+
+func (c *Client) execute(req *fasthttp.Request, resp *fasthttp.Response, do func() error) error {
+	cb := c.Breakers.Get(string(req.URI().Host()))
+
+	handled := false
+	var doErr error
+	_, err := cb.Execute(func() (interface{}, error) {
+		handled = true
+		if doErr = do(); doErr != nil {
+			return nil, doErr
+		}
+		return nil, c.Classifier.ClassifyStatus(resp.StatusCode())
+	})
+	if !handled {
+		return err
+	}
+
+	return doErr
+}
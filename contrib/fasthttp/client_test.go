@@ -0,0 +1,85 @@
+package fasthttpbreaker
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func serve(t *testing.T) *fasthttp.Client {
+	ln := fasthttputil.NewInmemoryListener()
+	go func() {
+		_ = fasthttp.Serve(ln, func(ctx *fasthttp.RequestCtx) {
+			switch string(ctx.Host()) {
+			case "reports.internal":
+				ctx.SetStatusCode(fasthttp.StatusBadGateway)
+			case "users.internal":
+				ctx.SetStatusCode(fasthttp.StatusNotFound)
+			default:
+				ctx.SetStatusCode(fasthttp.StatusOK)
+			}
+		})
+	}()
+	t.Cleanup(func() { ln.Close() })
+
+	return &fasthttp.Client{Dial: func(addr string) (net.Conn, error) { return ln.Dial() }}
+}
+
+func do(c *Client, url string) (int, error) {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(url)
+	err := c.Do(req, resp)
+	return resp.StatusCode(), err
+}
+
+func TestClient(t *testing.T) {
+	c := NewClient(serve(t), circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{MaxConsecutiveFailures: 2},
+	})
+
+	for i := 0; i < 2; i++ {
+		status, err := do(c, "http://reports.internal/daily")
+		assert.Nil(t, err)
+		assert.Equal(t, fasthttp.StatusBadGateway, status)
+
+		// client errors don't count
+		status, err = do(c, "http://users.internal/42")
+		assert.Nil(t, err)
+		assert.Equal(t, fasthttp.StatusNotFound, status)
+	}
+
+	_, err := do(c, "http://reports.internal/daily")
+	assert.Equal(t, circuit_breaker.ErrOpenState, err)
+
+	// the other hosts are not affected
+	status, err := do(c, "http://users.internal/42")
+	assert.Nil(t, err)
+	assert.Equal(t, fasthttp.StatusNotFound, status)
+	status, err = do(c, "http://billing.internal/")
+	assert.Nil(t, err)
+	assert.Equal(t, fasthttp.StatusOK, status)
+}
+
+func TestClientDoTimeout(t *testing.T) {
+	c := NewClient(serve(t), circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{MaxConsecutiveFailures: 1},
+	})
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI("http://reports.internal/daily")
+	assert.Nil(t, c.DoTimeout(req, resp, time.Second))
+	assert.Equal(t, circuit_breaker.ErrOpenState, c.DoTimeout(req, resp, time.Second))
+}
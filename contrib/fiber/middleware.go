@@ -0,0 +1,100 @@
+// Package fiberbreaker protects Fiber handlers with circuit breakers,
+// with the same classification and rejection semantics as circuit_breaker.Middleware.
+package fiberbreaker
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+// Config configures the middleware.
+//
+// Breaker selects the breaker protecting the request, e.g. per route with PerRoute.
+// If Breaker returns nil, the request is not protected.
+//
+// Skipper excludes requests from the protection.
+//
+// Classifier maps the status code of the response to the request outcome.
+// The zero value treats 5xx and 429 as failures.
+//
+// OnReject handles a request rejected by the breaker.
+// If OnReject is nil, the request is rejected with 503 and Retry-After.
+type Config struct {
+	Breaker    func(c *fiber.Ctx) *circuit_breaker.CircuitBreaker
+	Skipper    func(c *fiber.Ctx) bool
+	Classifier circuit_breaker.HTTPClassifier
+	OnReject   func(c *fiber.Ctx, cb *circuit_breaker.CircuitBreaker, err error) error
+}
+
+// Middleware protects all the routes with a single breaker.
+func Middleware(cb *circuit_breaker.CircuitBreaker) fiber.Handler {
+	return New(Config{
+		Breaker: func(c *fiber.Ctx) *circuit_breaker.CircuitBreaker { return cb },
+	})
+}
+
+// PerRoute selects the breaker by the method and the route pattern (e.g. "GET /users/:id").
+// Fiber resolves the route pattern of the handler being executed,
+// so the middleware must be registered with the routes (or groups) it protects
+// rather than with app.Use.
+func PerRoute(kb *circuit_breaker.KeyedBreaker) func(c *fiber.Ctx) *circuit_breaker.CircuitBreaker {
+	return func(c *fiber.Ctx) *circuit_breaker.CircuitBreaker {
+		return kb.Get(c.Method() + " " + c.Route().Path)
+	}
+}
+
+// New creates the middleware.
+func New(cfg Config) fiber.Handler {
+	if cfg.OnReject == nil {
+		cfg.OnReject = defaultOnReject
+	}
+
+	return func(c *fiber.Ctx) error {
+		if cfg.Skipper != nil && cfg.Skipper(c) {
+			return c.Next()
+		}
+		cb := cfg.Breaker(c)
+		if cb == nil {
+			return c.Next()
+		}
+
+		handled := false
+		var handlerErr error
+		_, err := cb.ExecuteContext(c.UserContext(), func(ctx context.Context) (interface{}, error) {
+			handled = true
+			handlerErr = c.Next()
+			return nil, cfg.Classifier.ClassifyStatus(status(c, handlerErr))
+		})
+		if !handled {
+			return cfg.OnReject(c, cb, err)
+		}
+
+		return handlerErr
+	}
+}
+
+// status returns the status code the response will have once the handler error is handled by Fiber
+func status(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
+func defaultOnReject(c *fiber.Ctx, cb *circuit_breaker.CircuitBreaker, err error) error {
+	if err == nil {
+		err = circuit_breaker.ErrTooManyRequests
+	}
+	if retryAfter := circuit_breaker.RetryAfter(cb, err); retryAfter != "" {
+		c.Set(fiber.HeaderRetryAfter, retryAfter)
+	}
+	return c.Status(fiber.StatusServiceUnavailable).SendString(err.Error())
+}
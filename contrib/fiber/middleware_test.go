@@ -0,0 +1,69 @@
+package fiberbreaker
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(t *testing.T, app *fiber.App, path string) *http.Response {
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Nil(t, err)
+	return resp
+}
+
+func TestMiddleware(t *testing.T) {
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{MaxConsecutiveFailures: 2})
+	app := fiber.New()
+	app.Use(Middleware(cb))
+	app.Get("/fail", func(c *fiber.Ctx) error { return errors.New("boom") })
+	app.Get("/unavailable", func(c *fiber.Ctx) error { return fiber.ErrServiceUnavailable })
+	app.Get("/ok", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	assert.Equal(t, http.StatusOK, serve(t, app, "/ok").StatusCode)
+	assert.Equal(t, http.StatusInternalServerError, serve(t, app, "/fail").StatusCode)
+	assert.Equal(t, http.StatusServiceUnavailable, serve(t, app, "/unavailable").StatusCode)
+	assert.Equal(t, circuit_breaker.StateOpen, cb.State())
+
+	resp := serve(t, app, "/ok")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+}
+
+func TestDefaultOnRejectNilError(t *testing.T) {
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{})
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error { return defaultOnReject(c, cb, nil) })
+
+	resp := serve(t, app, "/")
+	body, err := io.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, circuit_breaker.ErrTooManyRequests.Error(), string(body))
+}
+
+func TestPerRoute(t *testing.T) {
+	kb := circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{MaxConsecutiveFailures: 1},
+	})
+	protect := New(Config{
+		Breaker: PerRoute(kb),
+		Skipper: func(c *fiber.Ctx) bool { return c.Query("skip") != "" },
+	})
+
+	app := fiber.New()
+	app.Get("/reports/:id", protect, func(c *fiber.Ctx) error { return c.SendStatus(http.StatusBadGateway) })
+	app.Get("/ping", protect, func(c *fiber.Ctx) error { return c.SendStatus(http.StatusOK) })
+
+	assert.Equal(t, http.StatusBadGateway, serve(t, app, "/reports/1").StatusCode)
+	assert.Equal(t, http.StatusServiceUnavailable, serve(t, app, "/reports/2").StatusCode)
+	assert.Equal(t, http.StatusBadGateway, serve(t, app, "/reports/3?skip=1").StatusCode)
+	assert.Equal(t, http.StatusOK, serve(t, app, "/ping").StatusCode)
+	assert.ElementsMatch(t, []string{"GET /reports/:id", "GET /ping"}, kb.Keys())
+}
@@ -0,0 +1,82 @@
+// Package ginbreaker protects Gin handlers with circuit breakers.
+package ginbreaker
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+// Config configures the middleware.
+//
+// Breaker selects the breaker protecting the request, e.g. per route.
+// If Breaker returns nil, the request is not protected.
+//
+// Classifier maps the status code written by the handler to the request outcome.
+// The zero value treats 5xx and 429 as failures.
+//
+// OnReject writes the response for a request rejected by the breaker.
+// If OnReject is nil, the request is aborted with 503 and a JSON error,
+// and the Retry-After header tells when an open breaker becomes half-open.
+type Config struct {
+	Breaker    func(c *gin.Context) *circuit_breaker.CircuitBreaker
+	Classifier circuit_breaker.HTTPClassifier
+	OnReject   func(c *gin.Context, err error)
+}
+
+// Middleware protects all the routes with a single breaker.
+func Middleware(cb *circuit_breaker.CircuitBreaker) gin.HandlerFunc {
+	return New(Config{
+		Breaker: func(c *gin.Context) *circuit_breaker.CircuitBreaker { return cb },
+	})
+}
+
+// PerRoute protects each route with its own breaker, keyed by the route pattern (e.g. "/users/:id").
+// Requests not matching any route are not protected.
+func PerRoute(kb *circuit_breaker.KeyedBreaker) gin.HandlerFunc {
+	return New(Config{
+		Breaker: func(c *gin.Context) *circuit_breaker.CircuitBreaker {
+			if c.FullPath() == "" {
+				return nil
+			}
+			return kb.Get(c.Request.Method + " " + c.FullPath())
+		},
+	})
+}
+
+// New creates the middleware.
+func New(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cb := cfg.Breaker(c)
+		if cb == nil {
+			c.Next()
+			return
+		}
+
+		handled := false
+		_, err := cb.ExecuteContext(c.Request.Context(), func(ctx context.Context) (interface{}, error) {
+			handled = true
+			c.Next()
+			return nil, cfg.Classifier.ClassifyStatus(c.Writer.Status())
+		})
+		if !handled {
+			if err == nil {
+				err = circuit_breaker.ErrTooManyRequests
+			}
+			if cfg.OnReject == nil {
+				defaultOnReject(c, cb, err)
+				return
+			}
+			cfg.OnReject(c, err)
+		}
+	}
+}
+
+func defaultOnReject(c *gin.Context, cb *circuit_breaker.CircuitBreaker, err error) {
+	if retryAfter := circuit_breaker.RetryAfter(cb, err); retryAfter != "" {
+		c.Header("Retry-After", retryAfter)
+	}
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+}
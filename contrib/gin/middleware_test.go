@@ -0,0 +1,73 @@
+package ginbreaker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(r *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{MaxConsecutiveFailures: 2})
+	r := gin.New()
+	r.Use(Middleware(cb))
+	r.GET("/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+	r.GET("/missing", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+
+	assert.Equal(t, http.StatusNotFound, serve(r, "/missing").Code)
+	assert.Equal(t, http.StatusInternalServerError, serve(r, "/fail").Code)
+	assert.Equal(t, http.StatusInternalServerError, serve(r, "/fail").Code)
+	assert.Equal(t, circuit_breaker.StateOpen, cb.State())
+
+	w := serve(r, "/missing")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"error":"circuit breaker is open"}`, w.Body.String())
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+}
+
+func TestPerRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	kb := circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{MaxConsecutiveFailures: 1},
+	})
+	r := gin.New()
+	r.Use(PerRoute(kb))
+	r.GET("/reports/:id", func(c *gin.Context) { c.Status(http.StatusBadGateway) })
+	r.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+
+	assert.Equal(t, http.StatusBadGateway, serve(r, "/reports/1").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, serve(r, "/reports/2").Code)
+	assert.Equal(t, http.StatusOK, serve(r, "/ping").Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, "/unknown").Code)
+	assert.ElementsMatch(t, []string{"GET /reports/:id", "GET /ping"}, kb.Keys())
+}
+
+func TestCustomRejection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{MaxConsecutiveFailures: 1})
+	r := gin.New()
+	r.Use(New(Config{
+		Breaker:    func(c *gin.Context) *circuit_breaker.CircuitBreaker { return cb },
+		Classifier: circuit_breaker.HTTPClassifier{FailureStatusCodes: []int{http.StatusNotFound}},
+		OnReject: func(c *gin.Context, err error) {
+			c.AbortWithStatus(http.StatusTooManyRequests)
+		},
+	}))
+	r.GET("/missing", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+
+	assert.Equal(t, http.StatusNotFound, serve(r, "/missing").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(r, "/missing").Code)
+}
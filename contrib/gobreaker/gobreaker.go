@@ -0,0 +1,304 @@
+// Package gobreaker is a drop-in replacement for the API of github.com/sony/gobreaker,
+// backed by circuit_breaker.CircuitBreaker.
+//
+// Switching libraries takes changing the import path only:
+//
+//	import "github.com/shirokovnv/circuit_breaker/contrib/gobreaker"
+//
+// The underlying breaker is available from Breaker, e.g. to register it in a circuit_breaker.Registry.
+package gobreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+const defaultTimeout = 60 * time.Second
+
+var (
+	// ErrTooManyRequests is returned when the state is half-open and the number of requests is over MaxRequests.
+	ErrTooManyRequests = circuit_breaker.ErrTooManyRequests
+	// ErrOpenState is returned when the state is open.
+	ErrOpenState = circuit_breaker.ErrOpenState
+
+	// errUnsuccessful is the outcome recorded for the requests reported as unsuccessful by TwoStepCircuitBreaker
+	errUnsuccessful = errors.New("unsuccessful request")
+)
+
+// State is the state of the breaker, numbered like in gobreaker.
+type State int
+
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	default:
+		return fmt.Sprintf("unknown state: %d", s)
+	}
+}
+
+func stateOf(s circuit_breaker.State) State {
+	switch s {
+	case circuit_breaker.StateHalfOpen:
+		return StateHalfOpen
+	case circuit_breaker.StateOpen:
+		return StateOpen
+	default:
+		return StateClosed
+	}
+}
+
+// Counts holds the numbers of requests and their outcomes.
+type Counts = circuit_breaker.Counts
+
+// Settings configures CircuitBreaker, with the defaults of gobreaker.
+//
+// MaxRequests is the number of requests allowed in the half-open state,
+// all of which must succeed to close the breaker. If MaxRequests is 0, one request is allowed.
+//
+// Interval is the cyclic period of the closed state after which Counts are cleared.
+// If Interval is 0, Counts are not cleared in the closed state.
+//
+// Timeout is the period of the open state, 60 seconds if it is 0.
+//
+// ReadyToTrip is called with a copy of Counts whenever a request fails in the closed state.
+// If ReadyToTrip is nil, the breaker trips after more than 5 consecutive failures.
+//
+// OnStateChange is called whenever the state changes.
+//
+// IsSuccessful reports whether the error of a request counts as a success.
+// If IsSuccessful is nil, only the nil errors do.
+type Settings struct {
+	Name          string
+	MaxRequests   uint32
+	Interval      time.Duration
+	Timeout       time.Duration
+	ReadyToTrip   func(counts Counts) bool
+	OnStateChange func(name string, from State, to State)
+	IsSuccessful  func(err error) bool
+}
+
+// CircuitBreaker is the breaker with the API of gobreaker.CircuitBreaker.
+type CircuitBreaker struct {
+	cb           *circuit_breaker.CircuitBreaker
+	interval     *intervalPolicy
+	isSuccessful func(err error) bool
+}
+
+// TwoStepCircuitBreaker is the breaker with the API of gobreaker.TwoStepCircuitBreaker.
+type TwoStepCircuitBreaker struct {
+	cb *CircuitBreaker
+}
+
+// NewCircuitBreaker returns a new CircuitBreaker configured with the given Settings.
+func NewCircuitBreaker(st Settings) *CircuitBreaker {
+	cfg := circuit_breaker.Config{
+		Name:             st.Name,
+		RequestThreshold: st.MaxRequests,
+		Timeout:          st.Timeout,
+		ReadyToTrip:      st.ReadyToTrip,
+	}
+	if cfg.RequestThreshold == 0 {
+		cfg.RequestThreshold = 1
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.ReadyToTrip == nil {
+		cfg.ReadyToTrip = defaultReadyToTrip
+	}
+	if st.OnStateChange != nil {
+		cfg.OnStateChange = func(name string, from circuit_breaker.State, to circuit_breaker.State) {
+			st.OnStateChange(name, stateOf(from), stateOf(to))
+		}
+	}
+
+	b := &CircuitBreaker{isSuccessful: st.IsSuccessful}
+	if b.isSuccessful == nil {
+		b.isSuccessful = func(err error) bool { return err == nil }
+	}
+	if st.Interval > 0 {
+		b.interval = &intervalPolicy{
+			DefaultPolicy: circuit_breaker.DefaultPolicy{
+				ReadyToTrip:      cfg.ReadyToTrip,
+				RequestThreshold: cfg.RequestThreshold,
+				Timeout:          cfg.Timeout,
+			},
+			interval: st.Interval,
+		}
+		cfg.Policy = b.interval
+	}
+	b.cb = circuit_breaker.NewCircuitBreaker(cfg)
+
+	return b
+}
+
+// NewTwoStepCircuitBreaker returns a new TwoStepCircuitBreaker configured with the given Settings.
+func NewTwoStepCircuitBreaker(st Settings) *TwoStepCircuitBreaker {
+	return &TwoStepCircuitBreaker{cb: NewCircuitBreaker(st)}
+}
+
+// Name returns the name of the CircuitBreaker.
+func (b *CircuitBreaker) Name() string {
+	return b.cb.Name()
+}
+
+// State returns the current state of the CircuitBreaker.
+func (b *CircuitBreaker) State() State {
+	return stateOf(b.cb.State())
+}
+
+// Counts returns the internal counters.
+func (b *CircuitBreaker) Counts() Counts {
+	if b.interval != nil && b.cb.State() == circuit_breaker.StateClosed {
+		return b.interval.counts(time.Now())
+	}
+	return b.cb.Counts()
+}
+
+// Breaker returns the underlying circuit breaker.
+func (b *CircuitBreaker) Breaker() *circuit_breaker.CircuitBreaker {
+	return b.cb
+}
+
+// Execute runs the given request if the CircuitBreaker accepts it.
+// Execute returns an error instantly if the CircuitBreaker rejects the request.
+// Otherwise, Execute returns the result of the request.
+// A panic in the request is recorded as a failure and re-panicked.
+func (b *CircuitBreaker) Execute(req func() (interface{}, error)) (interface{}, error) {
+	ran := false
+	var reqErr error
+	result, err := b.cb.Execute(func() (interface{}, error) {
+		ran = true
+		result, err := req()
+		reqErr = err
+		if b.isSuccessful(err) {
+			return result, nil
+		}
+		if err == nil {
+			return result, errUnsuccessful
+		}
+		return result, err
+	})
+	if ran {
+		return result, reqErr
+	}
+
+	return result, err
+}
+
+// Name returns the name of the TwoStepCircuitBreaker.
+func (tscb *TwoStepCircuitBreaker) Name() string {
+	return tscb.cb.Name()
+}
+
+// State returns the current state of the TwoStepCircuitBreaker.
+func (tscb *TwoStepCircuitBreaker) State() State {
+	return tscb.cb.State()
+}
+
+// Counts returns the internal counters.
+func (tscb *TwoStepCircuitBreaker) Counts() Counts {
+	return tscb.cb.Counts()
+}
+
+// Breaker returns the underlying circuit breaker.
+func (tscb *TwoStepCircuitBreaker) Breaker() *circuit_breaker.CircuitBreaker {
+	return tscb.cb.cb
+}
+
+// Allow checks if a new request can proceed. It returns a callback that should be used to
+// register the success or failure in a separate step. If the circuit breaker doesn't allow
+// requests, it returns an error.
+func (tscb *TwoStepCircuitBreaker) Allow() (done func(success bool), err error) {
+	d, err := tscb.cb.cb.Allow(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return func(success bool) {
+		if success {
+			d(nil)
+		} else {
+			d(errUnsuccessful)
+		}
+	}, nil
+}
+
+// defaultReadyToTrip is the ReadyToTrip of gobreaker
+func defaultReadyToTrip(counts Counts) bool {
+	return counts.ConsecutiveFailures > 5
+}
+
+// intervalPolicy trips by the Counts of the current interval of the closed state.
+// The methods of the Policy are called under the lock of the breaker, counts is called without it.
+type intervalPolicy struct {
+	circuit_breaker.DefaultPolicy
+	interval time.Duration
+
+	mu      sync.Mutex
+	closed  bool
+	expiry  time.Time
+	current Counts
+}
+
+func (p *intervalPolicy) OnCall(state circuit_breaker.State, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if state != circuit_breaker.StateClosed {
+		// the counts start over when the breaker closes again
+		p.closed = false
+		return
+	}
+
+	now := time.Now()
+	if !p.closed || !now.Before(p.expiry) {
+		p.closed = true
+		p.current = Counts{}
+		p.expiry = now.Add(p.interval)
+	}
+	p.current.Requests++
+	if err != nil {
+		p.current.TotalFailures++
+		p.current.ConsecutiveFailures++
+		p.current.ConsecutiveSuccesses = 0
+	} else {
+		p.current.TotalSuccesses++
+		p.current.ConsecutiveSuccesses++
+		p.current.ConsecutiveFailures = 0
+	}
+}
+
+func (p *intervalPolicy) ShouldTrip(counts Counts) bool {
+	p.mu.Lock()
+	current := p.current
+	p.mu.Unlock()
+
+	return p.DefaultPolicy.ShouldTrip(current)
+}
+
+func (p *intervalPolicy) counts(now time.Time) Counts {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.closed || !now.Before(p.expiry) {
+		return Counts{}
+	}
+	return p.current
+}
@@ -0,0 +1,131 @@
+package gobreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+var errFailed = errors.New("fail")
+
+func succeed(cb *CircuitBreaker) error {
+	_, err := cb.Execute(func() (interface{}, error) { return nil, nil })
+	return err
+}
+
+func fail(cb *CircuitBreaker) error {
+	_, err := cb.Execute(func() (interface{}, error) { return nil, errFailed })
+	return err
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var changes []string
+	cb := NewCircuitBreaker(Settings{
+		Name:        "cb",
+		MaxRequests: 2,
+		Timeout:     20 * time.Millisecond,
+		OnStateChange: func(name string, from State, to State) {
+			changes = append(changes, name+": "+from.String()+" -> "+to.String())
+		},
+	})
+	assert.Equal(t, "cb", cb.Name())
+	assert.Equal(t, StateClosed, cb.State())
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, errFailed, fail(cb))
+	}
+	assert.Equal(t, Counts{Requests: 5, TotalFailures: 5, ConsecutiveFailures: 5}, cb.Counts())
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, errFailed, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+	assert.Equal(t, ErrOpenState, succeed(cb))
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, StateHalfOpen, cb.State())
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateHalfOpen, cb.State())
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.State())
+
+	assert.Equal(t, []string{
+		"cb: closed -> open",
+		"cb: open -> half-open",
+		"cb: half-open -> closed",
+	}, changes)
+}
+
+func TestCircuitBreakerInterval(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{
+		Interval: 50 * time.Millisecond,
+		ReadyToTrip: func(counts Counts) bool {
+			return counts.TotalFailures >= 2
+		},
+	})
+
+	assert.Equal(t, errFailed, fail(cb))
+	assert.Equal(t, Counts{Requests: 1, TotalFailures: 1, ConsecutiveFailures: 1}, cb.Counts())
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, Counts{}, cb.Counts())
+
+	// the first failure was cleared with its interval
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, errFailed, fail(cb))
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, errFailed, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestCircuitBreakerIsSuccessful(t *testing.T) {
+	errNotFound := errors.New("not found")
+	cb := NewCircuitBreaker(Settings{
+		ReadyToTrip:  func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+		IsSuccessful: func(err error) bool { return err == nil || errors.Is(err, errNotFound) },
+	})
+
+	result, err := cb.Execute(func() (interface{}, error) { return "missing", errNotFound })
+	assert.Equal(t, "missing", result)
+	assert.Equal(t, errNotFound, err)
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, uint32(1), cb.Counts().TotalSuccesses)
+
+	assert.Equal(t, errFailed, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+	assert.True(t, errors.Is(succeed(cb), circuit_breaker.ErrOpenState))
+}
+
+func TestCircuitBreakerPanic(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 }})
+
+	assert.Panics(t, func() {
+		_, _ = cb.Execute(func() (interface{}, error) { panic("oops") })
+	})
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestTwoStepCircuitBreaker(t *testing.T) {
+	tscb := NewTwoStepCircuitBreaker(Settings{Name: "two-step", Timeout: 20 * time.Millisecond})
+	assert.Equal(t, "two-step", tscb.Name())
+	assert.Equal(t, "two-step", tscb.Breaker().Name())
+
+	for i := 0; i < 6; i++ {
+		done, err := tscb.Allow()
+		assert.Nil(t, err)
+		done(false)
+	}
+	assert.Equal(t, StateOpen, tscb.State())
+	done, err := tscb.Allow()
+	assert.Nil(t, done)
+	assert.Equal(t, ErrOpenState, err)
+
+	time.Sleep(30 * time.Millisecond)
+	done, err = tscb.Allow()
+	assert.Nil(t, err)
+	_, err = tscb.Allow()
+	assert.Equal(t, ErrTooManyRequests, err)
+	done(true)
+	assert.Equal(t, StateClosed, tscb.State())
+	assert.Equal(t, Counts{}, tscb.Counts())
+}
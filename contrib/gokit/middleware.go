@@ -0,0 +1,22 @@
+// Package gokitbreaker protects go-kit endpoints with circuit breakers.
+package gokitbreaker
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+// Middleware protects the endpoint with the breaker.
+// Every error returned by the endpoint counts as a failure.
+// Requests rejected by the breaker fail with its rejection error, e.g. circuit_breaker.ErrOpenState.
+func Middleware(cb *circuit_breaker.CircuitBreaker) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			return cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+				return next(ctx, request)
+			})
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package gokitbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{MaxConsecutiveFailures: 2})
+	errUnavailable := errors.New("unavailable")
+
+	calls := 0
+	var ep endpoint.Endpoint = func(ctx context.Context, request interface{}) (interface{}, error) {
+		calls++
+		if request == "fail" {
+			return nil, errUnavailable
+		}
+		return "report", nil
+	}
+	ep = endpoint.Chain(Middleware(cb))(ep)
+	ctx := context.Background()
+
+	response, err := ep(ctx, "get")
+	assert.Nil(t, err)
+	assert.Equal(t, "report", response)
+
+	for i := 0; i < 2; i++ {
+		_, err = ep(ctx, "fail")
+		assert.Equal(t, errUnavailable, err)
+	}
+	assert.Equal(t, circuit_breaker.StateOpen, cb.State())
+
+	_, err = ep(ctx, "get")
+	assert.Equal(t, circuit_breaker.ErrOpenState, err)
+	assert.Equal(t, 3, calls)
+}
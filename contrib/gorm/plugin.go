@@ -0,0 +1,125 @@
+// Package gormbreaker protects GORM operations with circuit breakers.
+package gormbreaker
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"gorm.io/gorm"
+)
+
+const doneKey = "circuit_breaker:done"
+
+// Config configures the plugin.
+//
+// Breakers holds the breakers of the databases, keyed by Database.
+// A KeyedBreaker can be shared by the plugins of several connections.
+//
+// Database is the name of the connection the plugin is registered on.
+// If Database is empty, the name of the dialector ("postgres", "sqlite") is used.
+//
+// Classifier maps the error of the operation to its outcome.
+// If Classifier is nil, DefaultClassifier is used.
+type Config struct {
+	Breakers   *circuit_breaker.KeyedBreaker
+	Database   string
+	Classifier func(err error) error
+}
+
+// Plugin registers breaker-protected callbacks around create, query, update and delete.
+// Operations rejected by the breaker fail with an error wrapping the rejection error
+// of the breaker and are not sent to the database.
+type Plugin struct {
+	cfg Config
+}
+
+var _ gorm.Plugin = (*Plugin)(nil)
+
+// New creates the plugin.
+func New(cfg Config) *Plugin {
+	if cfg.Classifier == nil {
+		cfg.Classifier = DefaultClassifier
+	}
+
+	return &Plugin{cfg: cfg}
+}
+
+// DefaultClassifier ignores the errors caused by the caller rather than the database,
+// such as gorm.ErrRecordNotFound.
+func DefaultClassifier(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound),
+		errors.Is(err, gorm.ErrMissingWhereClause),
+		errors.Is(err, gorm.ErrPrimaryKeyRequired),
+		errors.Is(err, gorm.ErrInvalidData):
+		return nil
+	default:
+		return err
+	}
+}
+
+// Name implements gorm.Plugin.
+func (p *Plugin) Name() string {
+	return "circuit_breaker"
+}
+
+// Initialize implements gorm.Plugin.
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	database := p.cfg.Database
+	if database == "" {
+		database = db.Dialector.Name()
+	}
+	cb := p.cfg.Breakers.Get(database)
+
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("circuit_breaker:before_create", p.before(cb)); err != nil {
+		return err
+	}
+	if err := callbacks.Create().After("gorm:create").Register("circuit_breaker:after_create", p.after); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("circuit_breaker:before_query", p.before(cb)); err != nil {
+		return err
+	}
+	if err := callbacks.Query().After("gorm:query").Register("circuit_breaker:after_query", p.after); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("circuit_breaker:before_update", p.before(cb)); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("circuit_breaker:after_update", p.after); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("circuit_breaker:before_delete", p.before(cb)); err != nil {
+		return err
+	}
+
+	return callbacks.Delete().After("gorm:delete").Register("circuit_breaker:after_delete", p.after)
+}
+
+func (p *Plugin) before(cb *circuit_breaker.CircuitBreaker) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil {
+			return
+		}
+
+		done, err := cb.Allow(db.Statement.Context)
+		db.InstanceSet(doneKey, done)
+		if err != nil {
+			_ = db.AddError(fmt.Errorf("rejected by circuit breaker %q: %w", cb.Name(), err))
+		}
+	}
+}
+
+func (p *Plugin) after(db *gorm.DB) {
+	value, _ := db.InstanceGet(doneKey)
+	done, _ := value.(func(error))
+	if done == nil {
+		// the operation was rejected
+		return
+	}
+
+	db.InstanceSet(doneKey, nil)
+	done(p.cfg.Classifier(db.Error))
+}
@@ -0,0 +1,83 @@
+package gormbreaker
+
+import (
+	"testing"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type Report struct {
+	ID    uint
+	Title string
+}
+
+func open(t *testing.T, kb *circuit_breaker.KeyedBreaker) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	assert.Nil(t, err)
+	assert.Nil(t, db.AutoMigrate(&Report{}))
+	assert.Nil(t, db.Use(New(Config{Breakers: kb, Database: "reports"})))
+
+	return db
+}
+
+func TestPlugin(t *testing.T) {
+	kb := circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{MaxConsecutiveFailures: 2},
+	})
+	db := open(t, kb)
+	cb, ok := kb.Lookup("reports")
+	assert.True(t, ok)
+
+	assert.Nil(t, db.Create(&Report{Title: "daily"}).Error)
+	assert.Nil(t, db.Model(&Report{}).Where("id = ?", 1).Update("title", "weekly").Error)
+
+	var report Report
+	assert.Nil(t, db.First(&report, 1).Error)
+	assert.Equal(t, "weekly", report.Title)
+
+	// the record is missing because of the caller, not the database
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(t, db.First(&report, 42).Error, gorm.ErrRecordNotFound)
+	}
+	assert.Equal(t, circuit_breaker.StateClosed, cb.State())
+	assert.Equal(t, uint32(0), cb.Counts().ConsecutiveFailures)
+
+	for i := 0; i < 2; i++ {
+		assert.NotNil(t, db.Table("missing").Find(&report).Error)
+	}
+	assert.Equal(t, circuit_breaker.StateOpen, cb.State())
+
+	err := db.Delete(&Report{}, 1).Error
+	assert.ErrorIs(t, err, circuit_breaker.ErrOpenState)
+	assert.Equal(t, `rejected by circuit breaker "reports": circuit breaker is open`, err.Error())
+	assert.Equal(t, 0, cb.InFlight())
+
+	// the rejected delete hasn't reached the database
+	cb.Reset()
+	var count int64
+	assert.Nil(t, db.Model(&Report{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestPluginSharedBreakers(t *testing.T) {
+	kb := circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{MaxConsecutiveFailures: 1},
+	})
+	reports := open(t, kb)
+
+	users, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	assert.Nil(t, err)
+	assert.Nil(t, users.AutoMigrate(&Report{}))
+	assert.Nil(t, users.Use(New(Config{Breakers: kb})))
+
+	var report Report
+	assert.NotNil(t, reports.Table("missing").Find(&report).Error)
+
+	// the breaker of the other connection is not affected
+	assert.Nil(t, users.Find(&[]Report{}).Error)
+	assert.ElementsMatch(t, []string{"reports", "sqlite"}, kb.Keys())
+}
@@ -0,0 +1,52 @@
+// Package graphqlbreaker protects genqlient GraphQL clients with circuit breakers.
+package graphqlbreaker
+
+import (
+	"errors"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// DefaultFailureCodes are the "code" extensions of GraphQL errors signalling that the server or its dependencies are unhealthy.
+var DefaultFailureCodes = []string{
+	"SERVICE_UNAVAILABLE",
+	"DOWNSTREAM_SERVICE_ERROR",
+}
+
+// Classifier maps the error of a GraphQL request to the outcome recorded by the breaker.
+//
+// Transport errors, when the request fails or the server responds with a non-200 status, are failures.
+// GraphQL errors returned in the response are usually caused by a resolver, and the response may still carry partial data,
+// so they count as failures only if the "code" extension of one of them is in FailureCodes.
+// If FailureCodes is empty, DefaultFailureCodes are used.
+type Classifier struct {
+	FailureCodes []string
+}
+
+// Classify returns err if it is a failure, and nil otherwise.
+func (c Classifier) Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var list gqlerror.List
+	if !errors.As(err, &list) {
+		return err
+	}
+
+	failureCodes := c.FailureCodes
+	if len(failureCodes) == 0 {
+		failureCodes = DefaultFailureCodes
+	}
+
+	for _, gqlErr := range list {
+		code, _ := gqlErr.Extensions["code"].(string)
+		for _, failure := range failureCodes {
+			if code == failure {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package graphqlbreaker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+func TestClassifier(t *testing.T) {
+	errTransport := errors.New("returned error 502 Bad Gateway")
+	notFound := gqlerror.List{{Message: "report not found"}}
+	unavailable := gqlerror.List{
+		{Message: "report not found"},
+		{Message: "users unavailable", Extensions: map[string]interface{}{"code": "SERVICE_UNAVAILABLE"}},
+	}
+
+	var c Classifier
+	assert.Nil(t, c.Classify(nil))
+	assert.Equal(t, errTransport, c.Classify(errTransport))
+	assert.Nil(t, c.Classify(notFound))
+	assert.Equal(t, unavailable, c.Classify(unavailable))
+
+	c = Classifier{FailureCodes: []string{"INTERNAL_SERVER_ERROR"}}
+	assert.Nil(t, c.Classify(unavailable))
+}
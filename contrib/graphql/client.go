@@ -0,0 +1,49 @@
+package graphqlbreaker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Khan/genqlient/graphql"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+var _ graphql.Client = (*Client)(nil)
+
+// Client is a graphql.Client maintaining an independent breaker per operation name,
+// so one failing query doesn't reject the other operations served by the same endpoint.
+// Requests rejected by the breaker fail with an error wrapping the rejection error, e.g. circuit_breaker.ErrOpenState.
+type Client struct {
+	// Client is the underlying client.
+	Client graphql.Client
+	// Breakers holds a breaker per operation name, created lazily.
+	Breakers *circuit_breaker.KeyedBreaker
+	// Classifier maps the error of the request to its outcome.
+	Classifier Classifier
+}
+
+// NewClient creates a Client with a breaker per operation name.
+func NewClient(client graphql.Client, cfg circuit_breaker.KeyedConfig) *Client {
+	return &Client{
+		Client:   client,
+		Breakers: circuit_breaker.NewKeyedBreaker(cfg),
+	}
+}
+
+// MakeRequest makes the request, see graphql.Client.MakeRequest.
+func (c *Client) MakeRequest(ctx context.Context, req *graphql.Request, resp *graphql.Response) error {
+	cb := c.Breakers.Get(req.OpName)
+
+	handled := false
+	var reqErr error
+	_, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+		handled = true
+		reqErr = c.Client.MakeRequest(ctx, req, resp)
+		return nil, c.Classifier.Classify(reqErr)
+	})
+	if !handled {
+		return fmt.Errorf("%s rejected by circuit breaker %q: %w", req.OpName, cb.Name(), err)
+	}
+
+	return reqErr
+}
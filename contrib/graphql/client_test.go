@@ -0,0 +1,52 @@
+package graphqlbreaker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Khan/genqlient/graphql"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphql.Request
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		switch req.OpName {
+		case "Reports":
+			w.WriteHeader(http.StatusBadGateway)
+		case "User":
+			_, _ = w.Write([]byte(`{"data":{"user":null},"errors":[{"message":"user not found"}]}`))
+		default:
+			_, _ = w.Write([]byte(`{"data":{"ok":true}}`))
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(graphql.NewClient(srv.URL, srv.Client()), circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{MaxConsecutiveFailures: 2},
+	})
+	ctx := context.Background()
+	request := func(opName string) error {
+		return c.MakeRequest(ctx, &graphql.Request{Query: "query " + opName + " { ok }", OpName: opName}, &graphql.Response{})
+	}
+
+	for i := 0; i < 2; i++ {
+		err := request("Reports")
+		assert.NotNil(t, err)
+		assert.False(t, errors.Is(err, circuit_breaker.ErrOpenState))
+
+		// resolver errors don't count
+		assert.NotNil(t, request("User"))
+	}
+
+	assert.True(t, errors.Is(request("Reports"), circuit_breaker.ErrOpenState))
+	assert.False(t, errors.Is(request("User"), circuit_breaker.ErrOpenState))
+	assert.Nil(t, request("Health"))
+}
@@ -0,0 +1,44 @@
+// Package grpcbreaker protects gRPC calls with circuit breakers.
+package grpcbreaker
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultFailureCodes are the status codes signalling that the peer is unhealthy.
+// Codes like NotFound or InvalidArgument are caused by the request and don't count as failures.
+var DefaultFailureCodes = []codes.Code{
+	codes.Unavailable,
+	codes.DeadlineExceeded,
+	codes.ResourceExhausted,
+	codes.Internal,
+	codes.Unknown,
+}
+
+// Classifier maps the error of an RPC to the outcome recorded by the breaker.
+// If FailureCodes is empty, DefaultFailureCodes are used.
+type Classifier struct {
+	FailureCodes []codes.Code
+}
+
+// Classify returns err if it is a failure, and nil otherwise.
+func (c Classifier) Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	failureCodes := c.FailureCodes
+	if len(failureCodes) == 0 {
+		failureCodes = DefaultFailureCodes
+	}
+
+	code := status.Code(err)
+	for _, failure := range failureCodes {
+		if code == failure {
+			return err
+		}
+	}
+
+	return nil
+}
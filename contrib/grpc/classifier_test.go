@@ -0,0 +1,25 @@
+package grpcbreaker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClassifier(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	notFound := status.Error(codes.NotFound, "not found")
+	plain := errors.New("plain")
+
+	assert.Nil(t, Classifier{}.Classify(nil))
+	assert.Equal(t, unavailable, Classifier{}.Classify(unavailable))
+	assert.Nil(t, Classifier{}.Classify(notFound))
+	assert.Equal(t, plain, Classifier{}.Classify(plain))
+
+	c := Classifier{FailureCodes: []codes.Code{codes.NotFound}}
+	assert.Nil(t, c.Classify(unavailable))
+	assert.Equal(t, notFound, c.Classify(notFound))
+}
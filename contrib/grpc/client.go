@@ -0,0 +1,79 @@
+package grpcbreaker
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ClientConfig configures the client interceptors.
+//
+// Breaker selects the breaker protecting the RPC by its full method name ("/pkg.Service/Method").
+// If Breaker returns nil, the RPC is not protected.
+//
+// Classifier maps the status of the RPC to its outcome.
+type ClientConfig struct {
+	Breaker    func(method string) *circuit_breaker.CircuitBreaker
+	Classifier Classifier
+}
+
+// UnaryClientInterceptor protects all the outgoing unary RPCs with a single breaker.
+// RPCs rejected by the breaker fail with codes.Unavailable.
+func UnaryClientInterceptor(cb *circuit_breaker.CircuitBreaker) grpc.UnaryClientInterceptor {
+	return NewUnaryClientInterceptor(ClientConfig{
+		Breaker: func(method string) *circuit_breaker.CircuitBreaker { return cb },
+	})
+}
+
+// UnaryClientInterceptorPerMethod protects each method with its own breaker, keyed by the full method name.
+func UnaryClientInterceptorPerMethod(kb *circuit_breaker.KeyedBreaker) grpc.UnaryClientInterceptor {
+	return NewUnaryClientInterceptor(ClientConfig{Breaker: kb.Get})
+}
+
+// NewUnaryClientInterceptor creates the client interceptor.
+func NewUnaryClientInterceptor(cfg ClientConfig) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		cb := cfg.Breaker(method)
+		if cb == nil {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		handled := false
+		var callErr error
+		_, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+			handled = true
+			callErr = invoker(ctx, method, req, reply, cc, opts...)
+			return nil, cfg.Classifier.Classify(callErr)
+		})
+		if !handled {
+			return rejection(codes.Unavailable, cb, method, err)
+		}
+
+		return callErr
+	}
+}
+
+// rejection converts the rejection error of the breaker into a gRPC status error.
+// While the breaker is open, the status carries RetryInfo with the time left until it becomes half-open.
+func rejection(code codes.Code, cb *circuit_breaker.CircuitBreaker, method string, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	st := status.Newf(code, "%s rejected by circuit breaker %q: %v", method, cb.Name(), err)
+	if errors.Is(err, circuit_breaker.ErrOpenState) {
+		if d := cb.RemainingOpenTime(); d > 0 {
+			if detailed, detailsErr := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(d)}); detailsErr == nil {
+				st = detailed
+			}
+		}
+	}
+
+	return st.Err()
+}
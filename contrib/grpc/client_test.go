@@ -0,0 +1,73 @@
+package grpcbreaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func invoker(err error) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return err
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "reports", MaxConsecutiveFailures: 2})
+	interceptor := UnaryClientInterceptor(cb)
+	ctx := context.Background()
+	notFound := status.Error(codes.NotFound, "not found")
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+
+	// NOT_FOUND doesn't count
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, notFound, interceptor(ctx, "/reports.Reports/Get", nil, nil, nil, invoker(notFound)))
+	}
+	assert.Equal(t, circuit_breaker.StateClosed, cb.State())
+
+	assert.Equal(t, unavailable, interceptor(ctx, "/reports.Reports/Get", nil, nil, nil, invoker(unavailable)))
+	assert.Equal(t, unavailable, interceptor(ctx, "/reports.Reports/Get", nil, nil, nil, invoker(unavailable)))
+	assert.Equal(t, circuit_breaker.StateOpen, cb.State())
+
+	err := interceptor(ctx, "/reports.Reports/Get", nil, nil, nil, invoker(nil))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, `/reports.Reports/Get rejected by circuit breaker "reports": circuit breaker is open`, status.Convert(err).Message())
+
+	// the rejection tells the client when to retry
+	details := status.Convert(err).Details()
+	if assert.Len(t, details, 1) {
+		retryInfo, ok := details[0].(*errdetails.RetryInfo)
+		if assert.True(t, ok) {
+			delay := retryInfo.GetRetryDelay().AsDuration()
+			assert.True(t, delay > 0 && delay <= cb.RemainingOpenTime()+time.Second)
+		}
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = interceptor(canceled, "/reports.Reports/Get", nil, nil, nil, invoker(nil))
+	assert.Equal(t, codes.Canceled, status.Code(err))
+}
+
+func TestUnaryClientInterceptorPerMethod(t *testing.T) {
+	kb := circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{MaxConsecutiveFailures: 1},
+	})
+	interceptor := UnaryClientInterceptorPerMethod(kb)
+	ctx := context.Background()
+	deadline := status.Error(codes.DeadlineExceeded, "deadline exceeded")
+
+	assert.Equal(t, deadline, interceptor(ctx, "/reports.Reports/GetReport", nil, nil, nil, invoker(deadline)))
+	err := interceptor(ctx, "/reports.Reports/GetReport", nil, nil, nil, invoker(nil))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	// other methods are not affected
+	assert.Nil(t, interceptor(ctx, "/reports.Reports/Ping", nil, nil, nil, invoker(nil)))
+}
@@ -0,0 +1,34 @@
+package grpcbreaker
+
+import (
+	"github.com/shirokovnv/circuit_breaker"
+	"google.golang.org/grpc"
+)
+
+// MethodConfig configures per-method breakers.
+//
+// Config is the template for the breaker of every method.
+//
+// Overrides replaces the template for particular methods, keyed by the full method name ("/pkg.Service/Method")
+// or by a pattern, e.g. "/pkg.Reports/*", see circuit_breaker.ConfigMap.
+type MethodConfig struct {
+	Config    circuit_breaker.Config
+	Overrides map[string]circuit_breaker.Config
+}
+
+// NewMethodBreakers creates the keyed breaker holding one breaker per full method name,
+// so a broken method doesn't open the circuit for healthy methods on the same connection.
+func NewMethodBreakers(cfg MethodConfig) *circuit_breaker.KeyedBreaker {
+	configs := circuit_breaker.ConfigMap{Default: cfg.Config, Endpoints: cfg.Overrides}
+	return circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{
+		Config:    cfg.Config,
+		ConfigFor: configs.ConfigFor,
+	})
+}
+
+// UnaryServerInterceptorPerMethod guards each method with its own breaker, keyed by the full method name.
+// Like NewUnaryServerInterceptor, it only sheds the RPCs of the methods whose breaker is open:
+// the breakers are fed by the handlers, e.g. through kb.Get(method).
+func UnaryServerInterceptorPerMethod(kb *circuit_breaker.KeyedBreaker) grpc.UnaryServerInterceptor {
+	return NewUnaryServerInterceptor(ServerConfig{Breaker: kb.Get})
+}
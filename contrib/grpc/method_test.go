@@ -0,0 +1,77 @@
+package grpcbreaker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewMethodBreakers(t *testing.T) {
+	kb := NewMethodBreakers(MethodConfig{
+		Config: circuit_breaker.Config{Name: "reports", MaxConsecutiveFailures: 5},
+		Overrides: map[string]circuit_breaker.Config{
+			"/reports.Reports/GetReport": {Name: "reports", MaxConsecutiveFailures: 1},
+		},
+	})
+	interceptor := UnaryClientInterceptorPerMethod(kb)
+	ctx := context.Background()
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+
+	assert.Equal(t, unavailable, interceptor(ctx, "/reports.Reports/GetReport", nil, nil, nil, invoker(unavailable)))
+	assert.Equal(t, unavailable, interceptor(ctx, "/reports.Reports/Ping", nil, nil, nil, invoker(unavailable)))
+
+	getReport, _ := kb.Lookup("/reports.Reports/GetReport")
+	assert.Equal(t, "reports/reports.Reports/GetReport", getReport.Name())
+	assert.Equal(t, circuit_breaker.StateOpen, getReport.State())
+
+	ping, _ := kb.Lookup("/reports.Reports/Ping")
+	assert.Equal(t, circuit_breaker.StateClosed, ping.State())
+	assert.Nil(t, interceptor(ctx, "/reports.Reports/Ping", nil, nil, nil, invoker(nil)))
+}
+
+func TestNewMethodBreakersPatterns(t *testing.T) {
+	kb := NewMethodBreakers(MethodConfig{
+		Config: circuit_breaker.Config{MaxConsecutiveFailures: 5},
+		Overrides: map[string]circuit_breaker.Config{
+			"/payments.Payments/*": {MaxConsecutiveFailures: 1},
+		},
+	})
+	interceptor := UnaryClientInterceptorPerMethod(kb)
+	ctx := context.Background()
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+
+	for _, method := range []string{"/payments.Payments/Charge", "/payments.Payments/Refund", "/reports.Reports/Ping"} {
+		assert.Equal(t, unavailable, interceptor(ctx, method, nil, nil, nil, invoker(unavailable)))
+	}
+
+	charge, _ := kb.Lookup("/payments.Payments/Charge")
+	refund, _ := kb.Lookup("/payments.Payments/Refund")
+	ping, _ := kb.Lookup("/reports.Reports/Ping")
+	assert.Equal(t, circuit_breaker.StateOpen, charge.State())
+	assert.Equal(t, circuit_breaker.StateOpen, refund.State())
+	assert.Equal(t, circuit_breaker.StateClosed, ping.State())
+}
+
+func TestUnaryServerInterceptorPerMethod(t *testing.T) {
+	kb := NewMethodBreakers(MethodConfig{Config: circuit_breaker.Config{MaxConsecutiveFailures: 1}})
+	interceptor := UnaryServerInterceptorPerMethod(kb)
+	ctx := context.Background()
+	getReport := &grpc.UnaryServerInfo{FullMethod: "/reports.Reports/GetReport"}
+	ping := &grpc.UnaryServerInfo{FullMethod: "/reports.Reports/Ping"}
+
+	_, err := interceptor(ctx, nil, getReport, handler(nil, status.Error(codes.Internal, "boom")))
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	_, _ = kb.Get(getReport.FullMethod).Execute(func() (interface{}, error) { return nil, status.Error(codes.Internal, "boom") })
+	_, err = interceptor(ctx, nil, getReport, handler("report", nil))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	resp, err := interceptor(ctx, nil, ping, handler("pong", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, "pong", resp)
+}
@@ -0,0 +1,41 @@
+package grpcbreaker
+
+import (
+	"context"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// ServerConfig configures the server interceptors.
+//
+// Breaker selects the breaker guarding the inbound RPC by its full method name ("/pkg.Service/Method").
+// If Breaker returns nil, the RPC is not guarded.
+type ServerConfig struct {
+	Breaker func(method string) *circuit_breaker.CircuitBreaker
+}
+
+// UnaryServerInterceptor guards all the inbound unary RPCs with a single breaker,
+// typically the one tracking the health of the server's own downstream dependencies.
+// While the breaker is open the server sheds inbound RPCs with codes.ResourceExhausted
+// instead of queueing them.
+func UnaryServerInterceptor(cb *circuit_breaker.CircuitBreaker) grpc.UnaryServerInterceptor {
+	return NewUnaryServerInterceptor(ServerConfig{
+		Breaker: func(method string) *circuit_breaker.CircuitBreaker { return cb },
+	})
+}
+
+// NewUnaryServerInterceptor creates the server interceptor.
+// It only checks the state of the breaker and doesn't record the outcome of the RPC:
+// the breaker is fed by the calls to the dependencies it tracks, and the half-open probes are left to them.
+func NewUnaryServerInterceptor(cfg ServerConfig) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		cb := cfg.Breaker(info.FullMethod)
+		if cb != nil && cb.State() == circuit_breaker.StateOpen {
+			return nil, rejection(codes.ResourceExhausted, cb, info.FullMethod, circuit_breaker.ErrOpenState)
+		}
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,76 @@
+package grpcbreaker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func handler(resp interface{}, err error) grpc.UnaryHandler {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		return resp, err
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "storage", MaxConsecutiveFailures: 2})
+	interceptor := UnaryServerInterceptor(cb)
+	ctx := context.Background()
+	info := &grpc.UnaryServerInfo{FullMethod: "/reports.Reports/Get"}
+	unavailable := status.Error(codes.Unavailable, "storage unavailable")
+
+	resp, err := interceptor(ctx, nil, info, handler("report", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, "report", resp)
+
+	// the outcomes of the RPCs are not recorded
+	for i := 0; i < 3; i++ {
+		_, err = interceptor(ctx, nil, info, handler(nil, unavailable))
+		assert.Equal(t, unavailable, err)
+	}
+	assert.Equal(t, circuit_breaker.StateClosed, cb.State())
+	assert.Equal(t, circuit_breaker.Counts{}, cb.Counts())
+
+	// the breaker is fed by the calls to the storage
+	for i := 0; i < 2; i++ {
+		_, _ = cb.Execute(func() (interface{}, error) { return nil, unavailable })
+	}
+	assert.Equal(t, circuit_breaker.StateOpen, cb.State())
+
+	called := false
+	resp, err = interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		called = true
+		return "report", nil
+	})
+	assert.False(t, called)
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, `/reports.Reports/Get rejected by circuit breaker "storage": circuit breaker is open`, status.Convert(err).Message())
+	assert.Equal(t, circuit_breaker.StateOpen, cb.State())
+}
+
+func TestUnaryServerInterceptorSkip(t *testing.T) {
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{MaxConsecutiveFailures: 1})
+	interceptor := NewUnaryServerInterceptor(ServerConfig{
+		Breaker: func(method string) *circuit_breaker.CircuitBreaker {
+			if method == "/grpc.health.v1.Health/Check" {
+				return nil
+			}
+			return cb
+		},
+	})
+	ctx := context.Background()
+	_, _ = cb.Execute(func() (interface{}, error) { return nil, status.Error(codes.Internal, "boom") })
+
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/reports.Reports/Get"}, handler("report", nil))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	resp, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, handler("SERVING", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, "SERVING", resp)
+}
@@ -0,0 +1,17 @@
+module github.com/shirokovnv/circuit_breaker/contrib/hdrhistogram
+
+go 1.21
+
+require (
+	github.com/HdrHistogram/hdrhistogram-go v1.1.2
+	github.com/shirokovnv/circuit_breaker v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.9.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/shirokovnv/circuit_breaker => ../..
@@ -0,0 +1,121 @@
+// Package hdrbreaker records the latencies of circuit breakers into HDR histograms.
+package hdrbreaker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+const (
+	defaultLowest             = time.Microsecond
+	defaultHighest            = time.Minute
+	defaultSignificantFigures = 3
+)
+
+// Config configures Histogram.
+//
+// Lowest and Highest are the range of the tracked durations, 1µs and 1m by default.
+// Lowest is also the resolution of the histogram. The durations out of the range are clamped to it.
+//
+// SignificantFigures is the precision of the recorded values, from 1 to 5, 3 by default.
+//
+// Bounds are the upper bounds of the buckets in the snapshots, circuit_breaker.DefaultHistogramBounds by default.
+type Config struct {
+	Lowest             time.Duration
+	Highest            time.Duration
+	SignificantFigures int
+	Bounds             []time.Duration
+}
+
+// Histogram is a circuit_breaker.Histogram backed by an HDR histogram,
+// which keeps the quantiles accurate to the configured significant figures over the whole range.
+type Histogram struct {
+	unit   time.Duration
+	bounds []time.Duration
+
+	mu   sync.Mutex
+	hdr  *hdrhistogram.Histogram
+	sum  time.Duration
+	high int64
+}
+
+// New creates the Histogram.
+func New(cfg Config) *Histogram {
+	if cfg.Lowest <= 0 {
+		cfg.Lowest = defaultLowest
+	}
+	if cfg.Highest <= cfg.Lowest {
+		cfg.Highest = defaultHighest
+	}
+	if cfg.SignificantFigures <= 0 {
+		cfg.SignificantFigures = defaultSignificantFigures
+	}
+	if len(cfg.Bounds) == 0 {
+		cfg.Bounds = circuit_breaker.DefaultHistogramBounds
+	}
+
+	high := int64(cfg.Highest / cfg.Lowest)
+	return &Histogram{
+		unit:   cfg.Lowest,
+		bounds: cfg.Bounds,
+		hdr:    hdrhistogram.New(1, high, cfg.SignificantFigures),
+		high:   high,
+	}
+}
+
+// Record records the duration in units of Lowest.
+func (h *Histogram) Record(d time.Duration) {
+	v := int64(d / h.unit)
+	if v < 1 {
+		v = 1
+	}
+	if v > h.high {
+		v = h.high
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_ = h.hdr.RecordValue(v)
+	h.sum += d
+}
+
+func (h *Histogram) Quantile(q float64) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.hdr.TotalCount() == 0 {
+		return 0
+	}
+
+	return time.Duration(h.hdr.ValueAtQuantile(q*100)) * h.unit
+}
+
+// Snapshot counts the recorded durations up to each bound,
+// with the precision of the configured significant figures.
+func (h *Histogram) Snapshot() circuit_breaker.HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := circuit_breaker.HistogramSnapshot{
+		Count:   uint64(h.hdr.TotalCount()),
+		Sum:     h.sum,
+		Buckets: make([]circuit_breaker.HistogramBucket, len(h.bounds)),
+	}
+
+	bars := h.hdr.Distribution()
+	var cumulative uint64
+	i := 0
+	for b, bound := range h.bounds {
+		limit := int64(bound / h.unit)
+		for ; i < len(bars) && bars[i].From <= limit; i++ {
+			cumulative += uint64(bars[i].Count)
+		}
+		s.Buckets[b] = circuit_breaker.HistogramBucket{UpperBound: bound, Count: cumulative}
+	}
+
+	return s
+}
@@ -0,0 +1,42 @@
+package hdrbreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogram(t *testing.T) {
+	h := New(Config{Bounds: []time.Duration{10 * time.Millisecond, 100 * time.Millisecond}})
+	assert.Equal(t, time.Duration(0), h.Quantile(0.99))
+
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * 100 * time.Microsecond)
+	}
+	// out of range
+	h.Record(time.Hour)
+
+	assert.InEpsilon(t, float64(50*time.Millisecond), float64(h.Quantile(0.5)), 0.01)
+	assert.InEpsilon(t, float64(99*time.Millisecond), float64(h.Quantile(0.99)), 0.01)
+	assert.InEpsilon(t, float64(time.Minute), float64(h.Quantile(1)), 0.01)
+
+	s := h.Snapshot()
+	assert.Equal(t, uint64(1001), s.Count)
+	assert.Equal(t, 50050*time.Millisecond+time.Hour, s.Sum)
+	assert.Equal(t, []circuit_breaker.HistogramBucket{
+		{UpperBound: 10 * time.Millisecond, Count: 100},
+		{UpperBound: 100 * time.Millisecond, Count: 1000},
+	}, s.Buckets)
+}
+
+func TestHistogramCircuitBreaker(t *testing.T) {
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{LatencyHistogram: New(Config{})})
+	_, err := cb.Execute(func() (interface{}, error) { return nil, nil })
+	assert.Nil(t, err)
+
+	s := cb.Snapshot().Latency
+	assert.Equal(t, uint64(1), s.Count)
+	assert.Equal(t, uint64(1), s.Buckets[len(s.Buckets)-1].Count)
+}
@@ -0,0 +1,24 @@
+package kafkabreaker
+
+import (
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// Fetcher is the fetch controlling part of *kgo.Client.
+type Fetcher interface {
+	PauseFetchTopics(topics ...string) []string
+	ResumeFetchTopics(topics ...string)
+}
+
+var _ Fetcher = (*kgo.Client)(nil)
+
+// PauseFetchOnOpen pauses fetching the topics while the breaker protecting the processor
+// of their records is open, see circuit_breaker.PauseOnOpen.
+// The records already fetched are still returned by PollFetches.
+func PauseFetchOnOpen(client Fetcher, cb *circuit_breaker.CircuitBreaker, topics ...string) (stop func()) {
+	return circuit_breaker.PauseOnOpen(cb,
+		func() { client.PauseFetchTopics(topics...) },
+		func() { client.ResumeFetchTopics(topics...) },
+	)
+}
@@ -0,0 +1,40 @@
+package kafkabreaker
+
+import (
+	"testing"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeFetcher records the paused topics
+type fakeFetcher struct {
+	paused map[string]bool
+}
+
+func (f *fakeFetcher) PauseFetchTopics(topics ...string) []string {
+	for _, topic := range topics {
+		f.paused[topic] = true
+	}
+	return topics
+}
+
+func (f *fakeFetcher) ResumeFetchTopics(topics ...string) {
+	for _, topic := range topics {
+		delete(f.paused, topic)
+	}
+}
+
+func TestPauseFetchOnOpen(t *testing.T) {
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{MaxConsecutiveFailures: 1})
+	client := &fakeFetcher{paused: make(map[string]bool)}
+
+	stop := PauseFetchOnOpen(client, cb, "payments", "refunds")
+	defer stop()
+
+	_, _ = cb.Execute(func() (interface{}, error) { return nil, errBrokerDown })
+	assert.Equal(t, map[string]bool{"payments": true, "refunds": true}, client.paused)
+
+	cb.Reset()
+	assert.Empty(t, client.paused)
+}
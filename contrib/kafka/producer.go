@@ -0,0 +1,148 @@
+// Package kafkabreaker protects franz-go Kafka clients with circuit breakers.
+package kafkabreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// Client is the producing part of *kgo.Client.
+type Client interface {
+	Produce(ctx context.Context, r *kgo.Record, promise func(*kgo.Record, error))
+	ProduceSync(ctx context.Context, rs ...*kgo.Record) kgo.ProduceResults
+}
+
+var _ Client = (*kgo.Client)(nil)
+
+// ProducerConfig configures Producer.
+//
+// Breakers holds the breakers of the records, keyed by Key.
+//
+// Key selects the breaker of the record. If Key is nil, TopicKey is used.
+//
+// Classifier maps the produce error to its outcome. If Classifier is nil, DefaultClassifier is used.
+//
+// Fallback receives the records rejected by the breaker, for example to buffer them until Kafka recovers.
+// If Fallback returns nil, the record is reported as produced. If Fallback is nil,
+// rejected records fail with an error wrapping the rejection error of the breaker.
+type ProducerConfig struct {
+	Breakers   *circuit_breaker.KeyedBreaker
+	Key        func(r *kgo.Record) string
+	Classifier func(err error) error
+	Fallback   func(ctx context.Context, r *kgo.Record, err error) error
+}
+
+// Producer protects produce calls with a breaker per topic, failing fast while Kafka is down
+// instead of blocking on the full buffer of the client.
+type Producer struct {
+	client Client
+	cfg    ProducerConfig
+}
+
+// NewProducer wraps the client.
+func NewProducer(client Client, cfg ProducerConfig) *Producer {
+	if cfg.Key == nil {
+		cfg.Key = TopicKey
+	}
+	if cfg.Classifier == nil {
+		cfg.Classifier = DefaultClassifier
+	}
+
+	return &Producer{client: client, cfg: cfg}
+}
+
+// TopicKey keys the breakers by the topic of the record.
+func TopicKey(r *kgo.Record) string {
+	return r.Topic
+}
+
+// DefaultClassifier ignores the errors caused by the record itself rather than by the cluster.
+func DefaultClassifier(err error) error {
+	switch {
+	case errors.Is(err, kerr.MessageTooLarge),
+		errors.Is(err, kerr.RecordListTooLarge),
+		errors.Is(err, kerr.InvalidRecord),
+		errors.Is(err, kerr.CorruptMessage):
+		return nil
+	default:
+		return err
+	}
+}
+
+// Produce asynchronously produces the record, calling the promise with the outcome.
+// A record rejected by the breaker is passed to the fallback and the promise is called immediately.
+func (p *Producer) Produce(ctx context.Context, r *kgo.Record, promise func(*kgo.Record, error)) {
+	if promise == nil {
+		promise = func(*kgo.Record, error) {}
+	}
+
+	cb := p.cfg.Breakers.Get(p.cfg.Key(r))
+	done, err := cb.Allow(ctx)
+	if err != nil {
+		promise(r, p.reject(ctx, cb, r, err))
+		return
+	}
+
+	p.client.Produce(ctx, r, func(r *kgo.Record, err error) {
+		done(p.cfg.Classifier(err))
+		promise(r, err)
+	})
+}
+
+// ProduceSync produces the records and waits for the outcome.
+// The records are grouped by key, and each group is produced through its own breaker.
+// The results are in the order of the records.
+func (p *Producer) ProduceSync(ctx context.Context, rs ...*kgo.Record) kgo.ProduceResults {
+	var keys []string
+	groups := make(map[string][]int)
+	for i, r := range rs {
+		key := p.cfg.Key(r)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	results := make(kgo.ProduceResults, len(rs))
+	for _, key := range keys {
+		indexes := groups[key]
+		group := make([]*kgo.Record, len(indexes))
+		for i, index := range indexes {
+			group[i] = rs[index]
+		}
+
+		cb := p.cfg.Breakers.Get(key)
+		handled := false
+		var groupResults kgo.ProduceResults
+		_, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+			handled = true
+			groupResults = p.client.ProduceSync(ctx, group...)
+			return nil, p.cfg.Classifier(groupResults.FirstErr())
+		})
+
+		for i, index := range indexes {
+			if handled {
+				results[index] = groupResults[i]
+			} else {
+				results[index] = kgo.ProduceResult{Record: rs[index], Err: p.reject(ctx, cb, rs[index], err)}
+			}
+		}
+	}
+
+	return results
+}
+
+// reject passes the record rejected by the breaker to the fallback
+func (p *Producer) reject(ctx context.Context, cb *circuit_breaker.CircuitBreaker, r *kgo.Record, err error) error {
+	err = fmt.Errorf("produce to %q rejected by circuit breaker %q: %w", r.Topic, cb.Name(), err)
+	if p.cfg.Fallback == nil {
+		return err
+	}
+
+	return p.cfg.Fallback(ctx, r, err)
+}
@@ -0,0 +1,107 @@
+package kafkabreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+var errBrokerDown = errors.New("broker down")
+
+// fakeClient fails the records of the topics in errs
+type fakeClient struct {
+	errs     map[string]error
+	produced []*kgo.Record
+}
+
+func (c *fakeClient) Produce(ctx context.Context, r *kgo.Record, promise func(*kgo.Record, error)) {
+	err := c.errs[r.Topic]
+	if err == nil {
+		c.produced = append(c.produced, r)
+	}
+	promise(r, err)
+}
+
+func (c *fakeClient) ProduceSync(ctx context.Context, rs ...*kgo.Record) kgo.ProduceResults {
+	results := make(kgo.ProduceResults, 0, len(rs))
+	for _, r := range rs {
+		c.Produce(ctx, r, func(r *kgo.Record, err error) {
+			results = append(results, kgo.ProduceResult{Record: r, Err: err})
+		})
+	}
+	return results
+}
+
+func breakers() *circuit_breaker.KeyedBreaker {
+	return circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{Name: "kafka", MaxConsecutiveFailures: 2},
+	})
+}
+
+func TestProducerProduceSync(t *testing.T) {
+	client := &fakeClient{errs: map[string]error{"payments": errBrokerDown, "huge": kerr.MessageTooLarge}}
+	kb := breakers()
+	producer := NewProducer(client, ProducerConfig{Breakers: kb})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		results := producer.ProduceSync(ctx, &kgo.Record{Topic: "payments"}, &kgo.Record{Topic: "reports"}, &kgo.Record{Topic: "huge"})
+		assert.Equal(t, errBrokerDown, results[0].Err)
+		assert.Nil(t, results[1].Err)
+		assert.Equal(t, kerr.MessageTooLarge, results[2].Err)
+	}
+
+	results := producer.ProduceSync(ctx, &kgo.Record{Topic: "reports"}, &kgo.Record{Topic: "payments"})
+	assert.Nil(t, results[0].Err)
+	assert.ErrorIs(t, results[1].Err, circuit_breaker.ErrOpenState)
+	assert.Equal(t, `produce to "payments" rejected by circuit breaker "kafka/payments": circuit breaker is open`, results[1].Err.Error())
+	assert.Equal(t, "payments", results[1].Record.Topic)
+	assert.Len(t, client.produced, 3)
+
+	huge, _ := kb.Lookup("huge")
+	assert.Equal(t, circuit_breaker.StateClosed, huge.State())
+}
+
+func TestProducerProduce(t *testing.T) {
+	client := &fakeClient{errs: map[string]error{"payments": errBrokerDown}}
+	producer := NewProducer(client, ProducerConfig{Breakers: breakers()})
+	ctx := context.Background()
+
+	var errs []error
+	promise := func(r *kgo.Record, err error) { errs = append(errs, err) }
+	for i := 0; i < 3; i++ {
+		producer.Produce(ctx, &kgo.Record{Topic: "payments"}, promise)
+	}
+	producer.Produce(ctx, &kgo.Record{Topic: "reports"}, promise)
+
+	assert.Equal(t, errBrokerDown, errs[0])
+	assert.Equal(t, errBrokerDown, errs[1])
+	assert.ErrorIs(t, errs[2], circuit_breaker.ErrOpenState)
+	assert.Nil(t, errs[3])
+}
+
+func TestProducerFallback(t *testing.T) {
+	client := &fakeClient{errs: map[string]error{"payments": errBrokerDown}}
+	var buffered []*kgo.Record
+	producer := NewProducer(client, ProducerConfig{
+		Breakers: breakers(),
+		Fallback: func(ctx context.Context, r *kgo.Record, err error) error {
+			buffered = append(buffered, r)
+			return nil
+		},
+	})
+	ctx := context.Background()
+
+	producer.ProduceSync(ctx, &kgo.Record{Topic: "payments"})
+	producer.ProduceSync(ctx, &kgo.Record{Topic: "payments"})
+
+	results := producer.ProduceSync(ctx, &kgo.Record{Topic: "payments", Value: []byte("1")}, &kgo.Record{Topic: "payments", Value: []byte("2")})
+	assert.Nil(t, results.FirstErr())
+	assert.Len(t, buffered, 2)
+	assert.Equal(t, []byte("2"), buffered[1].Value)
+}
@@ -0,0 +1,44 @@
+// Package logrusbreaker adapts logrus loggers to circuit_breaker.Logger.
+package logrusbreaker
+
+import (
+	"fmt"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/sirupsen/logrus"
+)
+
+type logger struct {
+	entry logrus.FieldLogger
+}
+
+// Logger returns a circuit_breaker.Logger writing entries with fields to the logrus logger,
+// with the "component" field set to "circuit_breaker".
+func Logger(l logrus.FieldLogger) circuit_breaker.Logger {
+	return &logger{entry: l.WithField("component", "circuit_breaker")}
+}
+
+func (l *logger) Debug(msg string, keysAndValues ...interface{}) {
+	l.with(keysAndValues).Debug(msg)
+}
+
+func (l *logger) Info(msg string, keysAndValues ...interface{}) {
+	l.with(keysAndValues).Info(msg)
+}
+
+func (l *logger) Warn(msg string, keysAndValues ...interface{}) {
+	l.with(keysAndValues).Warn(msg)
+}
+
+func (l *logger) Error(msg string, keysAndValues ...interface{}) {
+	l.with(keysAndValues).Error(msg)
+}
+
+func (l *logger) with(keysAndValues []interface{}) logrus.FieldLogger {
+	fields := make(logrus.Fields, len(keysAndValues)/2)
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		fields[fmt.Sprint(keysAndValues[i])] = keysAndValues[i+1]
+	}
+
+	return l.entry.WithFields(fields)
+}
@@ -0,0 +1,40 @@
+package logrusbreaker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger(t *testing.T) {
+	l, hook := test.NewNullLogger()
+	l.SetLevel(logrus.DebugLevel)
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{
+		Name:                   "payments",
+		MaxConsecutiveFailures: 1,
+		Logger:                 Logger(l),
+	})
+
+	errUnavailable := errors.New("unavailable")
+	_, _ = cb.Execute(func() (interface{}, error) { return nil, errUnavailable })
+	_, _ = cb.Execute(func() (interface{}, error) { return nil, nil })
+
+	entries := hook.AllEntries()
+	assert.Len(t, entries, 2)
+
+	assert.Equal(t, logrus.WarnLevel, entries[0].Level)
+	assert.Equal(t, "circuit breaker state changed", entries[0].Message)
+	assert.Equal(t, "circuit_breaker", entries[0].Data["component"])
+	assert.Equal(t, "payments", entries[0].Data["name"])
+	assert.Equal(t, "open", entries[0].Data["to"])
+	assert.Equal(t, circuit_breaker.ReasonTripped, entries[0].Data["reason"])
+	assert.Equal(t, uint32(1), entries[0].Data["failures"])
+
+	assert.Equal(t, logrus.DebugLevel, entries[1].Level)
+	assert.Equal(t, "circuit breaker rejected request", entries[1].Message)
+	assert.Equal(t, circuit_breaker.ErrOpenState, entries[1].Data["error"])
+}
@@ -0,0 +1,380 @@
+// Package memberlistbreaker coordinates the circuit breakers of the fleet by gossip over hashicorp/memberlist,
+// without a central store: the instances exchange the summaries of their breakers,
+// and a quorum of peers reporting a dependency down forces the local breaker open.
+package memberlistbreaker
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/memberlist"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+// Actor is the actor of the trips forced by the quorum, see circuit_breaker.AuditEntry.
+const Actor = "gossip"
+
+const (
+	defaultQuorum   = 0.5
+	defaultInterval = time.Second
+)
+
+// Config configures Gossip.
+//
+// Registry holds the breakers summarized to the peers and forced open by the quorum.
+//
+// Quorum is the fraction of the peers which must report a breaker down to force the local breaker of the same name open,
+// 0.5 by default. MinPeers is the number of peers below which the quorum is never reached, 1 by default,
+// e.g. so a single peer can't force the whole fleet open.
+//
+// Interval is the period of publishing the summary of the local breakers and checking the quorum, 1 second by default.
+// RetransmitMult is the memberlist retransmission multiplier of the summaries, 4 by default.
+//
+// OnError is called with the errors of the summaries of the peers which can't be decoded, which are skipped.
+type Config struct {
+	Registry       *circuit_breaker.Registry
+	Quorum         float64
+	MinPeers       int
+	Interval       time.Duration
+	RetransmitMult int
+	OnError        func(err error)
+}
+
+// Summary is the state of the breakers of an instance gossiped to its peers.
+// Down holds the states of the breakers which are not closed, by name.
+// Version orders the summaries of the node, the latest one replacing the others.
+type Summary struct {
+	Node    string
+	Version uint64
+	Down    map[string]circuit_breaker.State
+}
+
+// Gossip is the memberlist.Delegate and memberlist.EventDelegate exchanging the summaries of the breakers.
+// Set it as the Delegate and the Events of the memberlist configuration, then call Start with the created memberlist:
+//
+//	g := memberlistbreaker.New(memberlistbreaker.Config{Registry: registry})
+//	conf := memberlist.DefaultLANConfig()
+//	conf.Delegate = g
+//	conf.Events = g
+//	list, err := memberlist.Create(conf)
+//	...
+//	g.Start(list)
+//	defer g.Close()
+//
+// A breaker forced open by the quorum becomes half-open once its open period is over, as if it had tripped by itself,
+// and its probes decide whether it closes. Only the closed breakers are forced open,
+// so the half-open breakers keep probing while the summaries of the peers catch up.
+type Gossip struct {
+	cfg   Config
+	queue *memberlist.TransmitLimitedQueue
+
+	mu    sync.Mutex
+	list  *memberlist.Memberlist
+	local Summary
+	peers map[string]Summary
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+var (
+	_ memberlist.Delegate      = (*Gossip)(nil)
+	_ memberlist.EventDelegate = (*Gossip)(nil)
+)
+
+// New creates the Gossip.
+func New(cfg Config) *Gossip {
+	if cfg.Quorum <= 0 {
+		cfg.Quorum = defaultQuorum
+	}
+	if cfg.MinPeers <= 0 {
+		cfg.MinPeers = 1
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultInterval
+	}
+	if cfg.RetransmitMult <= 0 {
+		cfg.RetransmitMult = 4
+	}
+
+	g := &Gossip{
+		cfg:   cfg,
+		peers: make(map[string]Summary),
+		done:  make(chan struct{}),
+	}
+	g.queue = &memberlist.TransmitLimitedQueue{
+		NumNodes:       g.numNodes,
+		RetransmitMult: cfg.RetransmitMult,
+	}
+
+	return g
+}
+
+// Start starts publishing the summary of the local breakers and checking the quorum every Interval, until Close.
+func (g *Gossip) Start(list *memberlist.Memberlist) {
+	g.mu.Lock()
+	g.list = list
+	g.local.Node = list.LocalNode().Name
+	g.mu.Unlock()
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+
+		ticker := time.NewTicker(g.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			g.publish()
+			g.enforce()
+			select {
+			case <-ticker.C:
+			case <-g.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops publishing the summaries and checking the quorum. It doesn't leave or shut down the memberlist.
+func (g *Gossip) Close() {
+	close(g.done)
+	g.wg.Wait()
+}
+
+// Down returns the number of the peers reporting the breaker with the name down, and the number of the peers.
+func (g *Gossip) Down(name string) (down int, peers int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.down(name)
+}
+
+// down counts the peers reporting the breaker down, while the lock is held
+func (g *Gossip) down(name string) (down int, peers int) {
+	for _, s := range g.peers {
+		if _, ok := s.Down[name]; ok {
+			down++
+		}
+	}
+	if g.list != nil {
+		peers = g.list.NumMembers() - 1
+	}
+
+	return down, peers
+}
+
+// publish gossips the summary of the local breakers if it changed
+func (g *Gossip) publish() {
+	states := make(map[string]circuit_breaker.State)
+	g.cfg.Registry.Range(func(name string, cb *circuit_breaker.CircuitBreaker) bool {
+		if state := cb.State(); state != circuit_breaker.StateClosed {
+			states[name] = state
+		}
+		return true
+	})
+
+	g.mu.Lock()
+	if g.local.Version != 0 && sameStates(g.local.Down, states) {
+		g.mu.Unlock()
+		return
+	}
+	// the versions must keep growing across the restarts of the node
+	version := uint64(time.Now().UnixNano())
+	if version <= g.local.Version {
+		version = g.local.Version + 1
+	}
+	g.local.Version = version
+	g.local.Down = states
+	data, err := json.Marshal(g.local)
+	g.mu.Unlock()
+	if err != nil {
+		g.onError(err)
+		return
+	}
+
+	g.queue.QueueBroadcast(summaryBroadcast(data))
+}
+
+// enforce forces open the closed local breakers reported down by the quorum of the peers
+func (g *Gossip) enforce() {
+	type trip struct {
+		cb     *circuit_breaker.CircuitBreaker
+		reason string
+	}
+	var trips []trip
+
+	g.mu.Lock()
+	names := make(map[string]struct{})
+	for _, s := range g.peers {
+		for name := range s.Down {
+			names[name] = struct{}{}
+		}
+	}
+	for name := range names {
+		down, peers := g.down(name)
+		if peers < g.cfg.MinPeers || float64(down) < g.cfg.Quorum*float64(peers) {
+			continue
+		}
+		if cb, ok := g.cfg.Registry.Get(name); ok {
+			trips = append(trips, trip{cb: cb, reason: fmt.Sprintf("%d of %d peers report %s down", down, peers, name)})
+		}
+	}
+	g.mu.Unlock()
+
+	for _, t := range trips {
+		if t.cb.State() == circuit_breaker.StateClosed {
+			_ = t.cb.Apply(circuit_breaker.ActionTrip, Actor, t.reason)
+		}
+	}
+}
+
+// merge keeps the summary of a peer if it is newer than the known one
+func (g *Gossip) merge(data []byte) {
+	var s Summary
+	if err := json.Unmarshal(data, &s); err != nil {
+		g.onError(fmt.Errorf("memberlist: summary: %w", err))
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.mergeSummary(s)
+}
+
+// mergeSummary keeps the summary of a peer if it is newer than the known one, while the lock is held.
+// The summaries of the nodes which are not members, e.g. the ones which left, are dropped.
+func (g *Gossip) mergeSummary(s Summary) {
+	if s.Node == "" || s.Node == g.local.Node || !g.member(s.Node) {
+		return
+	}
+	if known, ok := g.peers[s.Node]; ok && known.Version >= s.Version {
+		return
+	}
+	g.peers[s.Node] = s
+}
+
+// member reports whether the node is a live member, while the lock is held
+func (g *Gossip) member(node string) bool {
+	if g.list == nil {
+		return false
+	}
+	for _, m := range g.list.Members() {
+		if m.Name == node {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (g *Gossip) numNodes() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.list == nil {
+		return 1
+	}
+	return g.list.NumMembers()
+}
+
+func (g *Gossip) onError(err error) {
+	if g.cfg.OnError != nil {
+		g.cfg.OnError(err)
+	}
+}
+
+// NodeMeta implements memberlist.Delegate.
+func (g *Gossip) NodeMeta(limit int) []byte {
+	return nil
+}
+
+// NotifyMsg implements memberlist.Delegate, receiving the summaries broadcast by the peers.
+func (g *Gossip) NotifyMsg(msg []byte) {
+	g.merge(msg)
+}
+
+// GetBroadcasts implements memberlist.Delegate.
+func (g *Gossip) GetBroadcasts(overhead, limit int) [][]byte {
+	return g.queue.GetBroadcasts(overhead, limit)
+}
+
+// LocalState implements memberlist.Delegate, sending all the known summaries on push/pull,
+// so the joining nodes learn the state of the fleet at once.
+func (g *Gossip) LocalState(join bool) []byte {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	summaries := make([]Summary, 0, len(g.peers)+1)
+	if g.local.Version != 0 {
+		summaries = append(summaries, g.local)
+	}
+	for _, s := range g.peers {
+		summaries = append(summaries, s)
+	}
+	data, err := json.Marshal(summaries)
+	if err != nil {
+		return nil
+	}
+
+	return data
+}
+
+// MergeRemoteState implements memberlist.Delegate.
+func (g *Gossip) MergeRemoteState(buf []byte, join bool) {
+	var summaries []Summary
+	if err := json.Unmarshal(buf, &summaries); err != nil {
+		g.onError(fmt.Errorf("memberlist: summaries: %w", err))
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, s := range summaries {
+		g.mergeSummary(s)
+	}
+}
+
+// NotifyJoin implements memberlist.EventDelegate.
+func (g *Gossip) NotifyJoin(node *memberlist.Node) {}
+
+// NotifyLeave implements memberlist.EventDelegate, forgetting the summary of the node.
+func (g *Gossip) NotifyLeave(node *memberlist.Node) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.peers, node.Name)
+}
+
+// NotifyUpdate implements memberlist.EventDelegate.
+func (g *Gossip) NotifyUpdate(node *memberlist.Node) {}
+
+// summaryBroadcast is the summary of the local node, replacing the ones still queued
+type summaryBroadcast []byte
+
+func (b summaryBroadcast) Invalidates(other memberlist.Broadcast) bool {
+	_, ok := other.(summaryBroadcast)
+	return ok
+}
+
+func (b summaryBroadcast) Message() []byte {
+	return b
+}
+
+func (b summaryBroadcast) Finished() {}
+
+func sameStates(a, b map[string]circuit_breaker.State) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, state := range a {
+		if other, ok := b[name]; !ok || other != state {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,140 @@
+package memberlistbreaker
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/memberlist"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+type node struct {
+	registry *circuit_breaker.Registry
+	gossip   *Gossip
+	list     *memberlist.Memberlist
+}
+
+func (n *node) breaker(name string) *circuit_breaker.CircuitBreaker {
+	return n.registry.GetOrCreate(name, circuit_breaker.Config{Timeout: time.Minute, AuditLogSize: 10})
+}
+
+func cluster(t *testing.T, size int, cfg Config) []*node {
+	nodes := make([]*node, 0, size)
+	for i := 0; i < size; i++ {
+		n := &node{registry: circuit_breaker.NewRegistry(circuit_breaker.RegistryConfig{})}
+		n.breaker("payments")
+		c := cfg
+		c.Registry = n.registry
+		c.Interval = 10 * time.Millisecond
+		n.gossip = New(c)
+
+		conf := memberlist.DefaultLocalConfig()
+		conf.Name = fmt.Sprintf("node-%d", i)
+		conf.BindAddr = "127.0.0.1"
+		conf.BindPort = 0
+		conf.GossipInterval = 10 * time.Millisecond
+		conf.PushPullInterval = 50 * time.Millisecond
+		conf.Logger = log.New(io.Discard, "", 0)
+		conf.Delegate = n.gossip
+		conf.Events = n.gossip
+		list, err := memberlist.Create(conf)
+		assert.Nil(t, err)
+		n.list = list
+		n.gossip.Start(list)
+		t.Cleanup(func() {
+			n.gossip.Close()
+			_ = list.Shutdown()
+		})
+
+		if i > 0 {
+			_, err := list.Join([]string{nodes[0].list.LocalNode().Address()})
+			assert.Nil(t, err)
+		}
+		nodes = append(nodes, n)
+	}
+
+	for _, n := range nodes {
+		n := n
+		assert.Eventually(t, func() bool { return n.list.NumMembers() == size }, 5*time.Second, 10*time.Millisecond)
+	}
+	return nodes
+}
+
+func TestGossipQuorum(t *testing.T) {
+	nodes := cluster(t, 3, Config{})
+
+	// a single peer of two is the quorum of 0.5
+	nodes[1].breaker("payments").Trip()
+	assert.Eventually(t, func() bool {
+		return nodes[0].breaker("payments").State() == circuit_breaker.StateOpen
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return nodes[2].breaker("payments").State() == circuit_breaker.StateOpen
+	}, 5*time.Second, 10*time.Millisecond)
+
+	audit := nodes[0].breaker("payments").AuditLog()
+	assert.Len(t, audit, 1)
+	assert.Equal(t, Actor, audit[0].Actor)
+	assert.Contains(t, audit[0].Reason, "of 2 peers report payments down")
+
+	assert.Eventually(t, func() bool {
+		down, peers := nodes[0].gossip.Down("payments")
+		return down == 2 && peers == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestGossipBelowQuorum(t *testing.T) {
+	nodes := cluster(t, 3, Config{Quorum: 1})
+
+	nodes[1].breaker("payments").Trip()
+	assert.Eventually(t, func() bool {
+		down, _ := nodes[0].gossip.Down("payments")
+		return down == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, circuit_breaker.StateClosed, nodes[0].breaker("payments").State())
+
+	// the breakers unknown locally are not created
+	nodes[1].breaker("search").Trip()
+	nodes[2].breaker("search").Trip()
+	assert.Eventually(t, func() bool {
+		down, _ := nodes[0].gossip.Down("search")
+		return down == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	_, ok := nodes[0].registry.Get("search")
+	assert.False(t, ok)
+}
+
+func TestGossipLeave(t *testing.T) {
+	nodes := cluster(t, 2, Config{MinPeers: 2})
+
+	nodes[1].breaker("payments").Trip()
+	assert.Eventually(t, func() bool {
+		down, _ := nodes[0].gossip.Down("payments")
+		return down == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	// a single peer is below MinPeers
+	assert.Equal(t, circuit_breaker.StateClosed, nodes[0].breaker("payments").State())
+
+	assert.Nil(t, nodes[1].list.Leave(time.Second))
+	assert.Eventually(t, func() bool {
+		down, peers := nodes[0].gossip.Down("payments")
+		return down == 0 && peers == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestGossipInvalidSummary(t *testing.T) {
+	var errs []error
+	g := New(Config{Registry: circuit_breaker.NewRegistry(circuit_breaker.RegistryConfig{}), OnError: func(err error) {
+		errs = append(errs, err)
+	}})
+
+	g.NotifyMsg([]byte("{"))
+	g.MergeRemoteState([]byte("{"), false)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, []byte("[]"), g.LocalState(false))
+}
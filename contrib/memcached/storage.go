@@ -0,0 +1,146 @@
+// Package memcachedbreaker shares the states and the statistics of circuit breakers through memcached,
+// for environments where memcached is the only shared cache available.
+//
+// Memcached may evict or lose the keys at any time, so the consistency is weaker than the one of a database:
+// a lost state is a missing state, and the breakers keep going on their local state,
+// see circuit_breaker.Config.Storage and WindowPolicy.
+package memcachedbreaker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+// DefaultPrefix is the prefix of the keys if the Prefix of the configuration is empty.
+const DefaultPrefix = "circuit_breaker:"
+
+// StorageConfig configures Storage.
+//
+// Client is the memcached client, whose Timeout bounds the operations instead of their contexts.
+// Prefix is the prefix of the keys, DefaultPrefix by default: the key of a breaker is the prefix followed by its escaped name.
+//
+// Expiration is the time after which a state expires since it was last stored, up to 30 days.
+// It must be longer than the open periods of the breakers. If Expiration is zero, the states don't expire,
+// but may still be evicted.
+type StorageConfig struct {
+	Client     *memcache.Client
+	Prefix     string
+	Expiration time.Duration
+}
+
+// Storage is the circuit_breaker.CASStorage keeping the states of the breakers in memcached,
+// replacing them by the gets and cas commands.
+// When memcached is unavailable, the breakers log the errors and keep going on their local state.
+type Storage struct {
+	cfg StorageConfig
+}
+
+var _ circuit_breaker.CASStorage = (*Storage)(nil)
+
+// NewStorage creates the Storage.
+func NewStorage(cfg StorageConfig) *Storage {
+	if cfg.Prefix == "" {
+		cfg.Prefix = DefaultPrefix
+	}
+
+	return &Storage{cfg: cfg}
+}
+
+// Load implements circuit_breaker.Storage.
+func (s *Storage) Load(ctx context.Context, name string) (circuit_breaker.StoredState, bool, error) {
+	state, item, err := s.get(name)
+	return state, item != nil, err
+}
+
+// Store implements circuit_breaker.Storage.
+func (s *Storage) Store(ctx context.Context, name string, state circuit_breaker.StoredState) error {
+	item, err := s.item(name, state)
+	if err != nil {
+		return err
+	}
+	if err := s.cfg.Client.Set(item); err != nil {
+		return fmt.Errorf("memcached: set %q: %w", item.Key, err)
+	}
+
+	return nil
+}
+
+// CompareAndSwap implements circuit_breaker.CASStorage.
+func (s *Storage) CompareAndSwap(ctx context.Context, name string, version uint64, state circuit_breaker.StoredState) (bool, error) {
+	current, stored, err := s.get(name)
+	if err != nil {
+		return false, err
+	}
+	if current.Version != version {
+		return false, nil
+	}
+
+	item, err := s.item(name, state)
+	if err != nil {
+		return false, err
+	}
+	if stored == nil {
+		err = s.cfg.Client.Add(item)
+	} else {
+		item.CasID = stored.CasID
+		err = s.cfg.Client.CompareAndSwap(item)
+	}
+	switch {
+	case errors.Is(err, memcache.ErrNotStored), errors.Is(err, memcache.ErrCASConflict), errors.Is(err, memcache.ErrCacheMiss):
+		// another instance stored the state first, or the state was evicted in between
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("memcached: cas %q: %w", item.Key, err)
+	}
+
+	return true, nil
+}
+
+// get reads the state of the breaker and the item holding it, which is nil if there is none
+func (s *Storage) get(name string) (circuit_breaker.StoredState, *memcache.Item, error) {
+	key := s.key(name)
+	item, err := s.cfg.Client.Get(key)
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return circuit_breaker.StoredState{}, nil, nil
+	}
+	if err != nil {
+		return circuit_breaker.StoredState{}, nil, fmt.Errorf("memcached: get %q: %w", key, err)
+	}
+
+	var state circuit_breaker.StoredState
+	if err := json.Unmarshal(item.Value, &state); err != nil {
+		return circuit_breaker.StoredState{}, nil, fmt.Errorf("memcached: key %q: %w", key, err)
+	}
+	return state, item, nil
+}
+
+func (s *Storage) item(name string, state circuit_breaker.StoredState) (*memcache.Item, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+
+	return &memcache.Item{Key: s.key(name), Value: data, Expiration: expiration(s.cfg.Expiration)}, nil
+}
+
+// key escapes the name, since the keys must not contain spaces
+func (s *Storage) key(name string) string {
+	return s.cfg.Prefix + url.PathEscape(name)
+}
+
+// expiration converts the duration to the seconds of a memcached expiration, rounded up
+func expiration(d time.Duration) int32 {
+	if d <= 0 {
+		return 0
+	}
+
+	return int32(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,180 @@
+package memcachedbreaker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeServer serves the gets, set, add, cas and incr commands of the memcached text protocol
+type fakeServer struct {
+	mu          sync.Mutex
+	items       map[string][]byte
+	casIDs      map[string]uint64
+	expirations map[string]int
+	nextCasID   uint64
+}
+
+func newFakeServer(t *testing.T) (*fakeServer, net.Listener) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	s := &fakeServer{items: make(map[string][]byte), casIDs: make(map[string]uint64), expirations: make(map[string]int)}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s, l
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return
+		}
+
+		s.mu.Lock()
+		switch fields[0] {
+		case "gets":
+			for _, key := range fields[1:] {
+				if value, ok := s.items[key]; ok {
+					fmt.Fprintf(rw, "VALUE %s 0 %d %d\r\n%s\r\n", key, len(value), s.casIDs[key], value)
+				}
+			}
+			fmt.Fprint(rw, "END\r\n")
+		case "set", "add", "cas":
+			size, _ := strconv.Atoi(fields[4])
+			data := make([]byte, size+2)
+			s.mu.Unlock()
+			_, err := io.ReadFull(rw, data)
+			s.mu.Lock()
+			if err != nil {
+				s.mu.Unlock()
+				return
+			}
+			key := fields[1]
+			_, exists := s.items[key]
+			switch {
+			case fields[0] == "add" && exists:
+				fmt.Fprint(rw, "NOT_STORED\r\n")
+			case fields[0] == "cas" && !exists:
+				fmt.Fprint(rw, "NOT_FOUND\r\n")
+			case fields[0] == "cas" && fields[5] != strconv.FormatUint(s.casIDs[key], 10):
+				fmt.Fprint(rw, "EXISTS\r\n")
+			default:
+				s.store(key, data[:size])
+				s.expirations[key], _ = strconv.Atoi(fields[3])
+				fmt.Fprint(rw, "STORED\r\n")
+			}
+		case "incr":
+			key := fields[1]
+			value, ok := s.items[key]
+			if !ok {
+				fmt.Fprint(rw, "NOT_FOUND\r\n")
+				break
+			}
+			n, _ := strconv.ParseUint(string(value), 10, 64)
+			delta, _ := strconv.ParseUint(fields[2], 10, 64)
+			n += delta
+			s.store(key, []byte(strconv.FormatUint(n, 10)))
+			fmt.Fprintf(rw, "%d\r\n", n)
+		default:
+			fmt.Fprint(rw, "ERROR\r\n")
+		}
+		s.mu.Unlock()
+		if rw.Flush() != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) store(key string, value []byte) {
+	s.nextCasID++
+	s.items[key] = value
+	s.casIDs[key] = s.nextCasID
+}
+
+func (s *fakeServer) get(key string) (string, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return string(s.items[key]), s.expirations[key]
+}
+
+func TestStorage(t *testing.T) {
+	ctx := context.Background()
+	server, l := newFakeServer(t)
+	storage := NewStorage(StorageConfig{Client: memcache.New(l.Addr().String()), Expiration: 90 * time.Second})
+	cfg := circuit_breaker.Config{Name: "GET /payments", MaxConsecutiveFailures: 1, Timeout: time.Minute, Storage: storage}
+
+	cb := circuit_breaker.NewCircuitBreaker(cfg)
+	_, _ = cb.Execute(func() (interface{}, error) { return nil, assert.AnError })
+	cb.WaitBackground()
+	state, ok, err := storage.Load(ctx, "GET /payments")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, circuit_breaker.StateOpen, state.State)
+	assert.Equal(t, uint64(1), state.Version)
+	value, exp := server.get("circuit_breaker:GET%20%2Fpayments")
+	assert.Contains(t, value, `"State":"open"`)
+	assert.Equal(t, 90, exp)
+
+	// a restarted instance restores the open state
+	restarted := circuit_breaker.NewCircuitBreaker(cfg)
+	assert.Equal(t, circuit_breaker.StateOpen, restarted.State())
+	assert.Equal(t, cb.OpensAt().UnixNano(), restarted.OpensAt().UnixNano())
+
+	// the swaps of an outdated version fail
+	swapped, err := storage.CompareAndSwap(ctx, "GET /payments", 0, circuit_breaker.StoredState{Version: 1})
+	assert.Nil(t, err)
+	assert.False(t, swapped)
+	swapped, err = storage.CompareAndSwap(ctx, "GET /payments", 1, circuit_breaker.StoredState{Version: 2})
+	assert.Nil(t, err)
+	assert.True(t, swapped)
+	assert.Nil(t, storage.Store(ctx, "search", circuit_breaker.StoredState{Version: 5}))
+	swapped, err = storage.CompareAndSwap(ctx, "search", 0, circuit_breaker.StoredState{Version: 1})
+	assert.Nil(t, err)
+	assert.False(t, swapped)
+
+	_, ok, err = storage.Load(ctx, "missing")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestStorageUnavailable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := l.Addr().String()
+	assert.Nil(t, l.Close())
+	storage := NewStorage(StorageConfig{Client: memcache.New(addr)})
+
+	// the breaker keeps going on its local state
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "payments", MaxConsecutiveFailures: 1, Storage: storage})
+	_, _ = cb.Execute(func() (interface{}, error) { return nil, assert.AnError })
+	assert.Equal(t, circuit_breaker.StateOpen, cb.State())
+	assert.NotNil(t, cb.SyncState(context.Background()))
+}
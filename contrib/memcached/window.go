@@ -0,0 +1,260 @@
+package memcachedbreaker
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+const (
+	defaultWindow        = 10 * time.Second
+	defaultFlushInterval = time.Second
+)
+
+// WindowConfig configures WindowPolicy.
+//
+// Client is the memcached client, and Prefix the prefix of the keys, DefaultPrefix by default.
+// Name is the name of the breaker, which the counters of the fleet are keyed by.
+//
+// Window is the length of the fixed windows of the counts of the fleet, 10 seconds by default.
+// FlushInterval is the period of the exchange of the counts with memcached, one second by default.
+//
+// ReadyToTrip, RequestThreshold and Timeout configure the embedded circuit_breaker.DefaultPolicy.
+// ReadyToTrip is called with the requests and the failures of the fleet in the current window,
+// and with the consecutive outcomes of the local breaker, e.g. circuit_breaker.TripOnFailureRate.
+//
+// OnError is called with the errors of memcached.
+type WindowConfig struct {
+	Client        *memcache.Client
+	Prefix        string
+	Name          string
+	Window        time.Duration
+	FlushInterval time.Duration
+
+	ReadyToTrip      func(counts circuit_breaker.Counts) bool
+	RequestThreshold uint32
+	Timeout          time.Duration
+
+	OnError func(err error)
+}
+
+// WindowPolicy is the circuit_breaker.Policy tripping by the counts of the whole fleet,
+// kept in windowed counters of memcached incremented by the add and incr commands, expiring with their windows.
+//
+// The outcomes are counted locally and flushed to memcached every FlushInterval, outside of the decisions of the breaker,
+// so the counts of the fleet lag by up to FlushInterval.
+// While memcached is unavailable, or the counts of the fleet are older than two FlushIntervals,
+// the policy falls back to the local Counts of the breaker.
+// A WindowPolicy serves a single breaker.
+type WindowPolicy struct {
+	circuit_breaker.DefaultPolicy
+	cfg WindowConfig
+
+	mu sync.Mutex
+	// pending are the outcomes of the window not flushed yet
+	pending circuit_breaker.Counts
+	window  int64
+	// fleet are the counts of the fleet in the window as of syncedAt, including the flushed local outcomes
+	fleet    circuit_breaker.Counts
+	syncedAt time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+var _ circuit_breaker.Policy = (*WindowPolicy)(nil)
+
+// NewWindowPolicy creates the WindowPolicy and starts flushing the counts until Close.
+func NewWindowPolicy(cfg WindowConfig) *WindowPolicy {
+	if cfg.Prefix == "" {
+		cfg.Prefix = DefaultPrefix
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultWindow
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultFlushInterval
+	}
+
+	p := &WindowPolicy{
+		DefaultPolicy: circuit_breaker.DefaultPolicy{
+			ReadyToTrip:      cfg.ReadyToTrip,
+			RequestThreshold: cfg.RequestThreshold,
+			Timeout:          cfg.Timeout,
+		},
+		cfg:  cfg,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go p.run()
+
+	return p
+}
+
+// OnCall implements circuit_breaker.Policy by counting the outcomes of the closed state.
+func (p *WindowPolicy) OnCall(state circuit_breaker.State, err error) {
+	if state != circuit_breaker.StateClosed {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.advance(time.Now())
+	p.pending.Requests++
+	if err != nil {
+		p.pending.TotalFailures++
+	} else {
+		p.pending.TotalSuccesses++
+	}
+}
+
+// ShouldTrip implements circuit_breaker.Policy.
+func (p *WindowPolicy) ShouldTrip(counts circuit_breaker.Counts) bool {
+	fleet, ok := p.Counts(time.Now())
+	if !ok {
+		return p.DefaultPolicy.ShouldTrip(counts)
+	}
+
+	fleet.ConsecutiveSuccesses = counts.ConsecutiveSuccesses
+	fleet.ConsecutiveFailures = counts.ConsecutiveFailures
+	return p.DefaultPolicy.ShouldTrip(fleet)
+}
+
+// Counts returns the counts of the fleet in the current window, including the local outcomes not flushed yet.
+// It reports false if they are outdated, e.g. while memcached is unavailable.
+func (p *WindowPolicy) Counts(now time.Time) (circuit_breaker.Counts, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.advance(now)
+	if p.syncedAt.IsZero() || now.Sub(p.syncedAt) > 2*p.cfg.FlushInterval {
+		return circuit_breaker.Counts{}, false
+	}
+
+	counts := p.fleet
+	counts.Requests += p.pending.Requests
+	counts.TotalSuccesses += p.pending.TotalSuccesses
+	counts.TotalFailures += p.pending.TotalFailures
+	return counts, true
+}
+
+// Close stops flushing the counts.
+func (p *WindowPolicy) Close() {
+	close(p.stop)
+	<-p.done
+}
+
+func (p *WindowPolicy) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			if err := p.flush(time.Now()); err != nil && p.cfg.OnError != nil {
+				p.cfg.OnError(err)
+			}
+		}
+	}
+}
+
+// advance starts the window of the time, discarding the counts of the previous one, while the lock is held
+func (p *WindowPolicy) advance(now time.Time) {
+	window := now.UnixNano() / int64(p.cfg.Window)
+	if window != p.window {
+		p.window = window
+		p.pending = circuit_breaker.Counts{}
+		p.fleet = circuit_breaker.Counts{}
+	}
+}
+
+// flush adds the pending outcomes to the counters of the window and reads the counts of the fleet
+func (p *WindowPolicy) flush(now time.Time) error {
+	p.mu.Lock()
+	p.advance(now)
+	window := p.window
+	pending := p.pending
+	p.pending = circuit_breaker.Counts{}
+	p.mu.Unlock()
+
+	requests, failures := p.keys(window)
+	fleet, err := p.exchange(requests, failures, pending)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.window != window {
+		return err
+	}
+	if err != nil {
+		// the outcomes are flushed again with the next ones
+		p.pending.Requests += pending.Requests
+		p.pending.TotalSuccesses += pending.TotalSuccesses
+		p.pending.TotalFailures += pending.TotalFailures
+		return err
+	}
+	p.fleet = fleet
+	p.syncedAt = now
+
+	return nil
+}
+
+// exchange increments the counters of the window by the pending outcomes and reads their totals
+func (p *WindowPolicy) exchange(requests, failures string, pending circuit_breaker.Counts) (circuit_breaker.Counts, error) {
+	var fleet circuit_breaker.Counts
+	total, err := p.increment(requests, uint64(pending.Requests))
+	if err != nil {
+		return fleet, err
+	}
+	failed, err := p.increment(failures, uint64(pending.TotalFailures))
+	if err != nil {
+		return fleet, err
+	}
+
+	fleet.Requests = uint32(total)
+	fleet.TotalFailures = uint32(failed)
+	if failed < total {
+		fleet.TotalSuccesses = uint32(total - failed)
+	}
+	return fleet, nil
+}
+
+// increment adds the delta to the counter, creating it with the expiration of two windows, and returns its value
+func (p *WindowPolicy) increment(key string, delta uint64) (uint64, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		value, err := p.cfg.Client.Increment(key, delta)
+		if !errors.Is(err, memcache.ErrCacheMiss) {
+			if err != nil {
+				return 0, fmt.Errorf("memcached: incr %q: %w", key, err)
+			}
+			return value, nil
+		}
+
+		item := &memcache.Item{Key: key, Value: []byte(strconv.FormatUint(delta, 10)), Expiration: expiration(2 * p.cfg.Window)}
+		err = p.cfg.Client.Add(item)
+		if err == nil {
+			return delta, nil
+		}
+		// another instance created the counter first
+		if !errors.Is(err, memcache.ErrNotStored) {
+			return 0, fmt.Errorf("memcached: add %q: %w", key, err)
+		}
+	}
+
+	return 0, fmt.Errorf("memcached: incr %q: %w", key, memcache.ErrCacheMiss)
+}
+
+func (p *WindowPolicy) keys(window int64) (requests, failures string) {
+	prefix := p.cfg.Prefix + url.PathEscape(p.cfg.Name) + ":" + strconv.FormatInt(window, 10)
+	return prefix + ":requests", prefix + ":failures"
+}
@@ -0,0 +1,81 @@
+package memcachedbreaker
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindowPolicy(t *testing.T) {
+	_, l := newFakeServer(t)
+	newPolicy := func() *WindowPolicy {
+		p := NewWindowPolicy(WindowConfig{
+			Client:        memcache.New(l.Addr().String()),
+			Name:          "payments",
+			Window:        time.Hour,
+			FlushInterval: 10 * time.Millisecond,
+			ReadyToTrip:   circuit_breaker.TripOnFailureRate(0.5, 4),
+			Timeout:       time.Minute,
+		})
+		t.Cleanup(p.Close)
+		return p
+	}
+	a, b := newPolicy(), newPolicy()
+	cbA := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "payments", Policy: a})
+	cbB := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "payments", Policy: b})
+
+	for i := 0; i < 2; i++ {
+		_, _ = cbA.Execute(func() (interface{}, error) { return nil, nil })
+	}
+	_, _ = cbA.Execute(func() (interface{}, error) { return nil, assert.AnError })
+	assert.Equal(t, circuit_breaker.StateClosed, cbA.State())
+
+	// the failure of b trips it by the counts of the fleet
+	assert.Eventually(t, func() bool {
+		counts, ok := b.Counts(time.Now())
+		return ok && counts.Requests == 3
+	}, time.Second, time.Millisecond)
+	_, _ = cbB.Execute(func() (interface{}, error) { return nil, assert.AnError })
+	assert.Equal(t, circuit_breaker.StateOpen, cbB.State())
+
+	assert.Eventually(t, func() bool {
+		counts, ok := a.Counts(time.Now())
+		return ok && counts == circuit_breaker.Counts{Requests: 4, TotalSuccesses: 2, TotalFailures: 2}
+	}, time.Second, time.Millisecond)
+}
+
+func TestWindowPolicyFallback(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := l.Addr().String()
+	assert.Nil(t, l.Close())
+
+	errs := make(chan error, 10)
+	p := NewWindowPolicy(WindowConfig{
+		Client:        memcache.New(addr),
+		Name:          "payments",
+		FlushInterval: 10 * time.Millisecond,
+		ReadyToTrip:   func(counts circuit_breaker.Counts) bool { return counts.ConsecutiveFailures >= 2 },
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+	defer p.Close()
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "payments", Policy: p})
+
+	// the local counts are used while memcached is unavailable
+	assert.NotNil(t, <-errs)
+	_, ok := p.Counts(time.Now())
+	assert.False(t, ok)
+	for i := 0; i < 2; i++ {
+		_, _ = cb.Execute(func() (interface{}, error) { return nil, assert.AnError })
+	}
+	assert.Equal(t, circuit_breaker.StateOpen, cb.State())
+}
@@ -0,0 +1,122 @@
+// Package microbreaker protects go-micro clients with circuit breakers.
+package microbreaker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"go-micro.dev/v4/client"
+	microerrors "go-micro.dev/v4/errors"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/selector"
+)
+
+// Config configures the client wrapper.
+//
+// Endpoints holds a breaker per service and endpoint, keyed by EndpointKey.
+//
+// Nodes holds a breaker per node, keyed by its address. If Nodes is set,
+// the selector skips the nodes whose circuit is open, so the calls go to the healthy nodes of the service.
+//
+// Classifier maps the error of the call to its outcome. If Classifier is nil, DefaultClassifier is used.
+type Config struct {
+	Endpoints  *circuit_breaker.KeyedBreaker
+	Nodes      *circuit_breaker.KeyedBreaker
+	Classifier func(err error) error
+}
+
+// EndpointKey is the key of the breaker of the request: the service and the endpoint joined with ".".
+func EndpointKey(req client.Request) string {
+	return req.Service() + "." + req.Endpoint()
+}
+
+// DefaultClassifier counts server errors and timeouts as failures, and ignores the other client errors.
+func DefaultClassifier(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	code := microerrors.FromError(err).Code
+	if code >= 400 && code < 500 && code != 408 {
+		return nil
+	}
+	return err
+}
+
+// NewClientWrapper creates the wrapper protecting the outgoing calls of the client.
+// Calls rejected by the breaker fail with an error wrapping its rejection error.
+func NewClientWrapper(cfg Config) client.Wrapper {
+	if cfg.Classifier == nil {
+		cfg.Classifier = DefaultClassifier
+	}
+
+	return func(c client.Client) client.Client {
+		return &breakerClient{Client: c, cfg: cfg}
+	}
+}
+
+type breakerClient struct {
+	client.Client
+	cfg Config
+}
+
+func (c *breakerClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	if c.cfg.Nodes != nil {
+		opts = append(opts,
+			client.WithSelectOption(selector.WithFilter(c.skipOpenNodes)),
+			client.WithCallWrapper(c.wrapNodeCall),
+		)
+	}
+	if c.cfg.Endpoints == nil {
+		return c.Client.Call(ctx, req, rsp, opts...)
+	}
+
+	return c.execute(ctx, c.cfg.Endpoints.Get(EndpointKey(req)), EndpointKey(req), func(ctx context.Context) error {
+		return c.Client.Call(ctx, req, rsp, opts...)
+	})
+}
+
+// wrapNodeCall protects the call to the selected node with the breaker of the node
+func (c *breakerClient) wrapNodeCall(next client.CallFunc) client.CallFunc {
+	return func(ctx context.Context, node *registry.Node, req client.Request, rsp interface{}, opts client.CallOptions) error {
+		return c.execute(ctx, c.cfg.Nodes.Get(node.Address), node.Address, func(ctx context.Context) error {
+			return next(ctx, node, req, rsp, opts)
+		})
+	}
+}
+
+// skipOpenNodes filters out the nodes whose circuit is open
+func (c *breakerClient) skipOpenNodes(services []*registry.Service) []*registry.Service {
+	filtered := make([]*registry.Service, 0, len(services))
+	for _, service := range services {
+		nodes := make([]*registry.Node, 0, len(service.Nodes))
+		for _, node := range service.Nodes {
+			if cb, ok := c.cfg.Nodes.Lookup(node.Address); ok && cb.State() == circuit_breaker.StateOpen {
+				continue
+			}
+			nodes = append(nodes, node)
+		}
+
+		copied := *service
+		copied.Nodes = nodes
+		filtered = append(filtered, &copied)
+	}
+
+	return filtered
+}
+
+func (c *breakerClient) execute(ctx context.Context, cb *circuit_breaker.CircuitBreaker, key string, req func(ctx context.Context) error) error {
+	handled := false
+	var callErr error
+	_, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+		handled = true
+		callErr = req(ctx)
+		return nil, c.cfg.Classifier(callErr)
+	})
+	if !handled {
+		return fmt.Errorf("%s rejected by circuit breaker %q: %w", key, cb.Name(), err)
+	}
+
+	return callErr
+}
@@ -0,0 +1,79 @@
+package microbreaker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+	"go-micro.dev/v4/client"
+	microerrors "go-micro.dev/v4/errors"
+	"go-micro.dev/v4/registry"
+)
+
+// fakeClient fails the calls to the endpoints in errs
+type fakeClient struct {
+	client.Client
+	errs  map[string]error
+	calls int
+}
+
+func (c *fakeClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	c.calls++
+	return c.errs[req.Endpoint()]
+}
+
+func TestClientWrapper(t *testing.T) {
+	kb := circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{MaxConsecutiveFailures: 2},
+	})
+	fake := &fakeClient{
+		Client: client.NewClient(),
+		errs: map[string]error{
+			"Reports.Get":  microerrors.InternalServerError("reports", "database is down"),
+			"Reports.Find": microerrors.NotFound("reports", "no such report"),
+		},
+	}
+	c := NewClientWrapper(Config{Endpoints: kb})(fake)
+	ctx := context.Background()
+
+	get := c.NewRequest("reports", "Reports.Get", nil)
+	find := c.NewRequest("reports", "Reports.Find", nil)
+	for i := 0; i < 2; i++ {
+		assert.NotNil(t, c.Call(ctx, get, nil))
+		assert.NotNil(t, c.Call(ctx, find, nil))
+	}
+
+	err := c.Call(ctx, get, nil)
+	assert.ErrorIs(t, err, circuit_breaker.ErrOpenState)
+	assert.Equal(t, 4, fake.calls)
+
+	// client errors don't open the circuit
+	findBreaker, _ := kb.Lookup("reports.Reports.Find")
+	assert.Equal(t, circuit_breaker.StateClosed, findBreaker.State())
+}
+
+func TestSkipOpenNodes(t *testing.T) {
+	nodes := circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{MaxConsecutiveFailures: 1},
+	})
+	c := &breakerClient{cfg: Config{Nodes: nodes, Classifier: DefaultClassifier}}
+
+	call := c.wrapNodeCall(func(ctx context.Context, node *registry.Node, req client.Request, rsp interface{}, opts client.CallOptions) error {
+		return microerrors.InternalServerError("reports", "out of memory")
+	})
+	assert.NotNil(t, call(context.Background(), &registry.Node{Address: "10.0.0.1:8080"}, nil, nil, client.CallOptions{}))
+
+	services := c.skipOpenNodes([]*registry.Service{{
+		Name:  "reports",
+		Nodes: []*registry.Node{{Address: "10.0.0.1:8080"}, {Address: "10.0.0.2:8080"}},
+	}})
+	assert.Equal(t, []*registry.Node{{Address: "10.0.0.2:8080"}}, services[0].Nodes)
+}
+
+func TestDefaultClassifier(t *testing.T) {
+	assert.Nil(t, DefaultClassifier(nil))
+	assert.Nil(t, DefaultClassifier(microerrors.BadRequest("reports", "invalid id")))
+	assert.NotNil(t, DefaultClassifier(microerrors.Timeout("reports", "timeout")))
+	assert.NotNil(t, DefaultClassifier(microerrors.InternalServerError("reports", "failed")))
+}
@@ -0,0 +1,135 @@
+// Package natsbreaker protects NATS requests and JetStream publishes with circuit breakers.
+package natsbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+// Config configures the wrappers.
+//
+// Breakers holds the breakers of the subjects, keyed by Key.
+//
+// Key selects the breaker of the subject. If Key is nil, the subject itself is the key.
+//
+// Classifier maps the error to its outcome. If Classifier is nil, DefaultClassifier is used.
+type Config struct {
+	Breakers   *circuit_breaker.KeyedBreaker
+	Key        func(subject string) string
+	Classifier func(err error) error
+}
+
+func (cfg Config) withDefaults() Config {
+	if cfg.Key == nil {
+		cfg.Key = func(subject string) string { return subject }
+	}
+	if cfg.Classifier == nil {
+		cfg.Classifier = DefaultClassifier
+	}
+
+	return cfg
+}
+
+// DefaultClassifier ignores the errors caused by the message itself rather than by the responder.
+func DefaultClassifier(err error) error {
+	switch {
+	case errors.Is(err, nats.ErrBadSubject),
+		errors.Is(err, nats.ErrMaxPayload),
+		errors.Is(err, nats.ErrInvalidMsg),
+		errors.Is(err, jetstream.ErrMsgAlreadyAckd):
+		return nil
+	default:
+		return err
+	}
+}
+
+// Requester is the request part of *nats.Conn.
+type Requester interface {
+	RequestWithContext(ctx context.Context, subj string, data []byte) (*nats.Msg, error)
+}
+
+var _ Requester = (*nats.Conn)(nil)
+
+// Conn protects requests with a breaker per subject. While the responder is known to be down,
+// requests fail immediately with an error wrapping circuit_breaker.ErrOpenState
+// instead of waiting out the request timeout.
+type Conn struct {
+	conn Requester
+	cfg  Config
+}
+
+// NewConn wraps the connection.
+func NewConn(conn Requester, cfg Config) *Conn {
+	return &Conn{conn: conn, cfg: cfg.withDefaults()}
+}
+
+// Request sends the request and waits for the response at most for the timeout.
+func (c *Conn) Request(subj string, data []byte, timeout time.Duration) (*nats.Msg, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return c.RequestWithContext(ctx, subj, data)
+}
+
+// RequestWithContext sends the request and waits for the response.
+func (c *Conn) RequestWithContext(ctx context.Context, subj string, data []byte) (*nats.Msg, error) {
+	var msg *nats.Msg
+	err := execute(ctx, c.cfg, subj, func(ctx context.Context) (err error) {
+		msg, err = c.conn.RequestWithContext(ctx, subj, data)
+		return err
+	})
+
+	return msg, err
+}
+
+// Publisher is the publish part of jetstream.JetStream.
+type Publisher interface {
+	Publish(ctx context.Context, subject string, payload []byte, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+var _ Publisher = (jetstream.JetStream)(nil)
+
+// JetStream protects publishes with a breaker per subject.
+type JetStream struct {
+	js  Publisher
+	cfg Config
+}
+
+// NewJetStream wraps the JetStream context.
+func NewJetStream(js Publisher, cfg Config) *JetStream {
+	return &JetStream{js: js, cfg: cfg.withDefaults()}
+}
+
+// Publish publishes the message and waits for the acknowledgement of the stream.
+func (j *JetStream) Publish(ctx context.Context, subject string, payload []byte, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	var ack *jetstream.PubAck
+	err := execute(ctx, j.cfg, subject, func(ctx context.Context) (err error) {
+		ack, err = j.js.Publish(ctx, subject, payload, opts...)
+		return err
+	})
+
+	return ack, err
+}
+
+func execute(ctx context.Context, cfg Config, subject string, req func(ctx context.Context) error) error {
+	cb := cfg.Breakers.Get(cfg.Key(subject))
+
+	handled := false
+	var reqErr error
+	_, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+		handled = true
+		reqErr = req(ctx)
+		return nil, cfg.Classifier(reqErr)
+	})
+	if !handled {
+		return fmt.Errorf("%s rejected by circuit breaker %q: %w", subject, cb.Name(), err)
+	}
+
+	return reqErr
+}
@@ -0,0 +1,80 @@
+package natsbreaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConn fails the requests to the subjects in errs
+type fakeConn struct {
+	errs  map[string]error
+	calls int
+}
+
+func (c *fakeConn) RequestWithContext(ctx context.Context, subj string, data []byte) (*nats.Msg, error) {
+	c.calls++
+	if err := c.errs[subj]; err != nil {
+		return nil, err
+	}
+	return &nats.Msg{Subject: subj, Data: data}, nil
+}
+
+func (c *fakeConn) Publish(ctx context.Context, subject string, payload []byte, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	c.calls++
+	if err := c.errs[subject]; err != nil {
+		return nil, err
+	}
+	return &jetstream.PubAck{Stream: "ORDERS", Sequence: uint64(c.calls)}, nil
+}
+
+func breakers() *circuit_breaker.KeyedBreaker {
+	return circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{MaxConsecutiveFailures: 2},
+	})
+}
+
+func TestConn(t *testing.T) {
+	fake := &fakeConn{errs: map[string]error{"reports.get": nats.ErrNoResponders, "reports.bad": nats.ErrMaxPayload}}
+	conn := NewConn(fake, Config{Breakers: breakers()})
+
+	for i := 0; i < 2; i++ {
+		_, err := conn.Request("reports.get", nil, time.Second)
+		assert.Equal(t, nats.ErrNoResponders, err)
+		_, err = conn.Request("reports.bad", nil, time.Second)
+		assert.Equal(t, nats.ErrMaxPayload, err)
+	}
+
+	_, err := conn.Request("reports.get", nil, time.Second)
+	assert.ErrorIs(t, err, circuit_breaker.ErrOpenState)
+	assert.Equal(t, `reports.get rejected by circuit breaker "reports.get": circuit breaker is open`, err.Error())
+	assert.Equal(t, 4, fake.calls)
+
+	msg, err := conn.RequestWithContext(context.Background(), "reports.list", []byte("ping"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("ping"), msg.Data)
+}
+
+func TestJetStream(t *testing.T) {
+	fake := &fakeConn{errs: map[string]error{"orders.created": context.DeadlineExceeded}}
+	js := NewJetStream(fake, Config{
+		Breakers: breakers(),
+		Key:      func(subject string) string { return "orders" },
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := js.Publish(ctx, "orders.created", nil)
+		assert.Equal(t, context.DeadlineExceeded, err)
+	}
+
+	// the subjects share the breaker of the key
+	_, err := js.Publish(ctx, "orders.paid", nil)
+	assert.ErrorIs(t, err, circuit_breaker.ErrOpenState)
+	assert.Equal(t, 2, fake.calls)
+}
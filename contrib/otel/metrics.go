@@ -0,0 +1,173 @@
+// Package otelbreaker instruments circuit breakers with OpenTelemetry.
+package otelbreaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const instrumentationName = "github.com/shirokovnv/circuit_breaker/contrib/otel"
+
+// Attribute keys of the instruments and span events.
+const (
+	NameKey    = attribute.Key("circuit_breaker.name")
+	StateKey   = attribute.Key("circuit_breaker.state")
+	OutcomeKey = attribute.Key("circuit_breaker.outcome")
+	// ErrorTypeKey is the semantic convention attribute describing the class of a rejection, see RejectionType.
+	ErrorTypeKey = attribute.Key("error.type")
+)
+
+// Outcomes of a call.
+const (
+	OutcomeSuccess  = "success"
+	OutcomeFailure  = "failure"
+	OutcomeRejected = "rejected"
+)
+
+var states = []circuit_breaker.State{
+	circuit_breaker.StateClosed,
+	circuit_breaker.StateHalfOpen,
+	circuit_breaker.StateOpen,
+}
+
+// Instruments records the calls made through circuit breakers as OpenTelemetry instruments:
+//
+//   - circuit_breaker.calls: counter of the calls by name, state at call time and outcome
+//   - circuit_breaker.rejections: counter of the rejected calls by name, state and error.type
+//   - circuit_breaker.active_calls: up-down counter of the calls in flight by name
+//   - circuit_breaker.call.duration: histogram of the duration of the admitted calls by name and outcome, in seconds
+//   - circuit_breaker.state: gauge of the observed breakers, 1 for the current state and 0 for the others
+type Instruments struct {
+	calls      metric.Int64Counter
+	rejections metric.Int64Counter
+	active     metric.Int64UpDownCounter
+	duration   metric.Float64Histogram
+
+	mu       sync.RWMutex
+	observed map[string]*circuit_breaker.CircuitBreaker
+}
+
+// NewInstruments creates the instruments with a meter of the provider.
+func NewInstruments(provider metric.MeterProvider) (*Instruments, error) {
+	meter := provider.Meter(instrumentationName)
+	in := Instruments{observed: make(map[string]*circuit_breaker.CircuitBreaker)}
+
+	var err error
+	if in.calls, err = meter.Int64Counter("circuit_breaker.calls",
+		metric.WithDescription("Calls made through the circuit breaker."),
+		metric.WithUnit("{call}")); err != nil {
+		return nil, err
+	}
+	if in.rejections, err = meter.Int64Counter("circuit_breaker.rejections",
+		metric.WithDescription("Calls rejected by the circuit breaker."),
+		metric.WithUnit("{call}")); err != nil {
+		return nil, err
+	}
+	if in.active, err = meter.Int64UpDownCounter("circuit_breaker.active_calls",
+		metric.WithDescription("Calls in flight."),
+		metric.WithUnit("{call}")); err != nil {
+		return nil, err
+	}
+	if in.duration, err = meter.Float64Histogram("circuit_breaker.call.duration",
+		metric.WithDescription("Duration of the calls admitted by the circuit breaker."),
+		metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if _, err = meter.Int64ObservableGauge("circuit_breaker.state",
+		metric.WithDescription("Current state of the circuit breaker."),
+		metric.WithInt64Callback(in.observeState)); err != nil {
+		return nil, err
+	}
+
+	return &in, nil
+}
+
+// Observe reports the state of the breakers by the circuit_breaker.state gauge.
+func (in *Instruments) Observe(cbs ...*circuit_breaker.CircuitBreaker) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	for _, cb := range cbs {
+		in.observed[cb.Name()] = cb
+	}
+}
+
+// ExecuteContext runs the request through the breaker and records the call.
+// Like the package ExecuteContext, it also records the decision of the breaker on the span of the context.
+func (in *Instruments) ExecuteContext(ctx context.Context, cb *circuit_breaker.CircuitBreaker, req func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	name := NameKey.String(cb.Name())
+	state := StateKey.String(cb.State().String())
+	active := metric.WithAttributes(name)
+
+	var start time.Time
+	result, err, handled := execute(ctx, cb, func(ctx context.Context) (interface{}, error) {
+		in.active.Add(ctx, 1, active)
+		defer in.active.Add(ctx, -1, active)
+
+		start = time.Now()
+		return req(ctx)
+	})
+
+	if !handled {
+		in.calls.Add(ctx, 1, metric.WithAttributes(name, state, OutcomeKey.String(OutcomeRejected)))
+		in.rejections.Add(ctx, 1, metric.WithAttributes(name, state, ErrorTypeKey.String(RejectionType(err))))
+		return result, err
+	}
+
+	outcome := OutcomeKey.String(OutcomeSuccess)
+	if err != nil {
+		outcome = OutcomeKey.String(OutcomeFailure)
+	}
+	in.calls.Add(ctx, 1, metric.WithAttributes(name, state, outcome))
+	in.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(name, outcome))
+
+	return result, err
+}
+
+func (in *Instruments) observeState(_ context.Context, o metric.Int64Observer) error {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+
+	for name, cb := range in.observed {
+		current := cb.State()
+		for _, state := range states {
+			v := int64(0)
+			if state == current {
+				v = 1
+			}
+			o.Observe(v, metric.WithAttributes(NameKey.String(name), StateKey.String(state.String())))
+		}
+	}
+
+	return nil
+}
+
+// RejectionType returns the error.type of a call rejected with the error.
+func RejectionType(err error) string {
+	switch {
+	case errors.Is(err, circuit_breaker.ErrOpenState):
+		return "open_state"
+	case errors.Is(err, circuit_breaker.ErrTooManyRequests):
+		return "too_many_requests"
+	case errors.Is(err, circuit_breaker.ErrBulkheadFull):
+		return "bulkhead_full"
+	case errors.Is(err, circuit_breaker.ErrLimitExceeded):
+		return "limit_exceeded"
+	case errors.Is(err, circuit_breaker.ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, circuit_breaker.ErrLoadShed):
+		return "load_shed"
+	case errors.Is(err, circuit_breaker.ErrInsufficientDeadline):
+		return "insufficient_deadline"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "context"
+	default:
+		return "_OTHER"
+	}
+}
@@ -0,0 +1,100 @@
+package otelbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// collect returns the int64 data points of the metric by their attributes
+func collect(t *testing.T, reader sdkmetric.Reader, name string) map[attribute.Distinct]int64 {
+	var rm metricdata.ResourceMetrics
+	assert.Nil(t, reader.Collect(context.Background(), &rm))
+
+	points := make(map[attribute.Distinct]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, p := range data.DataPoints {
+					points[p.Attributes.Equivalent()] = p.Value
+				}
+			case metricdata.Gauge[int64]:
+				for _, p := range data.DataPoints {
+					points[p.Attributes.Equivalent()] = p.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, p := range data.DataPoints {
+					points[p.Attributes.Equivalent()] = int64(p.Count)
+				}
+			}
+		}
+	}
+
+	return points
+}
+
+func attrs(kvs ...attribute.KeyValue) attribute.Distinct {
+	set := attribute.NewSet(kvs...)
+	return set.Equivalent()
+}
+
+func TestInstruments(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	in, err := NewInstruments(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	assert.Nil(t, err)
+
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "payments", MaxConsecutiveFailures: 1})
+	in.Observe(cb)
+	errUnavailable := errors.New("unavailable")
+	ctx := context.Background()
+
+	_, err = in.ExecuteContext(ctx, cb, func(ctx context.Context) (interface{}, error) { return nil, nil })
+	assert.Nil(t, err)
+	_, err = in.ExecuteContext(ctx, cb, func(ctx context.Context) (interface{}, error) { return nil, errUnavailable })
+	assert.Equal(t, errUnavailable, err)
+	_, err = in.ExecuteContext(ctx, cb, func(ctx context.Context) (interface{}, error) { return nil, nil })
+	assert.Equal(t, circuit_breaker.ErrOpenState, err)
+
+	name := NameKey.String("payments")
+	closed := StateKey.String("closed")
+	open := StateKey.String("open")
+
+	assert.Equal(t, map[attribute.Distinct]int64{
+		attrs(name, closed, OutcomeKey.String(OutcomeSuccess)): 1,
+		attrs(name, closed, OutcomeKey.String(OutcomeFailure)): 1,
+		attrs(name, open, OutcomeKey.String(OutcomeRejected)):  1,
+	}, collect(t, reader, "circuit_breaker.calls"))
+	assert.Equal(t, map[attribute.Distinct]int64{
+		attrs(name, open, ErrorTypeKey.String("open_state")): 1,
+	}, collect(t, reader, "circuit_breaker.rejections"))
+	assert.Equal(t, map[attribute.Distinct]int64{
+		attrs(name, OutcomeKey.String(OutcomeSuccess)): 1,
+		attrs(name, OutcomeKey.String(OutcomeFailure)): 1,
+	}, collect(t, reader, "circuit_breaker.call.duration"))
+	assert.Equal(t, map[attribute.Distinct]int64{
+		attrs(name): 0,
+	}, collect(t, reader, "circuit_breaker.active_calls"))
+	assert.Equal(t, map[attribute.Distinct]int64{
+		attrs(name, closed):                       0,
+		attrs(name, StateKey.String("half-open")): 0,
+		attrs(name, open):                         1,
+	}, collect(t, reader, "circuit_breaker.state"))
+}
+
+func TestRejectionType(t *testing.T) {
+	assert.Equal(t, "open_state", RejectionType(circuit_breaker.ErrOpenState))
+	assert.Equal(t, "too_many_requests", RejectionType(circuit_breaker.ErrTooManyRequests))
+	assert.Equal(t, "bulkhead_full", RejectionType(circuit_breaker.ErrBulkheadFull))
+	assert.Equal(t, "context", RejectionType(context.Canceled))
+	assert.Equal(t, "_OTHER", RejectionType(errors.New("unknown")))
+}
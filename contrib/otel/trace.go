@@ -0,0 +1,97 @@
+package otelbreaker
+
+import (
+	"context"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DecisionKey is the attribute of the decision made by the breaker for a call.
+const DecisionKey = attribute.Key("circuit_breaker.decision")
+
+// Decisions of the breaker.
+const (
+	DecisionAdmitted         = "admitted"
+	DecisionRejectedOpen     = "rejected_open"
+	DecisionRejectedHalfOpen = "rejected_half_open"
+	DecisionRejected         = "rejected"
+)
+
+// Names of the span events.
+const (
+	DecisionEvent   = "circuit_breaker.decision"
+	TransitionEvent = "circuit_breaker.transition"
+)
+
+// Attribute keys of the transition spans.
+const (
+	FromStateKey = attribute.Key("circuit_breaker.state.from")
+	ToStateKey   = attribute.Key("circuit_breaker.state.to")
+)
+
+// ExecuteContext runs the request through the breaker, see CircuitBreaker.ExecuteContext,
+// and records the decision of the breaker as an event of the span of the context,
+// with the state of the breaker at call time, so the trace explains why a request failed fast.
+func ExecuteContext(ctx context.Context, cb *circuit_breaker.CircuitBreaker, req func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	result, err, _ := execute(ctx, cb, req)
+	return result, err
+}
+
+// TraceTransitions records the state changes of the breaker as spans of a tracer of the provider,
+// each with a TransitionEvent. It returns a function which stops the recording.
+func TraceTransitions(provider trace.TracerProvider, cb *circuit_breaker.CircuitBreaker) (stop func()) {
+	tracer := provider.Tracer(instrumentationName)
+
+	return cb.Subscribe(func(name string, from, to circuit_breaker.State) {
+		attrs := trace.WithAttributes(NameKey.String(name), FromStateKey.String(from.String()), ToStateKey.String(to.String()))
+
+		_, span := tracer.Start(context.Background(), TransitionEvent, attrs)
+		span.AddEvent(TransitionEvent, attrs)
+		span.End()
+	})
+}
+
+// execute runs the request recording the decision on the span of the context.
+// It reports whether the request was admitted.
+func execute(ctx context.Context, cb *circuit_breaker.CircuitBreaker, req func(ctx context.Context) (interface{}, error)) (interface{}, error, bool) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		handled := false
+		result, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+			handled = true
+			return req(ctx)
+		})
+		return result, err, handled
+	}
+
+	name := NameKey.String(cb.Name())
+	state := cb.State()
+	stateAttr := StateKey.String(state.String())
+	span.SetAttributes(name, stateAttr)
+
+	handled := false
+	result, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+		handled = true
+		span.AddEvent(DecisionEvent, trace.WithAttributes(name, stateAttr, DecisionKey.String(DecisionAdmitted)))
+		return req(ctx)
+	})
+	if !handled {
+		span.AddEvent(DecisionEvent, trace.WithAttributes(name, stateAttr,
+			DecisionKey.String(rejectionDecision(state)), ErrorTypeKey.String(RejectionType(err))))
+	}
+
+	return result, err, handled
+}
+
+func rejectionDecision(state circuit_breaker.State) string {
+	switch state {
+	case circuit_breaker.StateOpen:
+		return DecisionRejectedOpen
+	case circuit_breaker.StateHalfOpen:
+		return DecisionRejectedHalfOpen
+	default:
+		return DecisionRejected
+	}
+}
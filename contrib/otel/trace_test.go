@@ -0,0 +1,78 @@
+package otelbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestExecuteContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := provider.Tracer("test")
+
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{
+		Name:                   "payments",
+		MaxConsecutiveFailures: 1,
+		Timeout:                time.Minute,
+	})
+	errUnavailable := errors.New("unavailable")
+
+	ctx, span := tracer.Start(context.Background(), "charge")
+	_, err := ExecuteContext(ctx, cb, func(ctx context.Context) (interface{}, error) { return nil, errUnavailable })
+	assert.Equal(t, errUnavailable, err)
+	span.End()
+
+	ctx, span = tracer.Start(context.Background(), "charge")
+	_, err = ExecuteContext(ctx, cb, func(ctx context.Context) (interface{}, error) { return nil, nil })
+	assert.Equal(t, circuit_breaker.ErrOpenState, err)
+	span.End()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+
+	assert.Len(t, spans[0].Events(), 1)
+	assert.Equal(t, DecisionEvent, spans[0].Events()[0].Name)
+	assert.Equal(t, []attribute.KeyValue{
+		NameKey.String("payments"),
+		StateKey.String("closed"),
+		DecisionKey.String(DecisionAdmitted),
+	}, spans[0].Events()[0].Attributes)
+
+	assert.Len(t, spans[1].Events(), 1)
+	assert.Equal(t, []attribute.KeyValue{
+		NameKey.String("payments"),
+		StateKey.String("open"),
+		DecisionKey.String(DecisionRejectedOpen),
+		ErrorTypeKey.String("open_state"),
+	}, spans[1].Events()[0].Attributes)
+	assert.Contains(t, spans[1].Attributes(), StateKey.String("open"))
+}
+
+func TestTraceTransitions(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "payments", MaxConsecutiveFailures: 1})
+	stop := TraceTransitions(provider, cb)
+
+	_, _ = cb.Execute(func() (interface{}, error) { return nil, errors.New("unavailable") })
+	stop()
+	cb.Reset()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, TransitionEvent, spans[0].Name())
+	assert.Equal(t, []attribute.KeyValue{
+		NameKey.String("payments"),
+		FromStateKey.String("closed"),
+		ToStateKey.String("open"),
+	}, spans[0].Attributes())
+}
@@ -0,0 +1,189 @@
+// Package prombreaker exports the statistics of circuit breakers as Prometheus metrics.
+package prombreaker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+var states = []circuit_breaker.State{
+	circuit_breaker.StateClosed,
+	circuit_breaker.StateHalfOpen,
+	circuit_breaker.StateOpen,
+}
+
+// CollectorConfig configures Collector.
+//
+// Namespace prefixes the metric names, "circuit_breaker" by default.
+//
+// Labels are the names of the breaker labels exported as dimensions, besides the breaker name.
+// A breaker without one of the labels exports it with an empty value.
+type CollectorConfig struct {
+	Namespace string
+	Labels    []string
+}
+
+// Collector is a prometheus.Collector exporting for each breaker:
+//
+//   - state: 1 for the current state and 0 for the others, by the "state" dimension
+//   - requests_total, successes_total, failures_total and rejections_total
+//   - transitions_total: the transitions into each state, by the "state" dimension
+//   - state_seconds_total: the time spent in each state, by the "state" dimension
+//   - seconds_since_last_transition
+//   - call_duration_seconds: the histogram of the call durations, if the breaker has a LatencyHistogram
+//
+// The metrics are read from the breaker snapshots at scrape time.
+// The breakers are exported by name, so the series never repeat: of the breakers sharing a name,
+// the one added by Add is exported, or else the one of the KeyedBreaker added first.
+type Collector struct {
+	labels   []string
+	breakers map[string]*circuit_breaker.CircuitBreaker
+	keyed    []*circuit_breaker.KeyedBreaker
+	mu       sync.RWMutex
+
+	state          *prometheus.Desc
+	requests       *prometheus.Desc
+	successes      *prometheus.Desc
+	failures       *prometheus.Desc
+	rejections     *prometheus.Desc
+	transitions    *prometheus.Desc
+	lastTransition *prometheus.Desc
+	timeInState    *prometheus.Desc
+	duration       *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+func NewCollector(cfg CollectorConfig) *Collector {
+	if cfg.Namespace == "" {
+		cfg.Namespace = "circuit_breaker"
+	}
+
+	labels := append([]string{"name"}, cfg.Labels...)
+	stateLabels := append(append([]string{}, labels...), "state")
+	desc := func(name, help string, labels []string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(cfg.Namespace, "", name), help, labels, nil)
+	}
+
+	return &Collector{
+		labels:   cfg.Labels,
+		breakers: make(map[string]*circuit_breaker.CircuitBreaker),
+
+		state:          desc("state", "Current state of the circuit breaker.", stateLabels),
+		requests:       desc("requests_total", "Requests admitted by the circuit breaker.", labels),
+		successes:      desc("successes_total", "Successful requests.", labels),
+		failures:       desc("failures_total", "Failed requests.", labels),
+		rejections:     desc("rejections_total", "Requests rejected by the circuit breaker.", labels),
+		transitions:    desc("transitions_total", "Transitions of the circuit breaker into the state.", stateLabels),
+		lastTransition: desc("seconds_since_last_transition", "Time since the last state change of the circuit breaker.", labels),
+		timeInState:    desc("state_seconds_total", "Time spent by the circuit breaker in the state.", stateLabels),
+		duration:       desc("call_duration_seconds", "Duration of the calls protected by the circuit breaker.", labels),
+	}
+}
+
+// Add exports the breakers. A breaker replaces the exported one with the same name.
+func (c *Collector) Add(cbs ...*circuit_breaker.CircuitBreaker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, cb := range cbs {
+		c.breakers[cb.Name()] = cb
+	}
+}
+
+// AddKeyed exports all the breakers of the KeyedBreaker, including the ones created after the call.
+func (c *Collector) AddKeyed(kb *circuit_breaker.KeyedBreaker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.keyed = append(c.keyed, kb)
+}
+
+// Remove stops exporting the breaker with the name.
+func (c *Collector) Remove(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.breakers, name)
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.state
+	ch <- c.requests
+	ch <- c.successes
+	ch <- c.failures
+	ch <- c.rejections
+	ch <- c.transitions
+	ch <- c.lastTransition
+	ch <- c.timeInState
+	ch <- c.duration
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	for _, cb := range c.collected() {
+		c.collect(ch, cb.Snapshot(), now)
+	}
+}
+
+// collected returns the exported breakers, one per name
+func (c *Collector) collected() []*circuit_breaker.CircuitBreaker {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	cbs := make([]*circuit_breaker.CircuitBreaker, 0, len(c.breakers))
+	names := make(map[string]bool, len(c.breakers))
+	for name, cb := range c.breakers {
+		cbs = append(cbs, cb)
+		names[name] = true
+	}
+	for _, kb := range c.keyed {
+		for _, key := range kb.Keys() {
+			cb, ok := kb.Lookup(key)
+			if !ok || names[cb.Name()] {
+				continue
+			}
+			cbs = append(cbs, cb)
+			names[cb.Name()] = true
+		}
+	}
+
+	return cbs
+}
+
+func (c *Collector) collect(ch chan<- prometheus.Metric, s circuit_breaker.Snapshot, now time.Time) {
+	values := []string{s.Name}
+	for _, label := range c.labels {
+		values = append(values, s.Labels[label])
+	}
+	counter := func(desc *prometheus.Desc, v uint64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(v), values...)
+	}
+
+	for _, state := range states {
+		stateValues := append(append([]string{}, values...), state.String())
+		current := 0.0
+		if s.State == state {
+			current = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.state, prometheus.GaugeValue, current, stateValues...)
+		ch <- prometheus.MustNewConstMetric(c.transitions, prometheus.CounterValue, float64(s.Totals.Transitions[state]), stateValues...)
+		ch <- prometheus.MustNewConstMetric(c.timeInState, prometheus.CounterValue, s.Totals.TimeInState[state].Seconds(), stateValues...)
+	}
+	counter(c.requests, s.Totals.Requests)
+	counter(c.successes, s.Totals.Successes)
+	counter(c.failures, s.Totals.Failures)
+	counter(c.rejections, s.Totals.Rejections)
+	ch <- prometheus.MustNewConstMetric(c.lastTransition, prometheus.GaugeValue, now.Sub(s.LastTransition).Seconds(), values...)
+
+	if s.Latency.Buckets != nil {
+		buckets := make(map[float64]uint64, len(s.Latency.Buckets))
+		for _, b := range s.Latency.Buckets {
+			buckets[b.UpperBound.Seconds()] = b.Count
+		}
+		ch <- prometheus.MustNewConstHistogram(c.duration, s.Latency.Count, s.Latency.Sum.Seconds(), buckets, values...)
+	}
+}
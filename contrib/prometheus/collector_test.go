@@ -0,0 +1,167 @@
+package prombreaker
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/expfmt"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	payments := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{
+		Name:                   "payments",
+		MaxConsecutiveFailures: 1,
+		Labels:                 map[string]string{"tier": "critical"},
+	})
+	hosts := circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{})
+	errUnavailable := errors.New("unavailable")
+
+	_, _ = payments.Execute(func() (interface{}, error) { return nil, nil })
+	_, _ = payments.Execute(func() (interface{}, error) { return nil, errUnavailable })
+	_, _ = payments.Execute(func() (interface{}, error) { return nil, nil })
+	_, _ = hosts.Execute("api.example.com", func() (interface{}, error) { return nil, nil })
+
+	c := NewCollector(CollectorConfig{Labels: []string{"tier"}})
+	c.Add(payments)
+	c.AddKeyed(hosts)
+
+	expected := `
+# HELP circuit_breaker_requests_total Requests admitted by the circuit breaker.
+# TYPE circuit_breaker_requests_total counter
+circuit_breaker_requests_total{name="api.example.com",tier=""} 1
+circuit_breaker_requests_total{name="payments",tier="critical"} 2
+# HELP circuit_breaker_failures_total Failed requests.
+# TYPE circuit_breaker_failures_total counter
+circuit_breaker_failures_total{name="api.example.com",tier=""} 0
+circuit_breaker_failures_total{name="payments",tier="critical"} 1
+# HELP circuit_breaker_rejections_total Requests rejected by the circuit breaker.
+# TYPE circuit_breaker_rejections_total counter
+circuit_breaker_rejections_total{name="api.example.com",tier=""} 0
+circuit_breaker_rejections_total{name="payments",tier="critical"} 1
+# HELP circuit_breaker_state Current state of the circuit breaker.
+# TYPE circuit_breaker_state gauge
+circuit_breaker_state{name="api.example.com",state="closed",tier=""} 1
+circuit_breaker_state{name="api.example.com",state="half-open",tier=""} 0
+circuit_breaker_state{name="api.example.com",state="open",tier=""} 0
+circuit_breaker_state{name="payments",state="closed",tier="critical"} 0
+circuit_breaker_state{name="payments",state="half-open",tier="critical"} 0
+circuit_breaker_state{name="payments",state="open",tier="critical"} 1
+# HELP circuit_breaker_transitions_total Transitions of the circuit breaker into the state.
+# TYPE circuit_breaker_transitions_total counter
+circuit_breaker_transitions_total{name="api.example.com",state="closed",tier=""} 0
+circuit_breaker_transitions_total{name="api.example.com",state="half-open",tier=""} 0
+circuit_breaker_transitions_total{name="api.example.com",state="open",tier=""} 0
+circuit_breaker_transitions_total{name="payments",state="closed",tier="critical"} 0
+circuit_breaker_transitions_total{name="payments",state="half-open",tier="critical"} 0
+circuit_breaker_transitions_total{name="payments",state="open",tier="critical"} 1
+`
+	assert.Nil(t, testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"circuit_breaker_requests_total",
+		"circuit_breaker_failures_total",
+		"circuit_breaker_rejections_total",
+		"circuit_breaker_state",
+		"circuit_breaker_transitions_total",
+	))
+	assert.Equal(t, 28, testutil.CollectAndCount(c))
+
+	c.Remove("payments")
+	assert.Equal(t, 14, testutil.CollectAndCount(c))
+}
+
+func TestCollectorDuplicateNames(t *testing.T) {
+	payments := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "payments"})
+	first := circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{})
+	second := circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{})
+	_, _ = first.Execute("payments", func() (interface{}, error) { return nil, nil })
+	_, _ = first.Execute("search", func() (interface{}, error) { return nil, nil })
+	_, _ = second.Execute("search", func() (interface{}, error) { return nil, errors.New("unavailable") })
+
+	c := NewCollector(CollectorConfig{})
+	c.Add(payments)
+	c.AddKeyed(first)
+	c.AddKeyed(second)
+
+	// the breakers sharing a name are exported once, so the registry accepts the metrics
+	registry := prometheus.NewPedanticRegistry()
+	assert.Nil(t, registry.Register(c))
+	_, err := registry.Gather()
+	assert.Nil(t, err)
+	assert.Equal(t, 28, testutil.CollectAndCount(c))
+
+	expected := `
+# HELP circuit_breaker_requests_total Requests admitted by the circuit breaker.
+# TYPE circuit_breaker_requests_total counter
+circuit_breaker_requests_total{name="payments"} 0
+circuit_breaker_requests_total{name="search"} 1
+# HELP circuit_breaker_failures_total Failed requests.
+# TYPE circuit_breaker_failures_total counter
+circuit_breaker_failures_total{name="payments"} 0
+circuit_breaker_failures_total{name="search"} 0
+`
+	assert.Nil(t, testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"circuit_breaker_requests_total",
+		"circuit_breaker_failures_total",
+	))
+}
+
+func TestCollectorLatencyHistogram(t *testing.T) {
+	h := circuit_breaker.NewBucketHistogram([]time.Duration{100 * time.Millisecond, time.Second})
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "search", LatencyHistogram: h})
+	h.Record(50 * time.Millisecond)
+	h.Record(200 * time.Millisecond)
+	h.Record(2 * time.Second)
+
+	c := NewCollector(CollectorConfig{})
+	c.Add(cb)
+
+	expected := `
+# HELP circuit_breaker_call_duration_seconds Duration of the calls protected by the circuit breaker.
+# TYPE circuit_breaker_call_duration_seconds histogram
+circuit_breaker_call_duration_seconds_bucket{name="search",le="0.1"} 1
+circuit_breaker_call_duration_seconds_bucket{name="search",le="1"} 2
+circuit_breaker_call_duration_seconds_bucket{name="search",le="+Inf"} 3
+circuit_breaker_call_duration_seconds_sum{name="search"} 2.25
+circuit_breaker_call_duration_seconds_count{name="search"} 3
+`
+	assert.Nil(t, testutil.CollectAndCompare(c, strings.NewReader(expected), "circuit_breaker_call_duration_seconds"))
+}
+
+func TestCollectorMatchesMetricsHandler(t *testing.T) {
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{
+		Name:             "payments",
+		LatencyHistogram: circuit_breaker.NewBucketHistogram(nil),
+	})
+	_, _ = cb.Execute(func() (interface{}, error) { return nil, nil })
+
+	c := NewCollector(CollectorConfig{})
+	c.Add(cb)
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(c)
+	gathered, err := registry.Gather()
+	assert.Nil(t, err)
+
+	w := httptest.NewRecorder()
+	circuit_breaker.MetricsHandler(circuit_breaker.MetricsConfig{
+		Breakers: func() []*circuit_breaker.CircuitBreaker { return []*circuit_breaker.CircuitBreaker{cb} },
+	}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	var parser expfmt.TextParser
+	parsed, err := parser.TextToMetricFamilies(w.Body)
+	assert.Nil(t, err)
+
+	// the dependency-free handler exports the same families
+	assert.Len(t, parsed, len(gathered))
+	for _, family := range gathered {
+		if assert.Contains(t, parsed, family.GetName()) {
+			assert.Equal(t, family.GetType(), parsed[family.GetName()].GetType())
+			assert.Len(t, parsed[family.GetName()].GetMetric(), len(family.GetMetric()))
+		}
+	}
+}
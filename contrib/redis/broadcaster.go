@@ -0,0 +1,101 @@
+package redisbreaker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+// DefaultChannel is the pub/sub channel of the broadcasts if BroadcasterConfig.Channel is empty.
+const DefaultChannel = "circuit_breaker:states"
+
+// BroadcasterConfig configures Broadcaster.
+//
+// Client is the Redis client, and Channel the pub/sub channel shared by the fleet, DefaultChannel by default.
+//
+// OnError is called with the broadcasts which can't be decoded, which are skipped.
+type BroadcasterConfig struct {
+	Client  redis.UniversalClient
+	Channel string
+	OnError func(err error)
+}
+
+// Broadcaster is the circuit_breaker.Broadcaster publishing the state changes of the breakers
+// to a Redis pub/sub channel, see circuit_breaker.BroadcastState.
+// Redis pub/sub delivers at most once: the broadcasts published while an instance is disconnected are lost,
+// and its breakers keep discovering the outages by themselves.
+type Broadcaster struct {
+	cfg    BroadcasterConfig
+	hub    *circuit_breaker.BroadcastHub
+	pubsub *redis.PubSub
+	done   chan struct{}
+}
+
+var _ circuit_breaker.Broadcaster = (*Broadcaster)(nil)
+
+// NewBroadcaster subscribes to the channel and delivers its broadcasts until Close.
+// It returns an error if the subscription fails.
+func NewBroadcaster(ctx context.Context, cfg BroadcasterConfig) (*Broadcaster, error) {
+	if cfg.Channel == "" {
+		cfg.Channel = DefaultChannel
+	}
+
+	pubsub := cfg.Client.Subscribe(ctx, cfg.Channel)
+	// wait for the subscription, so that no broadcast published afterwards is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, fmt.Errorf("redis: subscribe %q: %w", cfg.Channel, err)
+	}
+
+	b := &Broadcaster{
+		cfg:    cfg,
+		hub:    circuit_breaker.NewBroadcastHub(),
+		pubsub: pubsub,
+		done:   make(chan struct{}),
+	}
+	go b.receive()
+
+	return b, nil
+}
+
+// Publish implements circuit_breaker.Broadcaster.
+func (b *Broadcaster) Publish(ctx context.Context, msg circuit_breaker.StateBroadcast) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return b.cfg.Client.Publish(ctx, b.cfg.Channel, data).Err()
+}
+
+// Subscribe implements circuit_breaker.Broadcaster.
+func (b *Broadcaster) Subscribe(name string) <-chan circuit_breaker.StateBroadcast {
+	return b.hub.Subscribe(name)
+}
+
+// Close unsubscribes from the channel, closing the channels of the subscribers.
+func (b *Broadcaster) Close() error {
+	err := b.pubsub.Close()
+	<-b.done
+	b.hub.Close()
+
+	return err
+}
+
+func (b *Broadcaster) receive() {
+	defer close(b.done)
+
+	for m := range b.pubsub.Channel() {
+		var msg circuit_breaker.StateBroadcast
+		if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+			if b.cfg.OnError != nil {
+				b.cfg.OnError(fmt.Errorf("redis: channel %q: %w", b.cfg.Channel, err))
+			}
+			continue
+		}
+		b.hub.Deliver(msg)
+	}
+}
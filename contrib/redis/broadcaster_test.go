@@ -0,0 +1,59 @@
+package redisbreaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroadcaster(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	errs := make(chan error, 1)
+	cfg := circuit_breaker.Config{Name: "payments", MaxConsecutiveFailures: 1, Timeout: time.Minute}
+
+	var breakers []*circuit_breaker.CircuitBreaker
+	for _, origin := range []string{"a", "b"} {
+		rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { _ = rdb.Close() })
+		b, err := NewBroadcaster(ctx, BroadcasterConfig{Client: rdb, OnError: func(err error) { errs <- err }})
+		assert.Nil(t, err)
+		t.Cleanup(func() { _ = b.Close() })
+
+		cb := circuit_breaker.NewCircuitBreaker(cfg)
+		t.Cleanup(circuit_breaker.BroadcastState(cb, b, origin))
+		breakers = append(breakers, cb)
+	}
+
+	_, _ = breakers[0].Execute(func() (interface{}, error) { return nil, assert.AnError })
+	assert.Equal(t, circuit_breaker.StateOpen, breakers[0].State())
+	assert.Eventually(t, func() bool {
+		return breakers[1].State() == circuit_breaker.StateOpen
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, breakers[0].OpensAt().UnixNano(), breakers[1].OpensAt().UnixNano())
+
+	server.Publish(DefaultChannel, "not json")
+	assert.NotNil(t, <-errs)
+}
+
+func TestBroadcasterClose(t *testing.T) {
+	server := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer rdb.Close()
+
+	b, err := NewBroadcaster(context.Background(), BroadcasterConfig{Client: rdb, Channel: "states"})
+	assert.Nil(t, err)
+	ch := b.Subscribe("payments")
+	assert.Nil(t, b.Close())
+	_, ok := <-ch
+	assert.False(t, ok)
+
+	server.Close()
+	_, err = NewBroadcaster(context.Background(), BroadcasterConfig{Client: rdb})
+	assert.NotNil(t, err)
+}
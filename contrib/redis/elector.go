@@ -0,0 +1,91 @@
+package redisbreaker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+// DefaultElectorPrefix is the prefix of the lease keys if ElectorConfig.Prefix is empty.
+const DefaultElectorPrefix = "circuit_breaker:probers:"
+
+// elect takes the lease of KEYS[1] for ARGV[1] for ARGV[2] milliseconds, or extends it if ARGV[1] holds it
+var elect = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+return 0
+`)
+
+// resign removes the lease of KEYS[1] if ARGV[1] holds it
+var resign = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// ElectorConfig configures Elector.
+//
+// Client is the Redis client, and Prefix the prefix of the lease keys shared by the fleet, DefaultElectorPrefix by default:
+// the key of a breaker is the prefix followed by its name.
+//
+// ID identifies the instance holding the leases, e.g. its host name, a random one by default.
+// It must differ between the instances.
+type ElectorConfig struct {
+	Client redis.UniversalClient
+	Prefix string
+	ID     string
+}
+
+// Elector is the circuit_breaker.ProbeElector of an instance, electing the prober of a half-open breaker
+// by a lease key set if absent, see circuit_breaker.Config.ProbeElector.
+type Elector struct {
+	cfg ElectorConfig
+}
+
+var _ circuit_breaker.ProbeElector = (*Elector)(nil)
+
+// NewElector creates the Elector.
+func NewElector(cfg ElectorConfig) *Elector {
+	if cfg.Prefix == "" {
+		cfg.Prefix = DefaultElectorPrefix
+	}
+	if cfg.ID == "" {
+		id := make([]byte, 16)
+		_, _ = rand.Read(id)
+		cfg.ID = hex.EncodeToString(id)
+	}
+
+	return &Elector{cfg: cfg}
+}
+
+// Elect implements circuit_breaker.ProbeElector.
+func (e *Elector) Elect(ctx context.Context, name string, lease time.Duration) (bool, error) {
+	key := e.cfg.Prefix + name
+	won, err := elect.Run(ctx, e.cfg.Client, []string{key}, e.cfg.ID, lease.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("redis: elect %q: %w", key, err)
+	}
+
+	return won == 1, nil
+}
+
+// Resign implements circuit_breaker.ProbeElector.
+func (e *Elector) Resign(ctx context.Context, name string) error {
+	key := e.cfg.Prefix + name
+	if err := resign.Run(ctx, e.cfg.Client, []string{key}, e.cfg.ID).Err(); err != nil {
+		return fmt.Errorf("redis: resign %q: %w", key, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package redisbreaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestElector(t *testing.T) {
+	server := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer rdb.Close()
+	ctx := context.Background()
+	a := NewElector(ElectorConfig{Client: rdb, ID: "a"})
+	b := NewElector(ElectorConfig{Client: rdb})
+
+	won, err := a.Elect(ctx, "payments", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, won)
+	holder, err := server.Get(DefaultElectorPrefix + "payments")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", holder)
+	won, err = b.Elect(ctx, "payments", time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, won)
+
+	// the holder extends its lease
+	server.FastForward(50 * time.Second)
+	won, err = a.Elect(ctx, "payments", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, won)
+	assert.Equal(t, time.Minute, server.TTL(DefaultElectorPrefix+"payments"))
+
+	// only the holder resigns
+	assert.Nil(t, b.Resign(ctx, "payments"))
+	assert.True(t, server.Exists(DefaultElectorPrefix+"payments"))
+	assert.Nil(t, a.Resign(ctx, "payments"))
+	won, err = b.Elect(ctx, "payments", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, won)
+
+	// the lease expires
+	server.FastForward(time.Minute)
+	won, err = a.Elect(ctx, "payments", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, won)
+
+	server.Close()
+	_, err = a.Elect(ctx, "payments", time.Minute)
+	assert.NotNil(t, err)
+	assert.NotNil(t, a.Resign(ctx, "payments"))
+}
+
+func TestElectorProbing(t *testing.T) {
+	server := miniredis.RunT(t)
+	cfg := circuit_breaker.Config{Name: "payments", MaxConsecutiveFailures: 1, RequestThreshold: 1, Timeout: 10 * time.Millisecond}
+
+	var breakers []*circuit_breaker.CircuitBreaker
+	for _, id := range []string{"a", "b"} {
+		rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { _ = rdb.Close() })
+		cfg.ProbeElector = NewElector(ElectorConfig{Client: rdb, ID: id})
+		cb := circuit_breaker.NewCircuitBreaker(cfg)
+		cb.Trip()
+		breakers = append(breakers, cb)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// a probes, b waits
+	_, err := breakers[0].Execute(func() (interface{}, error) {
+		_, err := breakers[1].Execute(func() (interface{}, error) { return nil, nil })
+		assert.ErrorIs(t, err, circuit_breaker.ErrTooManyRequests)
+		return nil, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, circuit_breaker.StateClosed, breakers[0].State())
+	breakers[0].WaitBackground()
+	assert.False(t, server.Exists(DefaultElectorPrefix+"payments"))
+}
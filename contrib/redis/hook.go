@@ -0,0 +1,133 @@
+// Package redisbreaker protects go-redis commands with circuit breakers,
+// spreads the state changes of the breakers across the fleet by Redis pub/sub,
+// and elects the single instance probing a half-open breaker by a lease key.
+package redisbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+// PipelineKey is the key of the pipelines for PerCommand.
+const PipelineKey = "pipeline"
+
+// Config configures the hook.
+//
+// Breaker selects the breaker protecting the command by its name ("get", "set"),
+// or by PipelineKey for pipelines and transactions.
+// If Breaker returns nil, the command is not protected.
+//
+// Classifier maps the error of the command to its outcome.
+// If Classifier is nil, DefaultClassifier is used.
+type Config struct {
+	Breaker    func(name string) *circuit_breaker.CircuitBreaker
+	Classifier func(err error) error
+}
+
+// Hook runs every command and pipeline through a breaker.
+// Commands rejected by the breaker fail with an error wrapping the rejection error of the breaker.
+type Hook struct {
+	cfg Config
+}
+
+var _ redis.Hook = (*Hook)(nil)
+
+// New creates the hook protecting all the commands of the client with a single breaker.
+func New(cb *circuit_breaker.CircuitBreaker) *Hook {
+	return NewHook(Config{
+		Breaker: func(name string) *circuit_breaker.CircuitBreaker { return cb },
+	})
+}
+
+// PerCommand creates the hook protecting each command with its own breaker, keyed by the command name.
+func PerCommand(kb *circuit_breaker.KeyedBreaker) *Hook {
+	return NewHook(Config{Breaker: kb.Get})
+}
+
+// PerNode creates the hook protecting all the commands sent to the node with the breaker of its address.
+// Register it on every node of a cluster:
+//
+//	cluster.OnNewNode(func(node *redis.Client) {
+//		node.AddHook(redisbreaker.PerNode(kb, node.Options().Addr))
+//	})
+func PerNode(kb *circuit_breaker.KeyedBreaker, addr string) *Hook {
+	return New(kb.Get(addr))
+}
+
+// NewHook creates the hook.
+func NewHook(cfg Config) *Hook {
+	if cfg.Classifier == nil {
+		cfg.Classifier = DefaultClassifier
+	}
+
+	return &Hook{cfg: cfg}
+}
+
+// DefaultClassifier ignores redis.Nil, which reports a missing key rather than an unhealthy server.
+func DefaultClassifier(err error) error {
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+
+	return err
+}
+
+// DialHook implements redis.Hook.
+func (h *Hook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// ProcessHook implements redis.Hook.
+func (h *Hook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		rejected, err := h.execute(ctx, cmd.Name(), func(ctx context.Context) error {
+			return next(ctx, cmd)
+		})
+		if rejected {
+			cmd.SetErr(err)
+		}
+
+		return err
+	}
+}
+
+// ProcessPipelineHook implements redis.Hook.
+func (h *Hook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		rejected, err := h.execute(ctx, PipelineKey, func(ctx context.Context) error {
+			return next(ctx, cmds)
+		})
+		if rejected {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+		}
+
+		return err
+	}
+}
+
+// execute runs the request through the breaker of the name, reporting whether the breaker rejected it
+func (h *Hook) execute(ctx context.Context, name string, req func(ctx context.Context) error) (bool, error) {
+	cb := h.cfg.Breaker(name)
+	if cb == nil {
+		return false, req(ctx)
+	}
+
+	handled := false
+	var reqErr error
+	_, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+		handled = true
+		reqErr = req(ctx)
+		return nil, h.cfg.Classifier(reqErr)
+	})
+	if !handled {
+		return true, fmt.Errorf("%s rejected by circuit breaker %q: %w", name, cb.Name(), err)
+	}
+
+	return false, reqErr
+}
@@ -0,0 +1,108 @@
+package redisbreaker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func client(t *testing.T, hook redis.Hook) (*redis.Client, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	rdb.AddHook(hook)
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	return rdb, server
+}
+
+func TestHook(t *testing.T) {
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "cache", MaxConsecutiveFailures: 2})
+	rdb, server := client(t, New(cb))
+	ctx := context.Background()
+
+	assert.Nil(t, rdb.Set(ctx, "report", "daily", 0).Err())
+	assert.Equal(t, "daily", rdb.Get(ctx, "report").Val())
+
+	// a missing key is not a failure
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, redis.Nil, rdb.Get(ctx, "missing").Err())
+	}
+	assert.Equal(t, circuit_breaker.StateClosed, cb.State())
+
+	server.SetError("LOADING Redis is loading the dataset in memory")
+	for i := 0; i < 2; i++ {
+		assert.NotNil(t, rdb.Get(ctx, "report").Err())
+	}
+	assert.Equal(t, circuit_breaker.StateOpen, cb.State())
+
+	server.SetError("")
+	cmd := rdb.Get(ctx, "report")
+	assert.ErrorIs(t, cmd.Err(), circuit_breaker.ErrOpenState)
+	assert.Equal(t, `get rejected by circuit breaker "cache": circuit breaker is open`, cmd.Err().Error())
+}
+
+func TestHookPipeline(t *testing.T) {
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{MaxConsecutiveFailures: 1})
+	rdb, server := client(t, New(cb))
+	ctx := context.Background()
+
+	cmds, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, "report", "daily", 0)
+		pipe.Get(ctx, "missing")
+		return nil
+	})
+	assert.Equal(t, redis.Nil, err)
+	assert.Nil(t, cmds[0].Err())
+	assert.Equal(t, circuit_breaker.StateClosed, cb.State())
+
+	server.Close()
+	_, err = rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Get(ctx, "report")
+		return nil
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, circuit_breaker.StateOpen, cb.State())
+
+	cmds, err = rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Get(ctx, "report")
+		pipe.Get(ctx, "other")
+		return nil
+	})
+	assert.ErrorIs(t, err, circuit_breaker.ErrOpenState)
+	for _, cmd := range cmds {
+		assert.ErrorIs(t, cmd.Err(), circuit_breaker.ErrOpenState)
+	}
+}
+
+func TestPerCommand(t *testing.T) {
+	kb := circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{MaxConsecutiveFailures: 1},
+	})
+	rdb, _ := client(t, PerCommand(kb))
+	ctx := context.Background()
+
+	assert.Nil(t, rdb.Set(ctx, "report", "daily", 0).Err())
+	assert.NotNil(t, rdb.Incr(ctx, "report").Err())
+	assert.ErrorIs(t, rdb.Incr(ctx, "report").Err(), circuit_breaker.ErrOpenState)
+	assert.Nil(t, rdb.Set(ctx, "report", "weekly", 0).Err())
+
+	incr, _ := kb.Lookup("incr")
+	set, _ := kb.Lookup("set")
+	assert.Equal(t, circuit_breaker.StateOpen, incr.State())
+	assert.Equal(t, circuit_breaker.StateClosed, set.State())
+}
+
+func TestPerNode(t *testing.T) {
+	kb := circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{})
+	server := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	rdb.AddHook(PerNode(kb, server.Addr()))
+	defer rdb.Close()
+
+	assert.Nil(t, rdb.Ping(context.Background()).Err())
+	assert.Equal(t, []string{server.Addr()}, kb.Keys())
+}
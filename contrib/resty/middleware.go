@@ -0,0 +1,128 @@
+// Package restybreaker protects go-resty clients with circuit breakers.
+package restybreaker
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+// Config configures Install.
+//
+// Breakers holds a breaker per key, created lazily.
+//
+// Key returns the breaker key of the request. If Key is nil, HostKey is used.
+//
+// Classifier maps the status code of the response to the outcome of the request.
+// The zero value treats 5xx and 429 as failures.
+type Config struct {
+	Breakers   *circuit_breaker.KeyedBreaker
+	Key        func(req *resty.Request) string
+	Classifier circuit_breaker.HTTPClassifier
+}
+
+// Install registers the middleware and hooks admitting the requests of the client through the breakers.
+//
+// Every attempt of a request is admitted and recorded once: retries performed by resty
+// are separate attempts, each settled by the retry hook or the response middleware,
+// so an attempt that is retried is not counted again as the outcome of the whole request.
+// Requests rejected by the breaker fail with an error wrapping the rejection error, e.g. circuit_breaker.ErrOpenState,
+// and are not retried.
+func Install(client *resty.Client, cfg Config) *resty.Client {
+	if cfg.Key == nil {
+		cfg.Key = HostKey
+	}
+	m := middleware{cfg: cfg, pending: make(map[*resty.Request]func(err error))}
+
+	return client.
+		OnBeforeRequest(m.before).
+		OnAfterResponse(m.after).
+		AddRetryHook(m.retry).
+		OnSuccess(m.success).
+		OnError(m.error).
+		OnPanic(m.error)
+}
+
+// HostKey keys the requests by destination host (with port, if any).
+func HostKey(req *resty.Request) string {
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// middleware tracks the attempts admitted by the breakers until their outcome is known
+type middleware struct {
+	cfg     Config
+	mu      sync.Mutex
+	pending map[*resty.Request]func(err error)
+}
+
+func (m *middleware) before(_ *resty.Client, req *resty.Request) error {
+	// an attempt abandoned by resty before reaching the server, e.g. by a failing middleware
+	m.settle(req, nil)
+
+	key := m.cfg.Key(req)
+	cb := m.cfg.Breakers.Get(key)
+	done, err := cb.Allow(req.Context())
+	if err != nil {
+		return fmt.Errorf("%s rejected by circuit breaker %q: %w", key, cb.Name(), err)
+	}
+
+	m.mu.Lock()
+	m.pending[req] = done
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *middleware) after(_ *resty.Client, resp *resty.Response) error {
+	m.settle(resp.Request, m.cfg.Classifier.ClassifyStatus(resp.StatusCode()))
+	return nil
+}
+
+func (m *middleware) retry(resp *resty.Response, err error) {
+	if resp != nil {
+		m.settleResponse(resp.Request, resp, err)
+	}
+}
+
+func (m *middleware) success(_ *resty.Client, resp *resty.Response) {
+	m.settleResponse(resp.Request, resp, nil)
+}
+
+func (m *middleware) error(req *resty.Request, err error) {
+	var respErr *resty.ResponseError
+	if errors.As(err, &respErr) {
+		m.settleResponse(req, respErr.Response, respErr.Err)
+		return
+	}
+	m.settleResponse(req, nil, err)
+}
+
+// settleResponse records the outcome of the pending attempt from its response or error
+func (m *middleware) settleResponse(req *resty.Request, resp *resty.Response, err error) {
+	var raw *http.Response
+	if resp != nil {
+		raw = resp.RawResponse
+	}
+	m.settle(req, m.cfg.Classifier.Classify(raw, err))
+}
+
+// settle records the outcome of the pending attempt of the request, if any
+func (m *middleware) settle(req *resty.Request, err error) {
+	m.mu.Lock()
+	done, ok := m.pending[req]
+	delete(m.pending, req)
+	m.mu.Unlock()
+
+	if ok {
+		done(err)
+	}
+}
@@ -0,0 +1,77 @@
+package restybreaker
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func newClient(cfg circuit_breaker.Config) (*resty.Client, *circuit_breaker.KeyedBreaker) {
+	kb := circuit_breaker.NewKeyedBreaker(circuit_breaker.KeyedConfig{Config: cfg})
+	client := resty.New().
+		SetRetryCount(2).
+		SetRetryWaitTime(0).
+		AddRetryCondition(func(resp *resty.Response, err error) bool {
+			return err != nil || resp.StatusCode() >= http.StatusInternalServerError
+		})
+
+	return Install(client, Config{Breakers: kb}), kb
+}
+
+func TestInstall(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bad":
+			w.WriteHeader(http.StatusBadGateway)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	client, kb := newClient(circuit_breaker.Config{MaxConsecutiveFailures: 4})
+
+	resp, err := client.R().Get(srv.URL + "/missing")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode())
+
+	cb := kb.Get(HostKey(resp.Request))
+	assert.Equal(t, circuit_breaker.Counts{Requests: 1, TotalSuccesses: 1, ConsecutiveSuccesses: 1}, cb.Counts())
+
+	// each attempt is counted once
+	resp, err = client.R().Get(srv.URL + "/bad")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode())
+	assert.Equal(t, uint32(4), cb.Counts().Requests)
+	assert.Equal(t, uint32(3), cb.Counts().ConsecutiveFailures)
+
+	// the breaker opens on the second attempt, which is not retried
+	_, err = client.R().Get(srv.URL + "/bad")
+	assert.True(t, errors.Is(err, circuit_breaker.ErrOpenState))
+	assert.Equal(t, circuit_breaker.StateOpen, cb.State())
+
+	_, err = client.R().Get(srv.URL + "/missing")
+	assert.True(t, errors.Is(err, circuit_breaker.ErrOpenState))
+}
+
+func TestInstallTransportError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	client, kb := newClient(circuit_breaker.Config{MaxConsecutiveFailures: 5})
+
+	_, err := client.R().Get(url)
+	assert.NotNil(t, err)
+	assert.False(t, errors.Is(err, circuit_breaker.ErrOpenState))
+
+	cb, ok := kb.Lookup(url[len("http://"):])
+	assert.True(t, ok)
+	assert.Equal(t, uint32(3), cb.Counts().TotalFailures)
+	assert.Equal(t, circuit_breaker.StateClosed, cb.State())
+}
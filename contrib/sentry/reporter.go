@@ -0,0 +1,101 @@
+// Package sentrybreaker reports the trips of circuit breakers to Sentry.
+package sentrybreaker
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+// Config configures Reporter.
+//
+// Hub captures the events. If Hub is nil, sentry.CurrentHub is used.
+// Level is the level of the events. If Level is empty, sentry.LevelWarning is used.
+type Config struct {
+	Hub   *sentry.Hub
+	Level sentry.Level
+}
+
+// Reporter is the circuit_breaker.ErrorReporter capturing a Sentry event for every trip:
+//
+//   - the message names the breaker and the reason of the trip
+//   - the exceptions are the recent errors of the breaker, or the last error if they are disabled
+//   - the tags are the labels of the breaker and its name as circuit_breaker
+//   - the circuit_breaker context holds the counts which led to the trip
+//
+// The events of a breaker are grouped into one issue by their fingerprint.
+type Reporter struct {
+	hub   *sentry.Hub
+	level sentry.Level
+}
+
+var _ circuit_breaker.ErrorReporter = (*Reporter)(nil)
+
+// New creates the Reporter.
+func New(cfg Config) *Reporter {
+	if cfg.Level == "" {
+		cfg.Level = sentry.LevelWarning
+	}
+
+	return &Reporter{hub: cfg.Hub, level: cfg.Level}
+}
+
+// Watch reports the trips of the breaker, see circuit_breaker.ReportTrips.
+func (r *Reporter) Watch(cb *circuit_breaker.CircuitBreaker) (stop func()) {
+	return circuit_breaker.ReportTrips(cb, r)
+}
+
+// ReportTrip captures the event of the trip. The event is sent in the background by the transport of the client.
+func (r *Reporter) ReportTrip(report circuit_breaker.TripReport) {
+	hub := r.hub
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+
+	hub.CaptureEvent(r.event(report))
+}
+
+func (r *Reporter) event(report circuit_breaker.TripReport) *sentry.Event {
+	event := sentry.NewEvent()
+	event.Level = r.level
+	event.Logger = "circuit_breaker"
+	event.Message = fmt.Sprintf("circuit breaker %s opened: %s", report.Name, report.Reason)
+	event.Timestamp = report.At
+	event.Fingerprint = []string{"circuit_breaker", report.Name}
+
+	for k, v := range report.Labels {
+		event.Tags[k] = v
+	}
+	event.Tags["circuit_breaker"] = report.Name
+
+	event.Contexts["circuit_breaker"] = sentry.Context{
+		"name":                  report.Name,
+		"from":                  report.From.String(),
+		"reason":                report.Reason,
+		"requests":              report.Counts.Requests,
+		"successes":             report.Counts.TotalSuccesses,
+		"failures":              report.Counts.TotalFailures,
+		"consecutive_successes": report.Counts.ConsecutiveSuccesses,
+		"consecutive_failures":  report.Counts.ConsecutiveFailures,
+	}
+
+	// Sentry shows the last exception as the main one, so the most recent error goes last
+	for _, e := range report.RecentErrors {
+		exception := sentry.Exception{Type: errorType(e.Err), Value: e.Err.Error()}
+		if e.Count > 1 {
+			exception.Value = fmt.Sprintf("%s (%d times)", exception.Value, e.Count)
+		}
+		event.Exception = append(event.Exception, exception)
+	}
+	if len(event.Exception) == 0 && report.LastError != nil {
+		event.Exception = []sentry.Exception{{Type: errorType(report.LastError), Value: report.LastError.Error()}}
+	}
+
+	return event
+}
+
+func errorType(err error) string {
+	return reflect.TypeOf(err).String()
+}
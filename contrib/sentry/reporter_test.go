@@ -0,0 +1,72 @@
+package sentrybreaker
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+type transport struct {
+	mu     sync.Mutex
+	events []*sentry.Event
+}
+
+func (t *transport) Flush(time.Duration) bool       { return true }
+func (t *transport) Configure(sentry.ClientOptions) {}
+func (t *transport) SendEvent(event *sentry.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, event)
+}
+
+func TestReporter(t *testing.T) {
+	tr := &transport{}
+	client, err := sentry.NewClient(sentry.ClientOptions{Dsn: "https://key@sentry.example.com/1", Transport: tr})
+	assert.Nil(t, err)
+	reporter := New(Config{Hub: sentry.NewHub(client, sentry.NewScope())})
+
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{
+		Name:                   "payments",
+		MaxConsecutiveFailures: 3,
+		RecentErrorsSize:       4,
+		Labels:                 map[string]string{"team": "billing"},
+	})
+	stop := reporter.Watch(cb)
+	defer stop()
+
+	timeout := errors.New("timeout")
+	refused := errors.New("connection refused")
+	for _, e := range []error{timeout, refused, refused} {
+		_, _ = cb.Execute(func() (interface{}, error) { return nil, e })
+	}
+	assert.Equal(t, circuit_breaker.StateOpen, cb.State())
+
+	if assert.Len(t, tr.events, 1) {
+		event := tr.events[0]
+		assert.Equal(t, sentry.LevelWarning, event.Level)
+		assert.Equal(t, "circuit breaker payments opened: failure threshold reached", event.Message)
+		assert.Equal(t, []string{"circuit_breaker", "payments"}, event.Fingerprint)
+		assert.Equal(t, "payments", event.Tags["circuit_breaker"])
+		assert.Equal(t, "billing", event.Tags["team"])
+		assert.Equal(t, uint32(3), event.Contexts["circuit_breaker"]["failures"])
+		assert.Equal(t, []sentry.Exception{
+			{Type: "*errors.errorString", Value: "timeout"},
+			{Type: "*errors.errorString", Value: "connection refused (2 times)"},
+		}, event.Exception)
+	}
+
+	// without the recent errors, the last error is reported
+	cb = circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "search", MaxConsecutiveFailures: 1})
+	New(Config{Hub: sentry.NewHub(client, sentry.NewScope()), Level: sentry.LevelError}).Watch(cb)
+	_, _ = cb.Execute(func() (interface{}, error) { return nil, timeout })
+
+	if assert.Len(t, tr.events, 2) {
+		assert.Equal(t, sentry.LevelError, tr.events[1].Level)
+		assert.Equal(t, []sentry.Exception{{Type: "*errors.errorString", Value: "timeout"}}, tr.events[1].Exception)
+	}
+}
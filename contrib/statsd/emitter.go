@@ -0,0 +1,131 @@
+// Package statsdbreaker emits the events of circuit breakers to StatsD and DogStatsD.
+package statsdbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+const defaultPrefix = "circuit_breaker."
+
+// Client is the part of statsd.ClientInterface used by Emitter.
+type Client interface {
+	Incr(name string, tags []string, rate float64) error
+	Gauge(name string, value float64, tags []string, rate float64) error
+	Timing(name string, value time.Duration, tags []string, rate float64) error
+	Event(e *statsd.Event) error
+}
+
+var _ Client = (statsd.ClientInterface)(nil)
+
+// Emitter sends the metrics of the calls made through circuit breakers, tagged by the breaker name:
+//
+//   - calls: count of the calls by outcome (success, failure or rejected)
+//   - rejections: count of the rejected calls by reason
+//   - duration: timing of the admitted calls by outcome
+//   - transitions: count of the state changes by the from and to states
+//   - state: gauge of the current State value, 0 closed, 1 open and 2 half-open
+//
+// State changes are also sent as DogStatsD events.
+// The errors of the client are ignored: metrics must not fail the calls.
+type Emitter struct {
+	client Client
+	prefix string
+	tags   []string
+}
+
+// NewEmitter creates an Emitter prefixing the metric names with prefix, "circuit_breaker." if empty,
+// and adding the tags to all the metrics.
+func NewEmitter(client Client, prefix string, tags ...string) *Emitter {
+	if prefix == "" {
+		prefix = defaultPrefix
+	}
+
+	return &Emitter{client: client, prefix: prefix, tags: tags}
+}
+
+// ExecuteContext runs the request through the breaker, see CircuitBreaker.ExecuteContext, and emits the call.
+func (e *Emitter) ExecuteContext(ctx context.Context, cb *circuit_breaker.CircuitBreaker, req func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	handled := false
+	var start time.Time
+	result, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+		handled = true
+		start = time.Now()
+		return req(ctx)
+	})
+
+	name := "name:" + cb.Name()
+	if !handled {
+		_ = e.client.Incr(e.prefix+"calls", e.with(name, "outcome:rejected"), 1)
+		_ = e.client.Incr(e.prefix+"rejections", e.with(name, "reason:"+reason(err)), 1)
+		return result, err
+	}
+
+	outcome := "outcome:success"
+	if err != nil {
+		outcome = "outcome:failure"
+	}
+	_ = e.client.Incr(e.prefix+"calls", e.with(name, outcome), 1)
+	_ = e.client.Timing(e.prefix+"duration", time.Since(start), e.with(name, outcome), 1)
+
+	return result, err
+}
+
+// Watch emits the state changes of the breaker until stop is called.
+func (e *Emitter) Watch(cb *circuit_breaker.CircuitBreaker) (stop func()) {
+	_ = e.client.Gauge(e.prefix+"state", float64(cb.State()), e.with("name:"+cb.Name()), 1)
+
+	return cb.Subscribe(func(name string, from, to circuit_breaker.State) {
+		_ = e.client.Incr(e.prefix+"transitions", e.with("name:"+name, "from:"+from.String(), "to:"+to.String()), 1)
+		_ = e.client.Gauge(e.prefix+"state", float64(to), e.with("name:"+name), 1)
+		_ = e.client.Event(&statsd.Event{
+			Title:          fmt.Sprintf("Circuit breaker %s is %s", name, to),
+			Text:           fmt.Sprintf("Circuit breaker %s changed its state from %s to %s.", name, from, to),
+			AggregationKey: e.prefix + name,
+			AlertType:      alertType(to),
+			Tags:           e.with("name:"+name, "from:"+from.String(), "to:"+to.String()),
+		})
+	})
+}
+
+func (e *Emitter) with(tags ...string) []string {
+	return append(append(make([]string, 0, len(e.tags)+len(tags)), e.tags...), tags...)
+}
+
+func alertType(state circuit_breaker.State) statsd.EventAlertType {
+	switch state {
+	case circuit_breaker.StateOpen:
+		return statsd.Error
+	case circuit_breaker.StateHalfOpen:
+		return statsd.Warning
+	default:
+		return statsd.Success
+	}
+}
+
+// reason returns the tag value of the rejection error
+func reason(err error) string {
+	switch {
+	case errors.Is(err, circuit_breaker.ErrOpenState):
+		return "open"
+	case errors.Is(err, circuit_breaker.ErrTooManyRequests):
+		return "too_many_requests"
+	case errors.Is(err, circuit_breaker.ErrBulkheadFull):
+		return "bulkhead_full"
+	case errors.Is(err, circuit_breaker.ErrLimitExceeded):
+		return "limit_exceeded"
+	case errors.Is(err, circuit_breaker.ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, circuit_breaker.ErrLoadShed):
+		return "load_shed"
+	case errors.Is(err, circuit_breaker.ErrInsufficientDeadline):
+		return "insufficient_deadline"
+	default:
+		return "other"
+	}
+}
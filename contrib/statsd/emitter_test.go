@@ -0,0 +1,88 @@
+package statsdbreaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+type metric struct {
+	name  string
+	value float64
+	tags  []string
+}
+
+type fakeClient struct {
+	mu      sync.Mutex
+	counts  []metric
+	gauges  []metric
+	timings []metric
+	events  []*statsd.Event
+}
+
+func (c *fakeClient) Incr(name string, tags []string, rate float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts = append(c.counts, metric{name: name, value: 1, tags: tags})
+	return nil
+}
+
+func (c *fakeClient) Gauge(name string, value float64, tags []string, rate float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gauges = append(c.gauges, metric{name: name, value: value, tags: tags})
+	return nil
+}
+
+func (c *fakeClient) Timing(name string, value time.Duration, tags []string, rate float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timings = append(c.timings, metric{name: name, value: value.Seconds(), tags: tags})
+	return nil
+}
+
+func (c *fakeClient) Event(e *statsd.Event) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, e)
+	return nil
+}
+
+func TestEmitter(t *testing.T) {
+	client := &fakeClient{}
+	e := NewEmitter(client, "", "env:test")
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{Name: "payments", MaxConsecutiveFailures: 1})
+	stop := e.Watch(cb)
+	defer stop()
+	ctx := context.Background()
+
+	_, _ = e.ExecuteContext(ctx, cb, func(ctx context.Context) (interface{}, error) { return nil, nil })
+	_, _ = e.ExecuteContext(ctx, cb, func(ctx context.Context) (interface{}, error) { return nil, errors.New("unavailable") })
+	_, err := e.ExecuteContext(ctx, cb, func(ctx context.Context) (interface{}, error) { return nil, nil })
+	assert.Equal(t, circuit_breaker.ErrOpenState, err)
+
+	assert.Equal(t, []metric{
+		{name: "circuit_breaker.calls", value: 1, tags: []string{"env:test", "name:payments", "outcome:success"}},
+		{name: "circuit_breaker.transitions", value: 1, tags: []string{"env:test", "name:payments", "from:closed", "to:open"}},
+		{name: "circuit_breaker.calls", value: 1, tags: []string{"env:test", "name:payments", "outcome:failure"}},
+		{name: "circuit_breaker.calls", value: 1, tags: []string{"env:test", "name:payments", "outcome:rejected"}},
+		{name: "circuit_breaker.rejections", value: 1, tags: []string{"env:test", "name:payments", "reason:open"}},
+	}, client.counts)
+	assert.Equal(t, []metric{
+		{name: "circuit_breaker.state", value: 0, tags: []string{"env:test", "name:payments"}},
+		{name: "circuit_breaker.state", value: 1, tags: []string{"env:test", "name:payments"}},
+	}, client.gauges)
+
+	assert.Len(t, client.timings, 2)
+	assert.Equal(t, []string{"env:test", "name:payments", "outcome:failure"}, client.timings[1].tags)
+
+	assert.Len(t, client.events, 1)
+	assert.Equal(t, "Circuit breaker payments is open", client.events[0].Title)
+	assert.Equal(t, statsd.Error, client.events[0].AlertType)
+}
@@ -0,0 +1,88 @@
+// Package tdigestbreaker records the latencies of circuit breakers into t-digests.
+package tdigestbreaker
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/influxdata/tdigest"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+// Config configures Histogram.
+//
+// Compression trades the accuracy of the quantiles for memory.
+// If Compression is zero, the default of the t-digest library is used.
+//
+// Bounds are the upper bounds of the buckets in the snapshots, circuit_breaker.DefaultHistogramBounds by default.
+type Config struct {
+	Compression float64
+	Bounds      []time.Duration
+}
+
+// Histogram is a circuit_breaker.Histogram backed by a t-digest,
+// which keeps the extreme quantiles accurate in a small, bounded amount of memory.
+type Histogram struct {
+	bounds []time.Duration
+
+	mu     sync.Mutex
+	digest *tdigest.TDigest
+	count  uint64
+	sum    time.Duration
+}
+
+// New creates the Histogram.
+func New(cfg Config) *Histogram {
+	if len(cfg.Bounds) == 0 {
+		cfg.Bounds = circuit_breaker.DefaultHistogramBounds
+	}
+
+	digest := tdigest.New()
+	if cfg.Compression > 0 {
+		digest = tdigest.NewWithCompression(cfg.Compression)
+	}
+
+	return &Histogram{bounds: cfg.Bounds, digest: digest}
+}
+
+func (h *Histogram) Record(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.digest.Add(float64(d), 1)
+	h.count++
+	h.sum += d
+}
+
+func (h *Histogram) Quantile(q float64) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.count == 0 {
+		return 0
+	}
+
+	return time.Duration(h.digest.Quantile(q))
+}
+
+// Snapshot estimates the number of the recorded durations up to each bound from the distribution of the digest.
+func (h *Histogram) Snapshot() circuit_breaker.HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := circuit_breaker.HistogramSnapshot{
+		Count:   h.count,
+		Sum:     h.sum,
+		Buckets: make([]circuit_breaker.HistogramBucket, len(h.bounds)),
+	}
+	for i, bound := range h.bounds {
+		var n uint64
+		if h.count > 0 {
+			n = uint64(math.Round(h.digest.CDF(float64(bound)) * float64(h.count)))
+		}
+		s.Buckets[i] = circuit_breaker.HistogramBucket{UpperBound: bound, Count: n}
+	}
+
+	return s
+}
@@ -0,0 +1,38 @@
+package tdigestbreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogram(t *testing.T) {
+	h := New(Config{Bounds: []time.Duration{10 * time.Millisecond, 100 * time.Millisecond, time.Second}})
+	assert.Equal(t, time.Duration(0), h.Quantile(0.99))
+	assert.Equal(t, uint64(0), h.Snapshot().Buckets[0].Count)
+
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * 100 * time.Microsecond)
+	}
+
+	assert.InEpsilon(t, float64(50*time.Millisecond), float64(h.Quantile(0.5)), 0.01)
+	assert.InEpsilon(t, float64(99*time.Millisecond), float64(h.Quantile(0.99)), 0.01)
+
+	s := h.Snapshot()
+	assert.Equal(t, uint64(1000), s.Count)
+	assert.Equal(t, 50050*time.Millisecond, s.Sum)
+	assert.InDelta(t, 100, s.Buckets[0].Count, 2)
+	assert.Equal(t, uint64(1000), s.Buckets[1].Count)
+	assert.Equal(t, uint64(1000), s.Buckets[2].Count)
+}
+
+func TestHistogramCircuitBreaker(t *testing.T) {
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{LatencyHistogram: New(Config{Compression: 100})})
+	_, err := cb.Execute(func() (interface{}, error) { return nil, nil })
+	assert.Nil(t, err)
+
+	assert.Equal(t, uint64(1), cb.Snapshot().Latency.Count)
+	assert.Greater(t, cb.LatencyHistogram().Quantile(0.5), time.Duration(0))
+}
@@ -0,0 +1,47 @@
+// Package temporalbreaker protects the calls made by Temporal activities with circuit breakers.
+package temporalbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"go.temporal.io/sdk/temporal"
+)
+
+// ErrorType is the type of the application errors returned for the calls rejected by the breaker,
+// which can be listed in the NonRetryableErrorTypes of a retry policy to fail the activity at once instead.
+const ErrorType = "CircuitBreakerRejected"
+
+// Execute runs the call of an activity to an external service through the breaker.
+//
+// A rejected call fails with a retryable application error of ErrorType wrapping the rejection error.
+// If the breaker is open, the error asks the server to retry the activity once the open timeout expires,
+// rather than according to the backoff of the retry policy, so the attempts don't pile up against a dead dependency.
+func Execute(ctx context.Context, cb *circuit_breaker.CircuitBreaker, call func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	handled := false
+	result, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+		handled = true
+		return call(ctx)
+	})
+	if !handled && ctx.Err() == nil {
+		return nil, Rejection(cb, err)
+	}
+
+	return result, err
+}
+
+// Rejection converts the rejection error of the breaker into a retryable application error.
+func Rejection(cb *circuit_breaker.CircuitBreaker, err error) error {
+	opts := temporal.ApplicationErrorOptions{Cause: err}
+	if errors.Is(err, circuit_breaker.ErrOpenState) {
+		opts.NextRetryDelay = cb.RemainingOpenTime()
+	}
+
+	return temporal.NewApplicationErrorWithOptions(
+		fmt.Sprintf("rejected by circuit breaker %q: %v", cb.Name(), err),
+		ErrorType,
+		opts,
+	)
+}
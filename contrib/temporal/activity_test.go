@@ -0,0 +1,41 @@
+package temporalbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestExecute(t *testing.T) {
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{
+		Name:                   "payments",
+		MaxConsecutiveFailures: 1,
+		Timeout:                time.Minute,
+	})
+	errUnavailable := errors.New("unavailable")
+	ctx := context.Background()
+
+	_, err := Execute(ctx, cb, func(ctx context.Context) (interface{}, error) {
+		return nil, errUnavailable
+	})
+	assert.Equal(t, errUnavailable, err)
+
+	calls := 0
+	_, err = Execute(ctx, cb, func(ctx context.Context) (interface{}, error) {
+		calls++
+		return "charged", nil
+	})
+	assert.Equal(t, 0, calls)
+	assert.True(t, errors.Is(err, circuit_breaker.ErrOpenState))
+
+	var appErr *temporal.ApplicationError
+	assert.True(t, errors.As(err, &appErr))
+	assert.Equal(t, ErrorType, appErr.Type())
+	assert.False(t, appErr.NonRetryable())
+	assert.Greater(t, appErr.NextRetryDelay(), 59*time.Second)
+}
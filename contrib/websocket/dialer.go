@@ -0,0 +1,131 @@
+// Package wsbreaker protects gorilla/websocket connection attempts with circuit breakers.
+package wsbreaker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/shirokovnv/circuit_breaker"
+)
+
+const defaultRetryDelay = 100 * time.Millisecond
+
+// Dialer admits the connection attempts through a breaker per host.
+type Dialer struct {
+	// Dialer is the underlying dialer. If Dialer is nil, websocket.DefaultDialer is used.
+	Dialer *websocket.Dialer
+	// Breakers holds a breaker per host, created lazily.
+	Breakers *circuit_breaker.KeyedBreaker
+	// Classifier maps the status of a failed handshake to its outcome.
+	// The zero value treats 5xx and 429 as failures.
+	Classifier circuit_breaker.HTTPClassifier
+	// RetryDelay is the pause of Redial after a rejection in the half-open state,
+	// when another attempt is probing the host. If RetryDelay is zero, 100ms is used.
+	RetryDelay time.Duration
+}
+
+// NewDialer creates a Dialer with a breaker per host.
+func NewDialer(dialer *websocket.Dialer, cfg circuit_breaker.KeyedConfig) *Dialer {
+	return &Dialer{
+		Dialer:   dialer,
+		Breakers: circuit_breaker.NewKeyedBreaker(cfg),
+	}
+}
+
+// DialContext connects to the URL, see websocket.Dialer.DialContext.
+// Attempts rejected by the breaker fail with its rejection error, e.g. circuit_breaker.ErrOpenState.
+func (d *Dialer) DialContext(ctx context.Context, urlStr string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	cb, err := d.breaker(urlStr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return d.dial(ctx, cb, urlStr, header)
+}
+
+// Redial connects to the URL, retrying until it succeeds or the context is done.
+// The reconnect storm is paced by the breaker of the host rather than by a backoff loop:
+// failed attempts are retried at once until the breaker opens, and then Redial waits for its open timeout,
+// which follows the Policy of the breaker.
+func (d *Dialer) Redial(ctx context.Context, urlStr string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	cb, err := d.breaker(urlStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	retryDelay := d.RetryDelay
+	if retryDelay == 0 {
+		retryDelay = defaultRetryDelay
+	}
+
+	for {
+		conn, resp, err := d.dial(ctx, cb, urlStr, header)
+		if err == nil || ctx.Err() != nil {
+			return conn, resp, err
+		}
+		if errors.Is(err, websocket.ErrBadHandshake) && resp != nil && d.Classifier.ClassifyStatus(resp.StatusCode) == nil {
+			// the server refused the connection, retrying won't help
+			return nil, resp, err
+		}
+
+		var waitErr error
+		switch {
+		case errors.Is(err, circuit_breaker.ErrOpenState):
+			waitErr = cb.Wait(ctx)
+		case errors.Is(err, circuit_breaker.ErrTooManyRequests):
+			waitErr = sleep(ctx, retryDelay)
+		}
+		if waitErr != nil {
+			return nil, nil, waitErr
+		}
+	}
+}
+
+func (d *Dialer) breaker(urlStr string) (*circuit_breaker.CircuitBreaker, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Breakers.Get(u.Host), nil
+}
+
+func (d *Dialer) dial(ctx context.Context, cb *circuit_breaker.CircuitBreaker, urlStr string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	dialer := d.Dialer
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
+
+	// every caller dials its own connection, the breaker only admits the dial and records its outcome
+	done, err := cb.Allow(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, resp, err := dialer.DialContext(ctx, urlStr, header)
+	if errors.Is(err, websocket.ErrBadHandshake) && resp != nil {
+		done(d.Classifier.ClassifyStatus(resp.StatusCode))
+	} else {
+		done(err)
+	}
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return conn, resp, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,131 @@
+package wsbreaker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+// serve starts a websocket server answering the first failures handshakes with the status
+func serve(t *testing.T, failures int32, status int) (string, *int32) {
+	var handshakes int32
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&handshakes, 1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return "ws" + strings.TrimPrefix(server.URL, "http"), &handshakes
+}
+
+func TestDialerDialContext(t *testing.T) {
+	url, handshakes := serve(t, 10, http.StatusServiceUnavailable)
+	dialer := NewDialer(nil, circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{MaxConsecutiveFailures: 2},
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, resp, err := dialer.DialContext(ctx, url, nil)
+		assert.Equal(t, websocket.ErrBadHandshake, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	_, resp, err := dialer.DialContext(ctx, url, nil)
+	assert.Equal(t, circuit_breaker.ErrOpenState, err)
+	assert.Nil(t, resp)
+	assert.Equal(t, int32(2), atomic.LoadInt32(handshakes))
+}
+
+func TestDialerDialContextConcurrent(t *testing.T) {
+	url, handshakes := serve(t, 0, http.StatusOK)
+	dialer := NewDialer(nil, circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{CoalesceHalfOpen: true},
+	})
+
+	// every caller gets its own connection
+	conns := make(chan *websocket.Conn, 5)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(conns); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, _, err := dialer.DialContext(context.Background(), url, nil)
+			assert.Nil(t, err)
+			conns <- conn
+		}()
+	}
+	wg.Wait()
+	close(conns)
+
+	seen := make(map[*websocket.Conn]bool)
+	for conn := range conns {
+		assert.NotNil(t, conn)
+		assert.False(t, seen[conn])
+		seen[conn] = true
+		conn.Close()
+	}
+	assert.Equal(t, int32(5), atomic.LoadInt32(handshakes))
+}
+
+func TestDialerRedial(t *testing.T) {
+	url, handshakes := serve(t, 3, http.StatusBadGateway)
+	dialer := NewDialer(nil, circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{
+			RequestThreshold:       1,
+			MaxConsecutiveFailures: 2,
+			Timeout:                20 * time.Millisecond,
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	conn, _, err := dialer.Redial(ctx, url, nil)
+	assert.Nil(t, err)
+	conn.Close()
+
+	// two failures open the breaker, the probe after the timeout fails and reopens it, the next one succeeds
+	assert.Equal(t, int32(4), atomic.LoadInt32(handshakes))
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestDialerRedialRefused(t *testing.T) {
+	url, handshakes := serve(t, 10, http.StatusForbidden)
+	dialer := NewDialer(nil, circuit_breaker.KeyedConfig{})
+
+	_, resp, err := dialer.Redial(context.Background(), url, nil)
+	assert.Equal(t, websocket.ErrBadHandshake, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(handshakes))
+}
+
+func TestDialerRedialContext(t *testing.T) {
+	url, _ := serve(t, 1000, http.StatusServiceUnavailable)
+	dialer := NewDialer(nil, circuit_breaker.KeyedConfig{
+		Config: circuit_breaker.Config{MaxConsecutiveFailures: 1},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, _, err := dialer.Redial(ctx, url, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
@@ -0,0 +1,155 @@
+// Package yamlbreaker loads the configuration of circuit breakers from YAML manifests.
+package yamlbreaker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest is the declarative definition of the breakers of a service, e.g. checked into its config repository:
+//
+//	templates:
+//	  third-party-api:
+//	    timeout: 30s
+//	    request_threshold: 1
+//	    ready_to_trip: {policy: failure_rate, failure_rate: 0.5, min_requests: 20}
+//	breakers:
+//	  payments:
+//	    template: third-party-api
+//	    labels: {tier: critical}
+//	    critical: true
+//	  search:
+//	    max_consecutive_failures: 3
+//
+// The settings are the ones of circuit_breaker.ConfigFromJSON.
+// A breaker with a template has the settings of the template, replaced by its own top-level settings.
+// The names of the breakers replace their name settings.
+type Manifest struct {
+	Templates map[string]circuit_breaker.Config
+	Breakers  map[string]circuit_breaker.Config
+}
+
+type manifestFile struct {
+	Templates map[string]map[string]interface{} `yaml:"templates"`
+	Breakers  map[string]map[string]interface{} `yaml:"breakers"`
+}
+
+// ConfigFromYAML decodes the Config of one breaker from YAML, see circuit_breaker.ConfigFromJSON.
+func ConfigFromYAML(data []byte) (circuit_breaker.Config, error) {
+	var settings map[string]interface{}
+	if err := decode(data, &settings); err != nil {
+		return circuit_breaker.Config{}, fmt.Errorf("circuit breaker config: %w", err)
+	}
+
+	return configOf(settings)
+}
+
+// ParseManifest decodes and validates the Manifest.
+// It returns an error for unknown fields, invalid settings and unknown templates.
+func ParseManifest(data []byte) (Manifest, error) {
+	var f manifestFile
+	if err := decode(data, &f); err != nil {
+		return Manifest{}, fmt.Errorf("circuit breaker manifest: %w", err)
+	}
+
+	m := Manifest{
+		Templates: make(map[string]circuit_breaker.Config, len(f.Templates)),
+		Breakers:  make(map[string]circuit_breaker.Config, len(f.Breakers)),
+	}
+	for _, name := range sortedKeys(f.Templates) {
+		cfg, err := configOf(f.Templates[name])
+		if err != nil {
+			return Manifest{}, fmt.Errorf("circuit breaker template %q: %w", name, err)
+		}
+		m.Templates[name] = cfg
+	}
+	for _, name := range sortedKeys(f.Breakers) {
+		settings, err := f.resolve(f.Breakers[name])
+		if err != nil {
+			return Manifest{}, fmt.Errorf("circuit breaker %q: %w", name, err)
+		}
+		settings["name"] = name
+		cfg, err := configOf(settings)
+		if err != nil {
+			return Manifest{}, fmt.Errorf("circuit breaker %q: %w", name, err)
+		}
+		m.Breakers[name] = cfg
+	}
+
+	return m, nil
+}
+
+// Load parses the manifest, registers its templates in the Registry and creates its breakers,
+// see circuit_breaker.Registry.RegisterTemplate and circuit_breaker.Registry.GetOrCreate.
+// Nothing is registered if the manifest is invalid.
+func Load(r *circuit_breaker.Registry, data []byte) error {
+	m, err := ParseManifest(data)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range sortedKeys(m.Templates) {
+		r.RegisterTemplate(name, m.Templates[name])
+	}
+	for _, name := range sortedKeys(m.Breakers) {
+		r.GetOrCreate(name, m.Breakers[name])
+	}
+
+	return nil
+}
+
+// resolve returns the settings of the breaker merged into the settings of its template
+func (f manifestFile) resolve(settings map[string]interface{}) (map[string]interface{}, error) {
+	resolved := make(map[string]interface{}, len(settings))
+	if v, ok := settings["template"]; ok {
+		name, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("template must be a string, got %v", v)
+		}
+		template, ok := f.Templates[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown circuit breaker template %q", name)
+		}
+		for k, v := range template {
+			resolved[k] = v
+		}
+	}
+	for k, v := range settings {
+		if k != "template" {
+			resolved[k] = v
+		}
+	}
+
+	return resolved, nil
+}
+
+// configOf validates the settings by circuit_breaker.ConfigFromJSON
+func configOf(settings map[string]interface{}) (circuit_breaker.Config, error) {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return circuit_breaker.Config{}, err
+	}
+
+	return circuit_breaker.ConfigFromJSON(data)
+}
+
+func decode(data []byte, v interface{}) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	return dec.Decode(v)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
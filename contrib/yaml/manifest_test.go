@@ -0,0 +1,86 @@
+package yamlbreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+const manifest = `
+templates:
+  third-party-api:
+    timeout: 30s
+    request_threshold: 1
+    ready_to_trip: {policy: failure_rate, failure_rate: 0.5, min_requests: 20}
+    labels: {tier: optional}
+breakers:
+  payments:
+    template: third-party-api
+    labels: {tier: critical}
+    critical: true
+  search:
+    max_consecutive_failures: 3
+`
+
+func TestParseManifest(t *testing.T) {
+	m, err := ParseManifest([]byte(manifest))
+	assert.Nil(t, err)
+
+	template := m.Templates["third-party-api"]
+	assert.Equal(t, 30*time.Second, template.Timeout)
+	assert.Equal(t, map[string]string{"tier": "optional"}, template.Labels)
+
+	payments := m.Breakers["payments"]
+	assert.Equal(t, "payments", payments.Name)
+	assert.Equal(t, 30*time.Second, payments.Timeout)
+	assert.Equal(t, uint32(1), payments.RequestThreshold)
+	assert.Equal(t, map[string]string{"tier": "critical"}, payments.Labels)
+	assert.True(t, payments.Critical)
+	assert.True(t, payments.ReadyToTrip(circuit_breaker.Counts{Requests: 20, TotalFailures: 10}))
+
+	search := m.Breakers["search"]
+	assert.Equal(t, uint32(3), search.MaxConsecutiveFailures)
+	assert.Nil(t, search.ReadyToTrip)
+}
+
+func TestLoad(t *testing.T) {
+	r := circuit_breaker.NewRegistry(circuit_breaker.RegistryConfig{})
+	assert.Nil(t, Load(r, []byte(manifest)))
+
+	assert.Equal(t, []string{"payments", "search"}, r.Names())
+	payments, _ := r.Get("payments")
+	assert.True(t, payments.Critical())
+	assert.Equal(t, map[string]string{"tier": "critical"}, payments.Labels())
+
+	cb, err := r.GetOrCreateFrom("geocoding", "third-party-api")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"tier": "optional"}, cb.Labels())
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for _, data := range []string{
+		"breakers:\n  payments:\n    template: missing\n",
+		"breakers:\n  payments:\n    template: [a]\n",
+		"breakers:\n  payments:\n    timeout: 30\n",
+		"breakers:\n  payments:\n    time_out: 30s\n",
+		"templates:\n  api:\n    ready_to_trip: {policy: sometimes}\n",
+		"breaker:\n  payments: {}\n",
+	} {
+		r := circuit_breaker.NewRegistry(circuit_breaker.RegistryConfig{})
+		assert.NotNil(t, Load(r, []byte(data)), data)
+		assert.Empty(t, r.Names(), data)
+	}
+}
+
+func TestConfigFromYAML(t *testing.T) {
+	cfg, err := ConfigFromYAML([]byte("name: payments\ntimeout: 1m\nbulkhead: {max_concurrent: 10}\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "payments", cfg.Name)
+	assert.Equal(t, time.Minute, cfg.Timeout)
+	assert.Equal(t, uint32(10), cfg.Bulkhead.MaxConcurrent)
+
+	_, err = ConfigFromYAML([]byte("timeout: [1m]\n"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,33 @@
+// Package zapbreaker adapts zap loggers to circuit_breaker.Logger.
+package zapbreaker
+
+import (
+	"github.com/shirokovnv/circuit_breaker"
+	"go.uber.org/zap"
+)
+
+type logger struct {
+	sugar *zap.SugaredLogger
+}
+
+// Logger returns a circuit_breaker.Logger writing structured entries to the zap logger,
+// with the "component" field set to "circuit_breaker".
+func Logger(l *zap.Logger) circuit_breaker.Logger {
+	return &logger{sugar: l.Sugar().With("component", "circuit_breaker")}
+}
+
+func (l *logger) Debug(msg string, keysAndValues ...interface{}) {
+	l.sugar.Debugw(msg, keysAndValues...)
+}
+
+func (l *logger) Info(msg string, keysAndValues ...interface{}) {
+	l.sugar.Infow(msg, keysAndValues...)
+}
+
+func (l *logger) Warn(msg string, keysAndValues ...interface{}) {
+	l.sugar.Warnw(msg, keysAndValues...)
+}
+
+func (l *logger) Error(msg string, keysAndValues ...interface{}) {
+	l.sugar.Errorw(msg, keysAndValues...)
+}
@@ -0,0 +1,40 @@
+package zapbreaker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shirokovnv/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	cb := circuit_breaker.NewCircuitBreaker(circuit_breaker.Config{
+		Name:                   "payments",
+		MaxConsecutiveFailures: 1,
+		Logger:                 Logger(zap.New(core)),
+	})
+
+	_, _ = cb.Execute(func() (interface{}, error) { return nil, errors.New("unavailable") })
+	_, _ = cb.Execute(func() (interface{}, error) { return nil, nil })
+
+	entries := logs.AllUntimed()
+	assert.Len(t, entries, 2)
+
+	assert.Equal(t, zapcore.WarnLevel, entries[0].Level)
+	assert.Equal(t, "circuit breaker state changed", entries[0].Message)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "circuit_breaker", fields["component"])
+	assert.Equal(t, "payments", fields["name"])
+	assert.Equal(t, "open", fields["to"])
+	assert.Equal(t, circuit_breaker.ReasonTripped, fields["reason"])
+	assert.Equal(t, uint32(1), fields["failures"])
+
+	assert.Equal(t, zapcore.DebugLevel, entries[1].Level)
+	assert.Equal(t, "circuit breaker rejected request", entries[1].Message)
+	assert.Equal(t, "circuit breaker is open", entries[1].ContextMap()["error"])
+}
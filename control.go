@@ -0,0 +1,28 @@
+package circuit_breaker
+
+// Trip forces the CircuitBreaker into the open state, e.g. by an operator during an incident.
+// The breaker becomes half-open once the open period is over, as if it had tripped by itself.
+// Tripping an open breaker does nothing. Use Apply to record who tripped the breaker and why.
+func (cb *CircuitBreaker) Trip() {
+	_ = cb.Apply(ActionTrip, "", "")
+}
+
+// Disable passes every request through without accounting until Enable is called,
+// like MaintenanceDisable but without an end.
+// The state of the CircuitBreaker is kept as it was.
+func (cb *CircuitBreaker) Disable() {
+	_ = cb.Apply(ActionDisable, "", "")
+}
+
+// Enable restores the normal operation of the CircuitBreaker disabled by Disable.
+func (cb *CircuitBreaker) Enable() {
+	_ = cb.Apply(ActionEnable, "", "")
+}
+
+// Disabled reports whether the CircuitBreaker was disabled by Disable.
+func (cb *CircuitBreaker) Disabled() bool {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	return cb.disabled
+}
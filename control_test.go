@@ -0,0 +1,44 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerTrip(t *testing.T) {
+	cb := NewCircuitBreaker(Config{HistorySize: 2, Timeout: time.Minute})
+
+	cb.Trip()
+	assert.Equal(t, StateOpen, cb.State())
+	assert.Equal(t, ErrOpenState, succeed(cb))
+	assert.Equal(t, ReasonManualTrip, cb.History()[0].Reason)
+
+	// tripping again doesn't extend the open period
+	remaining := cb.RemainingOpenTime()
+	cb.Trip()
+	assert.LessOrEqual(t, cb.RemainingOpenTime(), remaining)
+	assert.Len(t, cb.History(), 1)
+}
+
+func TestCircuitBreakerDisable(t *testing.T) {
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 1})
+	assert.False(t, cb.Disabled())
+
+	cb.Disable()
+	assert.True(t, cb.Disabled())
+	assert.True(t, cb.Snapshot().Disabled)
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, Counts{}, cb.Counts())
+
+	// the state is kept while disabled
+	cb.Trip()
+	assert.Nil(t, succeed(cb))
+
+	cb.Enable()
+	assert.False(t, cb.Disabled())
+	assert.Equal(t, ErrOpenState, succeed(cb))
+}
@@ -0,0 +1,36 @@
+package circuit_breaker
+
+import "context"
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a copy of the context carrying the correlation ID of the requests made by ExecuteContext,
+// e.g. a request or trace ID. The ID is attached to the events and the log entries of the requests,
+// so they can be joined with the traces of the callers.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationIDFromContext returns the correlation ID of the context, or an empty string if it has none.
+func CorrelationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// correlationIDOf extracts the correlation ID of the request context
+func (cb *CircuitBreaker) correlationIDOf(ctx context.Context) string {
+	if cb.correlationID != nil {
+		return cb.correlationID(ctx)
+	}
+
+	return CorrelationIDFromContext(ctx)
+}
+
+// withCorrelationID appends the correlation ID to the fields of the log entry, if there is one
+func withCorrelationID(keysAndValues []interface{}, id string) []interface{} {
+	if id == "" {
+		return keysAndValues
+	}
+
+	return append(keysAndValues, "correlation_id", id)
+}
@@ -0,0 +1,66 @@
+package circuit_breaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorrelationID(t *testing.T) {
+	var events []Event
+	logger := &recordingLogger{}
+	cb := NewCircuitBreaker(Config{
+		Name:                   "payments",
+		MaxConsecutiveFailures: 1,
+		RequestThreshold:       1,
+		Timeout:                10 * time.Millisecond,
+		Logger:                 logger,
+		OnEvent:                func(e Event) { events = append(events, e) },
+	})
+	ctx := WithCorrelationID(context.Background(), "req-1")
+	assert.Equal(t, "req-1", CorrelationIDFromContext(ctx))
+	assert.Equal(t, "", CorrelationIDFromContext(context.Background()))
+
+	_, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) { return nil, errServiceError })
+	assert.Equal(t, errServiceError, err)
+	_, err = cb.ExecuteContext(WithCorrelationID(ctx, "req-2"), func(ctx context.Context) (interface{}, error) { return nil, nil })
+	assert.Equal(t, ErrOpenState, err)
+	time.Sleep(20 * time.Millisecond)
+	_, err = cb.ExecuteContext(WithCorrelationID(ctx, "req-3"), func(ctx context.Context) (interface{}, error) { return nil, nil })
+	assert.Nil(t, err)
+
+	assert.Equal(t, "req-1", events[1].(CallFailed).CorrelationID)
+	assert.Equal(t, "req-2", events[2].(CallRejected).CorrelationID)
+	assert.Equal(t, "req-3", events[5].(ProbeResult).CorrelationID)
+	assert.Contains(t, logger.entries, "DEBUG circuit breaker rejected request name=payments state=open error=circuit breaker is open correlation_id=req-2")
+	assert.Contains(t, logger.entries, "INFO circuit breaker probe succeeded name=payments correlation_id=req-3")
+
+	// the requests without the ID are logged without it
+	cb.Trip()
+	assert.Equal(t, ErrOpenState, succeed(cb))
+	assert.Contains(t, logger.entries, "DEBUG circuit breaker rejected request name=payments state=open error=circuit breaker is open")
+}
+
+func TestCorrelationIDExtractor(t *testing.T) {
+	type traceKey struct{}
+	var rejected CallRejected
+	cb := NewCircuitBreaker(Config{
+		CorrelationID: func(ctx context.Context) string {
+			id, _ := ctx.Value(traceKey{}).(string)
+			return id
+		},
+		OnEvent: func(e Event) {
+			if r, ok := e.(CallRejected); ok {
+				rejected = r
+			}
+		},
+	})
+	cb.Trip()
+
+	ctx := context.WithValue(context.Background(), traceKey{}, "4bf92f3577b34da6a3ce929d0e0e4736")
+	_, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) { return nil, nil })
+	assert.Equal(t, ErrOpenState, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", rejected.CorrelationID)
+}
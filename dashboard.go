@@ -0,0 +1,100 @@
+package circuit_breaker
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// defaultStreamInterval is the interval of the dashboard updates if AdminConfig.StreamInterval is zero
+const defaultStreamInterval = time.Second
+
+// dashboardBreaker is the state of a breaker streamed to the dashboard
+type dashboardBreaker struct {
+	Snapshot    Snapshot
+	Transitions []dashboardTransition
+}
+
+// dashboardTransition is a Transition with the error encoded as a string
+type dashboardTransition struct {
+	At        time.Time
+	From      State
+	To        State
+	Reason    string
+	LastError string `json:",omitempty"`
+}
+
+func serveDashboard(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(dashboardHTML)
+}
+
+// streamDashboard sends the state of the breakers as server-sent events every interval,
+// until the client disconnects.
+func streamDashboard(w http.ResponseWriter, r *http.Request, cfg AdminConfig) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAdminError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	interval := cfg.StreamInterval
+	if interval <= 0 {
+		interval = defaultStreamInterval
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		data, err := json.Marshal(dashboardState(cfg.Breakers()))
+		if err != nil {
+			return
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func dashboardState(cbs []*CircuitBreaker) []dashboardBreaker {
+	snapshots := SnapshotAll(cbs...).Breakers
+	byName := make(map[string]*CircuitBreaker, len(cbs))
+	for _, cb := range cbs {
+		byName[cb.name] = cb
+	}
+
+	state := make([]dashboardBreaker, 0, len(snapshots))
+	for _, s := range snapshots {
+		history := byName[s.Name].History()
+		transitions := make([]dashboardTransition, 0, len(history))
+		for _, t := range history {
+			dt := dashboardTransition{At: t.At, From: t.From, To: t.To, Reason: t.Reason}
+			if t.LastError != nil {
+				dt.LastError = t.LastError.Error()
+			}
+			transitions = append(transitions, dt)
+		}
+		state = append(state, dashboardBreaker{Snapshot: s, Transitions: transitions})
+	}
+
+	return state
+}
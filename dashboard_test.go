@@ -0,0 +1,68 @@
+package circuit_breaker
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminHandlerDashboard(t *testing.T) {
+	h := AdminHandler(AdminConfig{Breakers: func() []*CircuitBreaker { return nil }})
+
+	w := adminRequest(h, http.MethodGet, "/dashboard")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `new EventSource("stream")`)
+	assert.Equal(t, w.Body.String(), adminRequest(h, http.MethodGet, "/").Body.String())
+}
+
+func TestAdminHandlerStream(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "search", MaxConsecutiveFailures: 1, HistorySize: 10})
+	srv := httptest.NewServer(AdminHandler(AdminConfig{
+		Breakers:       func() []*CircuitBreaker { return []*CircuitBreaker{cb} },
+		StreamInterval: 10 * time.Millisecond,
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/stream")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := bufio.NewScanner(resp.Body)
+	next := func() []dashboardBreaker {
+		for events.Scan() {
+			if data := strings.TrimPrefix(events.Text(), "data: "); data != events.Text() {
+				var state []dashboardBreaker
+				assert.Nil(t, json.Unmarshal([]byte(data), &state))
+				return state
+			}
+		}
+		return nil
+	}
+
+	state := next()
+	assert.Len(t, state, 1)
+	assert.Equal(t, "search", state[0].Snapshot.Name)
+	assert.Equal(t, StateClosed, state[0].Snapshot.State)
+
+	assert.Equal(t, errServiceError, fail(cb))
+	for state[0].Snapshot.State != StateOpen {
+		state = next()
+	}
+	assert.Len(t, state[0].Transitions, 1)
+	assert.True(t, cb.History()[0].At.Equal(state[0].Transitions[0].At))
+	state[0].Transitions[0].At = time.Time{}
+	assert.Equal(t, dashboardTransition{
+		From:      StateClosed,
+		To:        StateOpen,
+		Reason:    ReasonTripped,
+		LastError: "service error",
+	}, state[0].Transitions[0])
+}
@@ -0,0 +1,56 @@
+package circuit_breaker
+
+import (
+	"context"
+	"net"
+)
+
+// Dialer protects connection establishment with an independent CircuitBreaker per target address,
+// failing fast with ErrOpenState instead of waiting for the connect timeout of a host known to be dead.
+// Only dialing is protected: the errors on established connections are not recorded.
+type Dialer struct {
+	// Base is the underlying dial function. If Base is nil, the DialContext of a zero net.Dialer is used.
+	Base func(ctx context.Context, network, address string) (net.Conn, error)
+	// Breakers holds a breaker per address, created lazily.
+	Breakers *KeyedBreaker
+}
+
+// NewDialer creates a Dialer with a breaker per target address.
+func NewDialer(base func(ctx context.Context, network, address string) (net.Conn, error), cfg KeyedConfig) *Dialer {
+	return &Dialer{
+		Base:     base,
+		Breakers: NewKeyedBreaker(cfg),
+	}
+}
+
+// DialContext connects to the address, see net.Dialer.DialContext.
+// It can be used as the DialContext of http.Transport or a database driver.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	base := d.Base
+	if base == nil {
+		var dialer net.Dialer
+		base = dialer.DialContext
+	}
+
+	// every caller dials its own connection, the breaker only admits the dial and records its outcome
+	done, err := d.Breakers.Get(address).Allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	returned := false
+	defer func() {
+		if !returned {
+			done(errPanic)
+		}
+	}()
+
+	conn, err := base(ctx, network, address)
+	returned = true
+	done(err)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,76 @@
+package circuit_breaker
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	alive := listener.Addr().String()
+
+	// the port of a closed listener refuses connections
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	dead := closed.Addr().String()
+	closed.Close()
+
+	dialer := NewDialer(nil, KeyedConfig{Config: Config{MaxConsecutiveFailures: 2}})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err = dialer.DialContext(ctx, "tcp", dead)
+		assert.NotNil(t, err)
+		assert.NotEqual(t, ErrOpenState, err)
+	}
+	_, err = dialer.DialContext(ctx, "tcp", dead)
+	assert.Equal(t, ErrOpenState, err)
+
+	// the other addresses are not affected
+	conn, err := dialer.DialContext(ctx, "tcp", alive)
+	assert.Nil(t, err)
+	conn.Close()
+	assert.ElementsMatch(t, []string{alive, dead}, dialer.Breakers.Keys())
+}
+
+func TestDialerConcurrent(t *testing.T) {
+	var dials int32
+	base := func(ctx context.Context, network, address string) (net.Conn, error) {
+		atomic.AddInt32(&dials, 1)
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	}
+	dialer := NewDialer(base, KeyedConfig{Config: Config{CoalesceHalfOpen: true}})
+
+	// every caller gets its own connection
+	conns := make(chan net.Conn, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(conns); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := dialer.DialContext(context.Background(), "tcp", "db:5432")
+			assert.Nil(t, err)
+			conns <- conn
+		}()
+	}
+	wg.Wait()
+	close(conns)
+
+	seen := make(map[net.Conn]bool)
+	for conn := range conns {
+		assert.NotNil(t, conn)
+		assert.False(t, seen[conn])
+		seen[conn] = true
+		conn.Close()
+	}
+	assert.Equal(t, int32(10), atomic.LoadInt32(&dials))
+}
@@ -0,0 +1,44 @@
+package circuit_breaker
+
+import "context"
+
+// InFlight returns the number of admitted requests that are still executing.
+func (cb *CircuitBreaker) InFlight() int {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	return cb.inFlight
+}
+
+// Drain waits until all the admitted requests in flight finish,
+// e.g. after the CircuitBreaker opens or during a graceful shutdown.
+// It returns the context error if the context is done first.
+// Drain doesn't prevent new requests from being admitted.
+func (cb *CircuitBreaker) Drain(ctx context.Context) error {
+	cb.mu.Lock()
+	if cb.inFlight == 0 {
+		cb.unlock()
+		return nil
+	}
+	if cb.drained == nil {
+		cb.drained = make(chan struct{})
+	}
+	drained := cb.drained
+	cb.unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// onDone is called under the lock whenever an admitted request finishes
+func (cb *CircuitBreaker) onDone() {
+	cb.inFlight--
+	if cb.inFlight == 0 && cb.drained != nil {
+		close(cb.drained)
+		cb.drained = nil
+	}
+}
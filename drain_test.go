@@ -0,0 +1,31 @@
+package circuit_breaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerDrain(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "draining circuit breaker"})
+	assert.Nil(t, cb.Drain(context.Background()))
+
+	release := make(chan struct{})
+	occupy(cb, release)
+	occupy(cb, release)
+	assert.Equal(t, 2, cb.InFlight())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, cb.Drain(ctx))
+
+	drained := make(chan error)
+	go func() { drained <- cb.Drain(context.Background()) }()
+
+	close(release)
+	assert.Nil(t, <-drained)
+	assert.Equal(t, 0, cb.InFlight())
+	assert.Equal(t, Counts{2, 2, 0, 2, 0}, cb.Counts())
+}
@@ -0,0 +1,93 @@
+package circuit_breaker
+
+import "time"
+
+// DualWindowConfig configures DualWindowPolicy.
+//
+// FastWindow is a short window (seconds) to react quickly to hard outages,
+// SlowWindow is a long window (minutes) to detect slow-burn degradation.
+//
+// ReadyToTrip is called with the Counts of both windows whenever a request fails in the closed state.
+// If ReadyToTrip returns true, the CircuitBreaker will be placed into the open state.
+//
+// RequestThreshold and Timeout have the same meaning as in DefaultPolicy.
+type DualWindowConfig struct {
+	FastWindow        time.Duration
+	FastWindowBuckets int
+	SlowWindow        time.Duration
+	SlowWindowBuckets int
+
+	ReadyToTrip func(fast, slow Counts) bool
+
+	RequestThreshold uint32
+	Timeout          time.Duration
+}
+
+// DualWindowPolicy is a Policy maintaining a fast and a slow rolling window simultaneously.
+// Only the requests made in the closed state are recorded,
+// and both windows are cleared when the CircuitBreaker closes.
+type DualWindowPolicy struct {
+	DefaultPolicy
+
+	fast        *Window
+	slow        *Window
+	readyToTrip func(fast, slow Counts) bool
+}
+
+func NewDualWindowPolicy(cfg DualWindowConfig) *DualWindowPolicy {
+	return &DualWindowPolicy{
+		DefaultPolicy: DefaultPolicy{
+			RequestThreshold: cfg.RequestThreshold,
+			Timeout:          cfg.Timeout,
+		},
+		fast:        NewWindow(cfg.FastWindow, cfg.FastWindowBuckets),
+		slow:        NewWindow(cfg.SlowWindow, cfg.SlowWindowBuckets),
+		readyToTrip: cfg.ReadyToTrip,
+	}
+}
+
+func (p *DualWindowPolicy) OnCall(state State, err error) {
+	if state != StateClosed {
+		return
+	}
+
+	now := time.Now()
+	p.fast.Record(now, err == nil)
+	p.slow.Record(now, err == nil)
+}
+
+func (p *DualWindowPolicy) ShouldTrip(counts Counts) bool {
+	if p.readyToTrip == nil {
+		return p.DefaultPolicy.ShouldTrip(counts)
+	}
+
+	now := time.Now()
+	return p.readyToTrip(p.fast.Counts(now), p.slow.Counts(now))
+}
+
+func (p *DualWindowPolicy) ShouldClose(counts Counts) bool {
+	if !p.DefaultPolicy.ShouldClose(counts) {
+		return false
+	}
+
+	p.fast.Reset()
+	p.slow.Reset()
+	return true
+}
+
+// ExportWindows implements WindowedPolicy, naming the windows "fast" and "slow".
+func (p *DualWindowPolicy) ExportWindows() []WindowState {
+	return []WindowState{p.fast.Export("fast"), p.slow.Export("slow")}
+}
+
+// ImportWindows implements WindowedPolicy.
+func (p *DualWindowPolicy) ImportWindows(windows []WindowState) {
+	for _, w := range windows {
+		switch w.Name {
+		case "fast":
+			p.fast.Import(w)
+		case "slow":
+			p.slow.Import(w)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDualWindowPolicy(t *testing.T) {
+	var fastCounts, slowCounts Counts
+	policy := NewDualWindowPolicy(DualWindowConfig{
+		FastWindow: 10 * time.Second,
+		SlowWindow: 10 * time.Minute,
+		ReadyToTrip: func(fast, slow Counts) bool {
+			fastCounts, slowCounts = fast, slow
+			return fast.ConsecutiveFailures >= 3 || slow.TotalFailures >= 5
+		},
+		RequestThreshold: 1,
+	})
+	cb := NewCircuitBreaker(Config{
+		Name:             "dual window circuit breaker",
+		RequestThreshold: 1,
+		Policy:           policy,
+	})
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, errServiceError, fail(cb))
+		assert.Nil(t, succeed(cb))
+	}
+	assert.Equal(t, StateClosed, cb.state)
+	assert.Equal(t, Counts{3, 1, 2, 0, 1}, slowCounts)
+
+	// slow-burn degradation trips the slow window
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, errServiceError, fail(cb))
+		assert.Nil(t, succeed(cb))
+	}
+	assert.Equal(t, StateClosed, cb.state)
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.state)
+	assert.Equal(t, uint32(1), fastCounts.ConsecutiveFailures)
+	assert.Equal(t, uint32(5), slowCounts.TotalFailures)
+
+	// closing clears both windows
+	pseudoSleep(cb, DefaultTimeout)
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.state)
+
+	// hard outage trips the fast window
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, errServiceError, fail(cb))
+	}
+	assert.Equal(t, StateOpen, cb.state)
+	assert.Equal(t, Counts{3, 0, 3, 0, 3}, fastCounts)
+}
@@ -0,0 +1,85 @@
+package circuit_breaker
+
+import "time"
+
+// Event is an event of a CircuitBreaker: StateChanged, CallRejected, CallFailed, ProbeResult or AuditEntry.
+//
+// The CorrelationID of the request events is the one of the context given to ExecuteContext, see WithCorrelationID.
+type Event interface {
+	isEvent()
+}
+
+// StateChanged is the Event of a state change.
+// Counts are the Counts which led to the change.
+type StateChanged struct {
+	Name   string
+	From   State
+	To     State
+	Reason string
+	Counts Counts
+	At     time.Time
+}
+
+// CallRejected is the Event of a request rejected in the State with Err, e.g. ErrOpenState or ErrBulkheadFull.
+type CallRejected struct {
+	Name          string
+	State         State
+	Err           error
+	CorrelationID string
+	At            time.Time
+}
+
+// CallFailed is the Event of a request counted as a failure in the State.
+type CallFailed struct {
+	Name          string
+	State         State
+	Err           error
+	Latency       time.Duration
+	CorrelationID string
+	At            time.Time
+}
+
+// ProbeResult is the Event of the outcome of a request admitted in the half-open state.
+// Err is the failure of the request, if any.
+type ProbeResult struct {
+	Name          string
+	Success       bool
+	Latency       time.Duration
+	Err           error
+	CorrelationID string
+	At            time.Time
+}
+
+func (StateChanged) isEvent() {}
+func (CallRejected) isEvent() {}
+func (CallFailed) isEvent()   {}
+func (ProbeResult) isEvent()  {}
+
+// SubscribeEvents registers a callback receiving all the events of the CircuitBreaker.
+// StateChanged events are delivered like the callbacks of Subscribe.
+// CallRejected, CallFailed and ProbeResult events are delivered in the goroutine making the request,
+// outside the lock, so they may arrive concurrently.
+// It returns the function removing the callback.
+func (cb *CircuitBreaker) SubscribeEvents(fn func(e Event)) (unsubscribe func()) {
+	return cb.subscribe(&listener{event: fn})
+}
+
+// emit delivers the request event to the event listeners, after the lock is released
+func emit(listeners []*listener, e Event) {
+	for _, l := range listeners {
+		if l.event != nil {
+			l.event(e)
+		}
+	}
+}
+
+func (change stateChange) event(name string) StateChanged {
+	return StateChanged{
+		Name:   name,
+		From:   change.from,
+		To:     change.to,
+		Reason: change.reason,
+		Counts: change.counts,
+		At:     change.at,
+	}
+}
@@ -0,0 +1,100 @@
+package circuit_breaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerEvents(t *testing.T) {
+	var events []Event
+	cb := NewCircuitBreaker(Config{
+		Name:                   "events",
+		MaxConsecutiveFailures: 1,
+		RequestThreshold:       1,
+		Timeout:                10 * time.Millisecond,
+		OnEvent:                func(e Event) { events = append(events, e) },
+	})
+
+	var subscribed []Event
+	unsubscribe := cb.SubscribeEvents(func(e Event) { subscribed = append(subscribed, e) })
+
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, ErrOpenState, succeed(cb))
+	time.Sleep(20 * time.Millisecond)
+	assert.Nil(t, succeed(cb))
+
+	assert.Equal(t, events, subscribed)
+	assert.Len(t, events, 6)
+
+	opened := events[0].(StateChanged)
+	assert.False(t, opened.At.IsZero())
+	opened.At = time.Time{}
+	assert.Equal(t, StateChanged{
+		Name:   "events",
+		From:   StateClosed,
+		To:     StateOpen,
+		Reason: ReasonTripped,
+		Counts: Counts{Requests: 2, TotalSuccesses: 1, TotalFailures: 1, ConsecutiveFailures: 1},
+	}, opened)
+
+	// the failure is reported after the state change it caused
+	failed := events[1].(CallFailed)
+	assert.Equal(t, StateClosed, failed.State)
+	assert.Equal(t, errServiceError, failed.Err)
+
+	rejected := events[2].(CallRejected)
+	assert.Equal(t, "events", rejected.Name)
+	assert.Equal(t, StateOpen, rejected.State)
+	assert.Equal(t, ErrOpenState, rejected.Err)
+
+	assert.Equal(t, StateHalfOpen, events[3].(StateChanged).To)
+	assert.Equal(t, ReasonTimeout, events[3].(StateChanged).Reason)
+
+	// the probe result is reported after the state change it caused
+	assert.Equal(t, StateClosed, events[4].(StateChanged).To)
+	probe := events[5].(ProbeResult)
+	assert.Equal(t, "events", probe.Name)
+	assert.True(t, probe.Success)
+	assert.Nil(t, probe.Err)
+	assert.Greater(t, probe.Latency, time.Duration(0))
+
+	unsubscribe()
+	cb.Trip()
+	assert.Len(t, subscribed, 6)
+	// the state change and the audit entry of the manual trip
+	assert.Len(t, events, 8)
+	assert.Equal(t, ActionTrip, events[7].(AuditEntry).Action)
+}
+
+func TestCircuitBreakerEventsBulkhead(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Bulkhead: Bulkhead{MaxConcurrent: 1}})
+
+	var events []Event
+	cb.SubscribeEvents(func(e Event) { events = append(events, e) })
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = cb.Execute(func() (interface{}, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+	}()
+	<-started
+
+	_, err := cb.ExecuteContext(context.Background(), func(ctx context.Context) (interface{}, error) { return nil, nil })
+	assert.Equal(t, ErrBulkheadFull, err)
+	close(release)
+	<-done
+
+	assert.Len(t, events, 1)
+	assert.Equal(t, ErrBulkheadFull, events[0].(CallRejected).Err)
+	assert.Equal(t, StateClosed, events[0].(CallRejected).State)
+}
@@ -0,0 +1,107 @@
+package circuit_breaker
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Eviction configures the eviction of the breakers managed by Registry and KeyedBreaker,
+// so the breakers created per host, tenant or URL don't grow the memory unboundedly.
+//
+// IdleTTL is the time after which a breaker without requests is evicted.
+// The idle breakers are evicted when a new breaker is created, at most once per IdleTTL, or by EvictIdle.
+// A breaker with requests in flight is never idle. Zero disables the eviction of the idle breakers.
+//
+// MaxSize is the maximum number of breakers. Creating a breaker over the limit evicts
+// the least recently used one. Zero means no limit.
+//
+// OnEvict is called with every evicted breaker, outside the locks, e.g. to remove its metrics.
+//
+// An evicted breaker keeps working for the callers holding it, but the next lookup of its name creates a fresh one.
+type Eviction struct {
+	IdleTTL time.Duration
+	MaxSize int
+	OnEvict func(cb *CircuitBreaker)
+}
+
+// managedBreaker is a breaker of Registry or KeyedBreaker with the time of its last use
+type managedBreaker struct {
+	cb *CircuitBreaker
+	// used is the time of the last lookup or request, in Unix nanoseconds
+	used int64
+	// activity is the number of requests and rejections of the breaker when used was last updated
+	activity uint64
+}
+
+func newManagedBreaker(cb *CircuitBreaker, now time.Time) *managedBreaker {
+	return &managedBreaker{cb: cb, used: now.UnixNano()}
+}
+
+// touch records the lookup of the breaker
+func (m *managedBreaker) touch(now time.Time) {
+	atomic.StoreInt64(&m.used, now.UnixNano())
+}
+
+// lastUsed returns the time of the last use of the breaker.
+// The requests made since the previous call count as a use, so the callers holding the breaker keep it alive.
+// It must be called with the write lock of the managing map held.
+func (m *managedBreaker) lastUsed(now time.Time) (time.Time, bool) {
+	activity, inFlight := m.cb.activity()
+	if activity != m.activity {
+		m.activity = activity
+		m.touch(now)
+	}
+
+	return time.Unix(0, atomic.LoadInt64(&m.used)), inFlight > 0
+}
+
+// activity returns the number of the requests and rejections of the CircuitBreaker, and the requests in flight
+func (cb *CircuitBreaker) activity() (uint64, int) {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	return cb.totals.Requests + cb.totals.Rejections, cb.inFlight
+}
+
+// sweepIdle removes the idle breakers from the map and returns them
+func (e Eviction) sweepIdle(breakers map[string]*managedBreaker, now time.Time) []*CircuitBreaker {
+	if e.IdleTTL <= 0 {
+		return nil
+	}
+
+	var evicted []*CircuitBreaker
+	for name, m := range breakers {
+		used, busy := m.lastUsed(now)
+		if !busy && now.Sub(used) >= e.IdleTTL {
+			delete(breakers, name)
+			evicted = append(evicted, m.cb)
+		}
+	}
+
+	return evicted
+}
+
+// leastRecentlyUsed returns the name and the last use of the least recently used breaker of the map without requests in flight
+func leastRecentlyUsed(breakers map[string]*managedBreaker, now time.Time) (string, time.Time, bool) {
+	var oldestName string
+	var oldest time.Time
+	found := false
+	for name, m := range breakers {
+		used, busy := m.lastUsed(now)
+		if !busy && (!found || used.Before(oldest)) {
+			oldestName, oldest, found = name, used, true
+		}
+	}
+
+	return oldestName, oldest, found
+}
+
+// evicted reports the evicted breakers to OnEvict
+func (e Eviction) evicted(cbs ...*CircuitBreaker) {
+	if e.OnEvict == nil {
+		return
+	}
+	for _, cb := range cbs {
+		e.OnEvict(cb)
+	}
+}
@@ -0,0 +1,100 @@
+package circuit_breaker
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryEvictIdle(t *testing.T) {
+	var mu sync.Mutex
+	var evicted []string
+	r := NewRegistry(RegistryConfig{Eviction: Eviction{
+		IdleTTL: 20 * time.Millisecond,
+		OnEvict: func(cb *CircuitBreaker) {
+			mu.Lock()
+			defer mu.Unlock()
+			evicted = append(evicted, cb.Name())
+		},
+	}})
+
+	idle := r.GetOrCreate("idle", Config{})
+	busy := r.GetOrCreate("busy", Config{})
+	held := r.GetOrCreate("held", Config{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = busy.Execute(func() (interface{}, error) {
+			<-release
+			return nil, nil
+		})
+	}()
+
+	time.Sleep(15 * time.Millisecond)
+	// the requests through a held breaker keep it alive
+	assert.Nil(t, succeed(held))
+	time.Sleep(15 * time.Millisecond)
+
+	assert.Equal(t, 1, r.EvictIdle())
+	assert.Equal(t, []string{"idle"}, evicted)
+	assert.Equal(t, []string{"busy", "held"}, r.Names())
+	assert.NotSame(t, idle, r.GetOrCreate("idle", Config{}))
+
+	close(release)
+	<-done
+}
+
+func TestRegistryEvictLeastRecentlyUsed(t *testing.T) {
+	var evicted []string
+	r := NewRegistry(RegistryConfig{Eviction: Eviction{
+		MaxSize: 2,
+		OnEvict: func(cb *CircuitBreaker) { evicted = append(evicted, cb.Name()) },
+	}})
+
+	r.GetOrCreate("a", Config{})
+	time.Sleep(time.Millisecond)
+	r.GetOrCreate("b", Config{})
+	time.Sleep(time.Millisecond)
+	r.Get("a")
+	time.Sleep(time.Millisecond)
+	r.GetOrCreate("c", Config{})
+
+	assert.Equal(t, []string{"b"}, evicted)
+	assert.Equal(t, []string{"a", "c"}, r.Names())
+}
+
+func TestKeyedBreakerEviction(t *testing.T) {
+	var mu sync.Mutex
+	var evicted []string
+	kb := NewKeyedBreaker(KeyedConfig{
+		Stripes: 4,
+		Eviction: Eviction{
+			IdleTTL: 20 * time.Millisecond,
+			MaxSize: 2,
+			OnEvict: func(cb *CircuitBreaker) {
+				mu.Lock()
+				defer mu.Unlock()
+				evicted = append(evicted, cb.Name())
+			},
+		},
+	})
+
+	kb.Get("a")
+	time.Sleep(time.Millisecond)
+	kb.Get("b")
+	time.Sleep(time.Millisecond)
+	kb.Get("c")
+	assert.Equal(t, []string{"a"}, evicted)
+	assert.Equal(t, 2, kb.Len())
+
+	time.Sleep(25 * time.Millisecond)
+	assert.Equal(t, 2, kb.EvictIdle())
+	assert.Equal(t, 0, kb.Len())
+
+	kb.Get("d")
+	kb.Remove("d")
+	assert.Equal(t, 0, kb.Len())
+}
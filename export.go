@@ -0,0 +1,150 @@
+package circuit_breaker
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ExportVersion is the version of the wire format of ExportedState written by Export.
+const ExportVersion = 1
+
+// ReasonImported is the reason of the state changes made by Import.
+const ReasonImported = "imported"
+
+// ExportedState is the state of a CircuitBreaker in the versioned wire format
+// shared by the admin API and the handoff tooling, e.g. to hand the breakers over to the next blue-green deployment.
+// It is encoded as JSON by encoding/json, and as protobuf by MarshalProto,
+// following the BreakerState message of proto/circuit_breaker/v1/state.proto.
+//
+// The persistence backends don't use it: a Storage keeps the StoredState, whose Version orders the writes
+// shared by the instances of the service, without the windows and the config fingerprint.
+//
+// Version is the version of the format, ExportVersion when exported.
+// Trips is the number of consecutive trips without closing, and ExpiredAt the end of the open period.
+// Windows are the rolling windows of the policy, if it is a WindowedPolicy.
+// ConfigFingerprint identifies the settings of the state machine, see CircuitBreaker.ConfigFingerprint.
+type ExportedState struct {
+	Version           uint32
+	Name              string
+	State             State
+	Counts            Counts
+	Trips             uint32
+	ChangedAt         time.Time
+	ExpiredAt         time.Time
+	Windows           []WindowState
+	ConfigFingerprint string
+	ExportedAt        time.Time
+}
+
+// WindowedPolicy is a Policy whose rolling windows are carried by Export and Import, e.g. DualWindowPolicy.
+// The methods are called while the CircuitBreaker lock is held, like the ones of Policy.
+type WindowedPolicy interface {
+	Policy
+	// ExportWindows returns the content of the windows, named to be told apart by ImportWindows.
+	ExportWindows() []WindowState
+	// ImportWindows replaces the content of the windows by the exported ones of the same name,
+	// skipping the ones of another size.
+	ImportWindows(windows []WindowState)
+}
+
+// Export returns the state of the CircuitBreaker in the wire format, see ExportedState.
+func (cb *CircuitBreaker) Export() ExportedState {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	now := time.Now()
+	cb.refreshState(now)
+
+	e := ExportedState{
+		Version:           ExportVersion,
+		Name:              cb.name,
+		State:             cb.state,
+		Counts:            cb.counts,
+		Trips:             cb.trips,
+		ChangedAt:         cb.changedAt,
+		ExpiredAt:         cb.expiredAt,
+		ConfigFingerprint: cb.configFingerprint(),
+		ExportedAt:        now,
+	}
+	if p, ok := cb.policy.(WindowedPolicy); ok {
+		e.Windows = p.ExportWindows()
+	}
+
+	return e
+}
+
+// Import moves the CircuitBreaker into the exported state, e.g. of the breaker of the previous deployment.
+// The state change is delivered like any other, with ReasonImported, and stored if a Storage is configured.
+//
+// The Counts and the Windows are imported only if the ConfigFingerprint matches the one of the CircuitBreaker,
+// as the statistics gathered under other settings would mislead the state machine.
+// It returns an error if the version of the format is not supported or the state belongs to another breaker,
+// leaving the CircuitBreaker unchanged.
+func (cb *CircuitBreaker) Import(e ExportedState) error {
+	if e.Version == 0 || e.Version > ExportVersion {
+		return fmt.Errorf("unsupported circuit breaker export version %d", e.Version)
+	}
+	if e.Name != "" && e.Name != cb.name {
+		return fmt.Errorf("circuit breaker %q can't import the state of %q", cb.name, e.Name)
+	}
+	if e.State != StateClosed && e.State != StateOpen && e.State != StateHalfOpen {
+		return errors.New("invalid circuit breaker state " + e.State.String())
+	}
+
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	if e.State != cb.state {
+		cb.setState(e.State, ReasonImported)
+		// the state started when the exporting breaker changed it, unless the clocks disagree
+		if !e.ChangedAt.IsZero() && e.ChangedAt.Before(cb.changedAt) {
+			cb.changedAt = e.ChangedAt
+		}
+	}
+	cb.trips = e.Trips
+	cb.expiredAt = e.ExpiredAt
+	if e.ConfigFingerprint == cb.configFingerprint() {
+		cb.counts = e.Counts
+		if p, ok := cb.policy.(WindowedPolicy); ok {
+			p.ImportWindows(e.Windows)
+		}
+	}
+	cb.refreshState(time.Now())
+
+	return nil
+}
+
+// ConfigFingerprint identifies the settings of the state machine of the CircuitBreaker:
+// the breakers with the same settings have the same fingerprint.
+// The ReadyToTrip functions and the custom policies are only told apart by their presence and type.
+func (cb *CircuitBreaker) ConfigFingerprint() string {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	return cb.configFingerprint()
+}
+
+// configFingerprint hashes the settings of the state machine, while the lock is held
+func (cb *CircuitBreaker) configFingerprint() string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "threshold=%d warmup=%d slow=%d ", cb.requestThreshold, cb.warmupDuration, cb.slowCallThreshold)
+	if p, ok := cb.policy.(DefaultPolicy); ok {
+		fmt.Fprintf(h, "policy=default max=%d threshold=%d timeout=%d ready_to_trip=%t ",
+			p.MaxConsecutiveFailures, p.RequestThreshold, p.Timeout, p.ReadyToTrip != nil)
+	} else {
+		fmt.Fprintf(h, "policy=%T ", cb.policy)
+	}
+
+	categories := make([]string, 0, len(cb.categoryThresholds))
+	for category, n := range cb.categoryThresholds {
+		categories = append(categories, fmt.Sprintf("%v=%d", category, n))
+	}
+	sort.Strings(categories)
+	fmt.Fprint(h, "categories=", categories)
+
+	return strconv.FormatUint(h.Sum64(), 16)
+}
@@ -0,0 +1,257 @@
+package circuit_breaker
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// The protobuf encoding of ExportedState, following proto/circuit_breaker/v1/state.proto.
+// It is written by hand to keep the package free of dependencies.
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errProtoTruncated = errors.New("circuit breaker export: truncated protobuf")
+
+// MarshalProto encodes the state as the BreakerState protobuf message.
+func (e ExportedState) MarshalProto() []byte {
+	var b []byte
+	b = appendVarintField(b, 1, uint64(e.Version))
+	b = appendStringField(b, 2, e.Name)
+	b = appendVarintField(b, 3, uint64(e.State))
+	b = appendMessageField(b, 4, appendCounts(nil, e.Counts))
+	b = appendVarintField(b, 5, uint64(e.Trips))
+	b = appendVarintField(b, 6, uint64(unixNano(e.ChangedAt)))
+	b = appendVarintField(b, 7, uint64(unixNano(e.ExpiredAt)))
+	for _, w := range e.Windows {
+		b = appendMessageField(b, 8, appendWindow(nil, w))
+	}
+	b = appendStringField(b, 9, e.ConfigFingerprint)
+	b = appendVarintField(b, 10, uint64(unixNano(e.ExportedAt)))
+
+	return b
+}
+
+// UnmarshalProto decodes the state from the BreakerState protobuf message, skipping the unknown fields.
+func (e *ExportedState) UnmarshalProto(data []byte) error {
+	*e = ExportedState{}
+
+	return decodeFields(data, func(field int, v uint64, bytes []byte) error {
+		switch field {
+		case 1:
+			e.Version = uint32(v)
+		case 2:
+			e.Name = string(bytes)
+		case 3:
+			e.State = State(v)
+		case 4:
+			return decodeCounts(bytes, &e.Counts)
+		case 5:
+			e.Trips = uint32(v)
+		case 6:
+			e.ChangedAt = fromUnixNano(int64(v))
+		case 7:
+			e.ExpiredAt = fromUnixNano(int64(v))
+		case 8:
+			var w WindowState
+			if err := decodeWindow(bytes, &w); err != nil {
+				return err
+			}
+			e.Windows = append(e.Windows, w)
+		case 9:
+			e.ConfigFingerprint = string(bytes)
+		case 10:
+			e.ExportedAt = fromUnixNano(int64(v))
+		}
+		return nil
+	})
+}
+
+func appendCounts(b []byte, c Counts) []byte {
+	b = appendVarintField(b, 1, uint64(c.Requests))
+	b = appendVarintField(b, 2, uint64(c.TotalSuccesses))
+	b = appendVarintField(b, 3, uint64(c.TotalFailures))
+	b = appendVarintField(b, 4, uint64(c.ConsecutiveSuccesses))
+	return appendVarintField(b, 5, uint64(c.ConsecutiveFailures))
+}
+
+func decodeCounts(data []byte, c *Counts) error {
+	return decodeFields(data, func(field int, v uint64, bytes []byte) error {
+		switch field {
+		case 1:
+			c.Requests = uint32(v)
+		case 2:
+			c.TotalSuccesses = uint32(v)
+		case 3:
+			c.TotalFailures = uint32(v)
+		case 4:
+			c.ConsecutiveSuccesses = uint32(v)
+		case 5:
+			c.ConsecutiveFailures = uint32(v)
+		}
+		return nil
+	})
+}
+
+func appendWindow(b []byte, w WindowState) []byte {
+	b = appendStringField(b, 1, w.Name)
+	b = appendVarintField(b, 2, uint64(w.BucketSize))
+	b = appendVarintField(b, 3, uint64(w.Head))
+	for _, c := range w.Buckets {
+		// the buckets are always written, so their positions are kept
+		b = appendTag(b, 4, wireBytes)
+		b = appendBytes(b, appendCounts(nil, c))
+	}
+	return appendMessageField(b, 5, appendCounts(nil, w.Last))
+}
+
+func decodeWindow(data []byte, w *WindowState) error {
+	return decodeFields(data, func(field int, v uint64, bytes []byte) error {
+		switch field {
+		case 1:
+			w.Name = string(bytes)
+		case 2:
+			w.BucketSize = time.Duration(v)
+		case 3:
+			w.Head = int64(v)
+		case 4:
+			var c Counts
+			if err := decodeCounts(bytes, &c); err != nil {
+				return err
+			}
+			w.Buckets = append(w.Buckets, c)
+		case 5:
+			return decodeCounts(bytes, &w.Last)
+		}
+		return nil
+	})
+}
+
+// decodeFields calls fn with the number and the value of every field of the message:
+// v for the varint and fixed fields, and bytes for the length-delimited ones
+func decodeFields(data []byte, fn func(field int, v uint64, bytes []byte) error) error {
+	for len(data) > 0 {
+		tag, n := consumeVarint(data)
+		if n == 0 {
+			return errProtoTruncated
+		}
+		data = data[n:]
+
+		field, wireType := int(tag>>3), int(tag&7)
+		if field == 0 || tag>>3 > math.MaxInt32 {
+			return fmt.Errorf("circuit breaker export: invalid protobuf field %d", tag>>3)
+		}
+		var v uint64
+		var bytes []byte
+		switch wireType {
+		case wireVarint:
+			v, n = consumeVarint(data)
+		case wireFixed64:
+			if n = 8; len(data) < n {
+				n = 0
+			}
+		case wireFixed32:
+			if n = 4; len(data) < n {
+				n = 0
+			}
+		case wireBytes:
+			var size uint64
+			size, n = consumeVarint(data)
+			if n > 0 && size <= uint64(len(data)-n) {
+				bytes = data[n : n+int(size)]
+				n += int(size)
+			} else {
+				n = 0
+			}
+		default:
+			return fmt.Errorf("circuit breaker export: unsupported protobuf wire type %d", wireType)
+		}
+		if n == 0 {
+			return errProtoTruncated
+		}
+		data = data[n:]
+
+		if err := fn(field, v, bytes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// consumeVarint decodes the varint at the start of the data, returning its length, or zero if it is invalid
+func consumeVarint(data []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < len(data) && i < 10; i++ {
+		v |= uint64(data[i]&0x7f) << (7 * i)
+		if data[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+
+	return 0, 0
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func appendTag(b []byte, field int, wireType int) []byte {
+	return appendVarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+func appendBytes(b []byte, v []byte) []byte {
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// appendVarintField appends the varint field, omitting the zero value like proto3
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendTag(b, field, wireVarint)
+	return appendVarint(b, v)
+}
+
+func appendStringField(b []byte, field int, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = appendTag(b, field, wireBytes)
+	return appendBytes(b, []byte(v))
+}
+
+// appendMessageField appends the embedded message, omitting the empty one
+func appendMessageField(b []byte, field int, msg []byte) []byte {
+	if len(msg) == 0 {
+		return b
+	}
+	b = appendTag(b, field, wireBytes)
+	return appendBytes(b, msg)
+}
+
+// unixNano returns the Unix time of t in nanoseconds, zero for the zero time
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
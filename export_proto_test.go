@@ -0,0 +1,70 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportedStateProto(t *testing.T) {
+	e := ExportedState{
+		Version:   ExportVersion,
+		Name:      "payments",
+		State:     StateOpen,
+		Counts:    Counts{3, 1, 2, 0, 2},
+		Trips:     2,
+		ChangedAt: time.Unix(1700000000, 5),
+		ExpiredAt: time.Unix(1700000060, 5),
+		Windows: []WindowState{{
+			Name:       "fast",
+			BucketSize: time.Second,
+			Head:       -1,
+			Buckets:    []Counts{{}, {1, 1, 0, 1, 0}},
+			Last:       Counts{1, 1, 0, 1, 0},
+		}},
+		ConfigFingerprint: "5f1c",
+		ExportedAt:        time.Unix(1700000001, 0),
+	}
+
+	var decoded ExportedState
+	assert.Nil(t, decoded.UnmarshalProto(e.MarshalProto()))
+	assert.True(t, e.ChangedAt.Equal(decoded.ChangedAt))
+	assert.True(t, e.ExpiredAt.Equal(decoded.ExpiredAt))
+	assert.True(t, e.ExportedAt.Equal(decoded.ExportedAt))
+	decoded.ChangedAt, decoded.ExpiredAt, decoded.ExportedAt = e.ChangedAt, e.ExpiredAt, e.ExportedAt
+	assert.Equal(t, e, decoded)
+
+	// the zero values are omitted and the zero times kept
+	assert.Equal(t, []byte{0x08, 0x01, 0x12, 0x01, 'a'}, ExportedState{Version: 1, Name: "a"}.MarshalProto())
+	assert.Nil(t, decoded.UnmarshalProto([]byte{0x08, 0x01, 0x12, 0x01, 'a'}))
+	assert.Equal(t, ExportedState{Version: 1, Name: "a"}, decoded)
+}
+
+func TestExportedStateProtoUnknownFields(t *testing.T) {
+	data := ExportedState{Version: 1, Name: "a"}.MarshalProto()
+	// field 20 of every wire type
+	data = append(data, 0xa0, 0x01, 0x05)
+	data = append(data, 0xa1, 0x01, 1, 2, 3, 4, 5, 6, 7, 8)
+	data = append(data, 0xa2, 0x01, 0x02, 'x', 'y')
+	data = append(data, 0xa5, 0x01, 1, 2, 3, 4)
+
+	var decoded ExportedState
+	assert.Nil(t, decoded.UnmarshalProto(data))
+	assert.Equal(t, ExportedState{Version: 1, Name: "a"}, decoded)
+}
+
+func TestExportedStateProtoInvalid(t *testing.T) {
+	var e ExportedState
+	for _, data := range [][]byte{
+		{0x08},
+		{0x08, 0x80},
+		{0x12, 0x05, 'a'},
+		{0x22, 0x02, 0x08},
+		{0x09, 1, 2},
+	} {
+		assert.Equal(t, errProtoTruncated, e.UnmarshalProto(data), "%x", data)
+	}
+	assert.EqualError(t, e.UnmarshalProto([]byte{0x0b}), "circuit breaker export: unsupported protobuf wire type 3")
+	assert.EqualError(t, e.UnmarshalProto([]byte{0x00}), "circuit breaker export: invalid protobuf field 0")
+}
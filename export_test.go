@@ -0,0 +1,103 @@
+package circuit_breaker
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImport(t *testing.T) {
+	cfg := Config{Name: "payments", MaxConsecutiveFailures: 2, RequestThreshold: 1, Timeout: time.Minute}
+	blue := NewCircuitBreaker(cfg)
+	assert.Equal(t, errServiceError, fail(blue))
+	assert.Equal(t, errServiceError, fail(blue))
+
+	e := blue.Export()
+	assert.Equal(t, uint32(ExportVersion), e.Version)
+	assert.Equal(t, "payments", e.Name)
+	assert.Equal(t, StateOpen, e.State)
+	assert.Equal(t, uint32(1), e.Trips)
+	assert.Equal(t, blue.OpensAt(), e.ExpiredAt)
+	assert.Equal(t, blue.ConfigFingerprint(), e.ConfigFingerprint)
+
+	// the next deployment starts open, through JSON
+	data, err := json.Marshal(e)
+	assert.Nil(t, err)
+	var decoded ExportedState
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+
+	var changes []string
+	green := NewCircuitBreaker(cfg)
+	green.SubscribeTransitions(func(_ string, t Transition) { changes = append(changes, t.Reason) })
+	assert.Nil(t, green.Import(decoded))
+	assert.Equal(t, StateOpen, green.State())
+	assert.Equal(t, ErrOpenState, succeed(green))
+	assert.Equal(t, blue.OpensAt().UnixNano(), green.OpensAt().UnixNano())
+	assert.Equal(t, e.ChangedAt.UnixNano(), green.Snapshot().LastTransition.UnixNano())
+	assert.Equal(t, []string{ReasonImported}, changes)
+}
+
+func TestExportImportCounts(t *testing.T) {
+	cfg := Config{Name: "payments", MaxConsecutiveFailures: 2}
+	blue := NewCircuitBreaker(cfg)
+	assert.Equal(t, errServiceError, fail(blue))
+
+	green := NewCircuitBreaker(cfg)
+	assert.Nil(t, green.Import(blue.Export()))
+	assert.Equal(t, Counts{1, 0, 1, 0, 1}, green.Counts())
+	assert.Equal(t, errServiceError, fail(green))
+	assert.Equal(t, StateOpen, green.State())
+
+	// the counts gathered under other settings are dropped
+	cfg.MaxConsecutiveFailures = 3
+	other := NewCircuitBreaker(cfg)
+	assert.NotEqual(t, blue.ConfigFingerprint(), other.ConfigFingerprint())
+	assert.Nil(t, other.Import(blue.Export()))
+	assert.Equal(t, Counts{}, other.Counts())
+}
+
+func TestExportImportWindows(t *testing.T) {
+	policy := func() *DualWindowPolicy {
+		return NewDualWindowPolicy(DualWindowConfig{
+			FastWindow:  time.Minute,
+			SlowWindow:  time.Hour,
+			ReadyToTrip: func(fast, slow Counts) bool { return fast.TotalFailures >= 2 },
+		})
+	}
+	blue := NewCircuitBreaker(Config{Name: "payments", Policy: policy()})
+	assert.Equal(t, errServiceError, fail(blue))
+
+	e := blue.Export()
+	assert.Len(t, e.Windows, 2)
+	assert.Equal(t, "fast", e.Windows[0].Name)
+	assert.Equal(t, "slow", e.Windows[1].Name)
+
+	var decoded ExportedState
+	assert.Nil(t, decoded.UnmarshalProto(e.MarshalProto()))
+	green := NewCircuitBreaker(Config{Name: "payments", Policy: policy()})
+	assert.Nil(t, green.Import(decoded))
+	assert.Equal(t, errServiceError, fail(green))
+	assert.Equal(t, StateOpen, green.State())
+}
+
+func TestImportErrors(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "payments"})
+	e := cb.Export()
+	e.State = StateOpen
+
+	e.Version = ExportVersion + 1
+	assert.EqualError(t, cb.Import(e), "unsupported circuit breaker export version 2")
+	e.Version = 0
+	assert.EqualError(t, cb.Import(e), "unsupported circuit breaker export version 0")
+
+	e.Version = ExportVersion
+	e.Name = "search"
+	assert.EqualError(t, cb.Import(e), `circuit breaker "payments" can't import the state of "search"`)
+
+	e.Name = ""
+	e.State = State(7)
+	assert.EqualError(t, cb.Import(e), "invalid circuit breaker state undefined state: 7")
+	assert.Equal(t, StateClosed, cb.State())
+}
@@ -0,0 +1,79 @@
+package circuit_breaker
+
+import (
+	"expvar"
+	"sync"
+)
+
+// ExpvarName is the name of the expvar variable published by PublishExpvar.
+const ExpvarName = "circuit_breakers"
+
+var published struct {
+	once     sync.Once
+	mu       sync.Mutex
+	breakers map[string]*CircuitBreaker
+	keyed    []*KeyedBreaker
+}
+
+// PublishExpvar exposes the Snapshot of the breakers under /debug/vars,
+// as the ExpvarName variable mapping the names of the breakers to their snapshots.
+// A breaker replaces the published one with the same name.
+func PublishExpvar(cbs ...*CircuitBreaker) {
+	publishExpvar()
+
+	published.mu.Lock()
+	defer published.mu.Unlock()
+
+	for _, cb := range cbs {
+		published.breakers[cb.name] = cb
+	}
+}
+
+// PublishExpvarKeyed exposes all the breakers of the KeyedBreaker like PublishExpvar,
+// including the ones created after the call.
+func PublishExpvarKeyed(kb *KeyedBreaker) {
+	publishExpvar()
+
+	published.mu.Lock()
+	defer published.mu.Unlock()
+
+	published.keyed = append(published.keyed, kb)
+}
+
+// UnpublishExpvar stops exposing the breaker with the name.
+func UnpublishExpvar(name string) {
+	published.mu.Lock()
+	defer published.mu.Unlock()
+
+	delete(published.breakers, name)
+}
+
+func publishExpvar() {
+	published.once.Do(func() {
+		published.breakers = make(map[string]*CircuitBreaker)
+		expvar.Publish(ExpvarName, expvar.Func(expvarSnapshots))
+	})
+}
+
+func expvarSnapshots() interface{} {
+	published.mu.Lock()
+	cbs := make([]*CircuitBreaker, 0, len(published.breakers))
+	for _, cb := range published.breakers {
+		cbs = append(cbs, cb)
+	}
+	for _, kb := range published.keyed {
+		for _, key := range kb.Keys() {
+			if cb, ok := kb.Lookup(key); ok {
+				cbs = append(cbs, cb)
+			}
+		}
+	}
+	published.mu.Unlock()
+
+	snapshots := make(map[string]Snapshot, len(cbs))
+	for _, cb := range cbs {
+		snapshots[cb.name] = cb.Snapshot()
+	}
+
+	return snapshots
+}
@@ -0,0 +1,38 @@
+package circuit_breaker
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "expvar circuit breaker", MaxConsecutiveFailures: 1})
+	kb := NewKeyedBreaker(KeyedConfig{Config: Config{Name: "expvar hosts"}})
+	PublishExpvar(cb)
+	PublishExpvarKeyed(kb)
+	defer UnpublishExpvar(cb.Name())
+
+	assert.Equal(t, errServiceError, fail(cb))
+	kb.Get("api.example.com")
+
+	var vars map[string]struct {
+		State  string
+		Totals struct {
+			Requests    uint64
+			Failures    uint64
+			Transitions map[string]uint64
+		}
+	}
+	assert.Nil(t, json.Unmarshal([]byte(expvar.Get(ExpvarName).String()), &vars))
+
+	assert.Equal(t, "open", vars["expvar circuit breaker"].State)
+	assert.Equal(t, uint64(1), vars["expvar circuit breaker"].Totals.Failures)
+	assert.Equal(t, map[string]uint64{"open": 1}, vars["expvar circuit breaker"].Totals.Transitions)
+	assert.Equal(t, "closed", vars["expvar hosts/api.example.com"].State)
+
+	UnpublishExpvar(cb.Name())
+	assert.NotContains(t, expvar.Get(ExpvarName).String(), "expvar circuit breaker")
+}
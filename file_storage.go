@@ -0,0 +1,172 @@
+package circuit_breaker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStorageConfig configures FileStorage.
+//
+// Path is the file of the states. Sync makes every write flushed to the disk by fsync before it is reported,
+// so the states survive a crash of the machine, not only of the process.
+type FileStorageConfig struct {
+	Path string
+	Sync bool
+}
+
+// FileStorage is the CASStorage keeping the states of the breakers of the process in a local JSON file,
+// so a crash-looping service restores its open breakers instead of hammering a dead dependency on every restart.
+//
+// The file is read on first use and rewritten atomically on every change, by renaming a temporary file over it.
+// If the file can't be read, the operations return the error without writing, and the next one reads it again.
+// A corrupt file is never overwritten: it is moved aside to the path with the ".corrupt" suffix,
+// the operation which found it returns the error, and the states start empty.
+// The file must not be shared by several processes. FileStorage is safe for concurrent use.
+type FileStorage struct {
+	cfg FileStorageConfig
+
+	mu     sync.Mutex
+	states map[string]StoredState
+}
+
+var _ CASStorage = (*FileStorage)(nil)
+
+// NewFileStorage creates the FileStorage.
+func NewFileStorage(cfg FileStorageConfig) *FileStorage {
+	return &FileStorage{cfg: cfg}
+}
+
+// Load implements Storage.
+func (f *FileStorage) Load(ctx context.Context, name string) (StoredState, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.read(); err != nil {
+		return StoredState{}, false, err
+	}
+	s, ok := f.states[name]
+	return s, ok, nil
+}
+
+// Store implements Storage.
+func (f *FileStorage) Store(ctx context.Context, name string, s StoredState) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.read(); err != nil {
+		return err
+	}
+	return f.write(name, s)
+}
+
+// CompareAndSwap implements CASStorage.
+func (f *FileStorage) CompareAndSwap(ctx context.Context, name string, version uint64, s StoredState) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.read(); err != nil {
+		return false, err
+	}
+	if f.states[name].Version != version {
+		return false, nil
+	}
+	return true, f.write(name, s)
+}
+
+// read reads the file until it succeeds, while the lock is held
+func (f *FileStorage) read() error {
+	if f.states != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(f.cfg.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		f.states = make(map[string]StoredState)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("circuit breaker state file: %w", err)
+	}
+	states := make(map[string]StoredState)
+	if err := json.Unmarshal(data, &states); err != nil {
+		err = fmt.Errorf("circuit breaker state file %s: %w", f.cfg.Path, err)
+		if renameErr := os.Rename(f.cfg.Path, f.cfg.Path+".corrupt"); renameErr != nil {
+			return fmt.Errorf("%w, not moved aside: %v", err, renameErr)
+		}
+		f.states = make(map[string]StoredState)
+		return err
+	}
+	f.states = states
+
+	return nil
+}
+
+// write replaces the file with the states including the given one, while the lock is held.
+// The state is kept in memory only if the file was written.
+func (f *FileStorage) write(name string, s StoredState) error {
+	states := make(map[string]StoredState, len(f.states)+1)
+	for n, state := range f.states {
+		states[n] = state
+	}
+	states[name] = s
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := f.replace(data); err != nil {
+		return fmt.Errorf("circuit breaker state file: %w", err)
+	}
+	f.states = states
+
+	return nil
+}
+
+// replace writes the data to a temporary file renamed over the file
+func (f *FileStorage) replace(data []byte) error {
+	dir := filepath.Dir(f.cfg.Path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(f.cfg.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if f.cfg.Sync {
+		if err := tmp.Sync(); err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), f.cfg.Path); err != nil {
+		return err
+	}
+	if f.cfg.Sync {
+		return syncDir(dir)
+	}
+
+	return nil
+}
+
+// syncDir flushes the rename to the disk
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,102 @@
+package circuit_breaker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStorage(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "breakers.json")
+	cfg := Config{Name: "payments", MaxConsecutiveFailures: 1, Timeout: time.Minute}
+
+	cfg.Storage = NewFileStorage(FileStorageConfig{Path: path, Sync: true})
+	cb := NewCircuitBreaker(cfg)
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+	cb.WaitBackground()
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"State": "open"`)
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+
+	// the restarted process restores the open state
+	storage := NewFileStorage(FileStorageConfig{Path: path})
+	cfg.Storage = storage
+	restarted := NewCircuitBreaker(cfg)
+	assert.Equal(t, StateOpen, restarted.State())
+	assert.Equal(t, ErrOpenState, succeed(restarted))
+	assert.Equal(t, cb.OpensAt().UnixNano(), restarted.OpensAt().UnixNano())
+
+	other := NewCircuitBreaker(Config{Name: "search", Storage: storage})
+	assert.Equal(t, StateClosed, other.State())
+	assert.Nil(t, other.SaveState(ctx))
+	s, ok, err := NewFileStorage(FileStorageConfig{Path: path}).Load(ctx, "search")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, StateClosed, s.State)
+
+	swapped, err := storage.CompareAndSwap(ctx, "payments", 0, StoredState{Version: 1})
+	assert.Nil(t, err)
+	assert.False(t, swapped)
+}
+
+func TestFileStorageErrors(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "breakers.json")
+	assert.Nil(t, os.WriteFile(path, []byte("{"), 0o600))
+
+	// the corrupt file is reported once and moved aside
+	storage := NewFileStorage(FileStorageConfig{Path: path})
+	_, _, err := storage.Load(ctx, "payments")
+	assert.NotNil(t, err)
+	data, err := os.ReadFile(path + ".corrupt")
+	assert.Nil(t, err)
+	assert.Equal(t, "{", string(data))
+	_, ok, err := storage.Load(ctx, "payments")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, storage.Store(ctx, "payments", StoredState{State: StateOpen, Version: 1}))
+	s, ok, err := NewFileStorage(FileStorageConfig{Path: path}).Load(ctx, "payments")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, StateOpen, s.State)
+
+	// the store which finds the file corrupt doesn't replace it
+	assert.Nil(t, os.WriteFile(path, []byte("{"), 0o600))
+	storage = NewFileStorage(FileStorageConfig{Path: path})
+	assert.NotNil(t, storage.Store(ctx, "search", StoredState{Version: 1}))
+	_, err = storage.CompareAndSwap(ctx, "search", 0, StoredState{Version: 1})
+	assert.Nil(t, err)
+	data, err = os.ReadFile(path + ".corrupt")
+	assert.Nil(t, err)
+	assert.Equal(t, "{", string(data))
+
+	// the file which can't be read is not replaced, and is read again by the next operation
+	unreadable := filepath.Join(filepath.Dir(path), "unreadable")
+	assert.Nil(t, os.Mkdir(unreadable, 0o700))
+	storage = NewFileStorage(FileStorageConfig{Path: unreadable})
+	assert.NotNil(t, storage.Store(ctx, "payments", StoredState{Version: 1}))
+	_, err = storage.CompareAndSwap(ctx, "payments", 0, StoredState{Version: 1})
+	assert.NotNil(t, err)
+	info, err := os.Stat(unreadable)
+	assert.Nil(t, err)
+	assert.True(t, info.IsDir())
+	assert.Nil(t, os.Remove(unreadable))
+	assert.Nil(t, storage.Store(ctx, "payments", StoredState{Version: 1}))
+
+	// the state which can't be written is not kept
+	missing := NewFileStorage(FileStorageConfig{Path: filepath.Join(filepath.Dir(path), "missing", "breakers.json")})
+	assert.NotNil(t, missing.Store(ctx, "payments", StoredState{Version: 1}))
+	_, ok, err = missing.Load(ctx, "payments")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
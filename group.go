@@ -0,0 +1,161 @@
+package circuit_breaker
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// GroupConfig configures Group.
+//
+// Config configures the shared breaker of the group, whose Policy decides when all the members trip and close.
+// Its Name is the name of the group.
+//
+// Member is the template for the breakers of the members, e.g. with a LatencyHistogram or a Logger.
+// Their trip settings are ignored: the members follow the state of the shared breaker.
+// The name of each member is the group Name and the member name joined with "/",
+// or just the member name if the group Name is empty.
+type GroupConfig struct {
+	Config Config
+	Member Config
+}
+
+// Group protects several logical operations hitting the same backend as one unit:
+// the failures of all the operations count towards the shared breaker,
+// and the breakers of the operations open and close together with it,
+// while keeping their own metrics, see Members.
+//
+// The requests must be made through Group.Execute or Group.ExecuteContext.
+// A request rejected by the shared breaker is counted as a rejection by the breaker of the operation too,
+// but a request admitted by the breaker of the operation and then rejected by the shared one
+// only counts as a request, without an outcome.
+// Group is safe for concurrent use.
+type Group struct {
+	cfg    GroupConfig
+	shared *CircuitBreaker
+
+	mu      sync.RWMutex
+	members map[string]*CircuitBreaker
+}
+
+func NewGroup(cfg GroupConfig) *Group {
+	g := &Group{
+		cfg:     cfg,
+		shared:  NewCircuitBreaker(cfg.Config),
+		members: make(map[string]*CircuitBreaker),
+	}
+	g.shared.SubscribeTransitions(func(_ string, t Transition) {
+		opensAt := g.shared.OpensAt()
+		for _, m := range g.Members() {
+			m.follow(t.To, t.Reason, opensAt)
+		}
+	})
+
+	return g
+}
+
+// Shared returns the shared breaker of the group, e.g. to trip or reset all the members at once.
+func (g *Group) Shared() *CircuitBreaker {
+	return g.shared
+}
+
+// Member returns the breaker of the operation, creating it if necessary.
+func (g *Group) Member(name string) *CircuitBreaker {
+	g.mu.RLock()
+	cb, ok := g.members[name]
+	g.mu.RUnlock()
+	if ok {
+		return cb
+	}
+
+	g.mu.Lock()
+	if cb, ok = g.members[name]; !ok {
+		cfg := g.cfg.Member
+		cfg.Name = name
+		if g.cfg.Config.Name != "" {
+			cfg.Name = g.cfg.Config.Name + "/" + name
+		}
+		cfg.Policy = followerPolicy{}
+		cfg.RequestThreshold = math.MaxUint32
+		cfg.WarmupDuration = 0
+		cfg.CategoryThresholds = nil
+		cb = NewCircuitBreaker(cfg)
+		g.members[name] = cb
+	}
+	g.mu.Unlock()
+
+	if !ok {
+		// the transitions delivered before the member was added are not followed
+		cb.follow(g.shared.State(), ReasonGroupJoined, g.shared.OpensAt())
+	}
+
+	return cb
+}
+
+// Members returns the breakers of all the operations.
+func (g *Group) Members() []*CircuitBreaker {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	cbs := make([]*CircuitBreaker, 0, len(g.members))
+	for _, cb := range g.members {
+		cbs = append(cbs, cb)
+	}
+
+	return cbs
+}
+
+// Execute runs the request of the operation through its breaker and the shared breaker.
+func (g *Group) Execute(member string, req func() (interface{}, error)) (interface{}, error) {
+	return g.ExecuteContext(context.Background(), member, func(ctx context.Context) (interface{}, error) {
+		return req()
+	})
+}
+
+// ExecuteContext is like Execute, but runs the request with the context, see CircuitBreaker.ExecuteContext.
+func (g *Group) ExecuteContext(ctx context.Context, member string, req func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	cb := g.Member(member)
+	// the members follow the shared breaker into the half-open state once its open period is over
+	g.shared.State()
+
+	var rejection error
+	result, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+		executed := false
+		result, err := g.shared.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+			executed = true
+			return req(ctx)
+		})
+		if !executed {
+			rejection = err
+			return nil, errAbandoned
+		}
+		return result, err
+	})
+	if rejection != nil {
+		return nil, rejection
+	}
+
+	return result, err
+}
+
+// follow moves the member of a Group into the state of the shared breaker
+func (cb *CircuitBreaker) follow(state State, reason string, opensAt time.Time) {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	if state == StateOpen {
+		cb.expiredAt = opensAt
+	} else {
+		cb.expiredAt = time.Time{}
+	}
+	cb.setState(state, reason)
+}
+
+// followerPolicy is the Policy of the members of a Group, which never change their state by themselves
+type followerPolicy struct{}
+
+func (followerPolicy) OnCall(state State, err error)               {}
+func (followerPolicy) ShouldTrip(counts Counts) bool               { return false }
+func (followerPolicy) ShouldClose(counts Counts) bool              { return false }
+func (followerPolicy) NextOpenDuration(trips uint32) time.Duration { return 0 }
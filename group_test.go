@@ -0,0 +1,90 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup(t *testing.T) {
+	g := NewGroup(GroupConfig{
+		Config: Config{Name: "billing-db", MaxConsecutiveFailures: 2, RequestThreshold: 1, Timeout: 20 * time.Millisecond},
+	})
+	ok := func() (interface{}, error) { return nil, nil }
+	failing := func() (interface{}, error) { return nil, errServiceError }
+
+	_, err := g.Execute("invoices", ok)
+	assert.Nil(t, err)
+	// the failures of different operations trip the group
+	_, err = g.Execute("invoices", failing)
+	assert.Equal(t, errServiceError, err)
+	_, err = g.Execute("payments", failing)
+	assert.Equal(t, errServiceError, err)
+
+	invoices := g.Member("invoices")
+	payments := g.Member("payments")
+	assert.Equal(t, "billing-db/invoices", invoices.Name())
+	assert.Equal(t, StateOpen, g.Shared().State())
+	assert.Equal(t, StateOpen, invoices.State())
+	assert.Equal(t, StateOpen, payments.State())
+	assert.Greater(t, invoices.RemainingOpenTime(), time.Duration(0))
+
+	// a new member joins in the state of the group
+	reports := g.Member("reports")
+	assert.Equal(t, StateOpen, reports.State())
+
+	_, err = g.Execute("reports", ok)
+	assert.Equal(t, ErrOpenState, err)
+
+	// the members keep their own metrics
+	assert.Equal(t, uint64(2), invoices.Snapshot().Totals.Requests)
+	assert.Equal(t, uint64(1), invoices.Snapshot().Totals.Failures)
+	assert.Equal(t, uint64(1), payments.Snapshot().Totals.Failures)
+	assert.Equal(t, uint64(1), reports.Snapshot().Totals.Rejections)
+	assert.Equal(t, uint64(3), g.Shared().Snapshot().Totals.Requests)
+
+	time.Sleep(30 * time.Millisecond)
+	_, err = g.Execute("payments", ok)
+	assert.Nil(t, err)
+	assert.Equal(t, StateClosed, g.Shared().State())
+	for _, cb := range g.Members() {
+		assert.Equal(t, StateClosed, cb.State(), cb.Name())
+	}
+
+	g.Shared().Trip()
+	assert.Equal(t, StateOpen, invoices.State())
+	g.Shared().Reset()
+	assert.Equal(t, StateClosed, invoices.State())
+}
+
+func TestGroupHalfOpenLimit(t *testing.T) {
+	g := NewGroup(GroupConfig{
+		Config: Config{MaxConsecutiveFailures: 1, RequestThreshold: 1, Timeout: 10 * time.Millisecond},
+	})
+
+	_, err := g.Execute("a", func() (interface{}, error) { return nil, errServiceError })
+	assert.Equal(t, errServiceError, err)
+	time.Sleep(20 * time.Millisecond)
+
+	// the probe of one member uses up the half-open requests of the group
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = g.Execute("a", func() (interface{}, error) {
+			<-release
+			return nil, nil
+		})
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	_, err = g.Execute("b", func() (interface{}, error) { return nil, nil })
+	assert.Equal(t, ErrTooManyRequests, err)
+	assert.Equal(t, uint64(1), g.Member("b").Snapshot().Totals.Requests)
+	assert.Equal(t, uint64(0), g.Member("b").Snapshot().Totals.Failures)
+
+	close(release)
+	<-done
+	assert.Equal(t, StateClosed, g.Member("b").State())
+}
@@ -0,0 +1,75 @@
+package circuit_breaker
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	defaultGuardInitialBackoff = 100 * time.Millisecond
+	defaultGuardMaxBackoff     = 10 * time.Second
+)
+
+// Guard admits the work of background workers and job processors through a CircuitBreaker.
+// Instead of failing with a rejection, Acquire blocks until the CircuitBreaker admits the request,
+// so the workers pause while the dependency is down and resume on their own once it recovers.
+type Guard struct {
+	cb      *CircuitBreaker
+	backoff func(retry uint32) time.Duration
+}
+
+// NewGuard creates a Guard for the breaker.
+// Backoff returns the delay before the next attempt after the given number of consecutive rejections,
+// when the breaker can't tell how long to wait, e.g. in the half-open state or when a limit is exceeded.
+// If backoff is nil, the delay doubles from 100ms up to 10s.
+// While the breaker is open, the workers wait for its open timeout instead.
+func NewGuard(cb *CircuitBreaker, backoff func(retry uint32) time.Duration) *Guard {
+	if backoff == nil {
+		backoff = ExponentialBackoff(defaultGuardInitialBackoff, defaultGuardMaxBackoff)
+	}
+
+	return &Guard{cb: cb, backoff: backoff}
+}
+
+// Acquire blocks until the breaker admits a request, see CircuitBreaker.Allow.
+// It returns an error only if the context is done first or the request can't be admitted by waiting,
+// like ErrInsufficientDeadline.
+func (g *Guard) Acquire(ctx context.Context) (done func(err error), err error) {
+	for retry := uint32(1); ; {
+		done, err := g.cb.Allow(ctx)
+		if err == nil {
+			return done, nil
+		}
+		if ctx.Err() != nil || errors.Is(err, ErrInsufficientDeadline) {
+			return nil, err
+		}
+
+		delay := g.cb.remainingOpenTime(time.Now())
+		if delay <= 0 {
+			delay = g.backoff(retry)
+			retry++
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Do runs the job once the breaker admits it and records its outcome.
+func (g *Guard) Do(ctx context.Context, job func(ctx context.Context) error) error {
+	done, err := g.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = job(ctx)
+	done(err)
+
+	return err
+}
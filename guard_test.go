@@ -0,0 +1,67 @@
+package circuit_breaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuardAcquire(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "guarding circuit breaker",
+		MaxConsecutiveFailures: 1,
+		RequestThreshold:       1,
+		Timeout:                20 * time.Millisecond,
+	})
+	g := NewGuard(cb, func(retry uint32) time.Duration { return time.Millisecond })
+	ctx := context.Background()
+
+	assert.Equal(t, errServiceError, fail(cb))
+
+	// the job waits for the open timeout instead of failing
+	start := time.Now()
+	calls := 0
+	assert.Nil(t, g.Do(ctx, func(ctx context.Context) error {
+		calls++
+		return nil
+	}))
+	assert.Equal(t, 1, calls)
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+	assert.Equal(t, StateClosed, cb.State())
+
+	// the half-open breaker admits one probe, the other job backs off until it completes
+	assert.Equal(t, errServiceError, fail(cb))
+	time.Sleep(30 * time.Millisecond)
+	done, err := g.Acquire(ctx)
+	assert.Nil(t, err)
+
+	acquired := make(chan struct{})
+	go func() {
+		done, err := g.Acquire(ctx)
+		assert.Nil(t, err)
+		done(nil)
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquired while the probe is in flight")
+	case <-time.After(10 * time.Millisecond):
+	}
+	done(nil)
+	<-acquired
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestGuardAcquireContext(t *testing.T) {
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 1})
+	assert.Equal(t, errServiceError, fail(cb))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	done, err := NewGuard(cb, nil).Acquire(ctx)
+	assert.Nil(t, done)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
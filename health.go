@@ -0,0 +1,73 @@
+package circuit_breaker
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// HealthStatus is the status of a breaker reported by HealthHandler.
+type HealthStatus struct {
+	Name     string `json:"name"`
+	State    State  `json:"state"`
+	Critical bool   `json:"critical"`
+	Counts   Counts `json:"counts"`
+}
+
+// HealthReport is the response body of HealthHandler.
+// Healthy is false if any critical breaker is open.
+type HealthReport struct {
+	Healthy  bool           `json:"healthy"`
+	Breakers []HealthStatus `json:"breakers"`
+}
+
+// Critical reports whether the CircuitBreaker protects a critical dependency, see Config.
+func (cb *CircuitBreaker) Critical() bool {
+	return cb.critical
+}
+
+// HealthHandler responds with the HealthReport of the breakers as JSON,
+// with 200 OK if the report is healthy and 503 Service Unavailable otherwise,
+// so the health checks of Kubernetes or load balancers reflect the health of the dependencies.
+// Open breakers which are not critical are reported without failing the check.
+func HealthHandler(cbs ...*CircuitBreaker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := Health(cbs...)
+
+		status := http.StatusOK
+		if !report.Healthy {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// Health builds the HealthReport of the breakers.
+func Health(cbs ...*CircuitBreaker) HealthReport {
+	snapshots := make([]Snapshot, 0, len(cbs))
+	for _, cb := range cbs {
+		snapshots = append(snapshots, cb.Snapshot())
+	}
+
+	return healthOf(snapshots)
+}
+
+func healthOf(snapshots []Snapshot) HealthReport {
+	report := HealthReport{Healthy: true, Breakers: make([]HealthStatus, 0, len(snapshots))}
+	for _, s := range snapshots {
+		if s.Critical && s.State == StateOpen {
+			report.Healthy = false
+		}
+		report.Breakers = append(report.Breakers, HealthStatus{
+			Name:     s.Name,
+			State:    s.State,
+			Critical: s.Critical,
+			Counts:   s.Counts,
+		})
+	}
+
+	return report
+}
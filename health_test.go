@@ -0,0 +1,37 @@
+package circuit_breaker
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthHandler(t *testing.T) {
+	critical := NewCircuitBreaker(Config{Name: "database", MaxConsecutiveFailures: 1, Critical: true})
+	optional := NewCircuitBreaker(Config{Name: "recommendations", MaxConsecutiveFailures: 1})
+	h := HealthHandler(critical, optional)
+
+	w := serve(h, "/health")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	// open breakers which are not critical don't fail the check
+	assert.Equal(t, errServiceError, fail(optional))
+	w = serve(h, "/health")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var report HealthReport
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.True(t, report.Healthy)
+	assert.Equal(t, []HealthStatus{
+		{Name: "database", State: StateClosed, Critical: true},
+		{Name: "recommendations", State: StateOpen},
+	}, report.Breakers)
+
+	assert.Equal(t, errServiceError, fail(critical))
+	w = serve(h, "/health")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `{"name":"database","state":"open","critical":true`)
+}
@@ -0,0 +1,103 @@
+package circuit_breaker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoRequests is returned by ExecuteHedged called without requests
+var ErrNoRequests = errors.New("no requests to execute")
+
+// HedgePolicy configures ExecuteHedged.
+//
+// Delay is the time to wait for an attempt before launching the next one.
+//
+// Percentile, from 0 to 1, makes the delay adaptive: once enough requests have completed,
+// the delay is the given percentile of their latencies, and Delay is used until then.
+// If Percentile is zero, Delay is always used.
+type HedgePolicy struct {
+	Delay      time.Duration
+	Percentile float64
+}
+
+type hedgeResult struct {
+	value interface{}
+	err   error
+}
+
+// ExecuteHedged runs the first request through the CircuitBreaker and, if it hasn't succeeded
+// within the delay of the policy, launches the next one as a backup, and so on.
+// The first successful result is returned and the context passed to the other attempts is cancelled.
+// If all the attempts fail, the error of the last one to finish is returned.
+//
+// Each attempt is admitted and counted by the CircuitBreaker individually,
+// except for the attempts that fail after another one has succeeded: these are cancelled by ExecuteHedged
+// rather than failed by the backend, so their outcome is not recorded.
+func (cb *CircuitBreaker) ExecuteHedged(ctx context.Context, policy HedgePolicy, reqs ...func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if len(reqs) == 0 {
+		return nil, ErrNoRequests
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var won int32
+	results := make(chan hedgeResult, len(reqs))
+	launch := func(req func(ctx context.Context) (interface{}, error)) {
+		go func() {
+			value, err := cb.execute(ctx, func() (interface{}, error) {
+				value, err := req(ctx)
+				if err != nil && atomic.LoadInt32(&won) == 1 {
+					return value, errAbandoned
+				}
+				return value, err
+			})
+			results <- hedgeResult{value: value, err: err}
+		}()
+	}
+
+	delay := cb.hedgeDelay(policy)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	launch(reqs[0])
+	launched, pending := 1, 1
+	for {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				atomic.StoreInt32(&won, 1)
+				return r.value, nil
+			}
+			if pending == 0 {
+				// hedging is not retrying: once all the launched attempts have failed, there is nothing to wait for
+				return r.value, r.err
+			}
+		case <-timer.C:
+			if launched < len(reqs) {
+				launch(reqs[launched])
+				launched++
+				pending++
+				timer.Reset(delay)
+			}
+		}
+	}
+}
+
+// hedgeDelay returns the time to wait for an attempt before launching the next one
+func (cb *CircuitBreaker) hedgeDelay(policy HedgePolicy) time.Duration {
+	if policy.Percentile <= 0 {
+		return policy.Delay
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.latencies.size < minLatencySamples {
+		return policy.Delay
+	}
+	return cb.latencies.percentile(policy.Percentile)
+}
@@ -0,0 +1,92 @@
+package circuit_breaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slow returns the value after the delay, or the context error if it is cancelled first
+func slow(value interface{}, delay time.Duration, cancelled chan<- struct{}) func(ctx context.Context) (interface{}, error) {
+	return func(ctx context.Context) (interface{}, error) {
+		select {
+		case <-time.After(delay):
+			return value, nil
+		case <-ctx.Done():
+			if cancelled != nil {
+				close(cancelled)
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func TestCircuitBreakerExecuteHedged(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "hedging circuit breaker",
+		MaxConsecutiveFailures: 1,
+	})
+
+	cancelled := make(chan struct{})
+	result, err := cb.ExecuteHedged(context.Background(), HedgePolicy{Delay: 10 * time.Millisecond},
+		slow("primary", time.Second, cancelled),
+		slow("backup", 0, nil),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "backup", result)
+
+	// the primary is cancelled and its failure is not recorded
+	<-cancelled
+	assert.Nil(t, cb.Drain(context.Background()))
+	assert.Equal(t, Counts{Requests: 2, TotalSuccesses: 1, ConsecutiveSuccesses: 1}, cb.Counts())
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestCircuitBreakerExecuteHedgedFastPrimary(t *testing.T) {
+	cb := NewCircuitBreaker(Config{})
+
+	launched := false
+	result, err := cb.ExecuteHedged(context.Background(), HedgePolicy{Delay: time.Second},
+		slow("primary", 0, nil),
+		func(ctx context.Context) (interface{}, error) {
+			launched = true
+			return "backup", nil
+		},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "primary", result)
+	assert.False(t, launched)
+	assert.Equal(t, uint32(1), cb.Counts().Requests)
+}
+
+func TestCircuitBreakerExecuteHedgedFailure(t *testing.T) {
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 1})
+
+	_, err := cb.ExecuteHedged(context.Background(), HedgePolicy{Delay: time.Second},
+		func(ctx context.Context) (interface{}, error) { return nil, errServiceError },
+		slow("backup", 0, nil),
+	)
+	assert.Equal(t, errServiceError, err)
+	assert.Equal(t, StateOpen, cb.State())
+
+	_, err = cb.ExecuteHedged(context.Background(), HedgePolicy{}, slow("primary", 0, nil))
+	assert.Equal(t, ErrOpenState, err)
+
+	_, err = cb.ExecuteHedged(context.Background(), HedgePolicy{})
+	assert.Equal(t, ErrNoRequests, err)
+}
+
+func TestCircuitBreakerHedgeDelay(t *testing.T) {
+	cb := NewCircuitBreaker(Config{})
+	policy := HedgePolicy{Delay: time.Second, Percentile: 0.5}
+
+	assert.Equal(t, time.Second, cb.hedgeDelay(policy))
+
+	for i := 1; i <= minLatencySamples; i++ {
+		cb.latencies.record(time.Duration(i) * time.Millisecond)
+	}
+	assert.Equal(t, 5*time.Millisecond, cb.hedgeDelay(policy))
+	assert.Equal(t, time.Second, cb.hedgeDelay(HedgePolicy{Delay: time.Second}))
+}
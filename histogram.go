@@ -0,0 +1,151 @@
+package circuit_breaker
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultHistogramBounds are the upper bounds of the buckets of NewBucketHistogram, from 1ms to 10s.
+var DefaultHistogramBounds = []time.Duration{
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Histogram records the duration of every protected call, see Config.LatencyHistogram.
+// Besides BucketHistogram, the HDR histogram and t-digest implementations live in contrib.
+// Implementations must be safe for concurrent use.
+//
+// Quantile returns the q-th (0 <= q <= 1) quantile of the recorded durations, or 0 if there are none.
+type Histogram interface {
+	Record(d time.Duration)
+	Quantile(q float64) time.Duration
+	Snapshot() HistogramSnapshot
+}
+
+// HistogramSnapshot is the state of a Histogram for metrics exporters.
+// Count and Sum are the number and the sum of the recorded durations.
+// Buckets are cumulative: the Count of each bucket is the number of the durations up to its UpperBound.
+type HistogramSnapshot struct {
+	Count   uint64
+	Sum     time.Duration
+	Buckets []HistogramBucket
+}
+
+// HistogramBucket is a bucket of HistogramSnapshot.
+type HistogramBucket struct {
+	UpperBound time.Duration
+	Count      uint64
+}
+
+// BucketHistogram is a Histogram counting the durations in buckets with fixed upper bounds,
+// like the histograms of Prometheus. Quantiles are interpolated linearly within the buckets.
+type BucketHistogram struct {
+	bounds []time.Duration
+
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    time.Duration
+	max    time.Duration
+}
+
+// NewBucketHistogram creates the BucketHistogram with the upper bounds of the buckets in increasing order.
+// The durations above the last bound are counted in an overflow bucket.
+// If bounds is empty, DefaultHistogramBounds are used.
+func NewBucketHistogram(bounds []time.Duration) *BucketHistogram {
+	if len(bounds) == 0 {
+		bounds = DefaultHistogramBounds
+	}
+
+	return &BucketHistogram{
+		bounds: append([]time.Duration(nil), bounds...),
+		counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+// Record counts the duration in its bucket.
+func (h *BucketHistogram) Record(d time.Duration) {
+	i := sort.Search(len(h.bounds), func(i int) bool { return d <= h.bounds[i] })
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.counts[i]++
+	h.count++
+	h.sum += d
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// Quantile interpolates the quantile within the bucket containing it.
+// The overflow bucket is bounded by the largest recorded duration.
+func (h *BucketHistogram) Quantile(q float64) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.count == 0 {
+		return 0
+	}
+
+	rank := q * float64(h.count)
+	var seen uint64
+	for i, n := range h.counts {
+		if n == 0 || float64(seen+n) < rank {
+			seen += n
+			continue
+		}
+
+		lower := time.Duration(0)
+		if i > 0 {
+			lower = h.bounds[i-1]
+		}
+		upper := h.max
+		if i < len(h.bounds) && h.bounds[i] < upper {
+			upper = h.bounds[i]
+		}
+		if upper < lower {
+			return upper
+		}
+
+		fraction := (rank - float64(seen)) / float64(n)
+		if fraction < 0 {
+			fraction = 0
+		}
+		return lower + time.Duration(fraction*float64(upper-lower))
+	}
+
+	return h.max
+}
+
+// Snapshot returns the cumulative counts of the buckets, excluding the overflow bucket.
+func (h *BucketHistogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := HistogramSnapshot{Count: h.count, Sum: h.sum, Buckets: make([]HistogramBucket, len(h.bounds))}
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		s.Buckets[i] = HistogramBucket{UpperBound: bound, Count: cumulative}
+	}
+
+	return s
+}
+
+// LatencyHistogram returns the configured LatencyHistogram, or nil, e.g. for percentile-based policies.
+func (cb *CircuitBreaker) LatencyHistogram() Histogram {
+	return cb.latencyHistogram
+}
@@ -0,0 +1,52 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucketHistogram(t *testing.T) {
+	h := NewBucketHistogram([]time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond})
+	assert.Equal(t, time.Duration(0), h.Quantile(0.5))
+
+	for i := 1; i <= 100; i++ {
+		h.Record(time.Duration(i) * 400 * time.Microsecond)
+	}
+
+	s := h.Snapshot()
+	assert.Equal(t, uint64(100), s.Count)
+	assert.Equal(t, 2020*time.Millisecond, s.Sum)
+	assert.Equal(t, []HistogramBucket{
+		{UpperBound: 10 * time.Millisecond, Count: 25},
+		{UpperBound: 20 * time.Millisecond, Count: 50},
+		{UpperBound: 40 * time.Millisecond, Count: 100},
+	}, s.Buckets)
+
+	assert.Equal(t, 20*time.Millisecond, h.Quantile(0.5))
+	assert.Equal(t, 30*time.Millisecond, h.Quantile(0.75))
+	assert.Equal(t, 5*time.Millisecond, h.Quantile(0.125))
+	assert.Equal(t, 40*time.Millisecond, h.Quantile(1))
+
+	// the overflow bucket is bounded by the largest duration
+	h.Record(time.Second)
+	assert.Equal(t, time.Second, h.Quantile(1))
+	assert.Equal(t, uint64(100), h.Snapshot().Buckets[2].Count)
+	assert.Equal(t, uint64(101), h.Snapshot().Count)
+}
+
+func TestCircuitBreakerLatencyHistogram(t *testing.T) {
+	assert.Equal(t, HistogramSnapshot{}, NewCircuitBreaker(Config{}).Snapshot().Latency)
+
+	h := NewBucketHistogram(nil)
+	cb := NewCircuitBreaker(Config{LatencyHistogram: h})
+	assert.Equal(t, h, cb.LatencyHistogram())
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, errServiceError, fail(cb))
+
+	s := cb.Snapshot().Latency
+	assert.Equal(t, uint64(2), s.Count)
+	assert.Len(t, s.Buckets, len(DefaultHistogramBounds))
+	assert.Equal(t, uint64(2), s.Buckets[len(s.Buckets)-1].Count)
+}
@@ -0,0 +1,63 @@
+package circuit_breaker
+
+import "time"
+
+// Transition is a state change kept in the history of the CircuitBreaker.
+//
+// Counts are the Counts which led to the change, and LastError is the last failure counted in them, if any.
+type Transition struct {
+	At        time.Time
+	From      State
+	To        State
+	Reason    string
+	Counts    Counts
+	LastError error
+}
+
+// history is a ring buffer of the last transitions
+type history struct {
+	entries []Transition
+	next    int
+}
+
+func newHistory(size int) history {
+	if size <= 0 {
+		return history{}
+	}
+
+	return history{entries: make([]Transition, 0, size)}
+}
+
+func (h *history) add(t Transition) {
+	if cap(h.entries) == 0 {
+		return
+	}
+
+	if len(h.entries) < cap(h.entries) {
+		h.entries = append(h.entries, t)
+	} else {
+		h.entries[h.next] = t
+	}
+	h.next = (h.next + 1) % cap(h.entries)
+}
+
+// list returns the transitions from the oldest to the newest
+func (h *history) list() []Transition {
+	list := make([]Transition, 0, len(h.entries))
+	if len(h.entries) == cap(h.entries) {
+		list = append(list, h.entries[h.next:]...)
+		return append(list, h.entries[:h.next]...)
+	}
+
+	return append(list, h.entries...)
+}
+
+// History returns the last state changes of the CircuitBreaker, from the oldest to the newest,
+// up to HistorySize of them.
+func (cb *CircuitBreaker) History() []Transition {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	cb.refreshState(time.Now())
+	return cb.history.list()
+}
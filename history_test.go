@@ -0,0 +1,52 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerHistory(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "history circuit breaker",
+		MaxConsecutiveFailures: 2,
+		RequestThreshold:       1,
+		Timeout:                10 * time.Millisecond,
+		HistorySize:            3,
+	})
+	assert.Empty(t, cb.History())
+
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, errServiceError, fail(cb))
+
+	history := cb.History()
+	assert.Len(t, history, 1)
+	assert.Equal(t, StateClosed, history[0].From)
+	assert.Equal(t, StateOpen, history[0].To)
+	assert.Equal(t, ReasonTripped, history[0].Reason)
+	assert.Equal(t, Counts{Requests: 3, TotalSuccesses: 1, TotalFailures: 2, ConsecutiveFailures: 2}, history[0].Counts)
+	assert.Equal(t, errServiceError, history[0].LastError)
+	assert.WithinDuration(t, time.Now(), history[0].At, time.Second)
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Nil(t, succeed(cb))
+	cb.Reset()
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, errServiceError, fail(cb))
+
+	// the oldest transition is dropped
+	history = cb.History()
+	assert.Len(t, history, 3)
+	assert.Equal(t, []State{StateHalfOpen, StateClosed, StateOpen}, []State{history[0].To, history[1].To, history[2].To})
+	assert.Equal(t, []string{ReasonTimeout, ReasonProbeSucceeded, ReasonTripped},
+		[]string{history[0].Reason, history[1].Reason, history[2].Reason})
+	assert.Nil(t, history[1].LastError)
+}
+
+func TestCircuitBreakerHistoryDisabled(t *testing.T) {
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 1})
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Empty(t, cb.History())
+}
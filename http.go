@@ -0,0 +1,97 @@
+package circuit_breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// StatusError is the failure recorded for an HTTP response with a failure status code
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("http status %d", e.StatusCode)
+}
+
+// HTTPClassifier maps HTTP responses to request outcomes.
+//
+// FailureStatusCodes are the status codes treated as failures.
+// If FailureStatusCodes is empty, 5xx and 429 are treated as failures and any other status code as success.
+//
+// IgnoreStatusCodes are never treated as failures, even if they are in FailureStatusCodes.
+type HTTPClassifier struct {
+	FailureStatusCodes []int
+	IgnoreStatusCodes  []int
+}
+
+// Classify returns err if the request failed at the transport level,
+// *StatusError if the response status code is a failure, and nil otherwise.
+func (c HTTPClassifier) Classify(resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	if resp == nil {
+		return nil
+	}
+
+	return c.ClassifyStatus(resp.StatusCode)
+}
+
+// ClassifyStatus returns *StatusError if the status code is a failure, and nil otherwise.
+// It is meant for server-side integrations classifying the status code written by a handler.
+func (c HTTPClassifier) ClassifyStatus(code int) error {
+	if !c.isFailure(code) {
+		return nil
+	}
+
+	return &StatusError{StatusCode: code}
+}
+
+func (c HTTPClassifier) isFailure(code int) bool {
+	for _, ignored := range c.IgnoreStatusCodes {
+		if code == ignored {
+			return false
+		}
+	}
+	if len(c.FailureStatusCodes) == 0 {
+		return code >= http.StatusInternalServerError || code == http.StatusTooManyRequests
+	}
+	for _, failure := range c.FailureStatusCodes {
+		if code == failure {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClassifyHTTPResponse classifies the response with the default HTTPClassifier:
+// 5xx and 429 are failures, 4xx are successes.
+func ClassifyHTTPResponse(resp *http.Response, err error) error {
+	return HTTPClassifier{}.Classify(resp, err)
+}
+
+// ExecuteHTTP runs the HTTP request if the CircuitBreaker accepts it,
+// and records its outcome according to the FailureStatusCodes and IgnoreStatusCodes of the Config.
+// The response is returned as is: a failure status code doesn't produce an error.
+func (cb *CircuitBreaker) ExecuteHTTP(req func() (*http.Response, error)) (*http.Response, error) {
+	return cb.executeHTTP(context.Background(), req)
+}
+
+func (cb *CircuitBreaker) executeHTTP(ctx context.Context, req func() (*http.Response, error)) (*http.Response, error) {
+	result, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+		resp, err := req()
+		return resp, cb.httpClassifier.Classify(resp, err)
+	})
+
+	resp, _ := result.(*http.Response)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return resp, nil
+	}
+
+	return resp, err
+}
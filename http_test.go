@@ -0,0 +1,56 @@
+package circuit_breaker
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func response(code int) *http.Response {
+	return &http.Response{StatusCode: code}
+}
+
+func TestClassifyHTTPResponse(t *testing.T) {
+	assert.Nil(t, ClassifyHTTPResponse(response(http.StatusOK), nil))
+	assert.Nil(t, ClassifyHTTPResponse(response(http.StatusNotFound), nil))
+	assert.Equal(t, &StatusError{StatusCode: http.StatusTooManyRequests}, ClassifyHTTPResponse(response(http.StatusTooManyRequests), nil))
+	assert.Equal(t, &StatusError{StatusCode: http.StatusBadGateway}, ClassifyHTTPResponse(response(http.StatusBadGateway), nil))
+	assert.Equal(t, errServiceError, ClassifyHTTPResponse(nil, errServiceError))
+}
+
+func TestHTTPClassifier(t *testing.T) {
+	c := HTTPClassifier{
+		FailureStatusCodes: []int{http.StatusServiceUnavailable, http.StatusNotImplemented},
+		IgnoreStatusCodes:  []int{http.StatusNotImplemented},
+	}
+
+	assert.Nil(t, c.Classify(response(http.StatusInternalServerError), nil))
+	assert.Nil(t, c.Classify(response(http.StatusNotImplemented), nil))
+	assert.Equal(t, &StatusError{StatusCode: http.StatusServiceUnavailable}, c.Classify(response(http.StatusServiceUnavailable), nil))
+	assert.Equal(t, "http status 503", c.Classify(response(http.StatusServiceUnavailable), nil).Error())
+	assert.Nil(t, c.ClassifyStatus(http.StatusInternalServerError))
+	assert.Equal(t, &StatusError{StatusCode: http.StatusServiceUnavailable}, c.ClassifyStatus(http.StatusServiceUnavailable))
+}
+
+func TestCircuitBreakerExecuteHTTP(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:              "http circuit breaker",
+		IgnoreStatusCodes: []int{http.StatusServiceUnavailable},
+	})
+
+	resp, err := cb.ExecuteHTTP(func() (*http.Response, error) { return response(http.StatusBadGateway), nil })
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, Counts{1, 0, 1, 0, 1}, cb.counts)
+
+	resp, err = cb.ExecuteHTTP(func() (*http.Response, error) { return response(http.StatusServiceUnavailable), nil })
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, Counts{2, 1, 1, 1, 0}, cb.counts)
+
+	resp, err = cb.ExecuteHTTP(func() (*http.Response, error) { return nil, errServiceError })
+	assert.Equal(t, errServiceError, err)
+	assert.Nil(t, resp)
+	assert.Equal(t, Counts{3, 1, 2, 0, 1}, cb.counts)
+}
@@ -0,0 +1,256 @@
+package circuit_breaker
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultStripes = 32
+
+// KeyedConfig configures KeyedBreaker.
+//
+// Config is the template for the breakers created per key.
+// The name of each breaker is the template Name and the key joined with "/",
+// or just the key if Name is empty. The separator is not repeated if the key starts with it,
+// e.g. the full method names of gRPC ("/pkg.Service/Method").
+//
+// ConfigFor overrides the template for particular keys. If ConfigFor is nil, Config is used for every key.
+//
+// Stripes is the number of independently locked partitions of the keys.
+// If Stripes is zero, 32 stripes are used.
+//
+// Eviction evicts the idle and the least recently used breakers.
+// The idle breakers of a stripe are evicted when a breaker is created in the stripe.
+type KeyedConfig struct {
+	Config    Config
+	ConfigFor func(key string) Config
+	Stripes   int
+	Eviction  Eviction
+}
+
+// KeyedBreaker manages one CircuitBreaker per key (host, tenant, shard)
+// behind a single object. Breakers are created lazily on first use.
+// KeyedBreaker is safe for concurrent use.
+type KeyedBreaker struct {
+	cfg     KeyedConfig
+	stripes []keyedStripe
+	// size is the number of the breakers of all the stripes
+	size int64
+}
+
+type keyedStripe struct {
+	mu        sync.RWMutex
+	breakers  map[string]*managedBreaker
+	lastSweep time.Time
+}
+
+func NewKeyedBreaker(cfg KeyedConfig) *KeyedBreaker {
+	if cfg.Stripes <= 0 {
+		cfg.Stripes = defaultStripes
+	}
+
+	kb := KeyedBreaker{
+		cfg:     cfg,
+		stripes: make([]keyedStripe, cfg.Stripes),
+	}
+	now := time.Now()
+	for i := range kb.stripes {
+		kb.stripes[i].breakers = make(map[string]*managedBreaker)
+		kb.stripes[i].lastSweep = now
+	}
+
+	return &kb
+}
+
+// Execute runs the request through the CircuitBreaker of the key.
+func (kb *KeyedBreaker) Execute(key string, req func() (interface{}, error)) (interface{}, error) {
+	return kb.Get(key).Execute(req)
+}
+
+// Get returns the CircuitBreaker of the key, creating it if necessary.
+func (kb *KeyedBreaker) Get(key string) *CircuitBreaker {
+	if cb, ok := kb.Lookup(key); ok {
+		return cb
+	}
+
+	s := kb.stripe(key)
+	now := time.Now()
+	s.mu.Lock()
+	if m, ok := s.breakers[key]; ok {
+		s.mu.Unlock()
+		m.touch(now)
+		return m.cb
+	}
+
+	var evicted []*CircuitBreaker
+	if now.Sub(s.lastSweep) >= kb.cfg.Eviction.IdleTTL {
+		evicted = kb.cfg.Eviction.sweepIdle(s.breakers, now)
+		s.lastSweep = now
+	}
+	cb := NewCircuitBreaker(kb.config(key))
+	s.breakers[key] = newManagedBreaker(cb, now)
+	size := atomic.AddInt64(&kb.size, int64(1-len(evicted)))
+	s.mu.Unlock()
+
+	if maxSize := kb.cfg.Eviction.MaxSize; maxSize > 0 && size > int64(maxSize) {
+		if lru := kb.evictLeastRecentlyUsed(now); lru != nil {
+			evicted = append(evicted, lru)
+		}
+	}
+	kb.cfg.Eviction.evicted(evicted...)
+
+	return cb
+}
+
+// Lookup returns the CircuitBreaker of the key if it exists.
+func (kb *KeyedBreaker) Lookup(key string) (*CircuitBreaker, bool) {
+	s := kb.stripe(key)
+
+	s.mu.RLock()
+	m, ok := s.breakers[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+
+	m.touch(time.Now())
+	return m.cb, true
+}
+
+// Remove forgets the CircuitBreaker of the key.
+// The next request with the key gets a fresh breaker.
+func (kb *KeyedBreaker) Remove(key string) {
+	s := kb.stripe(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.breakers[key]; ok {
+		delete(s.breakers, key)
+		atomic.AddInt64(&kb.size, -1)
+	}
+}
+
+// EvictIdle evicts the breakers idle for Eviction.IdleTTL and returns their number.
+func (kb *KeyedBreaker) EvictIdle() int {
+	now := time.Now()
+	var evicted []*CircuitBreaker
+	for i := range kb.stripes {
+		s := &kb.stripes[i]
+		s.mu.Lock()
+		stripeEvicted := kb.cfg.Eviction.sweepIdle(s.breakers, now)
+		s.lastSweep = now
+		atomic.AddInt64(&kb.size, -int64(len(stripeEvicted)))
+		s.mu.Unlock()
+		evicted = append(evicted, stripeEvicted...)
+	}
+
+	kb.cfg.Eviction.evicted(evicted...)
+	return len(evicted)
+}
+
+// evictLeastRecentlyUsed evicts the least recently used breaker of all the stripes.
+// The stripes are locked one at a time, so the choice is approximate under concurrent use.
+func (kb *KeyedBreaker) evictLeastRecentlyUsed(now time.Time) *CircuitBreaker {
+	var oldestStripe *keyedStripe
+	var oldestKey string
+	var oldest time.Time
+	for i := range kb.stripes {
+		s := &kb.stripes[i]
+		s.mu.Lock()
+		key, used, ok := leastRecentlyUsed(s.breakers, now)
+		s.mu.Unlock()
+		if ok && (oldestStripe == nil || used.Before(oldest)) {
+			oldestStripe, oldestKey, oldest = s, key, used
+		}
+	}
+	if oldestStripe == nil {
+		return nil
+	}
+
+	oldestStripe.mu.Lock()
+	defer oldestStripe.mu.Unlock()
+
+	m, ok := oldestStripe.breakers[oldestKey]
+	if !ok {
+		return nil
+	}
+	delete(oldestStripe.breakers, oldestKey)
+	atomic.AddInt64(&kb.size, -1)
+
+	return m.cb
+}
+
+// Keys returns the keys of all the existing breakers.
+func (kb *KeyedBreaker) Keys() []string {
+	var keys []string
+	for i := range kb.stripes {
+		s := &kb.stripes[i]
+		s.mu.RLock()
+		for key := range s.breakers {
+			keys = append(keys, key)
+		}
+		s.mu.RUnlock()
+	}
+
+	return keys
+}
+
+// Breakers returns all the existing breakers.
+func (kb *KeyedBreaker) Breakers() []*CircuitBreaker {
+	var cbs []*CircuitBreaker
+	for i := range kb.stripes {
+		s := &kb.stripes[i]
+		s.mu.RLock()
+		for _, m := range s.breakers {
+			cbs = append(cbs, m.cb)
+		}
+		s.mu.RUnlock()
+	}
+
+	return cbs
+}
+
+// Len returns the number of existing breakers.
+func (kb *KeyedBreaker) Len() int {
+	n := 0
+	for i := range kb.stripes {
+		s := &kb.stripes[i]
+		s.mu.RLock()
+		n += len(s.breakers)
+		s.mu.RUnlock()
+	}
+
+	return n
+}
+
+func (kb *KeyedBreaker) config(key string) Config {
+	cfg := kb.cfg.Config
+	if kb.cfg.ConfigFor != nil {
+		cfg = kb.cfg.ConfigFor(key)
+	}
+
+	switch {
+	case cfg.Name == "":
+		cfg.Name = key
+	case strings.HasPrefix(key, "/"):
+		cfg.Name = cfg.Name + key
+	default:
+		cfg.Name = cfg.Name + "/" + key
+	}
+
+	return cfg
+}
+
+func (kb *KeyedBreaker) stripe(key string) *keyedStripe {
+	// FNV-1a
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+
+	return &kb.stripes[h%uint32(len(kb.stripes))]
+}
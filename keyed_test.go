@@ -0,0 +1,73 @@
+package circuit_breaker
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedBreaker(t *testing.T) {
+	kb := NewKeyedBreaker(KeyedConfig{
+		Config: Config{Name: "hosts", MaxConsecutiveFailures: 2},
+		ConfigFor: func(key string) Config {
+			if key == "fragile" {
+				return Config{MaxConsecutiveFailures: 1}
+			}
+			return Config{Name: "hosts", MaxConsecutiveFailures: 2}
+		},
+	})
+
+	for i := 0; i < 2; i++ {
+		_, err := kb.Execute("a", func() (interface{}, error) { return nil, errServiceError })
+		assert.Equal(t, errServiceError, err)
+	}
+	_, err := kb.Execute("b", func() (interface{}, error) { return "ok", nil })
+	assert.Nil(t, err)
+	_, err = kb.Execute("fragile", func() (interface{}, error) { return nil, errServiceError })
+	assert.Equal(t, errServiceError, err)
+
+	// one failing key doesn't affect the others
+	assert.Equal(t, StateOpen, kb.Get("a").State())
+	assert.Equal(t, StateClosed, kb.Get("b").State())
+	assert.Equal(t, StateOpen, kb.Get("fragile").State())
+	assert.Equal(t, "hosts/a", kb.Get("a").Name())
+	assert.Equal(t, "fragile", kb.Get("fragile").Name())
+	assert.Equal(t, "hosts/pkg.Service/Method", kb.Get("/pkg.Service/Method").Name())
+	assert.Same(t, kb.Get("a"), kb.Get("a"))
+
+	keys := kb.Keys()
+	sort.Strings(keys)
+	assert.Equal(t, []string{"/pkg.Service/Method", "a", "b", "fragile"}, keys)
+	assert.Equal(t, 4, kb.Len())
+	assert.Len(t, kb.Breakers(), 4)
+	assert.Contains(t, kb.Breakers(), kb.Get("fragile"))
+
+	kb.Remove("a")
+	_, ok := kb.Lookup("a")
+	assert.False(t, ok)
+	assert.Equal(t, StateClosed, kb.Get("a").State())
+}
+
+func TestKeyedBreakerConcurrentCreation(t *testing.T) {
+	kb := NewKeyedBreaker(KeyedConfig{Stripes: 4})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, _ = kb.Execute(fmt.Sprint(j), func() (interface{}, error) { return nil, nil })
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 100, kb.Len())
+	for j := 0; j < 100; j++ {
+		assert.Equal(t, uint32(8), kb.Get(fmt.Sprint(j)).Counts().Requests)
+	}
+}
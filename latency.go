@@ -0,0 +1,67 @@
+package circuit_breaker
+
+import (
+	"sort"
+	"time"
+)
+
+const latencySampleSize = 128
+
+// latencySample keeps the durations of the most recent requests
+type latencySample struct {
+	values [latencySampleSize]time.Duration
+	size   int
+	next   int
+}
+
+func (s *latencySample) record(d time.Duration) {
+	s.values[s.next] = d
+	s.next = (s.next + 1) % latencySampleSize
+	if s.size < latencySampleSize {
+		s.size++
+	}
+}
+
+// percentile returns the p-th (0 < p <= 1) percentile of the sample, or 0 if it is empty
+func (s *latencySample) percentile(p float64) time.Duration {
+	return s.percentiles(p)[0]
+}
+
+// percentiles returns the given percentiles of the sample, sorting it once
+func (s *latencySample) percentiles(ps ...float64) []time.Duration {
+	result := make([]time.Duration, len(ps))
+	if s.size == 0 {
+		return result
+	}
+
+	sorted := s.values
+	values := sorted[:s.size]
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	for k, p := range ps {
+		i := int(float64(s.size)*p+0.5) - 1
+		if i < 0 {
+			i = 0
+		}
+		if i >= s.size {
+			i = s.size - 1
+		}
+		result[k] = values[i]
+	}
+
+	return result
+}
+
+// mean returns the mean of the sample, or 0 if it is empty
+func (s *latencySample) mean() time.Duration {
+	if s.size == 0 {
+		return 0
+	}
+
+	var sum time.Duration
+	for _, d := range s.values[:s.size] {
+		sum += d
+	}
+
+	return sum / time.Duration(s.size)
+}
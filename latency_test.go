@@ -0,0 +1,40 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencySample(t *testing.T) {
+	var s latencySample
+	assert.Equal(t, time.Duration(0), s.percentile(0.95))
+
+	for i := 100; i > 0; i-- {
+		s.record(time.Duration(i) * time.Millisecond)
+	}
+	assert.Equal(t, 100, s.size)
+	assert.Equal(t, 95*time.Millisecond, s.percentile(0.95))
+	assert.Equal(t, 50*time.Millisecond, s.percentile(0.5))
+	assert.Equal(t, 100*time.Millisecond, s.percentile(1))
+
+	// the oldest values are overwritten
+	for i := 0; i < latencySampleSize; i++ {
+		s.record(time.Second)
+	}
+	assert.Equal(t, latencySampleSize, s.size)
+	assert.Equal(t, time.Second, s.percentile(0.5))
+}
+
+func TestLatencySampleStats(t *testing.T) {
+	var s latencySample
+	assert.Equal(t, time.Duration(0), s.mean())
+	assert.Equal(t, []time.Duration{0, 0}, s.percentiles(0.5, 0.99))
+
+	for i := 1; i <= 100; i++ {
+		s.record(time.Duration(i) * time.Millisecond)
+	}
+	assert.Equal(t, 50500*time.Microsecond, s.mean())
+	assert.Equal(t, []time.Duration{50 * time.Millisecond, 95 * time.Millisecond, 99 * time.Millisecond}, s.percentiles(0.5, 0.95, 0.99))
+}
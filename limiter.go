@@ -0,0 +1,88 @@
+package circuit_breaker
+
+import (
+	"errors"
+	"time"
+)
+
+const defaultBackoffRatio = 0.9
+
+// ErrLimitExceeded is returned when the number of requests in flight reaches the adaptive concurrency limit
+var ErrLimitExceeded = errors.New("concurrency limit exceeded")
+
+// ConcurrencyLimiter is an adaptive admission mode which grows and shrinks
+// the limit of requests in flight based on the observed outcomes.
+// All the methods are called while the CircuitBreaker lock is held.
+type ConcurrencyLimiter interface {
+	// Limit returns the current maximum number of requests in flight.
+	Limit() int
+	// OnSample is called when a request completes, with its latency,
+	// the number of requests in flight when it completed (including itself) and whether it failed.
+	OnSample(latency time.Duration, inFlight int, failed bool)
+}
+
+// AIMDConfig configures AIMDLimiter.
+//
+// InitialLimit, MinLimit and MaxLimit bound the limit, 20, 1 and 200 by default.
+//
+// LatencyThreshold is the latency above which a request is considered a sign of congestion.
+// If LatencyThreshold is zero, only failures are.
+//
+// BackoffRatio is the factor applied to the limit on congestion, 0.9 by default.
+type AIMDConfig struct {
+	InitialLimit     int
+	MinLimit         int
+	MaxLimit         int
+	LatencyThreshold time.Duration
+	BackoffRatio     float64
+}
+
+// AIMDLimiter is an additive-increase/multiplicative-decrease ConcurrencyLimiter:
+// the limit grows by one on each successful request made while the limit is well utilized,
+// and shrinks by BackoffRatio on each failed or slow request.
+type AIMDLimiter struct {
+	cfg   AIMDConfig
+	limit float64
+}
+
+func NewAIMDLimiter(cfg AIMDConfig) *AIMDLimiter {
+	if cfg.MinLimit <= 0 {
+		cfg.MinLimit = 1
+	}
+	if cfg.MaxLimit <= 0 {
+		cfg.MaxLimit = 200
+	}
+	if cfg.InitialLimit <= 0 {
+		cfg.InitialLimit = 20
+	}
+	if cfg.BackoffRatio <= 0 || cfg.BackoffRatio >= 1 {
+		cfg.BackoffRatio = defaultBackoffRatio
+	}
+
+	l := AIMDLimiter{cfg: cfg}
+	l.set(float64(cfg.InitialLimit))
+
+	return &l
+}
+
+func (l *AIMDLimiter) Limit() int {
+	return int(l.limit)
+}
+
+func (l *AIMDLimiter) OnSample(latency time.Duration, inFlight int, failed bool) {
+	if failed || l.cfg.LatencyThreshold > 0 && latency > l.cfg.LatencyThreshold {
+		l.set(l.limit * l.cfg.BackoffRatio)
+	} else if inFlight*2 >= l.Limit() {
+		l.set(l.limit + 1)
+	}
+}
+
+func (l *AIMDLimiter) set(limit float64) {
+	if limit < float64(l.cfg.MinLimit) {
+		limit = float64(l.cfg.MinLimit)
+	}
+	if limit > float64(l.cfg.MaxLimit) {
+		limit = float64(l.cfg.MaxLimit)
+	}
+	l.limit = limit
+}
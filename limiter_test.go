@@ -0,0 +1,60 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAIMDLimiter(t *testing.T) {
+	l := NewAIMDLimiter(AIMDConfig{
+		InitialLimit:     10,
+		MinLimit:         2,
+		MaxLimit:         12,
+		LatencyThreshold: 100 * time.Millisecond,
+		BackoffRatio:     0.5,
+	})
+	assert.Equal(t, 10, l.Limit())
+
+	// the limit is not utilized enough to grow
+	l.OnSample(time.Millisecond, 2, false)
+	assert.Equal(t, 10, l.Limit())
+
+	// additive increase up to MaxLimit
+	for i := 0; i < 5; i++ {
+		l.OnSample(time.Millisecond, 10, false)
+	}
+	assert.Equal(t, 12, l.Limit())
+
+	// multiplicative decrease down to MinLimit
+	l.OnSample(time.Second, 10, false)
+	assert.Equal(t, 6, l.Limit())
+	l.OnSample(time.Millisecond, 10, true)
+	assert.Equal(t, 3, l.Limit())
+	l.OnSample(time.Millisecond, 10, true)
+	assert.Equal(t, 2, l.Limit())
+}
+
+func TestCircuitBreakerConcurrencyLimiter(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:               "limited circuit breaker",
+		ConcurrencyLimiter: NewAIMDLimiter(AIMDConfig{InitialLimit: 2, MinLimit: 1}),
+	})
+
+	release := make(chan struct{})
+	occupy(cb, release)
+	occupy(cb, release)
+	assert.Equal(t, ErrLimitExceeded, succeed(cb))
+
+	close(release)
+	assert.Eventually(t, func() bool { return succeed(cb) == nil }, time.Second, time.Millisecond)
+	grown := cb.concurrencyLimiter.Limit()
+	assert.Greater(t, grown, 2)
+
+	// failures shrink the limit
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, errServiceError, fail(cb))
+	}
+	assert.Less(t, cb.concurrencyLimiter.Limit(), grown)
+}
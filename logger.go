@@ -0,0 +1,62 @@
+package circuit_breaker
+
+import "time"
+
+// Logger is a leveled structured logger, taking the fields of the message as alternating keys and values.
+// *slog.Logger implements it, see SlogLogger.
+//
+// The CircuitBreaker logs the rejected requests at the debug level,
+// the state changes at the warn level when the circuit opens and at the info level otherwise,
+// and the outcomes of the half-open probes at the info level when they succeed and at the warn level when they fail.
+// The methods are called without the CircuitBreaker lock held.
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+func (cb *CircuitBreaker) logStateChange(change stateChange) {
+	log := cb.logger.Info
+	if change.to == StateOpen {
+		log = cb.logger.Warn
+	}
+
+	log("circuit breaker state changed",
+		"name", cb.name,
+		"from", change.from.String(),
+		"to", change.to.String(),
+		"reason", change.reason,
+		"requests", change.counts.Requests,
+		"failures", change.counts.TotalFailures,
+		"consecutive_failures", change.counts.ConsecutiveFailures,
+		"consecutive_successes", change.counts.ConsecutiveSuccesses,
+	)
+}
+
+// onProbe reports the outcome of the half-open probe
+func (cb *CircuitBreaker) onProbe(listeners []*listener, err error, latency time.Duration, correlationID string, at time.Time) {
+	if cb.logger != nil {
+		cb.logProbe(err, latency, correlationID)
+	}
+	emit(listeners, ProbeResult{
+		Name:          cb.name,
+		Success:       err == nil,
+		Latency:       latency,
+		Err:           err,
+		CorrelationID: correlationID,
+		At:            at,
+	})
+}
+
+func (cb *CircuitBreaker) logProbe(err error, latency time.Duration, correlationID string) {
+	if err != nil {
+		cb.logger.Warn("circuit breaker probe failed", withCorrelationID([]interface{}{
+			"name", cb.name, "latency", latency, "error", err,
+		}, correlationID)...)
+		return
+	}
+	cb.logger.Info("circuit breaker probe succeeded", withCorrelationID([]interface{}{
+		"name", cb.name, "latency", latency,
+	}, correlationID)...)
+}
@@ -0,0 +1,91 @@
+package circuit_breaker
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingLogger struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (l *recordingLogger) log(level, msg string, keysAndValues []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := level + " " + msg
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		if keysAndValues[i] == "latency" {
+			continue
+		}
+		entry += fmt.Sprintf(" %v=%v", keysAndValues[i], keysAndValues[i+1])
+	}
+	l.entries = append(l.entries, entry)
+}
+
+func (l *recordingLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.log("DEBUG", msg, keysAndValues)
+}
+
+func (l *recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.log("INFO", msg, keysAndValues)
+}
+
+func (l *recordingLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.log("WARN", msg, keysAndValues)
+}
+
+func (l *recordingLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.log("ERROR", msg, keysAndValues)
+}
+
+func TestCircuitBreakerLogger(t *testing.T) {
+	logger := &recordingLogger{}
+	cb := NewCircuitBreaker(Config{
+		Name:                   "logging",
+		MaxConsecutiveFailures: 1,
+		RequestThreshold:       1,
+		Timeout:                10 * time.Millisecond,
+		Logger:                 logger,
+	})
+
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, ErrOpenState, succeed(cb))
+	time.Sleep(20 * time.Millisecond)
+	assert.Nil(t, succeed(cb))
+
+	assert.Equal(t, []string{
+		"WARN circuit breaker state changed name=logging from=closed to=open reason=failure threshold reached " +
+			"requests=1 failures=1 consecutive_failures=1 consecutive_successes=0",
+		"DEBUG circuit breaker rejected request name=logging state=open error=circuit breaker is open",
+		"INFO circuit breaker state changed name=logging from=open to=half-open reason=open timeout expired " +
+			"requests=0 failures=0 consecutive_failures=0 consecutive_successes=0",
+		"INFO circuit breaker state changed name=logging from=half-open to=closed reason=half-open probe succeeded " +
+			"requests=1 failures=0 consecutive_failures=0 consecutive_successes=1",
+		"INFO circuit breaker probe succeeded name=logging",
+	}, logger.entries)
+}
+
+func TestCircuitBreakerLoggerProbeFailed(t *testing.T) {
+	logger := &recordingLogger{}
+	cb := NewCircuitBreaker(Config{
+		Name:                   "logging",
+		MaxConsecutiveFailures: 1,
+		RequestThreshold:       1,
+		Timeout:                10 * time.Millisecond,
+		Logger:                 logger,
+	})
+
+	assert.Equal(t, errServiceError, fail(cb))
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, errServiceError, fail(cb))
+
+	assert.Contains(t, logger.entries, "WARN circuit breaker probe failed name=logging error=service error")
+	assert.Contains(t, logger.entries, "WARN circuit breaker state changed name=logging from=half-open to=open "+
+		"reason=half-open probe failed requests=1 failures=1 consecutive_failures=1 consecutive_successes=0")
+}
@@ -0,0 +1,35 @@
+package circuit_breaker
+
+import "time"
+
+type MaintenanceMode uint32
+
+const (
+	// MaintenanceForceOpen rejects every request with ErrOpenState during the window
+	MaintenanceForceOpen MaintenanceMode = iota
+	// MaintenanceDisable passes every request through without accounting during the window
+	MaintenanceDisable
+)
+
+// MaintenanceWindow is a planned period [Start, End) during which
+// the CircuitBreaker ignores its state and behaves according to Mode.
+// The normal operation is restored automatically once the window is over.
+type MaintenanceWindow struct {
+	Start time.Time
+	End   time.Time
+	Mode  MaintenanceMode
+}
+
+func (w MaintenanceWindow) contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+func (cb *CircuitBreaker) activeMaintenance(now time.Time) (MaintenanceWindow, bool) {
+	for _, w := range cb.maintenanceWindows {
+		if w.contains(now) {
+			return w, true
+		}
+	}
+
+	return MaintenanceWindow{}, false
+}
@@ -0,0 +1,38 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerMaintenance(t *testing.T) {
+	now := time.Now()
+	cb := NewCircuitBreaker(Config{
+		Name: "maintenance circuit breaker",
+		MaintenanceWindows: []MaintenanceWindow{
+			{Start: now.Add(-time.Minute), End: now.Add(time.Minute), Mode: MaintenanceForceOpen},
+			{Start: now.Add(time.Minute), End: now.Add(2 * time.Minute), Mode: MaintenanceDisable},
+		},
+	})
+
+	// forced open
+	assert.Equal(t, ErrOpenState, succeed(cb))
+	assert.Equal(t, StateClosed, cb.state)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0}, cb.counts)
+
+	// disabled: requests pass through without accounting
+	cb.maintenanceWindows[0].End = now.Add(-time.Second)
+	cb.maintenanceWindows[1].Start = now.Add(-time.Second)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, errServiceError, fail(cb))
+	}
+	assert.Equal(t, StateClosed, cb.state)
+	assert.Equal(t, Counts{0, 0, 0, 0, 0}, cb.counts)
+
+	// back to normal operation
+	cb.maintenanceWindows[1].End = now.Add(-time.Second)
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, Counts{1, 1, 0, 1, 0}, cb.counts)
+}
@@ -0,0 +1,193 @@
+package circuit_breaker
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMetricsNamespace = "circuit_breaker"
+
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+	textMetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// metricsStates are the states exported by MetricsHandler, in the order of the prometheus Collector
+var metricsStates = []State{StateClosed, StateHalfOpen, StateOpen}
+
+// MetricsConfig configures MetricsHandler.
+//
+// Breakers lists the exported breakers, e.g. KeyedBreaker.Breakers.
+//
+// Namespace prefixes the metric names, "circuit_breaker" by default.
+//
+// Labels are the names of the breaker labels exported as dimensions, besides the breaker name.
+// A breaker without one of the labels exports it with an empty value.
+type MetricsConfig struct {
+	Breakers  func() []*CircuitBreaker
+	Namespace string
+	Labels    []string
+}
+
+// MetricsHandler serves the metrics of the breakers in the Prometheus text exposition format,
+// or in the OpenMetrics one if the scraper accepts it, without depending on the Prometheus client library.
+// The metrics are the ones of the Collector in contrib/prometheus:
+//
+//   - state: 1 for the current state and 0 for the others, by the "state" dimension
+//   - requests_total, successes_total, failures_total and rejections_total
+//   - transitions_total: the transitions into each state, by the "state" dimension
+//   - state_seconds_total: the time spent in each state, by the "state" dimension
+//   - seconds_since_last_transition
+//   - call_duration_seconds: the histogram of the call durations, if the breaker has a LatencyHistogram
+//
+// The metrics are read from the breaker snapshots at scrape time.
+// The breakers are exported by name, so the series never repeat: of the breakers sharing a name,
+// the first one listed by Breakers is exported.
+func MetricsHandler(cfg MetricsConfig) http.Handler {
+	if cfg.Namespace == "" {
+		cfg.Namespace = defaultMetricsNamespace
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+
+		cbs := cfg.Breakers()
+		snapshots := make([]Snapshot, 0, len(cbs))
+		names := make(map[string]bool, len(cbs))
+		for _, cb := range cbs {
+			if names[cb.Name()] {
+				continue
+			}
+			snapshots = append(snapshots, cb.Snapshot())
+			names[cb.Name()] = true
+		}
+		sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Name < snapshots[j].Name })
+
+		mw := metricsWriter{cfg: cfg, openMetrics: openMetrics}
+		mw.write(snapshots, time.Now())
+
+		if openMetrics {
+			w.Header().Set("Content-Type", openMetricsContentType)
+		} else {
+			w.Header().Set("Content-Type", textMetricsContentType)
+		}
+		_, _ = w.Write(mw.buf.Bytes())
+	})
+}
+
+// metricsWriter renders the metric families, grouping the samples of all the breakers under each family
+type metricsWriter struct {
+	cfg         MetricsConfig
+	openMetrics bool
+	buf         bytes.Buffer
+}
+
+func (mw *metricsWriter) write(snapshots []Snapshot, now time.Time) {
+	mw.family("state", "gauge", "Current state of the circuit breaker.")
+	for _, s := range snapshots {
+		for _, state := range metricsStates {
+			current := 0.0
+			if s.State == state {
+				current = 1
+			}
+			mw.sample("state", s, stateLabel(state), current)
+		}
+	}
+
+	counters := []struct {
+		name  string
+		help  string
+		value func(t Totals) uint64
+	}{
+		{"requests", "Requests admitted by the circuit breaker.", func(t Totals) uint64 { return t.Requests }},
+		{"successes", "Successful requests.", func(t Totals) uint64 { return t.Successes }},
+		{"failures", "Failed requests.", func(t Totals) uint64 { return t.Failures }},
+		{"rejections", "Requests rejected by the circuit breaker.", func(t Totals) uint64 { return t.Rejections }},
+	}
+	for _, c := range counters {
+		mw.family(c.name, "counter", c.help)
+		for _, s := range snapshots {
+			mw.sample(c.name+"_total", s, "", float64(c.value(s.Totals)))
+		}
+	}
+
+	mw.family("transitions", "counter", "Transitions of the circuit breaker into the state.")
+	for _, s := range snapshots {
+		for _, state := range metricsStates {
+			mw.sample("transitions_total", s, stateLabel(state), float64(s.Totals.Transitions[state]))
+		}
+	}
+
+	mw.family("state_seconds", "counter", "Time spent by the circuit breaker in the state.")
+	for _, s := range snapshots {
+		for _, state := range metricsStates {
+			mw.sample("state_seconds_total", s, stateLabel(state), s.Totals.TimeInState[state].Seconds())
+		}
+	}
+
+	mw.family("seconds_since_last_transition", "gauge", "Time since the last state change of the circuit breaker.")
+	for _, s := range snapshots {
+		mw.sample("seconds_since_last_transition", s, "", now.Sub(s.LastTransition).Seconds())
+	}
+
+	histograms := make([]Snapshot, 0, len(snapshots))
+	for _, s := range snapshots {
+		if s.Latency.Buckets != nil {
+			histograms = append(histograms, s)
+		}
+	}
+	if len(histograms) > 0 {
+		mw.family("call_duration_seconds", "histogram", "Duration of the calls protected by the circuit breaker.")
+	}
+	for _, s := range histograms {
+		for _, b := range s.Latency.Buckets {
+			mw.sample("call_duration_seconds_bucket", s, `,le="`+formatMetricValue(b.UpperBound.Seconds())+`"`, float64(b.Count))
+		}
+		mw.sample("call_duration_seconds_bucket", s, `,le="+Inf"`, float64(s.Latency.Count))
+		mw.sample("call_duration_seconds_sum", s, "", s.Latency.Sum.Seconds())
+		mw.sample("call_duration_seconds_count", s, "", float64(s.Latency.Count))
+	}
+
+	if mw.openMetrics {
+		mw.buf.WriteString("# EOF\n")
+	}
+}
+
+// family writes the metadata of the family. The counter families are named with the _total suffix
+// in the Prometheus format, and without it in the OpenMetrics one.
+func (mw *metricsWriter) family(name, typ, help string) {
+	name = mw.cfg.Namespace + "_" + name
+	if typ == "counter" && !mw.openMetrics {
+		name += "_total"
+	}
+
+	mw.buf.WriteString("# HELP " + name + " " + help + "\n")
+	mw.buf.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+// sample writes the sample of the breaker, followed by the extra dimensions, e.g. stateLabel
+func (mw *metricsWriter) sample(name string, s Snapshot, extra string, value float64) {
+	mw.buf.WriteString(mw.cfg.Namespace + "_" + name + `{name="` + escapeLabelValue(s.Name) + `"`)
+	for _, label := range mw.cfg.Labels {
+		mw.buf.WriteString("," + label + `="` + escapeLabelValue(s.Labels[label]) + `"`)
+	}
+	mw.buf.WriteString(extra + "} " + formatMetricValue(value) + "\n")
+}
+
+func stateLabel(state State) string {
+	return `,state="` + state.String() + `"`
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
+
+func formatMetricValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
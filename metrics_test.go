@@ -0,0 +1,85 @@
+package circuit_breaker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:             `api "v2"`,
+		Labels:           map[string]string{"team": "billing"},
+		LatencyHistogram: NewBucketHistogram([]time.Duration{time.Second}),
+	})
+	other := NewCircuitBreaker(Config{Name: "search", MaxConsecutiveFailures: 1})
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, errServiceError, fail(other))
+	assert.Equal(t, ErrOpenState, succeed(other))
+
+	h := MetricsHandler(MetricsConfig{
+		Breakers: func() []*CircuitBreaker { return []*CircuitBreaker{other, cb} },
+		Labels:   []string{"team"},
+	})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	for _, line := range []string{
+		"# HELP circuit_breaker_requests_total Requests admitted by the circuit breaker.",
+		"# TYPE circuit_breaker_requests_total counter",
+		`circuit_breaker_requests_total{name="api \"v2\"",team="billing"} 1`,
+		`circuit_breaker_requests_total{name="search",team=""} 1`,
+		`circuit_breaker_rejections_total{name="search",team=""} 1`,
+		`circuit_breaker_state{name="search",team="",state="open"} 1`,
+		`circuit_breaker_state{name="search",team="",state="closed"} 0`,
+		`circuit_breaker_transitions_total{name="search",team="",state="open"} 1`,
+		"# TYPE circuit_breaker_call_duration_seconds histogram",
+		`circuit_breaker_call_duration_seconds_bucket{name="api \"v2\"",team="billing",le="1"} 1`,
+		`circuit_breaker_call_duration_seconds_bucket{name="api \"v2\"",team="billing",le="+Inf"} 1`,
+		`circuit_breaker_call_duration_seconds_count{name="api \"v2\"",team="billing"} 1`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	// the samples of a family are grouped and sorted by the breaker name
+	assert.Less(t, strings.Index(body, `circuit_breaker_requests_total{name="api`), strings.Index(body, `circuit_breaker_requests_total{name="search"`))
+	assert.Less(t, strings.Index(body, `circuit_breaker_requests_total{name="search"`), strings.Index(body, "# HELP circuit_breaker_successes_total"))
+	assert.NotContains(t, body, "# EOF")
+	// search has no histogram
+	assert.NotContains(t, body, `circuit_breaker_call_duration_seconds_count{name="search"`)
+
+	r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	r.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, "application/openmetrics-text; version=1.0.0; charset=utf-8", w.Header().Get("Content-Type"))
+	body = w.Body.String()
+	assert.Contains(t, body, "# TYPE circuit_breaker_requests counter\n")
+	assert.Contains(t, body, `circuit_breaker_requests_total{name="search",team=""} 1`+"\n")
+	assert.True(t, strings.HasSuffix(body, "# EOF\n"))
+}
+
+func TestMetricsHandlerDuplicateNames(t *testing.T) {
+	first := NewCircuitBreaker(Config{Name: "search"})
+	second := NewCircuitBreaker(Config{Name: "search", MaxConsecutiveFailures: 1})
+	assert.Nil(t, succeed(first))
+	assert.Equal(t, errServiceError, fail(second))
+
+	h := MetricsHandler(MetricsConfig{
+		Breakers: func() []*CircuitBreaker { return []*CircuitBreaker{first, second} },
+	})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	// the breakers sharing a name are exported once, the first one listed
+	assert.Equal(t, 1, strings.Count(body, `circuit_breaker_requests_total{name="search"}`))
+	assert.Contains(t, body, `circuit_breaker_successes_total{name="search"} 1`+"\n")
+	assert.Contains(t, body, `circuit_breaker_state{name="search",state="closed"} 1`+"\n")
+}
@@ -0,0 +1,176 @@
+package circuit_breaker
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MiddlewareConfig configures Middleware.
+//
+// Breaker selects the breaker protecting the request, e.g. per route with PerKey.
+// If Breaker returns nil, the request is not protected.
+//
+// Skipper excludes requests (health checks, static files) from the protection.
+//
+// Classifier maps the status code written by the handler to the request outcome.
+// The zero value treats 5xx and 429 as failures.
+//
+// OnReject writes the response for a request rejected by the breaker.
+// If OnReject is nil, WriteRejection is used.
+type MiddlewareConfig struct {
+	Breaker    func(r *http.Request) *CircuitBreaker
+	Skipper    func(r *http.Request) bool
+	Classifier HTTPClassifier
+	OnReject   func(w http.ResponseWriter, r *http.Request, cb *CircuitBreaker, err error)
+}
+
+// Middleware protects net/http handlers with circuit breakers.
+func Middleware(cfg MiddlewareConfig) func(next http.Handler) http.Handler {
+	if cfg.OnReject == nil {
+		cfg.OnReject = func(w http.ResponseWriter, r *http.Request, cb *CircuitBreaker, err error) {
+			WriteRejection(w, cb, err)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.Skipper != nil && cfg.Skipper(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cb := cfg.Breaker(r)
+			if cb == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			handled := false
+			_, err := cb.ExecuteContext(r.Context(), func(ctx context.Context) (interface{}, error) {
+				handled = true
+				sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+				next.ServeHTTP(sw, r)
+				return nil, cfg.Classifier.ClassifyStatus(sw.status)
+			})
+			if !handled {
+				if err == nil {
+					// a request which didn't run is a rejection, whatever the breaker returned
+					err = ErrTooManyRequests
+				}
+				cfg.OnReject(w, r, cb, err)
+			}
+		})
+	}
+}
+
+// SingleBreaker selects the same breaker for every request.
+func SingleBreaker(cb *CircuitBreaker) func(r *http.Request) *CircuitBreaker {
+	return func(r *http.Request) *CircuitBreaker { return cb }
+}
+
+// PerKey selects the breaker of the request key, e.g. HostKey or a route.
+func PerKey(kb *KeyedBreaker, key func(r *http.Request) string) func(r *http.Request) *CircuitBreaker {
+	return func(r *http.Request) *CircuitBreaker { return kb.Get(key(r)) }
+}
+
+// PerEndpoint selects the breaker of the request by the endpoint patterns of the ConfigMap,
+// written as "METHOD /path", e.g. "POST /payments/*"; the patterns without a method match any method.
+// The requests matching the same pattern share its breaker, named after the pattern if its Config has no Name,
+// and the requests matching no pattern share the breaker of Default.
+func PerEndpoint(m ConfigMap) func(r *http.Request) *CircuitBreaker {
+	endpoints := make(map[string]Config, len(m.Endpoints))
+	breakers := make(map[string]*CircuitBreaker, len(m.Endpoints))
+	for pattern, cfg := range m.Endpoints {
+		if cfg.Name == "" {
+			cfg.Name = pattern
+		}
+		if !strings.Contains(pattern, " ") {
+			pattern = "* " + pattern
+		}
+		endpoints[pattern] = cfg
+		breakers[pattern] = NewCircuitBreaker(cfg)
+	}
+	matcher := newEndpointMatcher(endpoints)
+	fallback := NewCircuitBreaker(m.Default)
+
+	return func(r *http.Request) *CircuitBreaker {
+		if pattern, ok := matcher.match(r.Method + " " + r.URL.Path); ok {
+			return breakers[pattern]
+		}
+		return fallback
+	}
+}
+
+// WriteRejection responds to a request rejected by the breaker with 503 Service Unavailable.
+// If the breaker is open, the Retry-After header tells when it becomes half-open.
+// A nil err is reported as ErrTooManyRequests.
+func WriteRejection(w http.ResponseWriter, cb *CircuitBreaker, err error) {
+	if err == nil {
+		err = ErrTooManyRequests
+	}
+	if retryAfter := RetryAfter(cb, err); retryAfter != "" {
+		w.Header().Set("Retry-After", retryAfter)
+	}
+	http.Error(w, err.Error(), http.StatusServiceUnavailable)
+}
+
+// RetryAfter returns the value of the Retry-After header (in seconds) for a request rejected by the breaker,
+// or "" if the rejection is not caused by the open state.
+func RetryAfter(cb *CircuitBreaker, err error) string {
+	if !errors.Is(err, ErrOpenState) {
+		return ""
+	}
+
+	d := cb.remainingOpenTime(time.Now())
+	if d <= 0 {
+		return ""
+	}
+	return strconv.Itoa(int((d + time.Second - 1) / time.Second))
+}
+
+// statusWriter records the status code written by the handler
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush supports the streaming handlers if the underlying writer is an http.Flusher
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Hijack supports the websocket handlers if the underlying writer is an http.Hijacker
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+
+	return h.Hijack()
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
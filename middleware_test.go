@@ -0,0 +1,158 @@
+package circuit_breaker
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(h http.Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "middleware circuit breaker", MaxConsecutiveFailures: 2})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	h := Middleware(MiddlewareConfig{
+		Breaker: SingleBreaker(cb),
+		Skipper: func(r *http.Request) bool { return r.URL.Path == "/healthz" },
+	})(mux)
+
+	assert.Equal(t, http.StatusOK, serve(h, "/ok").Code)
+	assert.Equal(t, http.StatusInternalServerError, serve(h, "/fail").Code)
+	assert.Equal(t, http.StatusInternalServerError, serve(h, "/fail").Code)
+	assert.Equal(t, StateOpen, cb.State())
+
+	w := serve(h, "/ok")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.Equal(t, "circuit breaker is open\n", w.Body.String())
+
+	// skipped requests are not protected
+	assert.Equal(t, http.StatusNotFound, serve(h, "/healthz").Code)
+}
+
+func TestMiddlewarePerKey(t *testing.T) {
+	kb := NewKeyedBreaker(KeyedConfig{Config: Config{MaxConsecutiveFailures: 1}})
+	h := Middleware(MiddlewareConfig{
+		Breaker: PerKey(kb, func(r *http.Request) string { return r.URL.Path }),
+		OnReject: func(w http.ResponseWriter, r *http.Request, cb *CircuitBreaker, err error) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/report" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+
+	assert.Equal(t, http.StatusBadGateway, serve(h, "/report").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(h, "/report").Code)
+	assert.Equal(t, http.StatusOK, serve(h, "/ping").Code)
+}
+
+func TestMiddlewarePerEndpoint(t *testing.T) {
+	breaker := PerEndpoint(ConfigMap{
+		Default: Config{Name: "default", MaxConsecutiveFailures: 3},
+		Endpoints: map[string]Config{
+			"POST /payments/*": {MaxConsecutiveFailures: 1},
+			"/batch/*":         {Name: "batch", MaxConsecutiveFailures: 10},
+		},
+	})
+	h := Middleware(MiddlewareConfig{Breaker: breaker})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	request := func(method, path string) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusInternalServerError, request(http.MethodPost, "/payments/1"))
+	assert.Equal(t, http.StatusServiceUnavailable, request(http.MethodPost, "/payments/2"))
+	// other methods use the default breaker
+	assert.Equal(t, http.StatusInternalServerError, request(http.MethodGet, "/payments/1"))
+	assert.Equal(t, "default", breaker(httptest.NewRequest(http.MethodGet, "/payments/1", nil)).Name())
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusInternalServerError, request(http.MethodPut, "/batch/import"))
+	}
+	assert.Equal(t, "batch", breaker(httptest.NewRequest(http.MethodGet, "/batch/export", nil)).Name())
+	assert.Equal(t, "POST /payments/*", breaker(httptest.NewRequest(http.MethodPost, "/payments/3", nil)).Name())
+}
+
+func TestRetryAfter(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "retry after circuit breaker", MaxConsecutiveFailures: 1})
+	assert.Equal(t, "", RetryAfter(cb, ErrOpenState))
+
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, "60", RetryAfter(cb, ErrOpenState))
+	assert.Equal(t, "", RetryAfter(cb, ErrBulkheadFull))
+}
+
+func TestWriteRejectionNilError(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "nil rejection circuit breaker"})
+	w := httptest.NewRecorder()
+
+	assert.NotPanics(t, func() { WriteRejection(w, cb, nil) })
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), ErrTooManyRequests.Error())
+}
+
+func TestMiddlewareFlush(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "flush circuit breaker"})
+	h := Middleware(MiddlewareConfig{Breaker: SingleBreaker(cb)})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("event"))
+		f, ok := w.(http.Flusher)
+		if assert.True(t, ok) {
+			f.Flush()
+		}
+	}))
+
+	w := serve(h, "/events")
+	assert.True(t, w.Flushed)
+	assert.Equal(t, "event", w.Body.String())
+}
+
+func TestMiddlewareHijack(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "hijack circuit breaker", MaxConsecutiveFailures: 1})
+	h := Middleware(MiddlewareConfig{Breaker: SingleBreaker(cb)})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hj, ok := w.(http.Hijacker)
+		if !assert.True(t, ok) {
+			return
+		}
+		conn, rw, err := hj.Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: close\r\n\r\n")
+		_ = rw.Flush()
+	}))
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if assert.NoError(t, err) {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	}
+
+	// the writers without hijacking support report an error
+	sw := &statusWriter{ResponseWriter: httptest.NewRecorder()}
+	_, _, err = sw.Hijack()
+	assert.Error(t, err)
+}
@@ -0,0 +1,277 @@
+package circuit_breaker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultNotifyTimeout        = 5 * time.Second
+	defaultNotifyMaxAttempts    = 3
+	defaultNotifyInitialBackoff = time.Second
+	defaultNotifyMaxBackoff     = 30 * time.Second
+	defaultNotifyQueueSize      = 64
+)
+
+// ErrNotificationQueueFull is passed to the error callbacks when a notification is dropped
+// because the deliveries can't keep up with the state changes.
+var ErrNotificationQueueFull = errors.New("notification queue is full")
+
+// ErrDispatcherClosed is passed to the error callbacks when a watched breaker changes its state after Close.
+var ErrDispatcherClosed = errors.New("notification dispatcher is closed")
+
+// Notification describes a state change of a CircuitBreaker to external systems.
+type Notification struct {
+	Name   string            `json:"name"`
+	From   State             `json:"from"`
+	To     State             `json:"to"`
+	Reason string            `json:"reason"`
+	Counts Counts            `json:"counts"`
+	At     time.Time         `json:"at"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// NewNotification builds the Notification of the transition of the breaker.
+func NewNotification(cb *CircuitBreaker, t Transition) Notification {
+	return Notification{
+		Name:   cb.name,
+		From:   t.From,
+		To:     t.To,
+		Reason: t.Reason,
+		Counts: t.Counts,
+		At:     t.At,
+		Labels: cb.Labels(),
+	}
+}
+
+// Notifier delivers the notifications of the state changes to an external system,
+// e.g. Webhook, SlackNotifier or PagerDutyNotifier.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// DispatcherConfig configures Dispatcher.
+//
+// QueueSize is the number of the notifications waiting for delivery.
+// If QueueSize is zero, 64 notifications are queued.
+//
+// OnError is called when a notification is not delivered by a notifier, or is dropped
+// because the queue is full or the Dispatcher is closed.
+type DispatcherConfig struct {
+	Notifiers []Notifier
+	QueueSize int
+	OnError   func(n Notification, err error)
+}
+
+// Dispatcher delivers the state changes of the watched breakers to the notifiers in the background,
+// one notification at a time, in the order of the changes.
+type Dispatcher struct {
+	cfg DispatcherConfig
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan Notification
+	done   chan struct{}
+}
+
+// NewDispatcher creates the Dispatcher and starts the delivery of the queued notifications.
+// Close stops it.
+func NewDispatcher(cfg DispatcherConfig) *Dispatcher {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultNotifyQueueSize
+	}
+
+	d := &Dispatcher{
+		cfg:   cfg,
+		queue: make(chan Notification, cfg.QueueSize),
+		done:  make(chan struct{}),
+	}
+	go d.run()
+
+	return d
+}
+
+// Watch queues the notifications of the state changes of the breaker for delivery.
+// The callbacks of the breaker don't wait for the delivery: if the queue is full, the notification is dropped
+// and OnError is called with ErrNotificationQueueFull.
+// stop stops watching the breaker.
+func (d *Dispatcher) Watch(cb *CircuitBreaker) (stop func()) {
+	return cb.SubscribeTransitions(func(_ string, t Transition) {
+		d.enqueue(NewNotification(cb, t))
+	})
+}
+
+// Close stops the Dispatcher after delivering the queued notifications.
+// The state changes of the breakers still watched after Close are dropped
+// and OnError is called with ErrDispatcherClosed.
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	<-d.done
+}
+
+func (d *Dispatcher) enqueue(n Notification) {
+	var err error
+
+	d.mu.RLock()
+	if d.closed {
+		err = ErrDispatcherClosed
+	} else {
+		select {
+		case d.queue <- n:
+		default:
+			err = ErrNotificationQueueFull
+		}
+	}
+	d.mu.RUnlock()
+
+	if err != nil {
+		d.onError(n, err)
+	}
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+
+	for n := range d.queue {
+		for _, notifier := range d.cfg.Notifiers {
+			if err := notifier.Notify(context.Background(), n); err != nil {
+				d.onError(n, err)
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) onError(n Notification, err error) {
+	if d.cfg.OnError != nil {
+		d.cfg.OnError(n, err)
+	}
+}
+
+// alertAction is what a Notification means to an alerting system
+type alertAction int
+
+const (
+	alertNone alertAction = iota
+	alertTrigger
+	alertResolve
+)
+
+// alerts tracks the breakers with an open alert,
+// so a breaker flapping between the open and half-open states raises a single alert,
+// resolved once the breaker closes.
+type alerts struct {
+	mu   sync.Mutex
+	open map[string]bool
+}
+
+// action returns the action of the notification, or alertNone if the alert is already in that state
+func (a *alerts) action(n Notification) alertAction {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch {
+	case n.To == StateOpen && !a.open[n.Name]:
+		return alertTrigger
+	case n.To == StateClosed && a.open[n.Name]:
+		return alertResolve
+	default:
+		return alertNone
+	}
+}
+
+// done records the delivered action
+func (a *alerts) done(n Notification, action alertAction) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.open == nil {
+		a.open = make(map[string]bool)
+	}
+	if action == alertTrigger {
+		a.open[n.Name] = true
+	} else {
+		delete(a.open, n.Name)
+	}
+}
+
+// poster posts JSON bodies to HTTP endpoints, retrying the failed attempts
+type poster struct {
+	client      *http.Client
+	timeout     time.Duration
+	header      http.Header
+	maxAttempts uint32
+	backoff     func(retry uint32) time.Duration
+}
+
+func newPoster(client *http.Client, timeout time.Duration, header http.Header, maxAttempts uint32, backoff func(retry uint32) time.Duration) poster {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if timeout <= 0 {
+		timeout = defaultNotifyTimeout
+	}
+	if maxAttempts == 0 {
+		maxAttempts = defaultNotifyMaxAttempts
+	}
+	if backoff == nil {
+		backoff = ExponentialBackoff(defaultNotifyInitialBackoff, defaultNotifyMaxBackoff)
+	}
+
+	return poster{client: client, timeout: timeout, header: header, maxAttempts: maxAttempts, backoff: backoff}
+}
+
+// deliver posts the body to the URL, retrying network errors, 5xx and 429 responses
+func (p poster) deliver(ctx context.Context, url string, body []byte) error {
+	for retry := uint32(1); ; retry++ {
+		retryable, err := p.post(ctx, url, body)
+		if err == nil || !retryable || retry >= p.maxAttempts {
+			return err
+		}
+
+		timer := time.NewTimer(p.backoff(retry))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (p poster) post(ctx context.Context, url string, body []byte) (retryable bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for key, values := range p.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retryable, fmt.Errorf("POST %s: %s", url, resp.Status)
+	}
+
+	return false, nil
+}
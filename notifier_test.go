@@ -0,0 +1,88 @@
+package circuit_breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingNotifier struct {
+	mu            sync.Mutex
+	notifications []Notification
+	err           error
+}
+
+func (r *recordingNotifier) Notify(ctx context.Context, n Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.notifications = append(r.notifications, n)
+	return r.err
+}
+
+func TestDispatcher(t *testing.T) {
+	ok := &recordingNotifier{}
+	failing := &recordingNotifier{err: errors.New("unreachable")}
+
+	var errs []error
+	d := NewDispatcher(DispatcherConfig{
+		Notifiers: []Notifier{ok, failing},
+		OnError:   func(n Notification, err error) { errs = append(errs, err) },
+	})
+
+	cb := NewCircuitBreaker(Config{Name: "search", MaxConsecutiveFailures: 1})
+	stop := d.Watch(cb)
+	assert.Equal(t, errServiceError, fail(cb))
+	cb.Reset()
+	stop()
+	cb.Trip()
+	d.Close()
+
+	assert.Len(t, ok.notifications, 2)
+	assert.Equal(t, "search", ok.notifications[0].Name)
+	assert.Equal(t, StateOpen, ok.notifications[0].To)
+	assert.Equal(t, StateClosed, ok.notifications[1].To)
+	assert.Equal(t, ok.notifications, failing.notifications)
+	assert.Equal(t, []error{failing.err, failing.err}, errs)
+}
+
+func TestAlerts(t *testing.T) {
+	var a alerts
+	notify := func(from, to State) alertAction {
+		n := Notification{Name: "search", From: from, To: to}
+		action := a.action(n)
+		if action != alertNone {
+			a.done(n, action)
+		}
+		return action
+	}
+
+	assert.Equal(t, alertTrigger, notify(StateClosed, StateOpen))
+	// the flaps don't raise new alerts
+	assert.Equal(t, alertNone, notify(StateOpen, StateHalfOpen))
+	assert.Equal(t, alertTrigger, a.action(Notification{Name: "other", To: StateOpen}))
+	assert.Equal(t, alertNone, notify(StateHalfOpen, StateOpen))
+	assert.Equal(t, alertNone, notify(StateOpen, StateHalfOpen))
+	assert.Equal(t, alertResolve, notify(StateHalfOpen, StateClosed))
+	assert.Equal(t, alertNone, notify(StateOpen, StateClosed))
+}
+
+func TestDispatcherClosed(t *testing.T) {
+	var errs []error
+	d := NewDispatcher(DispatcherConfig{
+		Notifiers: []Notifier{&recordingNotifier{}},
+		OnError:   func(n Notification, err error) { errs = append(errs, err) },
+	})
+
+	cb := NewCircuitBreaker(Config{Name: "search"})
+	d.Watch(cb)
+	d.Close()
+	d.Close()
+
+	// the state changes after Close are dropped
+	assert.NotPanics(t, func() { cb.Trip() })
+	assert.Equal(t, []error{ErrDispatcherClosed}, errs)
+}
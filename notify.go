@@ -0,0 +1,99 @@
+package circuit_breaker
+
+import "time"
+
+// Reasons of the state changes.
+const (
+	ReasonTripped        = "failure threshold reached"
+	ReasonTimeout        = "open timeout expired"
+	ReasonProbeSucceeded = "half-open probe succeeded"
+	ReasonProbeFailed    = "half-open probe failed"
+	ReasonReset          = "reset"
+	ReasonManualTrip     = "tripped manually"
+	ReasonGroupJoined    = "joined group"
+)
+
+type stateChange struct {
+	from   State
+	to     State
+	reason string
+	// counts are the Counts which led to the change
+	counts  Counts
+	at      time.Time
+	lastErr error
+	// save is true when the state set by the change must be stored, see Config.Storage
+	save       bool
+	stored     StoredState
+	generation uint64
+	// resign is true when the change ends the half-open state probed by the instance, see Config.ProbeElector
+	resign bool
+}
+
+func (change stateChange) transition() Transition {
+	return Transition{
+		At:        change.at,
+		From:      change.from,
+		To:        change.to,
+		Reason:    change.reason,
+		Counts:    change.counts,
+		LastError: change.lastErr,
+	}
+}
+
+// unlock releases the CircuitBreaker lock and then delivers the pending state changes.
+//
+// The callbacks always run outside the lock, so they may call back into the CircuitBreaker.
+// They are delivered one at a time, in the order of the transitions:
+// the first goroutine to find pending changes keeps delivering them,
+// including the ones caused by other goroutines or by the callbacks themselves,
+// until the queue is empty.
+// If a callback panics, the rest of its batch is dropped and the next unlock delivers the later changes.
+func (cb *CircuitBreaker) unlock() {
+	if cb.notifying || len(cb.pending) == 0 {
+		cb.mu.Unlock()
+		return
+	}
+
+	cb.notifying = true
+	locked := true
+	defer func() {
+		if !locked {
+			// a callback panicked, its batch may still be in use by the new pending changes
+			cb.mu.Lock()
+			cb.spare = nil
+		}
+		cb.notifying = false
+		cb.mu.Unlock()
+	}()
+
+	for len(cb.pending) > 0 {
+		batch := cb.pending
+		cb.pending = cb.spare[:0]
+		listeners := cb.listeners
+		cb.mu.Unlock()
+		locked = false
+
+		for _, change := range batch {
+			if cb.onStateChange != nil {
+				cb.onStateChange(cb.name, change.from, change.to)
+			}
+			for _, l := range listeners {
+				l.deliver(cb.name, change)
+			}
+			if cb.logger != nil {
+				cb.logStateChange(change)
+			}
+			if change.save {
+				change := change
+				cb.background.run(func() { cb.persist(change) })
+			}
+			if change.resign {
+				cb.background.run(cb.resign)
+			}
+		}
+
+		cb.mu.Lock()
+		locked = true
+		cb.spare = batch
+	}
+}
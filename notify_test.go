@@ -0,0 +1,134 @@
+package circuit_breaker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerCallbackReentrancy(t *testing.T) {
+	var cb *CircuitBreaker
+	var changes []string
+	var counts []Counts
+
+	cb = NewCircuitBreaker(Config{
+		Name:                   "reentrant circuit breaker",
+		MaxConsecutiveFailures: 1,
+		OnStateChange: func(name string, from, to State) {
+			// calling back into the breaker must not deadlock
+			counts = append(counts, cb.Counts())
+			changes = append(changes, fmt.Sprintf("%s->%s", from, to))
+			if to == StateOpen {
+				cb.Reset()
+				changes = append(changes, "reset")
+			}
+		},
+	})
+
+	assert.Equal(t, errServiceError, fail(cb))
+
+	// the transition caused by the callback is delivered after the callback returns
+	assert.Equal(t, []string{"closed->open", "reset", "open->closed"}, changes)
+	assert.Equal(t, []Counts{{}, {}}, counts)
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestCircuitBreakerCallbackPanic(t *testing.T) {
+	var changes []string
+	cb := NewCircuitBreaker(Config{
+		Name: "panicking circuit breaker",
+		OnStateChange: func(name string, from, to State) {
+			if to == StateOpen {
+				panic("callback failed")
+			}
+			changes = append(changes, fmt.Sprintf("%s->%s", from, to))
+		},
+	})
+
+	assert.Panics(t, cb.Trip)
+	assert.Equal(t, StateOpen, cb.State())
+
+	// the panic doesn't stop the delivery of the later changes
+	cb.Reset()
+	assert.Equal(t, []string{"open->closed"}, changes)
+	assert.Panics(t, cb.Trip)
+	cb.Reset()
+	assert.Equal(t, []string{"open->closed", "open->closed"}, changes)
+}
+
+func TestCircuitBreakerCallbackPanicAccounting(t *testing.T) {
+	panicOn := func(state State) func(name string, from, to State) {
+		return func(name string, from, to State) {
+			if to == state {
+				panic("callback failed")
+			}
+		}
+	}
+
+	// the request is recorded once, although the panic of the callback leaves Execute
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 1, OnStateChange: panicOn(StateOpen)})
+	assert.Panics(t, func() { _ = fail(cb) })
+	assert.Equal(t, 0, cb.InFlight())
+	s := cb.Snapshot()
+	assert.Equal(t, uint64(1), s.Totals.Requests)
+	assert.Equal(t, uint64(1), s.Totals.Failures)
+
+	done, err := NewCircuitBreaker(Config{MaxConsecutiveFailures: 1, OnStateChange: panicOn(StateOpen)}).Allow(context.Background())
+	assert.Nil(t, err)
+	assert.Panics(t, func() { done(errServiceError) })
+
+	// the coalesced probe is completed once, and the followers are admitted again
+	cb = NewCircuitBreaker(Config{
+		MaxConsecutiveFailures: 1,
+		RequestThreshold:       1,
+		CoalesceHalfOpen:       true,
+		OnStateChange:          panicOn(StateClosed),
+	})
+	assert.Equal(t, errServiceError, fail(cb))
+	pseudoSleep(cb, time.Minute)
+	assert.Panics(t, func() { _ = succeed(cb) })
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, 0, cb.InFlight())
+	assert.Nil(t, succeed(cb))
+}
+
+func TestCircuitBreakerCallbackOrdering(t *testing.T) {
+	var mu sync.Mutex
+	var changes []State
+
+	cb := NewCircuitBreaker(Config{
+		Name:                   "ordered circuit breaker",
+		RequestThreshold:       1,
+		MaxConsecutiveFailures: 1,
+		OnStateChange: func(name string, from, to State) {
+			mu.Lock()
+			defer mu.Unlock()
+			if len(changes) > 0 {
+				assert.Equal(t, changes[len(changes)-1], from)
+			}
+			changes = append(changes, to)
+			time.Sleep(time.Millisecond) // a slow callback
+		},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				_ = fail(cb)
+				cb.Reset()
+			}
+		}()
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, StateClosed, changes[len(changes)-1])
+}
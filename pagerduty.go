@@ -0,0 +1,114 @@
+package circuit_breaker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// PagerDutyEventsURL is the endpoint of the PagerDuty Events API v2.
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+const (
+	defaultPagerDutySource   = "circuit_breaker"
+	defaultPagerDutySeverity = "error"
+)
+
+// PagerDutyConfig configures PagerDutyNotifier.
+//
+// RoutingKey is the integration key of the PagerDuty service.
+//
+// Source is the affected system reported in the alerts, e.g. the host name. If Source is empty, "circuit_breaker" is used.
+// Severity is the severity of the alerts: "critical", "error", "warning" or "info". If Severity is empty, "error" is used.
+//
+// URL is the endpoint of the Events API. If URL is empty, PagerDutyEventsURL is used.
+// Client sends the requests. If Client is nil, http.DefaultClient is used.
+type PagerDutyConfig struct {
+	RoutingKey string
+	Source     string
+	Severity   string
+	URL        string
+	Client     *http.Client
+}
+
+// PagerDutyNotifier is the Notifier triggering a PagerDuty alert when a breaker opens, and resolving it when the breaker closes.
+// The alert of a breaker is deduplicated by its name, so a breaker flapping between the open and half-open states
+// raises a single alert.
+type PagerDutyNotifier struct {
+	cfg    PagerDutyConfig
+	poster poster
+	alerts alerts
+}
+
+// NewPagerDutyNotifier creates the PagerDutyNotifier.
+func NewPagerDutyNotifier(cfg PagerDutyConfig) *PagerDutyNotifier {
+	if cfg.Source == "" {
+		cfg.Source = defaultPagerDutySource
+	}
+	if cfg.Severity == "" {
+		cfg.Severity = defaultPagerDutySeverity
+	}
+	if cfg.URL == "" {
+		cfg.URL = PagerDutyEventsURL
+	}
+
+	return &PagerDutyNotifier{cfg: cfg, poster: newPoster(cfg.Client, 0, nil, 0, nil)}
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp"`
+	Component     string                 `json:"component"`
+	CustomDetails map[string]interface{} `json:"custom_details"`
+}
+
+// Notify sends the event of the notification, if it triggers or resolves an alert.
+func (p *PagerDutyNotifier) Notify(ctx context.Context, n Notification) error {
+	action := p.alerts.action(n)
+	if action == alertNone {
+		return nil
+	}
+
+	event := pagerDutyEvent{
+		RoutingKey:  p.cfg.RoutingKey,
+		EventAction: "resolve",
+		DedupKey:    "circuit_breaker/" + n.Name,
+	}
+	if action == alertTrigger {
+		event.EventAction = "trigger"
+		event.Payload = &pagerDutyPayload{
+			Summary:   fmt.Sprintf("Circuit breaker %s opened: %s", n.Name, n.Reason),
+			Source:    p.cfg.Source,
+			Severity:  p.cfg.Severity,
+			Timestamp: n.At.Format(time.RFC3339),
+			Component: n.Name,
+			CustomDetails: map[string]interface{}{
+				"reason": n.Reason,
+				"counts": n.Counts,
+				"labels": n.Labels,
+			},
+		}
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if err := p.poster.deliver(ctx, p.cfg.URL, body); err != nil {
+		return err
+	}
+	p.alerts.done(n, action)
+
+	return nil
+}
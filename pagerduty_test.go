@@ -0,0 +1,51 @@
+package circuit_breaker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPagerDutyNotifier(t *testing.T) {
+	var events []pagerDutyEvent
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first trigger fails, so the alert is not considered open
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var event pagerDutyEvent
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&event))
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	p := NewPagerDutyNotifier(PagerDutyConfig{RoutingKey: "key", URL: srv.URL})
+	ctx := context.Background()
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	open := Notification{Name: "payments", From: StateClosed, To: StateOpen, Reason: ReasonTripped, At: at}
+
+	assert.NotNil(t, p.Notify(ctx, open))
+	assert.Nil(t, p.Notify(ctx, open))
+	assert.Nil(t, p.Notify(ctx, Notification{Name: "payments", From: StateOpen, To: StateHalfOpen}))
+	assert.Nil(t, p.Notify(ctx, Notification{Name: "payments", From: StateHalfOpen, To: StateOpen}))
+	assert.Nil(t, p.Notify(ctx, Notification{Name: "payments", From: StateHalfOpen, To: StateClosed}))
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, "trigger", events[0].EventAction)
+	assert.Equal(t, "key", events[0].RoutingKey)
+	assert.Equal(t, "circuit_breaker/payments", events[0].DedupKey)
+	assert.Equal(t, "Circuit breaker payments opened: failure threshold reached", events[0].Payload.Summary)
+	assert.Equal(t, "circuit_breaker", events[0].Payload.Source)
+	assert.Equal(t, "error", events[0].Payload.Severity)
+	assert.Equal(t, "2024-01-02T03:04:05Z", events[0].Payload.Timestamp)
+	assert.Equal(t, pagerDutyEvent{RoutingKey: "key", EventAction: "resolve", DedupKey: "circuit_breaker/payments"}, events[1])
+}
@@ -0,0 +1,86 @@
+package circuit_breaker
+
+import (
+	"sync"
+	"time"
+)
+
+// PauseOnOpen pauses a message consumer while the CircuitBreaker protecting its processor is open,
+// so the consumer doesn't drain the queue into a dead dependency.
+//
+// pause is called when the breaker opens, and resume when it leaves the open state.
+// The consumer is resumed in the half-open state, so the messages it receives can probe the dependency;
+// if they fail, the breaker opens and the consumer is paused again.
+// Since a paused consumer makes no requests, PauseOnOpen itself moves the breaker
+// to the half-open state once the open timeout expires.
+//
+// If the breaker is already open, the consumer is paused immediately.
+// stop detaches the consumer from the breaker, resuming it if it is paused.
+func PauseOnOpen(cb *CircuitBreaker, pause, resume func()) (stop func()) {
+	p := &pauser{cb: cb, pause: pause, resume: resume}
+
+	unsubscribe := cb.Subscribe(func(_ string, _ State, to State) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		p.notified = true
+		p.sync(to)
+	})
+
+	// State may deliver a transition itself, and then the callback has already synced the consumer
+	state := cb.State()
+	p.mu.Lock()
+	if !p.notified {
+		p.sync(state)
+	}
+	p.mu.Unlock()
+
+	return func() {
+		unsubscribe()
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		p.sync(StateClosed)
+		p.stopped = true
+	}
+}
+
+type pauser struct {
+	cb     *CircuitBreaker
+	pause  func()
+	resume func()
+
+	mu       sync.Mutex
+	notified bool
+	paused   bool
+	stopped  bool
+	timer    *time.Timer
+}
+
+// sync pauses or resumes the consumer according to the state of the breaker
+func (p *pauser) sync(state State) {
+	if p.stopped {
+		return
+	}
+
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+
+	if state != StateOpen {
+		if p.paused {
+			p.paused = false
+			p.resume()
+		}
+		return
+	}
+
+	if !p.paused {
+		p.paused = true
+		p.pause()
+	}
+	// State moves the breaker to the half-open state once the timeout expires
+	p.timer = time.AfterFunc(p.cb.remainingOpenTime(time.Now()), func() { p.cb.State() })
+}
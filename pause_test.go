@@ -0,0 +1,56 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPauseOnOpen(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "consumer circuit breaker",
+		RequestThreshold:       1,
+		MaxConsecutiveFailures: 1,
+		Timeout:                50 * time.Millisecond,
+	})
+
+	events := make(chan string, 10)
+	stop := PauseOnOpen(cb, func() { events <- "pause" }, func() { events <- "resume" })
+
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, "pause", <-events)
+
+	// the paused consumer makes no requests, yet the breaker becomes half-open
+	select {
+	case event := <-events:
+		assert.Equal(t, "resume", event)
+	case <-time.After(time.Second):
+		t.Fatal("the consumer is not resumed")
+	}
+	assert.Equal(t, StateHalfOpen, cb.State())
+
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, "pause", <-events)
+
+	stop()
+	assert.Equal(t, "resume", <-events)
+
+	cb.Reset()
+	assert.Equal(t, errServiceError, fail(cb))
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, events)
+}
+
+func TestPauseOnOpenAlreadyOpen(t *testing.T) {
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 1})
+	assert.Equal(t, errServiceError, fail(cb))
+
+	paused := false
+	stop := PauseOnOpen(cb, func() { paused = true }, func() { paused = false })
+	assert.True(t, paused)
+
+	cb.Reset()
+	assert.False(t, paused)
+	stop()
+}
@@ -0,0 +1,111 @@
+package circuit_breaker
+
+import "time"
+
+// Policy drives the transitions of the CircuitBreaker state machine.
+// All the methods are called while the CircuitBreaker lock is held,
+// so implementations don't need their own synchronization.
+type Policy interface {
+	// OnCall is called with the state at call time and the outcome of every executed request.
+	OnCall(state State, err error)
+	// ShouldTrip is called with a copy of Counts whenever a request fails in the closed state.
+	// If it returns true, the CircuitBreaker will be placed into the open state.
+	ShouldTrip(counts Counts) bool
+	// ShouldClose is called with a copy of Counts whenever a request succeeds in the half-open state.
+	// If it returns true, the CircuitBreaker will be placed into the closed state.
+	ShouldClose(counts Counts) bool
+	// NextOpenDuration returns the period of the open state,
+	// given the number of consecutive trips without closing (starting from 1).
+	NextOpenDuration(trips uint32) time.Duration
+}
+
+// DefaultPolicy is the built-in Policy, configured by the Config fields
+// ReadyToTrip, MaxConsecutiveFailures, RequestThreshold and Timeout.
+//
+// If ReadyToTrip is nil, the policy trips after MaxConsecutiveFailures consecutive failures.
+// If both are unset, it trips by DefaultReadyToTrip, i.e. after DefaultMaxConsecutiveFailures consecutive failures.
+type DefaultPolicy struct {
+	ReadyToTrip            func(counts Counts) bool
+	MaxConsecutiveFailures uint32
+	RequestThreshold       uint32
+	Timeout                time.Duration
+}
+
+func (p DefaultPolicy) OnCall(state State, err error) {}
+
+func (p DefaultPolicy) ShouldTrip(counts Counts) bool {
+	if p.ReadyToTrip != nil {
+		return p.ReadyToTrip(counts)
+	}
+	if p.MaxConsecutiveFailures > 0 {
+		return counts.ConsecutiveFailures >= p.MaxConsecutiveFailures
+	}
+	return DefaultReadyToTrip(counts)
+}
+
+func (p DefaultPolicy) ShouldClose(counts Counts) bool {
+	if p.RequestThreshold == 0 {
+		return counts.ConsecutiveSuccesses >= DefaultRequestThreshold
+	}
+	return counts.ConsecutiveSuccesses >= p.RequestThreshold
+}
+
+func (p DefaultPolicy) NextOpenDuration(trips uint32) time.Duration {
+	if p.Timeout == 0 {
+		return DefaultTimeout
+	}
+	return p.Timeout
+}
+
+// DefaultReadyToTrip is the ReadyToTrip used by default,
+// which trips after DefaultMaxConsecutiveFailures consecutive failures.
+func DefaultReadyToTrip(counts Counts) bool {
+	return counts.ConsecutiveFailures >= DefaultMaxConsecutiveFailures
+}
+
+// requestThresholdOf returns the RequestThreshold of the config, DefaultRequestThreshold if it is not set
+func requestThresholdOf(cfg Config) uint32 {
+	if cfg.RequestThreshold == 0 {
+		return DefaultRequestThreshold
+	}
+	return cfg.RequestThreshold
+}
+
+// policyOf returns the Policy of the config, DefaultPolicy if it is not set
+func policyOf(cfg Config) Policy {
+	if cfg.Policy != nil {
+		return cfg.Policy
+	}
+
+	return DefaultPolicy{
+		ReadyToTrip:            cfg.ReadyToTrip,
+		MaxConsecutiveFailures: cfg.MaxConsecutiveFailures,
+		RequestThreshold:       cfg.RequestThreshold,
+		Timeout:                cfg.Timeout,
+	}
+}
+
+// TripAfterConsecutiveFailures returns the ReadyToTrip which trips after n consecutive failures.
+func TripAfterConsecutiveFailures(n uint32) func(counts Counts) bool {
+	return func(counts Counts) bool {
+		return counts.ConsecutiveFailures >= n
+	}
+}
+
+// TripAfterFailures returns the ReadyToTrip which trips after n failures in the closed state.
+func TripAfterFailures(n uint32) func(counts Counts) bool {
+	return func(counts Counts) bool {
+		return counts.TotalFailures >= n
+	}
+}
+
+// TripOnFailureRate returns the ReadyToTrip which trips when the fraction of the failed requests
+// reaches rate, once there were at least minRequests requests.
+func TripOnFailureRate(rate float64, minRequests uint32) func(counts Counts) bool {
+	return func(counts Counts) bool {
+		if counts.Requests == 0 || counts.Requests < minRequests {
+			return false
+		}
+		return float64(counts.TotalFailures)/float64(counts.Requests) >= rate
+	}
+}
@@ -0,0 +1,103 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type backoffPolicy struct {
+	DefaultPolicy
+	calls []State
+}
+
+func (p *backoffPolicy) OnCall(state State, err error) {
+	p.calls = append(p.calls, state)
+}
+
+func (p *backoffPolicy) ShouldTrip(counts Counts) bool {
+	return counts.ConsecutiveFailures >= 2
+}
+
+func (p *backoffPolicy) NextOpenDuration(trips uint32) time.Duration {
+	return time.Duration(trips) * time.Minute
+}
+
+func TestCircuitBreakerPolicy(t *testing.T) {
+	policy := &backoffPolicy{DefaultPolicy: DefaultPolicy{RequestThreshold: 1}}
+	cb := NewCircuitBreaker(Config{
+		Name:             "policy circuit breaker",
+		RequestThreshold: 1,
+		Policy:           policy,
+	})
+
+	// StateClosed -> StateOpen
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.state)
+	assert.Equal(t, uint32(1), cb.trips)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), cb.expiredAt, time.Second)
+
+	// StateOpen -> StateHalfOpen -> StateOpen
+	pseudoSleep(cb, time.Minute)
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.state)
+	assert.Equal(t, uint32(2), cb.trips)
+	assert.WithinDuration(t, time.Now().Add(2*time.Minute), cb.expiredAt, time.Second)
+
+	// StateOpen -> StateHalfOpen -> StateClosed
+	pseudoSleep(cb, 2*time.Minute)
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.state)
+	assert.Equal(t, uint32(0), cb.trips)
+
+	assert.Equal(t, []State{StateClosed, StateClosed, StateHalfOpen, StateHalfOpen}, policy.calls)
+}
+
+func TestDefaultPolicy(t *testing.T) {
+	p := DefaultPolicy{RequestThreshold: 2}
+
+	assert.False(t, p.ShouldTrip(Counts{ConsecutiveFailures: 5}))
+	assert.True(t, p.ShouldTrip(Counts{ConsecutiveFailures: 6}))
+	assert.False(t, p.ShouldClose(Counts{ConsecutiveSuccesses: 1}))
+	assert.True(t, p.ShouldClose(Counts{ConsecutiveSuccesses: 2}))
+	assert.Equal(t, DefaultTimeout, p.NextOpenDuration(1))
+	assert.Equal(t, time.Second, DefaultPolicy{Timeout: time.Second}.NextOpenDuration(3))
+
+	p = DefaultPolicy{MaxConsecutiveFailures: 3}
+	assert.False(t, p.ShouldTrip(Counts{ConsecutiveFailures: 2}))
+	assert.True(t, p.ShouldTrip(Counts{ConsecutiveFailures: 3}))
+
+	p.ReadyToTrip = func(counts Counts) bool { return false }
+	assert.False(t, p.ShouldTrip(Counts{ConsecutiveFailures: 3}))
+
+	// the threshold of the unset policy is configurable
+	defer func(failures uint32) { DefaultMaxConsecutiveFailures = failures }(DefaultMaxConsecutiveFailures)
+	DefaultMaxConsecutiveFailures = 2
+	assert.True(t, DefaultPolicy{}.ShouldTrip(Counts{ConsecutiveFailures: 2}))
+	assert.False(t, DefaultPolicy{}.ShouldTrip(Counts{ConsecutiveFailures: 1}))
+}
+
+func TestCircuitBreakerMaxConsecutiveFailures(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "max consecutive failures circuit breaker",
+		MaxConsecutiveFailures: 3,
+	})
+
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateClosed, cb.state)
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.state)
+}
+
+func TestTripFunctions(t *testing.T) {
+	assert.False(t, TripAfterConsecutiveFailures(2)(Counts{ConsecutiveFailures: 1}))
+	assert.True(t, TripAfterConsecutiveFailures(2)(Counts{ConsecutiveFailures: 2}))
+	assert.False(t, TripAfterFailures(2)(Counts{TotalFailures: 1}))
+	assert.True(t, TripAfterFailures(2)(Counts{TotalFailures: 2}))
+	assert.False(t, TripOnFailureRate(0.5, 0)(Counts{}))
+	assert.False(t, TripOnFailureRate(0.5, 10)(Counts{Requests: 9, TotalFailures: 9}))
+	assert.True(t, TripOnFailureRate(0.5, 10)(Counts{Requests: 10, TotalFailures: 5}))
+}
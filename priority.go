@@ -0,0 +1,87 @@
+package circuit_breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultShedWindow      = 10 * time.Second
+	defaultShedMinRequests = 10
+)
+
+// ErrLoadShed is returned when the request is shed in the degraded mode because of its low priority
+var ErrLoadShed = errors.New("request shed")
+
+type Priority uint32
+
+const (
+	PriorityNormal Priority = iota
+	PriorityLow
+	PriorityCritical
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityNormal:
+		return "normal"
+	case PriorityLow:
+		return "low"
+	case PriorityCritical:
+		return "critical"
+	default:
+		return fmt.Sprintf("undefined priority: %d", p)
+	}
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a copy of the context tagging the requests made by ExecuteContext with the priority.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority of the context, PriorityNormal by default.
+func PriorityFromContext(ctx context.Context) Priority {
+	p, _ := ctx.Value(priorityKey{}).(Priority)
+	return p
+}
+
+// LoadShedding configures the degraded mode of the closed state, a graceful brown-out before the CircuitBreaker opens.
+//
+// When the failure rate over the recent Window reaches LowPriorityFailureRate, the low priority requests are shed,
+// and when it reaches NormalPriorityFailureRate, the normal priority requests are shed too.
+// Critical requests are never shed. A zero rate disables shedding of the priority.
+//
+// MinRequests is the number of requests in the Window required to compute the failure rate, 10 by default.
+// Window is 10 seconds by default.
+type LoadShedding struct {
+	LowPriorityFailureRate    float64
+	NormalPriorityFailureRate float64
+	MinRequests               uint32
+	Window                    time.Duration
+}
+
+func (ls LoadShedding) enabled() bool {
+	return ls.LowPriorityFailureRate > 0 || ls.NormalPriorityFailureRate > 0
+}
+
+// shouldShed reports whether the request of the priority must be shed, given the recent counts
+func (ls LoadShedding) shouldShed(p Priority, counts Counts) bool {
+	if counts.Requests == 0 || counts.Requests < ls.MinRequests {
+		return false
+	}
+
+	rate := float64(counts.TotalFailures) / float64(counts.Requests)
+	switch p {
+	case PriorityLow:
+		return ls.LowPriorityFailureRate > 0 && rate >= ls.LowPriorityFailureRate ||
+			ls.NormalPriorityFailureRate > 0 && rate >= ls.NormalPriorityFailureRate
+	case PriorityNormal:
+		return ls.NormalPriorityFailureRate > 0 && rate >= ls.NormalPriorityFailureRate
+	default:
+		return false
+	}
+}
@@ -0,0 +1,71 @@
+package circuit_breaker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriorityFromContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, PriorityNormal, PriorityFromContext(ctx))
+	assert.Equal(t, PriorityCritical, PriorityFromContext(WithPriority(ctx, PriorityCritical)))
+	assert.Equal(t, "low", PriorityLow.String())
+}
+
+func TestLoadShedding(t *testing.T) {
+	ls := LoadShedding{LowPriorityFailureRate: 0.3, NormalPriorityFailureRate: 0.6, MinRequests: 10}
+
+	assert.False(t, ls.shouldShed(PriorityLow, Counts{Requests: 5, TotalFailures: 5}))
+	assert.True(t, ls.shouldShed(PriorityLow, Counts{Requests: 10, TotalFailures: 3}))
+	assert.False(t, ls.shouldShed(PriorityNormal, Counts{Requests: 10, TotalFailures: 3}))
+	assert.True(t, ls.shouldShed(PriorityNormal, Counts{Requests: 10, TotalFailures: 6}))
+	assert.False(t, ls.shouldShed(PriorityCritical, Counts{Requests: 10, TotalFailures: 10}))
+
+	// shedding normal requests sheds the low priority ones too
+	ls = LoadShedding{NormalPriorityFailureRate: 0.5, MinRequests: 1}
+	assert.True(t, ls.shouldShed(PriorityLow, Counts{Requests: 2, TotalFailures: 1}))
+}
+
+func TestCircuitBreakerLoadShedding(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "shedding circuit breaker",
+		MaxConsecutiveFailures: 100,
+		LoadShedding: LoadShedding{
+			LowPriorityFailureRate:    0.2,
+			NormalPriorityFailureRate: 0.5,
+		},
+	})
+	call := func(p Priority) error {
+		_, err := cb.ExecuteContext(WithPriority(context.Background(), p), func(ctx context.Context) (interface{}, error) {
+			return nil, nil
+		})
+		return err
+	}
+
+	for i := 0; i < 8; i++ {
+		assert.Nil(t, succeed(cb))
+	}
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Nil(t, call(PriorityLow)) // 10% failures
+
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, errServiceError, fail(cb)) // 25% failures
+	assert.Equal(t, ErrLoadShed, call(PriorityLow))
+	assert.Nil(t, call(PriorityNormal))
+
+	for i := 0; i < 7; i++ {
+		assert.Equal(t, errServiceError, fail(cb))
+	}
+	assert.Equal(t, ErrLoadShed, call(PriorityNormal)) // 50% failures
+	assert.Equal(t, ErrLoadShed, fail(cb))
+	assert.Nil(t, call(PriorityCritical))
+	assert.Equal(t, StateClosed, cb.State())
+
+	// shed requests are not counted
+	assert.Equal(t, uint32(21), cb.Counts().Requests)
+
+	cb.Reset()
+	assert.Nil(t, call(PriorityLow))
+}
@@ -0,0 +1,51 @@
+package circuit_breaker
+
+import (
+	"context"
+	"time"
+)
+
+// probeCall is a half-open probe request the other half-open callers wait for, see Config.CoalesceHalfOpen
+type probeCall struct {
+	done chan struct{}
+}
+
+func newProbeCall() *probeCall {
+	return &probeCall{done: make(chan struct{})}
+}
+
+// complete releases the callers waiting for the probe. It is a no-op on a nil probe.
+func (p *probeCall) complete() {
+	if p == nil {
+		return
+	}
+
+	close(p.done)
+}
+
+// wait waits for the probe to complete, or returns the error of the context if it is done first
+func (p *probeCall) wait(ctx context.Context) error {
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// admitRequest admits the request or returns the rejection error.
+// While a coalesced half-open probe is in flight, it waits for the probe and then decides again,
+// so the request either runs itself once the probe is over or is rejected, never sharing the result of the probe.
+// It returns the admission time of the request.
+func (cb *CircuitBreaker) admitRequest(ctx context.Context) (ticket, time.Time, error) {
+	for {
+		now := time.Now()
+		t, err := cb.beforeRequest(ctx, now)
+		if err != nil || !t.follower {
+			return t, now, err
+		}
+		if err := t.probe.wait(ctx); err != nil {
+			return ticket{}, now, err
+		}
+	}
+}
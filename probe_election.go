@@ -0,0 +1,142 @@
+package circuit_breaker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultProbeLease is the lease of the elected prober if ProbeLease is not set.
+const DefaultProbeLease = 10 * time.Second
+
+// ProbeElector elects the single instance of the fleet probing a half-open breaker, e.g. by a lock in Redis,
+// so that N instances don't send N times the half-open requests to a recovering backend, see Config.ProbeElector.
+// A ProbeElector represents one instance and must be safe for concurrent use.
+type ProbeElector interface {
+	// Elect makes the instance the prober of the breaker with the name for the lease, unless another instance is.
+	// It reports whether the instance is the prober, extending the lease if it already was.
+	Elect(ctx context.Context, name string, lease time.Duration) (bool, error)
+	// Resign ends the lease of the instance, if it holds it, once its half-open period is over.
+	Resign(ctx context.Context, name string) error
+}
+
+type electionStatus uint8
+
+const (
+	electionNone electionStatus = iota
+	electionRunning
+	electionWon
+	electionLost
+)
+
+// probeElection is the outcome of the election of the half-open period started by the generation
+type probeElection struct {
+	generation uint64
+	status     electionStatus
+	// until is the end of the lease of the winner, after which the losers elect again
+	until time.Time
+}
+
+// prober reports whether the CircuitBreaker probes the current half-open period, while the lock is held
+func (cb *CircuitBreaker) prober() bool {
+	return cb.election.generation == cb.generation && cb.election.status == electionWon
+}
+
+// needsElection reports whether the current half-open period has no prober yet, while the lock is held.
+// It is the case when no election was held, or when the lease of the winner is over without a result.
+func (cb *CircuitBreaker) needsElection(now time.Time) bool {
+	if cb.election.generation != cb.generation || cb.election.status == electionNone {
+		return true
+	}
+
+	return cb.election.status == electionLost && now.After(cb.election.until)
+}
+
+// elect runs the election of the prober of the half-open period.
+// It is called with the lock held, which it releases during the election, and returns the admission decision.
+// If the election fails, the CircuitBreaker probes on its own.
+func (cb *CircuitBreaker) elect(ctx context.Context, now time.Time) (ticket, error) {
+	generation := cb.generation
+	cb.election = probeElection{generation: generation, status: electionRunning}
+	cb.unlock()
+
+	// the election is on the request path, so a stalled elector can't hold the request longer than a storage call
+	electCtx, cancel := context.WithTimeout(ctx, cb.storageTimeout)
+	won, err := cb.probeElector.Elect(electCtx, cb.name, cb.probeLease)
+	cancel()
+	if err != nil {
+		if cb.logger != nil {
+			cb.logger.Warn("circuit breaker probe election failed", "name", cb.name, "error", err)
+		}
+		won = true
+	}
+
+	cb.mu.Lock()
+	if cb.election.generation == generation && cb.election.status == electionRunning {
+		cb.election.status = electionLost
+		if won {
+			cb.election.status = electionWon
+		}
+		cb.election.until = time.Now().Add(cb.probeLease)
+	}
+
+	return cb.admit(ctx, now)
+}
+
+// resign ends the lease of the prober, called in the background
+func (cb *CircuitBreaker) resign() {
+	ctx, cancel := cb.storageContext()
+	defer cancel()
+
+	if err := cb.probeElector.Resign(ctx, cb.name); err != nil && cb.logger != nil {
+		cb.logger.Warn("circuit breaker probe resignation failed", "name", cb.name, "error", err)
+	}
+}
+
+// MemoryElection elects the probers among the breakers of the process sharing it, e.g. in tests,
+// each one taking part as a Candidate. MemoryElection is safe for concurrent use.
+type MemoryElection struct {
+	mu     sync.Mutex
+	leases map[string]probeLease
+}
+
+type probeLease struct {
+	holder *memoryCandidate
+	until  time.Time
+}
+
+// NewMemoryElection creates the MemoryElection.
+func NewMemoryElection() *MemoryElection {
+	return &MemoryElection{leases: make(map[string]probeLease)}
+}
+
+// Candidate returns the ProbeElector of a new instance taking part in the election.
+func (e *MemoryElection) Candidate() ProbeElector {
+	return &memoryCandidate{election: e}
+}
+
+type memoryCandidate struct {
+	election *MemoryElection
+}
+
+func (c *memoryCandidate) Elect(ctx context.Context, name string, lease time.Duration) (bool, error) {
+	c.election.mu.Lock()
+	defer c.election.mu.Unlock()
+
+	now := time.Now()
+	if l, ok := c.election.leases[name]; ok && l.holder != c && now.Before(l.until) {
+		return false, nil
+	}
+	c.election.leases[name] = probeLease{holder: c, until: now.Add(lease)}
+	return true, nil
+}
+
+func (c *memoryCandidate) Resign(ctx context.Context, name string) error {
+	c.election.mu.Lock()
+	defer c.election.mu.Unlock()
+
+	if c.election.leases[name].holder == c {
+		delete(c.election.leases, name)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package circuit_breaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingProbe runs a half-open request in the background until release is closed
+func blockingProbe(t *testing.T, cb *CircuitBreaker) (release chan struct{}, done chan error) {
+	started := make(chan struct{})
+	release = make(chan struct{})
+	done = make(chan error, 1)
+	go func() {
+		_, err := cb.Execute(func() (interface{}, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+		done <- err
+	}()
+
+	select {
+	case <-started:
+	case err := <-done:
+		t.Fatalf("probe rejected: %v", err)
+	}
+	return release, done
+}
+
+func TestProbeElection(t *testing.T) {
+	hub := NewBroadcastHub()
+	defer hub.Close()
+	election := NewMemoryElection()
+	cfg := Config{Name: "fleet", MaxConsecutiveFailures: 1, RequestThreshold: 1, Timeout: time.Minute}
+	cfg.ProbeElector = election.Candidate()
+	a := NewCircuitBreaker(cfg)
+	cfg.ProbeElector = election.Candidate()
+	b := NewCircuitBreaker(cfg)
+	defer BroadcastState(a, hub, "a")()
+	defer BroadcastState(b, hub, "b")()
+
+	assert.Equal(t, errServiceError, fail(a))
+	assert.Eventually(t, func() bool { return b.State() == StateOpen }, time.Second, time.Millisecond)
+	pseudoSleep(a, time.Minute)
+	pseudoSleep(b, time.Minute)
+
+	// a is elected, b waits for its result
+	release, done := blockingProbe(t, a)
+	assert.Equal(t, ErrTooManyRequests, succeed(b))
+	assert.Equal(t, StateHalfOpen, b.State())
+	won, err := election.Candidate().Elect(context.Background(), "fleet", time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, won)
+
+	close(release)
+	assert.Nil(t, <-done)
+	assert.Equal(t, StateClosed, a.State())
+	assert.Eventually(t, func() bool { return b.State() == StateClosed }, time.Second, time.Millisecond)
+	a.WaitBackground()
+
+	// the prober resigned with the end of the half-open state
+	won, err = election.Candidate().Elect(context.Background(), "fleet", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, won)
+}
+
+func TestProbeElectionProbeFailed(t *testing.T) {
+	election := NewMemoryElection()
+	cb := NewCircuitBreaker(Config{
+		MaxConsecutiveFailures: 1,
+		RequestThreshold:       1,
+		Timeout:                time.Minute,
+		ProbeElector:           election.Candidate(),
+	})
+
+	assert.Equal(t, errServiceError, fail(cb))
+	pseudoSleep(cb, time.Minute)
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+
+	// the prober is elected again in the next half-open state
+	pseudoSleep(cb, 2*time.Minute)
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.State())
+	cb.WaitBackground()
+	assert.Empty(t, election.leases)
+}
+
+func TestProbeElectionLeaseExpired(t *testing.T) {
+	election := NewMemoryElection()
+	cfg := Config{Name: "fleet", MaxConsecutiveFailures: 1, RequestThreshold: 1, Timeout: time.Minute, ProbeLease: 20 * time.Millisecond}
+	cfg.ProbeElector = election.Candidate()
+	a := NewCircuitBreaker(cfg)
+	cfg.ProbeElector = election.Candidate()
+	b := NewCircuitBreaker(cfg)
+	a.Trip()
+	b.Trip()
+	pseudoSleep(a, time.Minute)
+	pseudoSleep(b, time.Minute)
+
+	release, done := blockingProbe(t, a)
+	defer func() {
+		close(release)
+		<-done
+	}()
+	assert.Equal(t, ErrTooManyRequests, succeed(b))
+
+	// a never reported its result, so b takes over
+	time.Sleep(30 * time.Millisecond)
+	assert.Nil(t, succeed(b))
+	assert.Equal(t, StateClosed, b.State())
+}
+
+type failingElector struct{}
+
+func (failingElector) Elect(ctx context.Context, name string, lease time.Duration) (bool, error) {
+	return false, errors.New("election unavailable")
+}
+
+func (failingElector) Resign(ctx context.Context, name string) error {
+	return errors.New("election unavailable")
+}
+
+func TestProbeElectionFailed(t *testing.T) {
+	logger := &recordingLogger{}
+	cb := NewCircuitBreaker(Config{
+		Name:                   "fleet",
+		MaxConsecutiveFailures: 1,
+		RequestThreshold:       1,
+		Timeout:                time.Minute,
+		ProbeElector:           failingElector{},
+		Logger:                 logger,
+	})
+
+	// the breaker probes on its own
+	assert.Equal(t, errServiceError, fail(cb))
+	pseudoSleep(cb, time.Minute)
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.State())
+	cb.WaitBackground()
+	assert.Contains(t, logger.entries, "WARN circuit breaker probe election failed name=fleet error=election unavailable")
+	assert.Contains(t, logger.entries, "WARN circuit breaker probe resignation failed name=fleet error=election unavailable")
+}
+
+type stalledElector struct{}
+
+func (stalledElector) Elect(ctx context.Context, name string, lease time.Duration) (bool, error) {
+	<-ctx.Done()
+	return false, ctx.Err()
+}
+
+func (stalledElector) Resign(ctx context.Context, name string) error {
+	return nil
+}
+
+func TestProbeElectionTimeout(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "fleet",
+		MaxConsecutiveFailures: 1,
+		RequestThreshold:       1,
+		Timeout:                time.Minute,
+		StorageTimeout:         20 * time.Millisecond,
+		ProbeElector:           stalledElector{},
+	})
+
+	// the stalled election doesn't hold the request, and the breaker probes on its own
+	assert.Equal(t, errServiceError, fail(cb))
+	pseudoSleep(cb, time.Minute)
+	start := time.Now()
+	assert.Nil(t, succeed(cb))
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, StateClosed, cb.State())
+}
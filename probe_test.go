@@ -0,0 +1,114 @@
+package circuit_breaker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerCoalesceHalfOpen(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "coalescing circuit breaker",
+		RequestThreshold:       1,
+		MaxConsecutiveFailures: 1,
+		CoalesceHalfOpen:       true,
+	})
+
+	assert.Equal(t, errServiceError, fail(cb))
+	pseudoSleep(cb, DefaultTimeout)
+	assert.Equal(t, StateHalfOpen, cb.State())
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_, _ = cb.Execute(func() (interface{}, error) {
+			close(started)
+			<-release
+			return "probe", nil
+		})
+	}()
+	<-started
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 4)
+	var calls int32
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = cb.Execute(func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				return "own", nil
+			})
+		}(i)
+	}
+
+	// the followers are waiting for the probe
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+	close(release)
+	wg.Wait()
+
+	// and run their own requests once the breaker closed
+	assert.Equal(t, int32(4), atomic.LoadInt32(&calls))
+	assert.Equal(t, []interface{}{"own", "own", "own", "own"}, results)
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Nil(t, cb.probe)
+}
+
+func TestCircuitBreakerCoalesceHalfOpenFailure(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "coalescing circuit breaker",
+		RequestThreshold:       1,
+		MaxConsecutiveFailures: 1,
+		CoalesceHalfOpen:       true,
+	})
+
+	assert.Equal(t, errServiceError, fail(cb))
+	pseudoSleep(cb, DefaultTimeout)
+
+	follower := make(chan error, 1)
+	_, err := cb.Execute(func() (interface{}, error) {
+		go func() { follower <- succeed(cb) }()
+		time.Sleep(10 * time.Millisecond) // let the follower join the probe
+		return nil, errServiceError
+	})
+
+	// the follower is rejected by the open breaker instead of sharing the failure
+	assert.Equal(t, errServiceError, err)
+	assert.Equal(t, ErrOpenState, <-follower)
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestCircuitBreakerCoalesceHalfOpenContext(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "coalescing circuit breaker",
+		RequestThreshold:       1,
+		MaxConsecutiveFailures: 1,
+		CoalesceHalfOpen:       true,
+	})
+
+	assert.Equal(t, errServiceError, fail(cb))
+	pseudoSleep(cb, DefaultTimeout)
+
+	_, err := cb.Execute(func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		// the follower gives up waiting for the probe with its context
+		ran := false
+		_, err := cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+			ran = true
+			return nil, nil
+		})
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.False(t, ran)
+		return nil, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, StateClosed, cb.State())
+}
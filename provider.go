@@ -0,0 +1,122 @@
+package circuit_breaker
+
+import "sync"
+
+// ConfigProvider is a source of configuration updates, e.g. a configuration file, Consul or etcd,
+// so the thresholds and timeouts can be tuned in production without restarts, see WatchConfig.
+type ConfigProvider interface {
+	// Watch returns the channel delivering the configurations of the breaker with the name.
+	// The channel is closed when the provider stops.
+	Watch(name string) <-chan Config
+}
+
+// WatchConfig applies the configurations of the CircuitBreaker delivered by the provider,
+// until stop is called or the provider stops.
+//
+// The configurations are applied by UpdateConfig, atomically between the decisions of the CircuitBreaker,
+// never in the middle of one. The invalid configurations are logged by the Logger and skipped.
+func WatchConfig(cb *CircuitBreaker, provider ConfigProvider) (stop func()) {
+	updates := provider.Watch(cb.name)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case cfg, ok := <-updates:
+				if !ok {
+					return
+				}
+				// the update may be received together with stop
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if err := cb.UpdateConfig(cfg); err != nil && cb.logger != nil {
+					cb.logger.Error("circuit breaker config update rejected", "name", cb.name, "error", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// ConfigFeed is the ConfigProvider of the configurations set by the application,
+// e.g. from a configuration file reloaded on SIGHUP, or by the providers of remote configuration stores.
+// The watchers of a breaker receive its current configuration, if any, and then its updates;
+// a slow watcher only receives the latest of the updates it missed.
+// ConfigFeed is safe for concurrent use.
+type ConfigFeed struct {
+	mu       sync.Mutex
+	configs  map[string]Config
+	watchers map[string][]chan Config
+	closed   bool
+}
+
+var _ ConfigProvider = (*ConfigFeed)(nil)
+
+func NewConfigFeed() *ConfigFeed {
+	return &ConfigFeed{
+		configs:  make(map[string]Config),
+		watchers: make(map[string][]chan Config),
+	}
+}
+
+// Watch implements ConfigProvider.
+func (f *ConfigFeed) Watch(name string) <-chan Config {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch := make(chan Config, 1)
+	if f.closed {
+		close(ch)
+		return ch
+	}
+	if cfg, ok := f.configs[name]; ok {
+		ch <- cfg
+	}
+	f.watchers[name] = append(f.watchers[name], ch)
+
+	return ch
+}
+
+// Set delivers the configuration of the breaker with the name to its watchers.
+func (f *ConfigFeed) Set(name string, cfg Config) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return
+	}
+	f.configs[name] = cfg
+	for _, ch := range f.watchers[name] {
+		// replace the update the watcher has not received yet
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- cfg
+	}
+}
+
+// Close stops the feed, closing the channels of the watchers.
+func (f *ConfigFeed) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return
+	}
+	f.closed = true
+	for _, watchers := range f.watchers {
+		for _, ch := range watchers {
+			close(ch)
+		}
+	}
+	f.watchers = nil
+}
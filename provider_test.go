@@ -0,0 +1,70 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchConfig(t *testing.T) {
+	feed := NewConfigFeed()
+	feed.Set("payments", Config{MaxConsecutiveFailures: 3, RequestThreshold: 2})
+
+	cb := NewCircuitBreaker(Config{Name: "payments", MaxConsecutiveFailures: 1})
+	requestThreshold := func() uint32 {
+		cb.mu.Lock()
+		defer cb.unlock()
+		return cb.requestThreshold
+	}
+	stop := WatchConfig(cb, feed)
+	defer stop()
+
+	// the current configuration is applied on watch
+	assert.Eventually(t, func() bool { return requestThreshold() == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+
+	// an update keeps the state and recomputes the open period
+	feed.Set("payments", Config{MaxConsecutiveFailures: 1, RequestThreshold: 1, Timeout: time.Second})
+	feed.Set("search", Config{MaxConsecutiveFailures: 100})
+	assert.Eventually(t, func() bool { return requestThreshold() == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, StateOpen, cb.State())
+	assert.LessOrEqual(t, cb.RemainingOpenTime(), time.Second)
+
+	cb.Reset()
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+
+	// the invalid updates are skipped
+	feed.Set("payments", Config{Timeout: -time.Second, RequestThreshold: 2})
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, uint32(1), requestThreshold())
+
+	// the updates after stop are ignored
+	stop()
+	feed.Set("payments", Config{MaxConsecutiveFailures: 100, RequestThreshold: 3})
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, uint32(1), requestThreshold())
+}
+
+func TestConfigFeed(t *testing.T) {
+	feed := NewConfigFeed()
+	updates := feed.Watch("payments")
+
+	// a slow watcher receives the latest update
+	feed.Set("payments", Config{Timeout: time.Second})
+	feed.Set("payments", Config{Timeout: time.Minute})
+	assert.Equal(t, time.Minute, (<-updates).Timeout)
+
+	feed.Close()
+	_, ok := <-updates
+	assert.False(t, ok)
+	_, ok = <-feed.Watch("payments")
+	assert.False(t, ok)
+	feed.Set("payments", Config{})
+	feed.Close()
+}
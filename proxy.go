@@ -0,0 +1,95 @@
+package circuit_breaker
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httputil"
+)
+
+// ReverseProxyConfig configures ProtectReverseProxy.
+//
+// Breakers holds a breaker per backend, created lazily.
+//
+// Key returns the breaker key of the outgoing request, after the Director of the proxy has chosen the backend.
+// If Key is nil, HostKey is used.
+//
+// OnReject writes the response for a request rejected by the breaker of its backend.
+// If OnReject is nil, WriteRejection is used.
+type ReverseProxyConfig struct {
+	Breakers *KeyedBreaker
+	Key      func(r *http.Request) string
+	OnReject func(w http.ResponseWriter, r *http.Request, cb *CircuitBreaker, err error)
+}
+
+// ProtectReverseProxy protects the backends of the proxy with a breaker each.
+//
+// The Transport of the proxy is wrapped so that the requests to a backend whose circuit is open
+// are rejected without being sent, and the responses are classified according to the FailureStatusCodes
+// and IgnoreStatusCodes of the breaker Config. The ErrorHandler of the proxy is wrapped so that
+// the rejected requests are answered by OnReject, 503 Service Unavailable by default;
+// the other errors are still passed to the original ErrorHandler.
+func ProtectReverseProxy(proxy *httputil.ReverseProxy, cfg ReverseProxyConfig) {
+	if cfg.Key == nil {
+		cfg.Key = HostKey
+	}
+	if cfg.OnReject == nil {
+		cfg.OnReject = func(w http.ResponseWriter, r *http.Request, cb *CircuitBreaker, err error) {
+			WriteRejection(w, cb, err)
+		}
+	}
+
+	base := proxy.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	proxy.Transport = &proxyTransport{base: base, cfg: cfg}
+
+	errorHandler := proxy.ErrorHandler
+	if errorHandler == nil {
+		errorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		var rejected *proxyRejection
+		if errors.As(err, &rejected) {
+			cfg.OnReject(w, r, rejected.cb, rejected.err)
+			return
+		}
+		errorHandler(w, r, err)
+	}
+}
+
+// proxyRejection carries the breaker that rejected the request from the transport to the error handler
+type proxyRejection struct {
+	cb  *CircuitBreaker
+	err error
+}
+
+func (e *proxyRejection) Error() string {
+	return e.err.Error()
+}
+
+func (e *proxyRejection) Unwrap() error {
+	return e.err
+}
+
+type proxyTransport struct {
+	base http.RoundTripper
+	cfg  ReverseProxyConfig
+}
+
+func (t *proxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cb := t.cfg.Breakers.Get(t.cfg.Key(req))
+
+	handled := false
+	resp, err := cb.executeHTTP(req.Context(), func() (*http.Response, error) {
+		handled = true
+		return t.base.RoundTrip(req)
+	})
+	if !handled {
+		return nil, &proxyRejection{cb: cb, err: err}
+	}
+
+	return resp, err
+}
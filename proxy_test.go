@@ -0,0 +1,77 @@
+package circuit_breaker
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProtectReverseProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer backend.Close()
+	target, _ := url.Parse(backend.URL)
+
+	kb := NewKeyedBreaker(KeyedConfig{Config: Config{MaxConsecutiveFailures: 2}})
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	ProtectReverseProxy(proxy, ReverseProxyConfig{Breakers: kb})
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		proxy.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reports", nil))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	}
+
+	cb, ok := kb.Lookup(target.Host)
+	assert.True(t, ok)
+	assert.Equal(t, StateOpen, cb.State())
+
+	w := httptest.NewRecorder()
+	proxy.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reports", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+}
+
+// failingTransport fails every round trip
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errServiceError
+}
+
+func TestProtectReverseProxyErrorHandler(t *testing.T) {
+	target, _ := url.Parse("http://reports.internal")
+	kb := NewKeyedBreaker(KeyedConfig{Config: Config{MaxConsecutiveFailures: 1}})
+
+	var handled []error
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = failingTransport{}
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		handled = append(handled, err)
+		w.WriteHeader(http.StatusGatewayTimeout)
+	}
+	ProtectReverseProxy(proxy, ReverseProxyConfig{
+		Breakers: kb,
+		OnReject: func(w http.ResponseWriter, r *http.Request, cb *CircuitBreaker, err error) {
+			assert.Equal(t, "reports.internal", cb.Name())
+			assert.True(t, errors.Is(err, ErrOpenState))
+			w.WriteHeader(http.StatusTooManyRequests)
+		},
+	})
+
+	w := httptest.NewRecorder()
+	proxy.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reports", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Equal(t, []error{errServiceError}, handled)
+
+	w = httptest.NewRecorder()
+	proxy.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reports", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Len(t, handled, 1)
+}
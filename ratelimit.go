@@ -0,0 +1,62 @@
+package circuit_breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned when the request exceeds the rate limit of the CircuitBreaker
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateLimiter bounds the rate of the requests admitted by the CircuitBreaker.
+// *rate.Limiter from golang.org/x/time/rate implements it, as does TokenBucket.
+// Allow is called while the CircuitBreaker lock is held, only for the requests
+// that passed all the other admission checks, so rejected requests don't consume tokens.
+type RateLimiter interface {
+	Allow() bool
+}
+
+// TokenBucket is a token bucket RateLimiter. It is safe for concurrent use.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a full bucket of burst tokens, refilled by rate tokens per second.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Allow takes a token from the bucket if there is one.
+func (b *TokenBucket) Allow() bool {
+	return b.allowAt(time.Now())
+}
+
+func (b *TokenBucket) allowAt(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+
+	return true
+}
@@ -0,0 +1,60 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := NewTokenBucket(10, 2)
+	now := b.last
+
+	assert.True(t, b.allowAt(now))
+	assert.True(t, b.allowAt(now))
+	assert.False(t, b.allowAt(now))
+
+	// a token is refilled every 100ms
+	assert.False(t, b.allowAt(now.Add(50*time.Millisecond)))
+	assert.True(t, b.allowAt(now.Add(100*time.Millisecond)))
+	assert.False(t, b.allowAt(now.Add(100*time.Millisecond)))
+
+	// the bucket holds at most burst tokens
+	later := now.Add(time.Minute)
+	assert.True(t, b.allowAt(later))
+	assert.True(t, b.allowAt(later))
+	assert.False(t, b.allowAt(later))
+}
+
+// rateLimiterFunc adapts a function to RateLimiter
+type rateLimiterFunc func() bool
+
+func (f rateLimiterFunc) Allow() bool { return f() }
+
+func TestCircuitBreakerRateLimiter(t *testing.T) {
+	calls := 0
+	cb := NewCircuitBreaker(Config{
+		Name:                   "rate limited circuit breaker",
+		MaxConsecutiveFailures: 1,
+		RateLimiter: rateLimiterFunc(func() bool {
+			calls++
+			return calls <= 2
+		}),
+	})
+
+	assert.Nil(t, succeed(cb))
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, ErrRateLimited, succeed(cb))
+	// rate limited requests are not counted
+	assert.Equal(t, uint32(2), cb.Counts().Requests)
+	assert.Equal(t, StateClosed, cb.State())
+
+	calls = 0
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+
+	// the open state rejects the requests before they consume tokens
+	assert.Equal(t, ErrOpenState, succeed(cb))
+	assert.Equal(t, 1, calls)
+}
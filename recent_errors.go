@@ -0,0 +1,54 @@
+package circuit_breaker
+
+import "time"
+
+// RecentError is a distinct error counted as a failure by the CircuitBreaker, see RecentErrors.
+// Errors are distinct by their message. Err is the last occurrence of the error,
+// Count the number of its occurrences, and FirstSeen and LastSeen the times of the first and the last one.
+type RecentError struct {
+	Err       error
+	Count     uint64
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// recentErrors keeps the last distinct errors, ordered by LastSeen
+type recentErrors struct {
+	size    int
+	entries []RecentError
+}
+
+func (r *recentErrors) add(err error, at time.Time) {
+	if r.size <= 0 {
+		return
+	}
+
+	msg := err.Error()
+	for i, e := range r.entries {
+		if e.Err.Error() == msg {
+			e.Err = err
+			e.Count++
+			e.LastSeen = at
+			// keep the entries ordered by LastSeen
+			copy(r.entries[i:], r.entries[i+1:])
+			r.entries[len(r.entries)-1] = e
+			return
+		}
+	}
+
+	if len(r.entries) == r.size {
+		copy(r.entries, r.entries[1:])
+		r.entries = r.entries[:len(r.entries)-1]
+	}
+	r.entries = append(r.entries, RecentError{Err: err, Count: 1, FirstSeen: at, LastSeen: at})
+}
+
+// RecentErrors returns the last distinct errors counted as failures, from the least to the most recently seen,
+// up to RecentErrorsSize of them. They are kept across the state changes,
+// so they tell which errors tripped the CircuitBreaker.
+func (cb *CircuitBreaker) RecentErrors() []RecentError {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	return append([]RecentError(nil), cb.recentErrors.entries...)
+}
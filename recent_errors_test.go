@@ -0,0 +1,54 @@
+package circuit_breaker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecentErrors(t *testing.T) {
+	r := recentErrors{size: 2}
+	start := time.Now()
+	at := func(s int) time.Time { return start.Add(time.Duration(s) * time.Second) }
+
+	r.add(errors.New("timeout"), at(0))
+	r.add(errors.New("refused"), at(1))
+	last := errors.New("timeout")
+	r.add(last, at(2))
+	assert.Equal(t, []RecentError{
+		{Err: errors.New("refused"), Count: 1, FirstSeen: at(1), LastSeen: at(1)},
+		{Err: last, Count: 2, FirstSeen: at(0), LastSeen: at(2)},
+	}, r.entries)
+	assert.Same(t, last, r.entries[1].Err)
+
+	// the least recently seen error is evicted
+	r.add(errors.New("reset"), at(3))
+	assert.Len(t, r.entries, 2)
+	assert.Equal(t, "timeout", r.entries[0].Err.Error())
+	assert.Equal(t, "reset", r.entries[1].Err.Error())
+
+	var disabled recentErrors
+	disabled.add(last, at(0))
+	assert.Nil(t, disabled.entries)
+}
+
+func TestCircuitBreakerRecentErrors(t *testing.T) {
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 3, RecentErrorsSize: 5})
+	assert.Empty(t, cb.RecentErrors())
+
+	for i := 0; i < 3; i++ {
+		_, err := cb.Execute(func() (interface{}, error) { return nil, fmt.Errorf("dial tcp: connection refused") })
+		assert.NotNil(t, err)
+	}
+	assert.Equal(t, StateOpen, cb.State())
+
+	// the errors which tripped the breaker are kept after the state change
+	recent := cb.RecentErrors()
+	assert.Len(t, recent, 1)
+	assert.Equal(t, "dial tcp: connection refused", recent[0].Err.Error())
+	assert.Equal(t, uint64(3), recent[0].Count)
+	assert.False(t, recent[0].FirstSeen.After(recent[0].LastSeen))
+}
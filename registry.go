@@ -0,0 +1,339 @@
+package circuit_breaker
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RegistryConfig configures Registry.
+//
+// Eviction evicts the idle and the least recently used breakers.
+//
+// Templates are the named configurations of the breakers created by GetOrCreateFrom, see RegisterTemplate.
+type RegistryConfig struct {
+	Eviction  Eviction
+	Templates map[string]Config
+}
+
+// Registry holds the named breakers of an application, creating them on first use.
+// Registry is safe for concurrent use.
+type Registry struct {
+	cfg RegistryConfig
+
+	mu        sync.RWMutex
+	breakers  map[string]*managedBreaker
+	templates map[string]Config
+	lastSweep time.Time
+	onCreate  []func(cb *CircuitBreaker)
+	onRemove  []func(cb *CircuitBreaker)
+}
+
+func NewRegistry(cfg RegistryConfig) *Registry {
+	r := &Registry{
+		cfg:       cfg,
+		breakers:  make(map[string]*managedBreaker),
+		templates: make(map[string]Config, len(cfg.Templates)),
+		lastSweep: time.Now(),
+	}
+	for name, template := range cfg.Templates {
+		r.templates[name] = template
+	}
+
+	return r
+}
+
+// RegisterTemplate registers the named configuration template, e.g. "aggressive" or "third-party-api",
+// so the teams create their breakers with the same policies, see GetOrCreateFrom.
+// It replaces the template with the same name, but not the breakers already created from it.
+//
+// The fields holding state, like LatencyHistogram, ConcurrencyLimiter, RateLimiter or a stateful Policy,
+// would be shared by all the breakers created from the template, so they should be set by the overrides.
+func (r *Registry) RegisterTemplate(name string, cfg Config) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.templates[name] = cfg
+}
+
+// Template returns the configuration template with the name if it is registered.
+func (r *Registry) Template(name string) (Config, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cfg, ok := r.templates[name]
+	return cfg, ok
+}
+
+// GetOrCreateFrom is like GetOrCreate, but creates the breaker from the template with the name,
+// changed by the overrides in order. It returns an error if the template is not registered.
+// The template and the overrides are ignored if the breaker already exists.
+func (r *Registry) GetOrCreateFrom(name, template string, overrides ...func(cfg *Config)) (*CircuitBreaker, error) {
+	if cb, ok := r.Get(name); ok {
+		return cb, nil
+	}
+
+	cfg, ok := r.Template(template)
+	if !ok {
+		return nil, fmt.Errorf("unknown circuit breaker template %q", template)
+	}
+	cfg.Labels = copyLabels(cfg.Labels)
+	for _, override := range overrides {
+		override(&cfg)
+	}
+
+	return r.GetOrCreate(name, cfg), nil
+}
+
+// GetOrCreate returns the CircuitBreaker with the name, creating it from the config if necessary.
+// The name replaces the Name of the config. The config is ignored if the breaker already exists,
+// so concurrent callers always share the same breaker.
+func (r *Registry) GetOrCreate(name string, cfg Config) *CircuitBreaker {
+	if cb, ok := r.Get(name); ok {
+		return cb
+	}
+
+	// the breaker is built outside the lock, so the sync with the storage doesn't stall the other callers;
+	// if another caller creates the breaker first, this one is dropped
+	cfg.Name = name
+	cb := NewCircuitBreaker(cfg)
+
+	now := time.Now()
+	r.mu.Lock()
+	if m, ok := r.breakers[name]; ok {
+		r.mu.Unlock()
+		m.touch(now)
+		return m.cb
+	}
+
+	var evicted []*CircuitBreaker
+	if now.Sub(r.lastSweep) >= r.cfg.Eviction.IdleTTL {
+		evicted = r.cfg.Eviction.sweepIdle(r.breakers, now)
+		r.lastSweep = now
+	}
+	if maxSize := r.cfg.Eviction.MaxSize; maxSize > 0 && len(r.breakers) >= maxSize {
+		if oldest, _, ok := leastRecentlyUsed(r.breakers, now); ok {
+			evicted = append(evicted, r.breakers[oldest].cb)
+			delete(r.breakers, oldest)
+		}
+	}
+
+	for _, hook := range r.onCreate {
+		hook(cb)
+	}
+	r.breakers[name] = newManagedBreaker(cb, now)
+	onRemove := r.onRemove
+	r.mu.Unlock()
+
+	r.evicted(evicted, onRemove)
+	return cb
+}
+
+// OnCreate registers the hook called with every breaker created by the Registry afterwards,
+// e.g. to add it to a metrics collector or to watch it by a Dispatcher.
+// The hooks are called before the breaker is returned to anyone, while the Registry is locked,
+// so they must not call the Registry.
+func (r *Registry) OnCreate(hook func(cb *CircuitBreaker)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.onCreate = append(r.onCreate, hook)
+}
+
+// OnRemove registers the hook called with every breaker removed or evicted from the Registry afterwards,
+// e.g. to remove it from a metrics collector. The hooks are called after the breaker is removed.
+func (r *Registry) OnRemove(hook func(cb *CircuitBreaker)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.onRemove = append(r.onRemove, hook)
+}
+
+// evicted reports the evicted breakers to Eviction.OnEvict and to the OnRemove hooks
+func (r *Registry) evicted(cbs []*CircuitBreaker, onRemove []func(cb *CircuitBreaker)) {
+	r.cfg.Eviction.evicted(cbs...)
+	for _, cb := range cbs {
+		for _, hook := range onRemove {
+			hook(cb)
+		}
+	}
+}
+
+// Get returns the CircuitBreaker with the name if it exists.
+func (r *Registry) Get(name string) (*CircuitBreaker, bool) {
+	r.mu.RLock()
+	m, ok := r.breakers[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+
+	m.touch(time.Now())
+	return m.cb, true
+}
+
+// Names returns the sorted names of the breakers.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.breakers))
+	for name := range r.breakers {
+		names = append(names, name)
+	}
+	r.mu.RUnlock()
+
+	sort.Strings(names)
+	return names
+}
+
+// Breakers returns all the breakers, e.g. for AdminConfig or MetricsConfig.
+func (r *Registry) Breakers() []*CircuitBreaker {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cbs := make([]*CircuitBreaker, 0, len(r.breakers))
+	for _, m := range r.breakers {
+		cbs = append(cbs, m.cb)
+	}
+
+	return cbs
+}
+
+// Range calls fn with the breakers sorted by name until fn returns false.
+// It iterates over a copy of the breakers, so fn may call the Registry.
+func (r *Registry) Range(fn func(name string, cb *CircuitBreaker) bool) {
+	r.mu.RLock()
+	cbs := make([]*CircuitBreaker, 0, len(r.breakers))
+	for _, m := range r.breakers {
+		cbs = append(cbs, m.cb)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(cbs, func(i, j int) bool { return cbs[i].name < cbs[j].name })
+	for _, cb := range cbs {
+		if !fn(cb.name, cb) {
+			return
+		}
+	}
+}
+
+// Filter selects breakers, see Registry.Select and Registry.TripAll.
+type Filter func(cb *CircuitBreaker) bool
+
+// HasLabel selects the breakers with the label set to the value.
+func HasLabel(key, value string) Filter {
+	return func(cb *CircuitBreaker) bool {
+		v, ok := cb.labels[key]
+		return ok && v == value
+	}
+}
+
+// InState selects the breakers in any of the states.
+func InState(states ...State) Filter {
+	return func(cb *CircuitBreaker) bool {
+		state := cb.State()
+		for _, s := range states {
+			if s == state {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Select returns the breakers selected by all the filters, sorted by name,
+// e.g. Select(InState(StateOpen), HasLabel("tier", "critical")).
+func (r *Registry) Select(filters ...Filter) []*CircuitBreaker {
+	var selected []*CircuitBreaker
+	r.Range(func(_ string, cb *CircuitBreaker) bool {
+		for _, filter := range filters {
+			if !filter(cb) {
+				return true
+			}
+		}
+		selected = append(selected, cb)
+		return true
+	})
+
+	return selected
+}
+
+// Remove forgets the CircuitBreaker with the name.
+// The next GetOrCreate with the name creates a fresh breaker.
+func (r *Registry) Remove(name string) {
+	r.mu.Lock()
+	m, ok := r.breakers[name]
+	delete(r.breakers, name)
+	onRemove := r.onRemove
+	r.mu.Unlock()
+
+	if ok {
+		for _, hook := range onRemove {
+			hook(m.cb)
+		}
+	}
+}
+
+// EvictIdle evicts the breakers idle for Eviction.IdleTTL and returns their number.
+func (r *Registry) EvictIdle() int {
+	now := time.Now()
+	r.mu.Lock()
+	evicted := r.cfg.Eviction.sweepIdle(r.breakers, now)
+	r.lastSweep = now
+	onRemove := r.onRemove
+	r.mu.Unlock()
+
+	r.evicted(evicted, onRemove)
+	return len(evicted)
+}
+
+// ResetAll resets all the breakers, see CircuitBreaker.Reset.
+func (r *Registry) ResetAll() {
+	for _, cb := range r.Breakers() {
+		cb.Reset()
+	}
+}
+
+// TripAll trips the breakers selected by the filter, e.g. the ones labeled with a region that went down,
+// and returns their number. A nil filter selects all the breakers. See CircuitBreaker.Trip.
+func (r *Registry) TripAll(filter func(cb *CircuitBreaker) bool) int {
+	n := 0
+	for _, cb := range r.Breakers() {
+		if filter == nil || filter(cb) {
+			cb.Trip()
+			n++
+		}
+	}
+
+	return n
+}
+
+// DisableMatching disables the breakers whose names match the pattern, and returns their number.
+// The pattern has the syntax of path.Match, e.g. "eu-west-1/*". See CircuitBreaker.Disable.
+func (r *Registry) DisableMatching(pattern string) (int, error) {
+	return r.applyMatching(pattern, (*CircuitBreaker).Disable)
+}
+
+// EnableMatching enables the breakers whose names match the pattern, and returns their number.
+// The pattern has the syntax of path.Match. See CircuitBreaker.Enable.
+func (r *Registry) EnableMatching(pattern string) (int, error) {
+	return r.applyMatching(pattern, (*CircuitBreaker).Enable)
+}
+
+func (r *Registry) applyMatching(pattern string, action func(cb *CircuitBreaker)) (int, error) {
+	// validate the pattern even if there are no breakers
+	if _, err := path.Match(pattern, ""); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, cb := range r.Breakers() {
+		if ok, _ := path.Match(pattern, cb.name); ok {
+			action(cb)
+			n++
+		}
+	}
+
+	return n, nil
+}
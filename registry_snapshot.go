@@ -0,0 +1,61 @@
+package circuit_breaker
+
+import (
+	"sort"
+	"time"
+)
+
+// RegistrySnapshot is the view of a set of breakers backing the admin API, the health checks and the dashboard.
+//
+// Breakers are the snapshots of the breakers, sorted by name.
+// Closed, Open and HalfOpen are the numbers of the breakers in each state, and Disabled the number of the disabled ones.
+// CallsPerSecond and RejectionsPerSecond are the sums of the rates of the breakers over their current windows, see Stats.
+type RegistrySnapshot struct {
+	At                  time.Time
+	Breakers            []Snapshot
+	Closed              int
+	Open                int
+	HalfOpen            int
+	Disabled            int
+	CallsPerSecond      float64
+	RejectionsPerSecond float64
+}
+
+// Snapshot returns the RegistrySnapshot of all the breakers of the Registry.
+func (r *Registry) Snapshot() RegistrySnapshot {
+	return SnapshotAll(r.Breakers()...)
+}
+
+// SnapshotAll returns the RegistrySnapshot of the breakers, e.g. of a KeyedBreaker.
+func SnapshotAll(cbs ...*CircuitBreaker) RegistrySnapshot {
+	rs := RegistrySnapshot{At: time.Now(), Breakers: make([]Snapshot, 0, len(cbs))}
+	for _, cb := range cbs {
+		rs.Breakers = append(rs.Breakers, cb.Snapshot())
+	}
+	sort.Slice(rs.Breakers, func(i, j int) bool { return rs.Breakers[i].Name < rs.Breakers[j].Name })
+
+	for _, s := range rs.Breakers {
+		switch s.State {
+		case StateClosed:
+			rs.Closed++
+		case StateOpen:
+			rs.Open++
+		case StateHalfOpen:
+			rs.HalfOpen++
+		}
+		if s.Disabled {
+			rs.Disabled++
+		}
+		rs.CallsPerSecond += s.Stats.CallsPerSecond
+		if s.Stats.Window > 0 {
+			rs.RejectionsPerSecond += float64(s.Rejections.Total()) / s.Stats.Window.Seconds()
+		}
+	}
+
+	return rs
+}
+
+// Health builds the HealthReport of the breakers of the snapshot, see Health.
+func (rs RegistrySnapshot) Health() HealthReport {
+	return healthOf(rs.Breakers)
+}
@@ -0,0 +1,46 @@
+package circuit_breaker
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistrySnapshot(t *testing.T) {
+	r := NewRegistry(RegistryConfig{})
+	payments := r.GetOrCreate("payments", Config{MaxConsecutiveFailures: 1, Critical: true})
+	search := r.GetOrCreate("search", Config{})
+	r.GetOrCreate("auth", Config{})
+
+	assert.Nil(t, succeed(search))
+	assert.Equal(t, errServiceError, fail(payments))
+	assert.Equal(t, ErrOpenState, succeed(payments))
+	search.Disable()
+
+	s := r.Snapshot()
+	assert.False(t, s.At.IsZero())
+	if assert.Len(t, s.Breakers, 3) {
+		assert.Equal(t, "auth", s.Breakers[0].Name)
+		assert.Equal(t, "payments", s.Breakers[1].Name)
+		assert.True(t, s.Breakers[1].Critical)
+	}
+	assert.Equal(t, 2, s.Closed)
+	assert.Equal(t, 1, s.Open)
+	assert.Equal(t, 0, s.HalfOpen)
+	assert.Equal(t, 1, s.Disabled)
+	assert.Greater(t, s.RejectionsPerSecond, 0.0)
+	assert.Greater(t, s.CallsPerSecond, 0.0)
+
+	health := s.Health()
+	assert.False(t, health.Healthy)
+	assert.Len(t, health.Breakers, 3)
+
+	w := adminRequest(AdminHandler(AdminConfig{Breakers: r.Breakers}), http.MethodGet, "/summary")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var summary struct{ Open, Closed int }
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, 1, summary.Open)
+	assert.Equal(t, 2, summary.Closed)
+}
@@ -0,0 +1,213 @@
+package circuit_breaker
+
+import (
+	"context"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(RegistryConfig{})
+
+	payments := r.GetOrCreate("payments", Config{Name: "ignored", MaxConsecutiveFailures: 1})
+	assert.Equal(t, "payments", payments.Name())
+	// the config of an existing breaker is ignored
+	assert.Same(t, payments, r.GetOrCreate("payments", Config{}))
+	r.GetOrCreate("auth", Config{})
+
+	cb, ok := r.Get("payments")
+	assert.True(t, ok)
+	assert.Same(t, payments, cb)
+	_, ok = r.Get("search")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"auth", "payments"}, r.Names())
+	assert.Len(t, r.Breakers(), 2)
+
+	assert.Equal(t, errServiceError, fail(payments))
+	assert.Equal(t, StateOpen, payments.State())
+	r.Remove("payments")
+	assert.Equal(t, []string{"auth"}, r.Names())
+	assert.Equal(t, StateClosed, r.GetOrCreate("payments", Config{}).State())
+}
+
+func TestRegistryConcurrentGetOrCreate(t *testing.T) {
+	r := NewRegistry(RegistryConfig{})
+
+	var wg sync.WaitGroup
+	cbs := make([]*CircuitBreaker, 16)
+	for i := range cbs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cbs[i] = r.GetOrCreate("payments", Config{})
+		}(i)
+	}
+	wg.Wait()
+
+	for _, cb := range cbs {
+		assert.Same(t, cbs[0], cb)
+	}
+}
+
+// gatedStorage blocks the loads until released
+type gatedStorage struct {
+	loading chan struct{}
+	release chan struct{}
+}
+
+func (s gatedStorage) Load(ctx context.Context, name string) (StoredState, bool, error) {
+	s.loading <- struct{}{}
+	<-s.release
+	return StoredState{}, false, nil
+}
+
+func (s gatedStorage) Store(ctx context.Context, name string, state StoredState) error {
+	return nil
+}
+
+func TestRegistryGetOrCreateDoesNotBlockOnSync(t *testing.T) {
+	r := NewRegistry(RegistryConfig{})
+	search := r.GetOrCreate("search", Config{})
+	storage := gatedStorage{loading: make(chan struct{}), release: make(chan struct{})}
+
+	created := make(chan *CircuitBreaker)
+	go func() { created <- r.GetOrCreate("payments", Config{Storage: storage}) }()
+	<-storage.loading
+
+	// the other breakers are available while payments syncs with the storage
+	cb, ok := r.Get("search")
+	assert.True(t, ok)
+	assert.Same(t, search, cb)
+	assert.NotNil(t, r.GetOrCreate("auth", Config{}))
+
+	close(storage.release)
+	payments := <-created
+	assert.Same(t, payments, r.GetOrCreate("payments", Config{}))
+}
+
+func TestRegistryBulkOperations(t *testing.T) {
+	r := NewRegistry(RegistryConfig{})
+	euPayments := r.GetOrCreate("eu-west-1/payments", Config{Labels: map[string]string{"region": "eu-west-1"}})
+	euSearch := r.GetOrCreate("eu-west-1/search", Config{Labels: map[string]string{"region": "eu-west-1"}})
+	usPayments := r.GetOrCreate("us-east-1/payments", Config{Labels: map[string]string{"region": "us-east-1"}})
+
+	n := r.TripAll(func(cb *CircuitBreaker) bool { return cb.Labels()["region"] == "eu-west-1" })
+	assert.Equal(t, 2, n)
+	assert.Equal(t, StateOpen, euPayments.State())
+	assert.Equal(t, StateOpen, euSearch.State())
+	assert.Equal(t, StateClosed, usPayments.State())
+
+	r.ResetAll()
+	assert.Equal(t, StateClosed, euPayments.State())
+	assert.Equal(t, StateClosed, euSearch.State())
+	assert.Equal(t, 3, r.TripAll(nil))
+	r.ResetAll()
+
+	n, err := r.DisableMatching("*/payments")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assert.True(t, euPayments.Disabled())
+	assert.True(t, usPayments.Disabled())
+	assert.False(t, euSearch.Disabled())
+
+	n, err = r.EnableMatching("eu-west-1/*")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assert.False(t, euPayments.Disabled())
+	assert.True(t, usPayments.Disabled())
+
+	_, err = r.DisableMatching("[")
+	assert.Equal(t, path.ErrBadPattern, err)
+	_, err = NewRegistry(RegistryConfig{}).DisableMatching("[")
+	assert.Equal(t, path.ErrBadPattern, err)
+}
+
+func TestRegistryTemplates(t *testing.T) {
+	r := NewRegistry(RegistryConfig{Templates: map[string]Config{
+		"aggressive": {MaxConsecutiveFailures: 1, Timeout: time.Minute, Labels: map[string]string{"policy": "aggressive"}},
+	}})
+	r.RegisterTemplate("lenient", Config{MaxConsecutiveFailures: 10})
+
+	cfg, ok := r.Template("lenient")
+	assert.True(t, ok)
+	assert.Equal(t, uint32(10), cfg.MaxConsecutiveFailures)
+
+	payments, err := r.GetOrCreateFrom("payments", "aggressive")
+	assert.Nil(t, err)
+	assert.Equal(t, "payments", payments.Name())
+	assert.Equal(t, errServiceError, fail(payments))
+	assert.Equal(t, StateOpen, payments.State())
+
+	search, err := r.GetOrCreateFrom("search", "aggressive", func(cfg *Config) {
+		cfg.MaxConsecutiveFailures = 2
+		cfg.Labels["team"] = "search"
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, errServiceError, fail(search))
+	assert.Equal(t, StateClosed, search.State())
+	assert.Equal(t, map[string]string{"policy": "aggressive", "team": "search"}, search.Labels())
+	// the overrides don't change the template
+	assert.Equal(t, map[string]string{"policy": "aggressive"}, payments.Labels())
+
+	// the existing breakers are returned as they are
+	same, err := r.GetOrCreateFrom("search", "unknown")
+	assert.Nil(t, err)
+	assert.Same(t, search, same)
+
+	_, err = r.GetOrCreateFrom("auth", "unknown")
+	assert.EqualError(t, err, `unknown circuit breaker template "unknown"`)
+}
+
+func TestRegistryHooks(t *testing.T) {
+	r := NewRegistry(RegistryConfig{Eviction: Eviction{MaxSize: 2}})
+	var created, removed []string
+	r.OnCreate(func(cb *CircuitBreaker) { created = append(created, cb.Name()) })
+	r.OnCreate(func(cb *CircuitBreaker) { cb.Disable() })
+	r.OnRemove(func(cb *CircuitBreaker) { removed = append(removed, cb.Name()) })
+
+	payments := r.GetOrCreate("payments", Config{})
+	r.GetOrCreate("payments", Config{})
+	assert.Equal(t, []string{"payments"}, created)
+	assert.True(t, payments.Disabled())
+
+	r.Remove("payments")
+	r.Remove("payments")
+	assert.Equal(t, []string{"payments"}, removed)
+
+	// the evicted breakers are removed too
+	r.GetOrCreate("a", Config{})
+	time.Sleep(time.Millisecond)
+	r.GetOrCreate("b", Config{})
+	r.GetOrCreate("c", Config{})
+	assert.Equal(t, []string{"payments", "a", "b", "c"}, created)
+	assert.Equal(t, []string{"payments", "a"}, removed)
+}
+
+func TestRegistryQueries(t *testing.T) {
+	r := NewRegistry(RegistryConfig{})
+	payments := r.GetOrCreate("payments", Config{Labels: map[string]string{"tier": "critical"}})
+	auth := r.GetOrCreate("auth", Config{Labels: map[string]string{"tier": "critical"}})
+	search := r.GetOrCreate("search", Config{Labels: map[string]string{"tier": "optional"}})
+	payments.Trip()
+	search.Trip()
+
+	var names []string
+	r.Range(func(name string, cb *CircuitBreaker) bool {
+		names = append(names, name)
+		return name != "payments"
+	})
+	assert.Equal(t, []string{"auth", "payments"}, names)
+
+	assert.Equal(t, []*CircuitBreaker{payments}, r.Select(InState(StateOpen), HasLabel("tier", "critical")))
+	assert.Equal(t, []*CircuitBreaker{auth, payments}, r.Select(HasLabel("tier", "critical")))
+	assert.Equal(t, []*CircuitBreaker{auth}, r.Select(InState(StateClosed, StateHalfOpen)))
+	assert.Len(t, r.Select(), 3)
+	assert.Empty(t, r.Select(HasLabel("region", "")))
+
+	assert.Equal(t, 2, r.TripAll(HasLabel("tier", "critical")))
+	assert.Equal(t, []*CircuitBreaker{auth, payments, search}, r.Select(InState(StateOpen)))
+}
@@ -0,0 +1,45 @@
+package circuit_breaker
+
+// Rejections are the numbers of the requests rejected by a CircuitBreaker, by reason:
+// ErrOpenState, ErrTooManyRequests, ErrBulkheadFull, ErrLoadShed, ErrLimitExceeded,
+// ErrRateLimited and ErrInsufficientDeadline.
+type Rejections struct {
+	Open                 uint64
+	TooManyRequests      uint64
+	BulkheadFull         uint64
+	LoadShed             uint64
+	LimitExceeded        uint64
+	RateLimited          uint64
+	InsufficientDeadline uint64
+}
+
+// Total returns the number of the rejected requests.
+func (r Rejections) Total() uint64 {
+	return r.Open + r.TooManyRequests + r.BulkheadFull + r.LoadShed + r.LimitExceeded + r.RateLimited + r.InsufficientDeadline
+}
+
+func (r *Rejections) add(err error) {
+	switch err {
+	case ErrOpenState:
+		r.Open++
+	case ErrTooManyRequests:
+		r.TooManyRequests++
+	case ErrBulkheadFull:
+		r.BulkheadFull++
+	case ErrLoadShed:
+		r.LoadShed++
+	case ErrLimitExceeded:
+		r.LimitExceeded++
+	case ErrRateLimited:
+		r.RateLimited++
+	case ErrInsufficientDeadline:
+		r.InsufficientDeadline++
+	}
+}
+
+// onRejection counts the rejected request while the lock is held
+func (cb *CircuitBreaker) onRejection(err error) {
+	cb.window.rejections.add(err)
+	cb.totals.Rejections++
+	cb.totals.RejectionsByReason.add(err)
+}
@@ -0,0 +1,54 @@
+package circuit_breaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRejections(t *testing.T) {
+	var r Rejections
+	for _, err := range []error{
+		ErrOpenState, ErrOpenState, ErrTooManyRequests, ErrBulkheadFull, ErrLoadShed,
+		ErrLimitExceeded, ErrRateLimited, ErrInsufficientDeadline, context.Canceled,
+	} {
+		r.add(err)
+	}
+
+	assert.Equal(t, Rejections{
+		Open:                 2,
+		TooManyRequests:      1,
+		BulkheadFull:         1,
+		LoadShed:             1,
+		LimitExceeded:        1,
+		RateLimited:          1,
+		InsufficientDeadline: 1,
+	}, r)
+	assert.Equal(t, uint64(8), r.Total())
+}
+
+func TestCircuitBreakerRejections(t *testing.T) {
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 1, Timeout: 10 * time.Millisecond})
+
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, ErrOpenState, succeed(cb))
+	assert.Equal(t, ErrOpenState, succeed(cb))
+	assert.Equal(t, Rejections{Open: 2}, cb.Snapshot().Rejections)
+
+	// half-open rejects the requests over the default RequestThreshold
+	time.Sleep(20 * time.Millisecond)
+	done, err := cb.Allow(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, ErrTooManyRequests, succeed(cb))
+
+	// the rejections of the window are cleared on the state change, the totals are not
+	s := cb.Snapshot()
+	assert.Equal(t, Rejections{TooManyRequests: 1}, s.Rejections)
+	assert.Equal(t, Rejections{Open: 2, TooManyRequests: 1}, s.Totals.RejectionsByReason)
+	assert.Equal(t, uint64(3), s.Totals.Rejections)
+
+	done(nil)
+	assert.Equal(t, StateClosed, cb.State())
+}
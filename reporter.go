@@ -0,0 +1,46 @@
+package circuit_breaker
+
+import "time"
+
+// TripReport describes a trip of a CircuitBreaker to an ErrorReporter.
+//
+// Counts are the Counts which led to the trip, and LastError is the last failure counted in them, if any.
+// RecentErrors are the last distinct failures of the breaker, see RecentErrors.
+type TripReport struct {
+	Name         string
+	From         State
+	Reason       string
+	Counts       Counts
+	At           time.Time
+	Labels       map[string]string
+	LastError    error
+	RecentErrors []RecentError
+}
+
+// ErrorReporter reports the trips of the breakers to an error tracking system, e.g. Sentry,
+// so they show up alongside the errors of the application.
+type ErrorReporter interface {
+	ReportTrip(r TripReport)
+}
+
+// ReportTrips reports every trip of the breaker to the reporter.
+// The reporter is called by the state change callbacks of the breaker, so it must not block.
+// stop stops reporting the trips.
+func ReportTrips(cb *CircuitBreaker, reporter ErrorReporter) (stop func()) {
+	return cb.SubscribeTransitions(func(_ string, t Transition) {
+		if t.To != StateOpen {
+			return
+		}
+
+		reporter.ReportTrip(TripReport{
+			Name:         cb.name,
+			From:         t.From,
+			Reason:       t.Reason,
+			Counts:       t.Counts,
+			At:           t.At,
+			Labels:       cb.Labels(),
+			LastError:    t.LastError,
+			RecentErrors: cb.RecentErrors(),
+		})
+	})
+}
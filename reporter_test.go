@@ -0,0 +1,50 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type reporterFunc func(r TripReport)
+
+func (f reporterFunc) ReportTrip(r TripReport) { f(r) }
+
+func TestReportTrips(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "payments",
+		MaxConsecutiveFailures: 2,
+		RecentErrorsSize:       4,
+		Labels:                 map[string]string{"team": "billing"},
+	})
+
+	var reports []TripReport
+	stop := ReportTrips(cb, reporterFunc(func(r TripReport) { reports = append(reports, r) }))
+
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Empty(t, reports)
+	assert.Equal(t, errServiceError, fail(cb))
+
+	if assert.Len(t, reports, 1) {
+		report := reports[0]
+		assert.Equal(t, "payments", report.Name)
+		assert.Equal(t, StateClosed, report.From)
+		assert.Equal(t, ReasonTripped, report.Reason)
+		assert.Equal(t, uint32(2), report.Counts.ConsecutiveFailures)
+		assert.Equal(t, map[string]string{"team": "billing"}, report.Labels)
+		assert.Equal(t, errServiceError, report.LastError)
+		if assert.Len(t, report.RecentErrors, 1) {
+			assert.Equal(t, uint64(2), report.RecentErrors[0].Count)
+		}
+		assert.WithinDuration(t, time.Now(), report.At, time.Second)
+	}
+
+	// closing the circuit is not reported
+	cb.Reset()
+	assert.Len(t, reports, 1)
+
+	stop()
+	cb.Trip()
+	assert.Len(t, reports, 1)
+}
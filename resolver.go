@@ -0,0 +1,156 @@
+package circuit_breaker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+// DNSResolver is the lookup part of *net.Resolver.
+type DNSResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+var _ DNSResolver = (*net.Resolver)(nil)
+
+// Resolver protects DNS lookups with an independent CircuitBreaker per host, or per zone with ZoneKey,
+// so a resolver outage fails lookups fast instead of stalling every outbound call.
+// A host that doesn't exist is an answer, not a failure.
+//
+// If the stale TTL is set, the last good answer of a lookup is served
+// while the breaker rejects it or it fails, for at most the TTL after it was received.
+type Resolver struct {
+	// Base is the underlying resolver. If Base is nil, net.DefaultResolver is used.
+	Base DNSResolver
+	// Breakers holds a breaker per key, created lazily.
+	Breakers *KeyedBreaker
+	// Key returns the breaker key of the host. If Key is nil, the host is the key.
+	Key func(host string) string
+
+	stale *StaleCache
+}
+
+// NewResolver creates a Resolver with a breaker per host, serving stale answers for staleTTL.
+// Zero staleTTL disables the stale answers.
+func NewResolver(base DNSResolver, cfg KeyedConfig, staleTTL time.Duration) *Resolver {
+	r := Resolver{
+		Base:     base,
+		Breakers: NewKeyedBreaker(cfg),
+	}
+	if staleTTL > 0 {
+		r.stale = NewStaleCache(nil, staleTTL)
+	}
+
+	return &r
+}
+
+// ZoneKey keys the lookups by the zone of the host: its last two labels, e.g. "example.com" for "api.example.com".
+// It doesn't know the public suffixes, so the hosts under a suffix of two labels share a key,
+// e.g. "co.uk" for both "bbc.co.uk" and "gov.co.uk": use it only for the hosts of known zones.
+func ZoneKey(host string) string {
+	host = strings.TrimSuffix(host, ".")
+	if net.ParseIP(host) != nil {
+		return host
+	}
+
+	labels := strings.Split(host, ".")
+	if len(labels) <= 2 {
+		return host
+	}
+	return strings.Join(labels[len(labels)-2:], ".")
+}
+
+func hostKey(host string) string {
+	return strings.TrimSuffix(host, ".")
+}
+
+// LookupHost looks up the addresses of the host, see net.Resolver.LookupHost.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addrs, err := r.lookup(ctx, "host", host, func(ctx context.Context, base DNSResolver) (interface{}, error) {
+		return base.LookupHost(ctx, host)
+	})
+	result, _ := addrs.([]string)
+	return result, err
+}
+
+// LookupIPAddr looks up the IP addresses of the host, see net.Resolver.LookupIPAddr.
+func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, err := r.lookup(ctx, "ipaddr", host, func(ctx context.Context, base DNSResolver) (interface{}, error) {
+		return base.LookupIPAddr(ctx, host)
+	})
+	result, _ := addrs.([]net.IPAddr)
+	return result, err
+}
+
+// LookupIP looks up the IP addresses of the host for the network, see net.Resolver.LookupIP.
+func (r *Resolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	ips, err := r.lookup(ctx, "ip/"+network, host, func(ctx context.Context, base DNSResolver) (interface{}, error) {
+		return base.LookupIP(ctx, network, host)
+	})
+	result, _ := ips.([]net.IP)
+	return result, err
+}
+
+// LookupCNAME looks up the canonical name of the host, see net.Resolver.LookupCNAME.
+func (r *Resolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	cname, err := r.lookup(ctx, "cname", host, func(ctx context.Context, base DNSResolver) (interface{}, error) {
+		return base.LookupCNAME(ctx, host)
+	})
+	result, _ := cname.(string)
+	return result, err
+}
+
+// LookupTXT looks up the TXT records of the name, see net.Resolver.LookupTXT.
+func (r *Resolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	txt, err := r.lookup(ctx, "txt", name, func(ctx context.Context, base DNSResolver) (interface{}, error) {
+		return base.LookupTXT(ctx, name)
+	})
+	result, _ := txt.([]string)
+	return result, err
+}
+
+func (r *Resolver) lookup(ctx context.Context, kind, host string, fn func(ctx context.Context, base DNSResolver) (interface{}, error)) (interface{}, error) {
+	var base DNSResolver = net.DefaultResolver
+	if r.Base != nil {
+		base = r.Base
+	}
+	key := r.Key
+	if key == nil {
+		key = hostKey
+	}
+
+	var notFound error
+	answer, err := r.Breakers.Get(key(host)).ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+		answer, err := fn(ctx, base)
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			notFound = err
+			return nil, nil
+		}
+		return answer, err
+	})
+
+	now := time.Now()
+	switch {
+	case notFound != nil:
+		return nil, notFound
+	case err == nil:
+		if r.stale != nil {
+			r.stale.store(kind+":"+host, answer, now)
+		}
+		return answer, nil
+	}
+
+	if r.stale != nil {
+		if answer, ok := r.stale.load(kind+":"+host, now); ok {
+			return answer, nil
+		}
+	}
+	return nil, err
+}
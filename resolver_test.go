@@ -0,0 +1,95 @@
+package circuit_breaker
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeResolver answers the lookups of the hosts in addrs, and fails the others with err
+type fakeResolver struct {
+	net.Resolver
+	addrs map[string][]string
+	err   error
+	calls int
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.calls++
+	if addrs, ok := r.addrs[host]; ok {
+		return addrs, nil
+	}
+	return nil, r.err
+}
+
+func TestResolver(t *testing.T) {
+	base := &fakeResolver{
+		addrs: map[string][]string{"api.example.com": {"10.0.0.1"}},
+		err:   &net.DNSError{Err: "no such host", Name: "missing.example.com", IsNotFound: true},
+	}
+	resolver := NewResolver(base, KeyedConfig{Config: Config{MaxConsecutiveFailures: 2}}, time.Minute)
+	resolver.Key = ZoneKey
+	ctx := context.Background()
+
+	addrs, err := resolver.LookupHost(ctx, "api.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, addrs)
+
+	// a missing host is not a failure
+	for i := 0; i < 3; i++ {
+		_, err = resolver.LookupHost(ctx, "missing.example.com")
+		assert.Equal(t, base.err, err)
+	}
+	cb, _ := resolver.Breakers.Lookup("example.com")
+	assert.Equal(t, StateClosed, cb.State())
+
+	// the resolver is down
+	base.addrs = nil
+	base.err = &net.DNSError{Err: "i/o timeout", Name: "api.example.com", IsTimeout: true}
+	for i := 0; i < 2; i++ {
+		addrs, err = resolver.LookupHost(ctx, "api.example.com")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"10.0.0.1"}, addrs)
+	}
+	assert.Equal(t, StateOpen, cb.State())
+
+	// the last good answer is served while the breaker is open
+	calls := base.calls
+	addrs, err = resolver.LookupHost(ctx, "api.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, addrs)
+	assert.Equal(t, calls, base.calls)
+
+	_, err = resolver.LookupHost(ctx, "www.example.com")
+	assert.Equal(t, ErrOpenState, err)
+}
+
+func TestResolverWithoutStale(t *testing.T) {
+	base := &fakeResolver{err: &net.DNSError{Err: "server misbehaving", Name: "api.example.com"}}
+	resolver := NewResolver(base, KeyedConfig{Config: Config{MaxConsecutiveFailures: 1}}, 0)
+	ctx := context.Background()
+
+	_, err := resolver.LookupHost(ctx, "api.example.com")
+	assert.Equal(t, base.err, err)
+	_, err = resolver.LookupHost(ctx, "api.example.com.")
+	assert.Equal(t, ErrOpenState, err)
+
+	// the breakers are per host by default
+	_, err = resolver.LookupHost(ctx, "www.example.com")
+	assert.Equal(t, base.err, err)
+	_, ok := resolver.Breakers.Lookup("example.com")
+	assert.False(t, ok)
+}
+
+func TestZoneKey(t *testing.T) {
+	assert.Equal(t, "example.com", ZoneKey("api.example.com"))
+	assert.Equal(t, "example.com", ZoneKey("v1.api.example.com."))
+	assert.Equal(t, "example.com", ZoneKey("example.com"))
+	assert.Equal(t, "localhost", ZoneKey("localhost"))
+	assert.Equal(t, "10.0.0.1", ZoneKey("10.0.0.1"))
+	// the public suffixes are not known
+	assert.Equal(t, "co.uk", ZoneKey("bbc.co.uk"))
+}
@@ -0,0 +1,84 @@
+package circuit_breaker
+
+import (
+	"math/rand"
+	"time"
+)
+
+const defaultMaxAttempts = 3
+
+// RetryPolicy configures ExecuteWithRetry.
+//
+// MaxAttempts is the maximum number of attempts, including the first one.
+// If MaxAttempts is zero, 3 attempts are made.
+//
+// Backoff returns the delay before the retry with the given number, starting from 1.
+// If Backoff is nil, the retries are made immediately.
+//
+// Jitter is the fraction of the delay, from 0 to 1, replaced with a random duration,
+// so that the clients failing at the same time don't retry at the same time.
+//
+// RetryOn reports whether the request failed with a retryable error.
+// If RetryOn is nil, all errors are retried.
+type RetryPolicy struct {
+	MaxAttempts uint32
+	Backoff     func(retry uint32) time.Duration
+	Jitter      float64
+	RetryOn     func(err error) bool
+}
+
+// ExponentialBackoff doubles the delay on each retry, starting from initial and capped at max.
+func ExponentialBackoff(initial, max time.Duration) func(retry uint32) time.Duration {
+	return func(retry uint32) time.Duration {
+		delay := initial
+		for i := uint32(1); i < retry && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+		return delay
+	}
+}
+
+// ExecuteWithRetry runs the request through the CircuitBreaker, retrying it according to the policy.
+// Each attempt is admitted and counted by the CircuitBreaker individually.
+// A rejected attempt is not retried: ExecuteWithRetry returns the rejection error at once,
+// so nothing is retried while the CircuitBreaker is open.
+func (cb *CircuitBreaker) ExecuteWithRetry(req func() (interface{}, error), policy RetryPolicy) (interface{}, error) {
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	for attempt := uint32(1); ; attempt++ {
+		handled := false
+		result, err := cb.Execute(func() (interface{}, error) {
+			handled = true
+			return req()
+		})
+		if err == nil || !handled || attempt == maxAttempts {
+			return result, err
+		}
+		if policy.RetryOn != nil && !policy.RetryOn(err) {
+			return result, err
+		}
+
+		time.Sleep(policy.delay(attempt))
+	}
+}
+
+// delay returns the jittered delay before the retry
+func (policy RetryPolicy) delay(retry uint32) time.Duration {
+	if policy.Backoff == nil {
+		return 0
+	}
+
+	delay := policy.Backoff(retry)
+	if policy.Jitter > 0 {
+		jitter := time.Duration(policy.Jitter * float64(delay))
+		delay = delay - jitter + time.Duration(rand.Int63n(int64(jitter)+1))
+	}
+
+	return delay
+}
@@ -0,0 +1,120 @@
+package circuit_breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerExecuteWithRetry(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "retrying circuit breaker",
+		MaxConsecutiveFailures: 5,
+	})
+
+	attempts := 0
+	result, err := cb.ExecuteWithRetry(func() (interface{}, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errServiceError
+		}
+		return "ok", nil
+	}, RetryPolicy{MaxAttempts: 3})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "ok", result)
+	assert.Equal(t, 3, attempts)
+	// each attempt is counted
+	assert.Equal(t, Counts{Requests: 3, TotalSuccesses: 1, TotalFailures: 2, ConsecutiveSuccesses: 1}, cb.Counts())
+}
+
+func TestCircuitBreakerExecuteWithRetryMaxAttempts(t *testing.T) {
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 10})
+
+	attempts := 0
+	_, err := cb.ExecuteWithRetry(func() (interface{}, error) {
+		attempts++
+		return nil, errServiceError
+	}, RetryPolicy{})
+
+	assert.Equal(t, errServiceError, err)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestCircuitBreakerExecuteWithRetryRetryOn(t *testing.T) {
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 10})
+	errNotFound := errors.New("not found")
+
+	attempts := 0
+	_, err := cb.ExecuteWithRetry(func() (interface{}, error) {
+		attempts++
+		return nil, errNotFound
+	}, RetryPolicy{
+		MaxAttempts: 5,
+		RetryOn:     func(err error) bool { return !errors.Is(err, errNotFound) },
+	})
+
+	assert.Equal(t, errNotFound, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestCircuitBreakerExecuteWithRetryOpen(t *testing.T) {
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 2})
+
+	attempts := 0
+	_, err := cb.ExecuteWithRetry(func() (interface{}, error) {
+		attempts++
+		return nil, errServiceError
+	}, RetryPolicy{MaxAttempts: 5})
+
+	// the breaker opens after two attempts and the rest are not retried
+	assert.Equal(t, ErrOpenState, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestCircuitBreakerExecuteWithRetryBackoff(t *testing.T) {
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 10})
+
+	var retries []uint32
+	start := time.Now()
+	_, _ = cb.ExecuteWithRetry(func() (interface{}, error) {
+		return nil, errServiceError
+	}, RetryPolicy{
+		MaxAttempts: 3,
+		Backoff: func(retry uint32) time.Duration {
+			retries = append(retries, retry)
+			return 10 * time.Millisecond
+		},
+		Jitter: 0.5,
+	})
+
+	assert.Equal(t, []uint32{1, 2}, retries)
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(100*time.Millisecond, time.Second)
+
+	assert.Equal(t, 100*time.Millisecond, backoff(1))
+	assert.Equal(t, 200*time.Millisecond, backoff(2))
+	assert.Equal(t, 800*time.Millisecond, backoff(4))
+	assert.Equal(t, time.Second, backoff(5))
+	assert.Equal(t, time.Second, backoff(100))
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{
+		Backoff: func(retry uint32) time.Duration { return time.Second },
+		Jitter:  0.2,
+	}
+
+	for i := 0; i < 100; i++ {
+		delay := policy.delay(1)
+		assert.GreaterOrEqual(t, delay, 800*time.Millisecond)
+		assert.LessOrEqual(t, delay, time.Second)
+	}
+	assert.Equal(t, time.Duration(0), RetryPolicy{}.delay(1))
+}
@@ -0,0 +1,111 @@
+package circuit_breaker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ScheduledJobConfig configures ScheduledJob.
+//
+// CatchUp compresses the runs skipped while the breaker is open into one catch-up run,
+// made as soon as the open timeout expires instead of at the next scheduled time.
+//
+// OnSkip is called with the rejection error for each skipped run.
+type ScheduledJobConfig struct {
+	CatchUp bool
+	OnSkip  func(err error)
+}
+
+// ScheduledJob wraps a periodic job, e.g. a sync with a third-party API run by a cron scheduler,
+// skipping its runs while the breaker rejects them.
+// ScheduledJob is safe for concurrent use.
+type ScheduledJob struct {
+	cb  *CircuitBreaker
+	job func(ctx context.Context) error
+	cfg ScheduledJobConfig
+
+	mu      sync.Mutex
+	skipped uint64
+	missed  uint64
+	timer   *time.Timer
+	stopped bool
+}
+
+func NewScheduledJob(cb *CircuitBreaker, job func(ctx context.Context) error, cfg ScheduledJobConfig) *ScheduledJob {
+	return &ScheduledJob{cb: cb, job: job, cfg: cfg}
+}
+
+// Run runs the job through the breaker. It is meant to be called by the scheduler on each tick.
+// A run rejected by the breaker is skipped and Run returns the rejection error.
+// The catch-up run, if any, is made with the context of the last skipped run.
+func (j *ScheduledJob) Run(ctx context.Context) error {
+	handled := false
+	_, err := j.cb.ExecuteContext(ctx, func(ctx context.Context) (interface{}, error) {
+		handled = true
+		j.mu.Lock()
+		j.missed = 0
+		j.mu.Unlock()
+		return nil, j.job(ctx)
+	})
+	if !handled && ctx.Err() == nil {
+		j.skip(ctx, err)
+	}
+
+	return err
+}
+
+// Skipped returns the total number of skipped runs.
+func (j *ScheduledJob) Skipped() uint64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.skipped
+}
+
+// Missed returns the number of runs skipped since the job last ran.
+func (j *ScheduledJob) Missed() uint64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.missed
+}
+
+// Stop cancels the pending catch-up run. It doesn't stop the job if it is running.
+func (j *ScheduledJob) Stop() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.stopped = true
+	if j.timer != nil {
+		j.timer.Stop()
+		j.timer = nil
+	}
+}
+
+func (j *ScheduledJob) skip(ctx context.Context, err error) {
+	delay := j.cb.remainingOpenTime(time.Now())
+
+	j.mu.Lock()
+	j.skipped++
+	j.missed++
+	if j.cfg.CatchUp && delay > 0 && j.timer == nil && !j.stopped {
+		j.timer = time.AfterFunc(delay, func() { j.catchUp(ctx) })
+	}
+	j.mu.Unlock()
+
+	if j.cfg.OnSkip != nil {
+		j.cfg.OnSkip(err)
+	}
+}
+
+func (j *ScheduledJob) catchUp(ctx context.Context) {
+	j.mu.Lock()
+	j.timer = nil
+	missed := j.missed > 0 && !j.stopped
+	j.mu.Unlock()
+
+	if missed {
+		_ = j.Run(ctx)
+	}
+}
@@ -0,0 +1,68 @@
+package circuit_breaker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduledJob(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "scheduled circuit breaker",
+		MaxConsecutiveFailures: 1,
+		RequestThreshold:       1,
+		Timeout:                30 * time.Millisecond,
+	})
+	var runs, skips int32
+	j := NewScheduledJob(cb, func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	}, ScheduledJobConfig{
+		CatchUp: true,
+		OnSkip: func(err error) {
+			assert.Equal(t, ErrOpenState, err)
+			atomic.AddInt32(&skips, 1)
+		},
+	})
+	defer j.Stop()
+	ctx := context.Background()
+
+	assert.Nil(t, j.Run(ctx))
+	assert.Equal(t, errServiceError, fail(cb))
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, ErrOpenState, j.Run(ctx))
+	}
+	assert.Equal(t, uint64(3), j.Skipped())
+	assert.Equal(t, uint64(3), j.Missed())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&skips))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+
+	// the missed runs are compressed into one catch-up run once the open timeout expires
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&runs) == 2 }, time.Second, 5*time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+	assert.Equal(t, uint64(0), j.Missed())
+	assert.Equal(t, uint64(3), j.Skipped())
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestScheduledJobStop(t *testing.T) {
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 1, Timeout: 10 * time.Millisecond})
+	var runs int32
+	j := NewScheduledJob(cb, func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	}, ScheduledJobConfig{CatchUp: true})
+
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, ErrOpenState, j.Run(context.Background()))
+	j.Stop()
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&runs))
+	assert.Equal(t, uint64(1), j.Missed())
+}
@@ -0,0 +1,61 @@
+package circuit_breaker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// SlackConfig configures SlackNotifier.
+//
+// WebhookURL is the URL of the Slack incoming webhook posting to the channel.
+// Client sends the requests. If Client is nil, http.DefaultClient is used.
+type SlackConfig struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+// SlackNotifier is the Notifier posting to Slack when a breaker opens, and again when it closes.
+// The flaps of a breaker between the open and half-open states are not posted.
+type SlackNotifier struct {
+	url    string
+	poster poster
+	alerts alerts
+}
+
+// NewSlackNotifier creates the SlackNotifier.
+func NewSlackNotifier(cfg SlackConfig) *SlackNotifier {
+	return &SlackNotifier{
+		url:    cfg.WebhookURL,
+		poster: newPoster(cfg.Client, 0, nil, 0, nil),
+	}
+}
+
+// Notify posts the message about the notification, if it opens or resolves an alert.
+func (s *SlackNotifier) Notify(ctx context.Context, n Notification) error {
+	action := s.alerts.action(n)
+	if action == alertNone {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]string{"text": slackMessage(n, action)})
+	if err != nil {
+		return err
+	}
+	if err := s.poster.deliver(ctx, s.url, body); err != nil {
+		return err
+	}
+	s.alerts.done(n, action)
+
+	return nil
+}
+
+func slackMessage(n Notification, action alertAction) string {
+	if action == alertTrigger {
+		return fmt.Sprintf(":red_circle: Circuit breaker *%s* opened: %s (%d of %d requests failed)",
+			n.Name, n.Reason, n.Counts.TotalFailures, n.Counts.Requests)
+	}
+
+	return fmt.Sprintf(":large_green_circle: Circuit breaker *%s* closed: %s", n.Name, n.Reason)
+}
@@ -0,0 +1,35 @@
+package circuit_breaker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlackNotifier(t *testing.T) {
+	var messages []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		messages = append(messages, body["text"])
+	}))
+	defer srv.Close()
+
+	s := NewSlackNotifier(SlackConfig{WebhookURL: srv.URL})
+	ctx := context.Background()
+	counts := Counts{Requests: 10, TotalFailures: 6}
+
+	assert.Nil(t, s.Notify(ctx, Notification{Name: "payments", From: StateClosed, To: StateOpen, Reason: ReasonTripped, Counts: counts}))
+	assert.Nil(t, s.Notify(ctx, Notification{Name: "payments", From: StateOpen, To: StateHalfOpen, Reason: ReasonTimeout}))
+	assert.Nil(t, s.Notify(ctx, Notification{Name: "payments", From: StateHalfOpen, To: StateOpen, Reason: ReasonProbeFailed}))
+	assert.Nil(t, s.Notify(ctx, Notification{Name: "payments", From: StateHalfOpen, To: StateClosed, Reason: ReasonProbeSucceeded}))
+
+	assert.Equal(t, []string{
+		":red_circle: Circuit breaker *payments* opened: failure threshold reached (6 of 10 requests failed)",
+		":large_green_circle: Circuit breaker *payments* closed: half-open probe succeeded",
+	}, messages)
+}
@@ -0,0 +1,17 @@
+//go:build go1.21
+
+package circuit_breaker
+
+import "log/slog"
+
+var _ Logger = (*slog.Logger)(nil)
+
+// SlogLogger returns a Logger writing to the slog logger with the "component" attribute set to "circuit_breaker".
+// If logger is nil, slog.Default() is used.
+func SlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return logger.With("component", "circuit_breaker")
+}
@@ -0,0 +1,23 @@
+//go:build go1.21
+
+package circuit_breaker
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	cb := NewCircuitBreaker(Config{Name: "slog", MaxConsecutiveFailures: 1, Logger: SlogLogger(logger)})
+
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, ErrOpenState, succeed(cb))
+
+	assert.Contains(t, buf.String(), `level=WARN msg="circuit breaker state changed" component=circuit_breaker name=slog from=closed to=open`)
+	assert.Contains(t, buf.String(), `level=DEBUG msg="circuit breaker rejected request" component=circuit_breaker name=slog state=open`)
+}
@@ -0,0 +1,211 @@
+package circuit_breaker
+
+import (
+	"context"
+	"time"
+)
+
+// Totals are the cumulative statistics of a CircuitBreaker since its creation.
+// Unlike Counts, they are not cleared on state changes, so they suit monotonic metrics.
+//
+// Requests is the number of admitted requests, and Successes and Failures their outcomes,
+// including the outcomes discarded by Counts because the state changed while the request was in flight.
+// Rejections is the number of requests rejected by the CircuitBreaker or its bulkhead,
+// and RejectionsByReason breaks it down by reason.
+// Transitions is the number of transitions into each state.
+// TimeInState is the time spent in each state, e.g. to report how long a dependency was unavailable.
+// Unenforced are the requests which would have been rejected while the enforcement was off, see Config.Enabled.
+type Totals struct {
+	Requests           uint64
+	Successes          uint64
+	Failures           uint64
+	Rejections         uint64
+	RejectionsByReason Rejections
+	Transitions        map[State]uint64
+	TimeInState        map[State]time.Duration
+	Unenforced         Rejections
+}
+
+func (t *Totals) onTransition(from, to State, spent time.Duration) {
+	if t.Transitions == nil {
+		t.Transitions = make(map[State]uint64)
+		t.TimeInState = make(map[State]time.Duration)
+	}
+	t.Transitions[to]++
+	t.TimeInState[from] += spent
+}
+
+func (t Totals) clone() Totals {
+	transitions := make(map[State]uint64, len(t.Transitions))
+	for state, n := range t.Transitions {
+		transitions[state] = n
+	}
+	t.Transitions = transitions
+
+	timeInState := make(map[State]time.Duration, len(t.TimeInState)+1)
+	for state, d := range t.TimeInState {
+		timeInState[state] = d
+	}
+	t.TimeInState = timeInState
+
+	return t
+}
+
+// Snapshot is a consistent point-in-time view of a CircuitBreaker for metrics exporters and dashboards.
+//
+// LastTransition is the time of the last state change, or the creation time if the state has never changed,
+// and StateDuration the time spent in State since then. Totals.TimeInState includes StateDuration.
+//
+// Stats are derived from the current window, the period covered by Counts.
+//
+// Rejections are the requests rejected in the current window by reason.
+// Counts only cover the admitted requests.
+//
+// OpensAt is the time at which the open CircuitBreaker becomes half-open, see CircuitBreaker.OpensAt.
+//
+// Latency is the state of the LatencyHistogram, if it is configured.
+//
+// Disabled reports whether the CircuitBreaker was disabled by Disable,
+// and Critical whether it protects a critical dependency, see Config.
+type Snapshot struct {
+	Name           string
+	Labels         map[string]string
+	State          State
+	Counts         Counts
+	Totals         Totals
+	LastTransition time.Time
+	StateDuration  time.Duration
+	OpensAt        time.Time
+	Stats          Stats
+	Rejections     Rejections
+	Latency        HistogramSnapshot
+	Disabled       bool
+	Critical       bool
+}
+
+// Stats are the statistics of the current window of a CircuitBreaker, which starts on each state change or Reset.
+//
+// Window is the time elapsed since the window started.
+//
+// FailureRate, SlowCallRate and RejectionRate are the fractions, from 0 to 1,
+// of the completed requests which failed, of the completed requests slower than SlowCallThreshold,
+// and of all the requests which were rejected.
+//
+// CallsPerSecond is the rate of the admitted requests over the window.
+//
+// The latencies are computed over the most recent requests, regardless of the window.
+type Stats struct {
+	Window         time.Duration
+	FailureRate    float64
+	SlowCallRate   float64
+	RejectionRate  float64
+	CallsPerSecond float64
+	MeanLatency    time.Duration
+	P50Latency     time.Duration
+	P95Latency     time.Duration
+	P99Latency     time.Duration
+}
+
+// windowStats are the statistics of the current window not tracked by Counts
+type windowStats struct {
+	start      time.Time
+	rejections Rejections
+	slowCalls  uint32
+}
+
+// Snapshot returns the current Snapshot of the CircuitBreaker.
+func (cb *CircuitBreaker) Snapshot() Snapshot {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	now := time.Now()
+	cb.refreshState(now)
+
+	totals := cb.totals.clone()
+	spent := now.Sub(cb.changedAt)
+	if spent < 0 {
+		spent = 0
+	}
+	totals.TimeInState[cb.state] += spent
+
+	var opensAt time.Time
+	if cb.state == StateOpen {
+		opensAt = cb.expiredAt
+	}
+	var latency HistogramSnapshot
+	if cb.latencyHistogram != nil {
+		latency = cb.latencyHistogram.Snapshot()
+	}
+
+	return Snapshot{
+		Name:           cb.name,
+		Labels:         copyLabels(cb.labels),
+		State:          cb.state,
+		Counts:         cb.counts,
+		Totals:         totals,
+		LastTransition: cb.changedAt,
+		StateDuration:  spent,
+		OpensAt:        opensAt,
+		Stats:          cb.stats(now),
+		Rejections:     cb.window.rejections,
+		Latency:        latency,
+		Disabled:       cb.disabled,
+		Critical:       cb.critical,
+	}
+}
+
+// stats computes the Stats of the current window while the lock is held
+func (cb *CircuitBreaker) stats(now time.Time) Stats {
+	s := Stats{Window: now.Sub(cb.window.start)}
+
+	if completed := cb.counts.TotalSuccesses + cb.counts.TotalFailures; completed > 0 {
+		s.FailureRate = float64(cb.counts.TotalFailures) / float64(completed)
+		s.SlowCallRate = float64(cb.window.slowCalls) / float64(completed)
+	}
+	rejections := cb.window.rejections.Total()
+	if all := uint64(cb.counts.Requests) + rejections; all > 0 {
+		s.RejectionRate = float64(rejections) / float64(all)
+	}
+	if s.Window > 0 {
+		s.CallsPerSecond = float64(cb.counts.Requests) / s.Window.Seconds()
+	}
+
+	s.MeanLatency = cb.latencies.mean()
+	percentiles := cb.latencies.percentiles(0.5, 0.95, 0.99)
+	s.P50Latency, s.P95Latency, s.P99Latency = percentiles[0], percentiles[1], percentiles[2]
+
+	return s
+}
+
+// Labels returns a copy of the labels of the CircuitBreaker.
+func (cb *CircuitBreaker) Labels() map[string]string {
+	return copyLabels(cb.labels)
+}
+
+// onBulkheadRejection counts the request rejected by the bulkhead
+func (cb *CircuitBreaker) onBulkheadRejection(ctx context.Context, err error) {
+	if err != ErrBulkheadFull {
+		return
+	}
+
+	cb.mu.Lock()
+	cb.onRejection(err)
+	state := cb.state
+	listeners := cb.listeners
+	cb.unlock()
+
+	emit(listeners, CallRejected{Name: cb.name, State: state, Err: err, CorrelationID: cb.correlationIDOf(ctx), At: time.Now()})
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	if labels == nil {
+		return nil
+	}
+
+	c := make(map[string]string, len(labels))
+	for k, v := range labels {
+		c[k] = v
+	}
+
+	return c
+}
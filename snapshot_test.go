@@ -0,0 +1,130 @@
+package circuit_breaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerSnapshot(t *testing.T) {
+	labels := map[string]string{"tier": "critical"}
+	cb := NewCircuitBreaker(Config{
+		Name:                   "snapshot circuit breaker",
+		MaxConsecutiveFailures: 2,
+		Labels:                 labels,
+	})
+	labels["tier"] = "batch"
+
+	s := cb.Snapshot()
+	assert.Equal(t, "snapshot circuit breaker", s.Name)
+	assert.Equal(t, map[string]string{"tier": "critical"}, s.Labels)
+	assert.Equal(t, StateClosed, s.State)
+	assert.False(t, s.LastTransition.IsZero())
+
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, ErrOpenState, succeed(cb))
+	assert.Equal(t, ErrOpenState, succeed(cb))
+
+	s = cb.Snapshot()
+	assert.Equal(t, StateOpen, s.State)
+	assert.Greater(t, s.Totals.TimeInState[StateClosed], time.Duration(0))
+	assert.Equal(t, s.StateDuration, s.Totals.TimeInState[StateOpen])
+	s.Totals.TimeInState = nil
+	// the counts are cleared on the state change, the totals are not
+	assert.Equal(t, Counts{}, s.Counts)
+	assert.Equal(t, Totals{
+		Requests:           3,
+		Successes:          1,
+		Failures:           2,
+		Rejections:         2,
+		RejectionsByReason: Rejections{Open: 2},
+		Transitions:        map[State]uint64{StateOpen: 1},
+	}, s.Totals)
+	assert.Equal(t, Rejections{Open: 2}, s.Rejections)
+	assert.WithinDuration(t, time.Now(), s.LastTransition, time.Second)
+
+	// the snapshot is a copy
+	s.Totals.Transitions[StateOpen] = 10
+	s.Labels["tier"] = "batch"
+	assert.Equal(t, uint64(1), cb.Snapshot().Totals.Transitions[StateOpen])
+	assert.Equal(t, map[string]string{"tier": "critical"}, cb.Labels())
+}
+
+func TestCircuitBreakerSnapshotBulkhead(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Bulkhead: Bulkhead{MaxConcurrent: 1}})
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		_, _ = cb.Execute(func() (interface{}, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+	}()
+	<-started
+
+	_, err := cb.ExecuteContext(context.Background(), func(ctx context.Context) (interface{}, error) { return nil, nil })
+	assert.Equal(t, ErrBulkheadFull, err)
+	close(release)
+
+	assert.Equal(t, uint64(1), cb.Snapshot().Totals.Rejections)
+}
+
+func TestCircuitBreakerSnapshotStats(t *testing.T) {
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 10, SlowCallThreshold: 5 * time.Millisecond})
+	s := cb.Snapshot().Stats
+	assert.Equal(t, Stats{Window: s.Window}, s)
+
+	_, _ = cb.Execute(func() (interface{}, error) {
+		time.Sleep(10 * time.Millisecond)
+		return nil, nil
+	})
+	assert.Nil(t, succeed(cb))
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, errServiceError, fail(cb))
+
+	s = cb.Snapshot().Stats
+	assert.Equal(t, 0.25, s.FailureRate)
+	assert.Equal(t, 0.25, s.SlowCallRate)
+	assert.Equal(t, float64(0), s.RejectionRate)
+	assert.Greater(t, s.CallsPerSecond, float64(0))
+	assert.GreaterOrEqual(t, s.P99Latency, 10*time.Millisecond)
+	assert.Less(t, s.P50Latency, 5*time.Millisecond)
+	assert.Greater(t, s.MeanLatency, 2*time.Millisecond)
+	assert.Greater(t, s.Window, 10*time.Millisecond)
+
+	// the rates restart with the window on the state change
+	cb = NewCircuitBreaker(Config{MaxConsecutiveFailures: 1})
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, ErrOpenState, succeed(cb))
+	assert.Equal(t, ErrOpenState, succeed(cb))
+
+	s = cb.Snapshot().Stats
+	assert.Equal(t, float64(0), s.FailureRate)
+	assert.Equal(t, float64(1), s.RejectionRate)
+	assert.Equal(t, float64(0), s.CallsPerSecond)
+}
+
+func TestCircuitBreakerSnapshotTimeInState(t *testing.T) {
+	cb := NewCircuitBreaker(Config{})
+	time.Sleep(10 * time.Millisecond)
+	cb.Trip()
+	time.Sleep(20 * time.Millisecond)
+
+	s := cb.Snapshot()
+	assert.GreaterOrEqual(t, s.Totals.TimeInState[StateClosed], 10*time.Millisecond)
+	assert.GreaterOrEqual(t, s.StateDuration, 20*time.Millisecond)
+	assert.Equal(t, s.StateDuration, s.Totals.TimeInState[StateOpen])
+	assert.Equal(t, time.Duration(0), s.Totals.TimeInState[StateHalfOpen])
+
+	// the time in the open state accumulates after it is left
+	cb.Reset()
+	s = cb.Snapshot()
+	assert.GreaterOrEqual(t, s.Totals.TimeInState[StateOpen], 20*time.Millisecond)
+	assert.Less(t, s.StateDuration, s.Totals.TimeInState[StateOpen])
+}
@@ -0,0 +1,94 @@
+package circuit_breaker
+
+import (
+	"sync"
+	"time"
+)
+
+// StaleCache serves the last good result of a request when the CircuitBreaker rejects it or it fails.
+//
+// Successful results are cached by the key supplied by the caller for the TTL.
+// A cached result is never returned while the request succeeds: it is only a fallback.
+// StaleCache is safe for concurrent use.
+type StaleCache struct {
+	cb  *CircuitBreaker
+	ttl time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]staleEntry
+	lastPrune time.Time
+}
+
+type staleEntry struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+// NewStaleCache creates the cache of the results of the requests made through the CircuitBreaker.
+func NewStaleCache(cb *CircuitBreaker, ttl time.Duration) *StaleCache {
+	return &StaleCache{
+		cb:        cb,
+		ttl:       ttl,
+		entries:   make(map[string]staleEntry),
+		lastPrune: time.Now(),
+	}
+}
+
+// Execute runs the request through the CircuitBreaker and caches its result on success.
+// If the request is rejected or fails, the cached result of the key is returned
+// with stale set to true and a nil error. If there is no cached result, the error is returned.
+func (c *StaleCache) Execute(key string, req func() (interface{}, error)) (value interface{}, stale bool, err error) {
+	value, err = c.cb.Execute(req)
+	now := time.Now()
+	if err == nil {
+		c.store(key, value, now)
+		return value, false, nil
+	}
+
+	if cached, ok := c.load(key, now); ok {
+		return cached, true, nil
+	}
+
+	return value, false, err
+}
+
+// Delete removes the cached result of the key.
+func (c *StaleCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
+
+func (c *StaleCache) store(key string, value interface{}, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = staleEntry{value: value, expiresAt: now.Add(c.ttl)}
+
+	// expired results are removed at most once per TTL, so the keys that are never requested again don't pile up
+	if now.Sub(c.lastPrune) >= c.ttl {
+		for k, e := range c.entries {
+			if !now.Before(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		c.lastPrune = now
+	}
+}
+
+func (c *StaleCache) load(key string, now time.Time) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !now.Before(e.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	return e.value, true
+}
@@ -0,0 +1,64 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaleCache(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "cached circuit breaker",
+		MaxConsecutiveFailures: 2,
+	})
+	cache := NewStaleCache(cb, time.Minute)
+
+	value, stale, err := cache.Execute("report", func() (interface{}, error) { return "daily", nil })
+	assert.Nil(t, err)
+	assert.False(t, stale)
+	assert.Equal(t, "daily", value)
+
+	// the failed call is served from the cache
+	value, stale, err = cache.Execute("report", func() (interface{}, error) { return nil, errServiceError })
+	assert.Nil(t, err)
+	assert.True(t, stale)
+	assert.Equal(t, "daily", value)
+
+	// the open breaker too
+	_, _, _ = cache.Execute("report", func() (interface{}, error) { return nil, errServiceError })
+	assert.Equal(t, StateOpen, cb.State())
+	value, stale, err = cache.Execute("report", func() (interface{}, error) { return "weekly", nil })
+	assert.Nil(t, err)
+	assert.True(t, stale)
+	assert.Equal(t, "daily", value)
+
+	// there is nothing cached for other keys
+	_, stale, err = cache.Execute("users", func() (interface{}, error) { return "all", nil })
+	assert.Equal(t, ErrOpenState, err)
+	assert.False(t, stale)
+
+	cache.Delete("report")
+	_, _, err = cache.Execute("report", func() (interface{}, error) { return "weekly", nil })
+	assert.Equal(t, ErrOpenState, err)
+}
+
+func TestStaleCacheTTL(t *testing.T) {
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 10})
+	cache := NewStaleCache(cb, time.Minute)
+	now := time.Now()
+
+	cache.store("report", "daily", now)
+	value, ok := cache.load("report", now.Add(59*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, "daily", value)
+
+	_, ok = cache.load("report", now.Add(time.Minute))
+	assert.False(t, ok)
+	assert.Empty(t, cache.entries)
+
+	// expired results of the other keys are pruned
+	cache.store("users", "all", now)
+	cache.store("report", "weekly", now.Add(2*time.Minute))
+	assert.Len(t, cache.entries, 1)
+}
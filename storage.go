@@ -0,0 +1,231 @@
+package circuit_breaker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// StoredState is the state of a CircuitBreaker kept in a Storage.
+// Unlike ExportedState, it doesn't carry the windows of the policy and the config fingerprint.
+//
+// ExpiredAt is the end of the open period, and Trips the number of consecutive trips without closing,
+// which sets the next open period, see Policy.NextOpenDuration.
+//
+// Version orders the writes of the state: the breaker stores the version following the last one it has seen,
+// and adopts only the stored states of a greater version.
+type StoredState struct {
+	State     State
+	Counts    Counts
+	Trips     uint32
+	ChangedAt time.Time
+	ExpiredAt time.Time
+	Version   uint64
+}
+
+// Storage keeps the state of the breakers by name outside of the process,
+// e.g. to restore it after a restart or to share it with the other instances of the service.
+// Storage must be safe for concurrent use.
+type Storage interface {
+	// Load returns the stored state of the breaker, and false if there is none.
+	Load(ctx context.Context, name string) (StoredState, bool, error)
+	// Store replaces the stored state of the breaker.
+	Store(ctx context.Context, name string, s StoredState) error
+}
+
+// CASStorage is a Storage replacing the states atomically.
+// The breakers use CompareAndSwap instead of Store when the Storage implements it,
+// so that the concurrent writes of the instances sharing a breaker don't overwrite each other:
+// the instance losing the race adopts the state of the winner.
+type CASStorage interface {
+	Storage
+	// CompareAndSwap stores the state only if the version of the stored state is version,
+	// or if there is none and version is zero. It reports whether the state was stored.
+	CompareAndSwap(ctx context.Context, name string, version uint64, s StoredState) (bool, error)
+}
+
+// ReasonSynced is the reason of the state changes adopted from the Storage.
+const ReasonSynced = "synced from storage"
+
+// MemoryStorage is the CASStorage keeping the states in memory,
+// e.g. to share the state of the breakers of the same dependency within the process, or in tests.
+type MemoryStorage struct {
+	mu     sync.Mutex
+	states map[string]StoredState
+}
+
+var _ CASStorage = (*MemoryStorage)(nil)
+
+// NewMemoryStorage creates an empty MemoryStorage.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{states: make(map[string]StoredState)}
+}
+
+func (m *MemoryStorage) Load(ctx context.Context, name string) (StoredState, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.states[name]
+	return s, ok, nil
+}
+
+func (m *MemoryStorage) Store(ctx context.Context, name string, s StoredState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.states[name] = s
+	return nil
+}
+
+func (m *MemoryStorage) CompareAndSwap(ctx context.Context, name string, version uint64, s StoredState) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.states[name].Version != version {
+		return false, nil
+	}
+	m.states[name] = s
+	return true, nil
+}
+
+// storageSync holds the progress of the CircuitBreaker in its Storage.
+// It is guarded by its own lock, which is never taken while the CircuitBreaker lock is held.
+type storageSync struct {
+	mu sync.Mutex
+	// version of the last state stored or adopted
+	version uint64
+	// generation of the last state stored, so that a stale SaveState doesn't overwrite a later change
+	generation uint64
+}
+
+// SyncState adopts the state kept in the Storage if it is newer than the local one, e.g. after a peer tripped.
+// It does nothing if no Storage is configured.
+func (cb *CircuitBreaker) SyncState(ctx context.Context) error {
+	if cb.storage == nil {
+		return nil
+	}
+
+	cb.sync.mu.Lock()
+	err := cb.load(ctx)
+	cb.sync.mu.Unlock()
+	cb.flush()
+
+	return err
+}
+
+// SaveState stores the current state of the CircuitBreaker, including its Counts.
+// The state is stored on every state change anyway, SaveState also keeps the Counts of the current state,
+// e.g. on shutdown. It does nothing if no Storage is configured.
+func (cb *CircuitBreaker) SaveState(ctx context.Context) error {
+	if cb.storage == nil {
+		return nil
+	}
+
+	cb.mu.Lock()
+	cb.refreshState(time.Now())
+	s := cb.storedState()
+	s.Counts = cb.counts
+	generation := cb.generation
+	cb.unlock()
+
+	cb.sync.mu.Lock()
+	err := cb.save(ctx, s, generation)
+	cb.sync.mu.Unlock()
+	cb.flush()
+
+	return err
+}
+
+// storedState returns the state to store, while the lock is held.
+func (cb *CircuitBreaker) storedState() StoredState {
+	return StoredState{
+		State:     cb.state,
+		Trips:     cb.trips,
+		ChangedAt: cb.changedAt,
+		ExpiredAt: cb.expiredAt,
+	}
+}
+
+// save stores the state, or adopts the stored one if another instance stored it first, while cb.sync is locked.
+func (cb *CircuitBreaker) save(ctx context.Context, s StoredState, generation uint64) error {
+	if generation < cb.sync.generation {
+		return nil
+	}
+
+	s.Version = cb.sync.version + 1
+	if cas, ok := cb.storage.(CASStorage); ok {
+		swapped, err := cas.CompareAndSwap(ctx, cb.name, cb.sync.version, s)
+		if err != nil {
+			return err
+		}
+		if !swapped {
+			return cb.load(ctx)
+		}
+	} else if err := cb.storage.Store(ctx, cb.name, s); err != nil {
+		return err
+	}
+	cb.sync.version = s.Version
+	cb.sync.generation = generation
+
+	return nil
+}
+
+// load adopts the stored state if it is newer than the local one, while cb.sync is locked.
+// The state changes it makes are delivered by the next unlock, see flush.
+func (cb *CircuitBreaker) load(ctx context.Context) error {
+	s, ok, err := cb.storage.Load(ctx, cb.name)
+	if err != nil || !ok || s.Version <= cb.sync.version {
+		return err
+	}
+	cb.sync.version = s.Version
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.adopt(s, ReasonSynced, time.Now())
+	cb.sync.generation = cb.generation
+
+	return nil
+}
+
+// adopt moves the CircuitBreaker into the state of another instance, while the lock is held.
+func (cb *CircuitBreaker) adopt(s StoredState, reason string, now time.Time) {
+	if s.State != cb.state {
+		cb.setState(s.State, reason)
+		cb.counts = s.Counts
+		// the state started when the other instance changed it, unless the clocks disagree
+		if !s.ChangedAt.IsZero() && s.ChangedAt.Before(cb.changedAt) {
+			cb.changedAt = s.ChangedAt
+		}
+	}
+	cb.trips = s.Trips
+	cb.expiredAt = s.ExpiredAt
+	cb.refreshState(now)
+}
+
+// flush delivers the pending state changes.
+func (cb *CircuitBreaker) flush() {
+	cb.mu.Lock()
+	cb.unlock()
+}
+
+// persist stores the state set by a state change, called in the background
+func (cb *CircuitBreaker) persist(change stateChange) {
+	ctx, cancel := cb.storageContext()
+	defer cancel()
+
+	cb.sync.mu.Lock()
+	err := cb.save(ctx, change.stored, change.generation)
+	cb.sync.mu.Unlock()
+	cb.flush()
+
+	if err != nil {
+		cb.onStorageError(err)
+	}
+}
+
+func (cb *CircuitBreaker) onStorageError(err error) {
+	if cb.logger != nil {
+		cb.logger.Warn("circuit breaker storage failed", "name", cb.name, "error", err)
+	}
+}
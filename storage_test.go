@@ -0,0 +1,162 @@
+package circuit_breaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// plainStorage hides the CompareAndSwap of the MemoryStorage
+type plainStorage struct {
+	Storage
+}
+
+// failingStorage fails all the operations
+type failingStorage struct{}
+
+var errStorageDown = errors.New("storage is down")
+
+func (failingStorage) Load(ctx context.Context, name string) (StoredState, bool, error) {
+	return StoredState{}, false, errStorageDown
+}
+
+func (failingStorage) Store(ctx context.Context, name string, s StoredState) error {
+	return errStorageDown
+}
+
+func TestCircuitBreakerStorage(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	cb := NewCircuitBreaker(Config{
+		Name:                   "storage",
+		MaxConsecutiveFailures: 1,
+		RequestThreshold:       1,
+		Timeout:                time.Minute,
+		Storage:                storage,
+	})
+
+	_, ok, err := storage.Load(ctx, "storage")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	assert.Equal(t, errServiceError, fail(cb))
+	cb.WaitBackground()
+	s, ok, err := storage.Load(ctx, "storage")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, StateOpen, s.State)
+	assert.Equal(t, uint32(1), s.Trips)
+	assert.Equal(t, cb.OpensAt(), s.ExpiredAt)
+	assert.Equal(t, uint64(1), s.Version)
+
+	// a restarted instance stays open until the stored open period is over
+	restarted := NewCircuitBreaker(Config{Name: "storage", RequestThreshold: 1, Timeout: time.Minute, Storage: storage})
+	assert.Equal(t, StateOpen, restarted.State())
+	assert.Equal(t, ErrOpenState, succeed(restarted))
+	assert.Equal(t, s.ExpiredAt, restarted.OpensAt())
+
+	pseudoSleep(cb, time.Minute)
+	assert.Nil(t, succeed(cb))
+	assert.Equal(t, StateClosed, cb.State())
+	cb.WaitBackground()
+	s, _, _ = storage.Load(ctx, "storage")
+	assert.Equal(t, StateClosed, s.State)
+	assert.Equal(t, uint32(0), s.Trips)
+	assert.Equal(t, uint64(3), s.Version)
+
+	assert.Nil(t, restarted.SyncState(ctx))
+	assert.Equal(t, StateClosed, restarted.State())
+	assert.Nil(t, succeed(restarted))
+
+	// SaveState keeps the counts of the current state
+	assert.Nil(t, succeed(cb))
+	assert.Nil(t, cb.SaveState(ctx))
+	s, _, _ = storage.Load(ctx, "storage")
+	assert.Equal(t, Counts{Requests: 1, TotalSuccesses: 1, ConsecutiveSuccesses: 1}, s.Counts)
+	assert.Equal(t, uint64(4), s.Version)
+}
+
+func TestCircuitBreakerStorageSharedState(t *testing.T) {
+	for name, storage := range map[string]func() Storage{
+		"cas":   func() Storage { return NewMemoryStorage() },
+		"plain": func() Storage { return plainStorage{NewMemoryStorage()} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			storage := storage()
+			var changes []string
+			cfg := Config{
+				Name:                   "shared",
+				MaxConsecutiveFailures: 2,
+				Timeout:                time.Minute,
+				Storage:                storage,
+				OnStateChange: func(name string, from State, to State) {
+					changes = append(changes, from.String()+" -> "+to.String())
+				},
+			}
+			a := NewCircuitBreaker(cfg)
+			b := NewCircuitBreaker(cfg)
+
+			assert.Equal(t, errServiceError, fail(a))
+			assert.Equal(t, errServiceError, fail(a))
+			assert.Equal(t, StateOpen, a.State())
+			a.WaitBackground()
+
+			// the peer adopts the trip instead of discovering the outage by itself
+			assert.Nil(t, b.SyncState(ctx))
+			assert.Equal(t, StateOpen, b.State())
+			assert.Equal(t, a.OpensAt(), b.OpensAt())
+			assert.Equal(t, []string{"closed -> open", "closed -> open"}, changes)
+
+			// an older state is not adopted
+			assert.Nil(t, storage.Store(ctx, "shared", StoredState{State: StateClosed}))
+			assert.Nil(t, b.SyncState(ctx))
+			assert.Equal(t, StateOpen, b.State())
+		})
+	}
+}
+
+func TestCircuitBreakerStorageConflict(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	cfg := Config{Name: "conflict", MaxConsecutiveFailures: 1, Timeout: time.Minute, Storage: storage}
+	a := NewCircuitBreaker(cfg)
+	b := NewCircuitBreaker(cfg)
+
+	assert.Equal(t, errServiceError, fail(a))
+	pseudoSleep(a, time.Minute)
+	assert.Equal(t, StateHalfOpen, a.State())
+	a.WaitBackground()
+
+	// the trip of b loses the race with the changes of a, so b adopts the stored state
+	assert.Equal(t, errServiceError, fail(b))
+	b.WaitBackground()
+	assert.Equal(t, StateHalfOpen, b.State())
+	s, _, _ := storage.Load(ctx, "conflict")
+	assert.Equal(t, StateHalfOpen, s.State)
+	assert.Equal(t, uint64(2), s.Version)
+}
+
+func TestCircuitBreakerStorageErrors(t *testing.T) {
+	logger := &recordingLogger{}
+	cb := NewCircuitBreaker(Config{
+		Name:                   "failing",
+		MaxConsecutiveFailures: 1,
+		Storage:                failingStorage{},
+		Logger:                 logger,
+	})
+
+	// the breaker keeps going on its local state
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+	cb.WaitBackground()
+	assert.Equal(t, errStorageDown, cb.SyncState(context.Background()))
+	assert.Equal(t, errStorageDown, cb.SaveState(context.Background()))
+	assert.Contains(t, logger.entries, "WARN circuit breaker storage failed name=failing error=storage is down")
+
+	assert.Nil(t, NewCircuitBreaker(Config{}).SyncState(context.Background()))
+	assert.Nil(t, NewCircuitBreaker(Config{}).SaveState(context.Background()))
+}
@@ -0,0 +1,64 @@
+package circuit_breaker
+
+import "sync"
+
+// listener is a callback registered with Subscribe, SubscribeTransitions or SubscribeEvents
+type listener struct {
+	fn         func(name string, from State, to State)
+	transition func(name string, t Transition)
+	event      func(e Event)
+	change     func(name string, change stateChange)
+}
+
+// Subscribe registers an additional state change callback, delivered after OnStateChange
+// with the same guarantees: outside the lock, one at a time, in the order of the transitions.
+// It returns the function removing the callback. The callback may still receive
+// the changes whose delivery has already started.
+func (cb *CircuitBreaker) Subscribe(fn func(name string, from State, to State)) (unsubscribe func()) {
+	return cb.subscribe(&listener{fn: fn})
+}
+
+// SubscribeTransitions is like Subscribe, but the callback receives the whole Transition,
+// including the reason and the counts which led to it.
+func (cb *CircuitBreaker) SubscribeTransitions(fn func(name string, t Transition)) (unsubscribe func()) {
+	return cb.subscribe(&listener{transition: fn})
+}
+
+func (cb *CircuitBreaker) subscribe(l *listener) (unsubscribe func()) {
+	cb.mu.Lock()
+	// the slice is never modified in place, so unlock can deliver a snapshot of it
+	listeners := make([]*listener, len(cb.listeners), len(cb.listeners)+1)
+	copy(listeners, cb.listeners)
+	cb.listeners = append(listeners, l)
+	cb.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cb.mu.Lock()
+			defer cb.mu.Unlock()
+
+			listeners := make([]*listener, 0, len(cb.listeners))
+			for _, other := range cb.listeners {
+				if other != l {
+					listeners = append(listeners, other)
+				}
+			}
+			cb.listeners = listeners
+		})
+	}
+}
+
+// deliver calls the listener with the state change
+func (l *listener) deliver(name string, change stateChange) {
+	switch {
+	case l.change != nil:
+		l.change(name, change)
+	case l.event != nil:
+		l.event(change.event(name))
+	case l.transition != nil:
+		l.transition(name, change.transition())
+	default:
+		l.fn(name, change.from, change.to)
+	}
+}
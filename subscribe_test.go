@@ -0,0 +1,79 @@
+package circuit_breaker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerSubscribe(t *testing.T) {
+	var changes []string
+	cb := NewCircuitBreaker(Config{
+		Name:                   "subscribed circuit breaker",
+		MaxConsecutiveFailures: 1,
+		OnStateChange: func(name string, from State, to State) {
+			changes = append(changes, "config: "+from.String()+" -> "+to.String())
+		},
+	})
+
+	unsubscribe := cb.Subscribe(func(name string, from State, to State) {
+		assert.Equal(t, "subscribed circuit breaker", name)
+		changes = append(changes, "first: "+from.String()+" -> "+to.String())
+	})
+	cb.Subscribe(func(name string, from State, to State) {
+		changes = append(changes, "second: "+from.String()+" -> "+to.String())
+	})
+
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, []string{
+		"config: closed -> open",
+		"first: closed -> open",
+		"second: closed -> open",
+	}, changes)
+
+	unsubscribe()
+	unsubscribe()
+	changes = nil
+	pseudoSleep(cb, DefaultTimeout)
+	assert.Equal(t, StateHalfOpen, cb.State())
+	assert.Equal(t, []string{
+		"config: open -> half-open",
+		"second: open -> half-open",
+	}, changes)
+}
+
+func TestCircuitBreakerSubscribeWithoutOnStateChange(t *testing.T) {
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 1})
+
+	var to []State
+	cb.Subscribe(func(name string, from State, state State) {
+		to = append(to, state)
+	})
+
+	assert.Equal(t, errServiceError, fail(cb))
+	cb.Reset()
+	assert.Equal(t, []State{StateOpen, StateClosed}, to)
+}
+
+func TestCircuitBreakerSubscribeTransitions(t *testing.T) {
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 2})
+
+	var transitions []Transition
+	unsubscribe := cb.SubscribeTransitions(func(name string, tr Transition) {
+		transitions = append(transitions, tr)
+	})
+
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Len(t, transitions, 1)
+	assert.Equal(t, StateClosed, transitions[0].From)
+	assert.Equal(t, StateOpen, transitions[0].To)
+	assert.Equal(t, ReasonTripped, transitions[0].Reason)
+	assert.Equal(t, uint32(2), transitions[0].Counts.ConsecutiveFailures)
+	assert.Equal(t, errServiceError, transitions[0].LastError)
+	assert.False(t, transitions[0].At.IsZero())
+
+	unsubscribe()
+	cb.Reset()
+	assert.Len(t, transitions, 1)
+}
@@ -0,0 +1,59 @@
+package circuit_breaker
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Transport is an http.RoundTripper maintaining an independent CircuitBreaker per destination,
+// so one failing upstream doesn't open the circuit for all the outbound traffic of a shared client.
+// Responses are classified according to the FailureStatusCodes and IgnoreStatusCodes of the breaker Config.
+type Transport struct {
+	// Base is the underlying RoundTripper. If Base is nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// Breakers holds a breaker per key, created lazily.
+	Breakers *KeyedBreaker
+	// Key returns the breaker key of the request. If Key is nil, HostKey is used.
+	Key func(req *http.Request) string
+}
+
+// NewTransport creates a Transport with a breaker per destination host.
+func NewTransport(base http.RoundTripper, cfg KeyedConfig) *Transport {
+	return &Transport{
+		Base:     base,
+		Breakers: NewKeyedBreaker(cfg),
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	key := t.Key
+	if key == nil {
+		key = HostKey
+	}
+
+	return t.Breakers.Get(key(req)).executeHTTP(req.Context(), func() (*http.Response, error) {
+		return base.RoundTrip(req)
+	})
+}
+
+// HostKey keys the requests by destination host (with port, if any).
+func HostKey(req *http.Request) string {
+	return req.URL.Host
+}
+
+// HostPathPrefixKey keys the requests by destination host and the first segments of the path,
+// e.g. "api.example.com/v1/users" for 2 segments.
+func HostPathPrefixKey(segments int) func(req *http.Request) string {
+	return func(req *http.Request) string {
+		parts := strings.SplitN(strings.TrimPrefix(req.URL.Path, "/"), "/", segments+1)
+		if len(parts) > segments {
+			parts = parts[:segments]
+		}
+
+		return req.URL.Host + "/" + strings.Join(parts, "/")
+	}
+}
@@ -0,0 +1,51 @@
+package circuit_breaker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	transport := NewTransport(nil, KeyedConfig{Config: Config{MaxConsecutiveFailures: 2}})
+	client := &http.Client{Transport: transport}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(failing.URL)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	_, err := client.Get(failing.URL)
+	assert.ErrorIs(t, err, ErrOpenState)
+
+	// the healthy upstream is not affected
+	resp, err := client.Get(healthy.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+	assert.Equal(t, 2, transport.Breakers.Len())
+}
+
+func TestHostPathPrefixKey(t *testing.T) {
+	key := HostPathPrefixKey(2)
+
+	req := httptest.NewRequest(http.MethodGet, "http://api.example.com/v1/users/42", nil)
+	assert.Equal(t, "api.example.com/v1/users", key(req))
+
+	req = httptest.NewRequest(http.MethodGet, "http://api.example.com/v1", nil)
+	assert.Equal(t, "api.example.com/v1", key(req))
+	assert.Equal(t, "api.example.com", HostKey(req))
+}
@@ -0,0 +1,62 @@
+package circuit_breaker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// UpdateConfig atomically replaces the settings of the state machine of the live CircuitBreaker:
+// RequestThreshold, Timeout, MaxConsecutiveFailures, ReadyToTrip, Policy, WarmupDuration,
+// MaintenanceWindows, ErrorCategorizer, CategoryThresholds and SlowCallThreshold.
+// The other fields of the config are ignored, except Name, which must be empty or the name of the CircuitBreaker.
+//
+// The update keeps the state, the counts of the current window and the statistics:
+//   - in the closed state, the new policy decides on the next failure, with the counts gathered so far
+//   - in the open state, the open period is recomputed from its start by the new policy,
+//     so a shorter Timeout may make the CircuitBreaker half-open right away
+//   - in the half-open state, the probes already admitted count towards the new RequestThreshold
+//   - a warmup in progress is recomputed from its start by the new WarmupDuration
+//
+// It returns an error if the config is invalid, leaving the CircuitBreaker unchanged.
+// The members of a Group can't be updated, as they follow the shared breaker.
+func (cb *CircuitBreaker) UpdateConfig(cfg Config) error {
+	if cfg.Name != "" && cfg.Name != cb.name {
+		return fmt.Errorf("circuit breaker %q can't be renamed to %q", cb.name, cfg.Name)
+	}
+	if cfg.Timeout < 0 || cfg.WarmupDuration < 0 || cfg.SlowCallThreshold < 0 {
+		return errors.New("circuit breaker durations must not be negative")
+	}
+
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	if _, ok := cb.policy.(followerPolicy); ok {
+		return fmt.Errorf("circuit breaker %q follows its group", cb.name)
+	}
+
+	now := time.Now()
+	cb.refreshState(now)
+
+	cb.requestThreshold = requestThresholdOf(cfg)
+	cb.policy = policyOf(cfg)
+	cb.maintenanceWindows = cfg.MaintenanceWindows
+	cb.errorCategorizer = cfg.ErrorCategorizer
+	if cb.errorCategorizer == nil {
+		cb.errorCategorizer = DefaultErrorCategorizer
+	}
+	cb.categoryThresholds = cfg.CategoryThresholds
+	cb.slowCallThreshold = cfg.SlowCallThreshold
+
+	if cb.inWarmup(now) {
+		cb.warmupUntil = cb.warmupUntil.Add(cfg.WarmupDuration - cb.warmupDuration)
+	}
+	cb.warmupDuration = cfg.WarmupDuration
+
+	if cb.state == StateOpen {
+		cb.expiredAt = cb.changedAt.Add(cb.policy.NextOpenDuration(cb.trips))
+		cb.refreshState(now)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateConfig(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "payments", MaxConsecutiveFailures: 3, Timeout: time.Minute})
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, errServiceError, fail(cb))
+
+	// the counts gathered so far count towards the new threshold
+	assert.Nil(t, cb.UpdateConfig(Config{MaxConsecutiveFailures: 2, Timeout: time.Minute}))
+	assert.Equal(t, Counts{2, 0, 2, 0, 2}, cb.Counts())
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+
+	// the open period is recomputed from its start
+	assert.Greater(t, cb.RemainingOpenTime(), 50*time.Second)
+	assert.Nil(t, cb.UpdateConfig(Config{Name: "payments", MaxConsecutiveFailures: 2, Timeout: 10 * time.Second}))
+	assert.LessOrEqual(t, cb.RemainingOpenTime(), 10*time.Second)
+	assert.Nil(t, cb.UpdateConfig(Config{MaxConsecutiveFailures: 2, RequestThreshold: 2, Timeout: time.Nanosecond}))
+	assert.Equal(t, StateHalfOpen, cb.State())
+
+	// the admitted probes count towards the new request threshold
+	assert.Nil(t, succeed(cb))
+	assert.Nil(t, cb.UpdateConfig(Config{MaxConsecutiveFailures: 2, RequestThreshold: 1}))
+	assert.Equal(t, ErrTooManyRequests, succeed(cb))
+	assert.Equal(t, StateHalfOpen, cb.State())
+}
+
+func TestUpdateConfigWarmup(t *testing.T) {
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 1, WarmupDuration: time.Hour})
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateClosed, cb.State())
+
+	assert.Nil(t, cb.UpdateConfig(Config{MaxConsecutiveFailures: 1}))
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestUpdateConfigInvalid(t *testing.T) {
+	cb := NewCircuitBreaker(Config{Name: "payments", MaxConsecutiveFailures: 1})
+
+	assert.NotNil(t, cb.UpdateConfig(Config{Name: "search"}))
+	assert.NotNil(t, cb.UpdateConfig(Config{Timeout: -time.Second}))
+	assert.NotNil(t, cb.UpdateConfig(Config{WarmupDuration: -time.Second}))
+	assert.Equal(t, errServiceError, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+
+	g := NewGroup(GroupConfig{})
+	assert.NotNil(t, g.Member("a").UpdateConfig(Config{}))
+}
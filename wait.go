@@ -0,0 +1,25 @@
+package circuit_breaker
+
+import (
+	"context"
+	"time"
+)
+
+// Wait blocks until the CircuitBreaker leaves the open state, or returns the context error if it is done first.
+// Clients reconnecting in a loop can use it to let the open timeout of the breaker drive their backoff.
+func (cb *CircuitBreaker) Wait(ctx context.Context) error {
+	for {
+		d := cb.remainingOpenTime(time.Now())
+		if d <= 0 {
+			return ctx.Err()
+		}
+
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
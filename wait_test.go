@@ -0,0 +1,37 @@
+package circuit_breaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerWait(t *testing.T) {
+	cb := NewCircuitBreaker(Config{
+		Name:                   "waiting circuit breaker",
+		MaxConsecutiveFailures: 1,
+		Timeout:                20 * time.Millisecond,
+	})
+	ctx := context.Background()
+
+	// the closed breaker doesn't block
+	assert.Nil(t, cb.Wait(ctx))
+
+	assert.Equal(t, errServiceError, fail(cb))
+	start := time.Now()
+	assert.Nil(t, cb.Wait(ctx))
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+	assert.Equal(t, StateHalfOpen, cb.State())
+}
+
+func TestCircuitBreakerWaitContext(t *testing.T) {
+	cb := NewCircuitBreaker(Config{MaxConsecutiveFailures: 1})
+	assert.Equal(t, errServiceError, fail(cb))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, cb.Wait(ctx))
+	assert.Equal(t, StateOpen, cb.State())
+}
@@ -0,0 +1,116 @@
+package circuit_breaker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// ErrWebhookQueueFull is passed to WebhookConfig.OnError when a notification is dropped
+// because the deliveries can't keep up with the state changes.
+//
+// Deprecated: use ErrNotificationQueueFull, shared by all the notifiers.
+var ErrWebhookQueueFull = ErrNotificationQueueFull
+
+// WebhookConfig configures Webhook.
+//
+// URLs are the endpoints receiving the notifications as JSON POST requests.
+//
+// Client sends the requests. If Client is nil, http.DefaultClient is used.
+// Timeout bounds each attempt. If Timeout is zero, the attempts time out after 5s.
+// Header is added to the requests, e.g. for authentication.
+//
+// MaxAttempts is the maximum number of attempts per URL, including the first one.
+// If MaxAttempts is zero, 3 attempts are made.
+// Only network errors, 5xx and 429 responses are retried.
+// Backoff returns the delay before the retry with the given number, starting from 1.
+// If Backoff is nil, the delay doubles from 1s up to 30s.
+//
+// QueueSize is the number of the notifications waiting for delivery, see Watch.
+// If QueueSize is zero, 64 notifications are queued.
+//
+// OnError is called when a notification is not delivered to the URL, e.g. to log it.
+type WebhookConfig struct {
+	URLs        []string
+	Client      *http.Client
+	Timeout     time.Duration
+	Header      http.Header
+	MaxAttempts uint32
+	Backoff     func(retry uint32) time.Duration
+	QueueSize   int
+	OnError     func(url string, n Notification, err error)
+}
+
+// Webhook is the Notifier posting the Notification of the state changes to the configured URLs,
+// so external systems can react to them without polling.
+type Webhook struct {
+	cfg        WebhookConfig
+	poster     poster
+	dispatcher *Dispatcher
+}
+
+// NewWebhook creates the Webhook and starts the delivery of the queued notifications.
+// Close stops it.
+func NewWebhook(cfg WebhookConfig) *Webhook {
+	wh := &Webhook{
+		cfg:    cfg,
+		poster: newPoster(cfg.Client, cfg.Timeout, cfg.Header, cfg.MaxAttempts, cfg.Backoff),
+	}
+	wh.dispatcher = NewDispatcher(DispatcherConfig{
+		Notifiers: []Notifier{wh},
+		QueueSize: cfg.QueueSize,
+		OnError: func(n Notification, err error) {
+			// the delivery errors are reported by Notify per URL
+			if err == ErrNotificationQueueFull || err == ErrDispatcherClosed {
+				for _, url := range wh.cfg.URLs {
+					wh.onError(url, n, err)
+				}
+			}
+		},
+	})
+
+	return wh
+}
+
+// Watch queues the notifications of the state changes of the breaker for delivery, in the order of the changes.
+// The callbacks of the breaker don't wait for the delivery: if the queue is full, the notification is dropped
+// and OnError is called with ErrNotificationQueueFull.
+// stop stops watching the breaker.
+func (wh *Webhook) Watch(cb *CircuitBreaker) (stop func()) {
+	return wh.dispatcher.Watch(cb)
+}
+
+// Notify delivers the notification to all the URLs, retrying the failed attempts.
+// It returns the first delivery error, if any.
+func (wh *Webhook) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, url := range wh.cfg.URLs {
+		if err := wh.poster.deliver(ctx, url, body); err != nil {
+			wh.onError(url, n, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// Close stops the Webhook after delivering the queued notifications.
+// The state changes of the breakers still watched after Close are dropped
+// and OnError is called with ErrDispatcherClosed.
+func (wh *Webhook) Close() {
+	wh.dispatcher.Close()
+}
+
+func (wh *Webhook) onError(url string, n Notification, err error) {
+	if wh.cfg.OnError != nil {
+		wh.cfg.OnError(url, n, err)
+	}
+}
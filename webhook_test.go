@@ -0,0 +1,126 @@
+package circuit_breaker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookWatch(t *testing.T) {
+	var mu sync.Mutex
+	var received []Notification
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first attempt fails and is retried
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "secret", r.Header.Get("X-Token"))
+
+		var n Notification
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&n))
+		mu.Lock()
+		received = append(received, n)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	wh := NewWebhook(WebhookConfig{
+		URLs:    []string{srv.URL},
+		Header:  http.Header{"X-Token": []string{"secret"}},
+		Backoff: func(retry uint32) time.Duration { return time.Millisecond },
+		OnError: func(url string, n Notification, err error) { t.Errorf("unexpected error: %v", err) },
+	})
+
+	cb := NewCircuitBreaker(Config{
+		Name:                   "payments",
+		MaxConsecutiveFailures: 1,
+		Labels:                 map[string]string{"tier": "critical"},
+	})
+	stop := wh.Watch(cb)
+	assert.Equal(t, errServiceError, fail(cb))
+	cb.Reset()
+	stop()
+	cb.Trip()
+	wh.Close()
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+	assert.Len(t, received, 2)
+	assert.Equal(t, "payments", received[0].Name)
+	assert.Equal(t, StateClosed, received[0].From)
+	assert.Equal(t, StateOpen, received[0].To)
+	assert.Equal(t, ReasonTripped, received[0].Reason)
+	assert.Equal(t, uint32(1), received[0].Counts.TotalFailures)
+	assert.Equal(t, map[string]string{"tier": "critical"}, received[0].Labels)
+	assert.False(t, received[0].At.IsZero())
+	assert.Equal(t, StateClosed, received[1].To)
+	assert.Equal(t, ReasonReset, received[1].Reason)
+}
+
+func TestWebhookNotifyErrors(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	var failed []string
+	wh := NewWebhook(WebhookConfig{
+		URLs:    []string{srv.URL},
+		OnError: func(url string, n Notification, err error) { failed = append(failed, url) },
+	})
+	defer wh.Close()
+
+	// client errors are not retried
+	err := wh.Notify(context.Background(), Notification{Name: "payments"})
+	assert.EqualError(t, err, "POST "+srv.URL+": 400 Bad Request")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+	assert.Equal(t, []string{srv.URL}, failed)
+}
+
+func TestWebhookQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var errs []error
+	wh := NewWebhook(WebhookConfig{
+		URLs:      []string{srv.URL},
+		QueueSize: 1,
+		OnError: func(url string, n Notification, err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		},
+	})
+
+	cb := NewCircuitBreaker(Config{})
+	wh.Watch(cb)
+	// the first notification is being delivered, the second one is queued, and the rest are dropped
+	for i := 0; i < 4; i++ {
+		cb.Trip()
+		cb.Reset()
+	}
+	close(release)
+	wh.Close()
+
+	assert.NotEmpty(t, errs)
+	for _, err := range errs {
+		assert.Equal(t, ErrNotificationQueueFull, err)
+		// the callers checking the deprecated name keep working
+		assert.ErrorIs(t, err, ErrWebhookQueueFull)
+	}
+}
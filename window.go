@@ -0,0 +1,126 @@
+package circuit_breaker
+
+import "time"
+
+const defaultWindowBuckets = 10
+
+// Window is a rolling window of Counts split into buckets of equal length.
+// Outcomes older than the window size are discarded bucket by bucket.
+// Window is not safe for concurrent use.
+type Window struct {
+	bucketSize time.Duration
+	buckets    []Counts
+	head       int64
+	last       Counts
+}
+
+// NewWindow creates a Window of the given size split into the given number of buckets.
+// If buckets is zero, 10 buckets are used.
+func NewWindow(size time.Duration, buckets int) *Window {
+	if buckets <= 0 {
+		buckets = defaultWindowBuckets
+	}
+	bucketSize := size / time.Duration(buckets)
+	if bucketSize <= 0 {
+		bucketSize = 1
+	}
+
+	return &Window{
+		bucketSize: bucketSize,
+		buckets:    make([]Counts, buckets),
+	}
+}
+
+// Record adds the outcome of a request made at the given time.
+func (w *Window) Record(now time.Time, success bool) {
+	b := &w.buckets[w.advance(now)]
+	b.onRequest()
+	w.last.onRequest()
+	if success {
+		b.onSuccess()
+		w.last.onSuccess()
+	} else {
+		b.onFailure()
+		w.last.onFailure()
+	}
+}
+
+// Counts returns the aggregated Counts of the window at the given time.
+// Consecutive counters reflect the latest outcomes regardless of bucket boundaries.
+func (w *Window) Counts(now time.Time) Counts {
+	w.advance(now)
+
+	var c Counts
+	for _, b := range w.buckets {
+		c.Requests += b.Requests
+		c.TotalSuccesses += b.TotalSuccesses
+		c.TotalFailures += b.TotalFailures
+	}
+	if c.Requests > 0 {
+		c.ConsecutiveSuccesses = w.last.ConsecutiveSuccesses
+		c.ConsecutiveFailures = w.last.ConsecutiveFailures
+	}
+
+	return c
+}
+
+// Reset discards all the recorded outcomes.
+func (w *Window) Reset() {
+	for i := range w.buckets {
+		w.buckets[i].reset()
+	}
+	w.last.reset()
+}
+
+// WindowState is the content of a Window, see Window.Export and WindowedPolicy.
+// Name tells apart the windows of a policy.
+type WindowState struct {
+	Name       string
+	BucketSize time.Duration
+	Head       int64
+	Buckets    []Counts
+	Last       Counts
+}
+
+// Export returns the content of the window.
+func (w *Window) Export(name string) WindowState {
+	return WindowState{
+		Name:       name,
+		BucketSize: w.bucketSize,
+		Head:       w.head,
+		Buckets:    append([]Counts(nil), w.buckets...),
+		Last:       w.last,
+	}
+}
+
+// Import replaces the content of the window by the exported one,
+// reporting whether the window has the same size and number of buckets.
+func (w *Window) Import(s WindowState) bool {
+	if s.BucketSize != w.bucketSize || len(s.Buckets) != len(w.buckets) {
+		return false
+	}
+
+	w.head = s.Head
+	copy(w.buckets, s.Buckets)
+	w.last = s.Last
+	return true
+}
+
+// advance clears the buckets that fell out of the window and returns the index of the current one.
+func (w *Window) advance(now time.Time) int {
+	n := int64(len(w.buckets))
+	head := now.UnixNano() / int64(w.bucketSize)
+
+	if head > w.head {
+		stale := head - w.head
+		if stale > n {
+			stale = n
+		}
+		for i := int64(1); i <= stale; i++ {
+			w.buckets[(w.head+i)%n].reset()
+		}
+		w.head = head
+	}
+
+	return int(w.head % n)
+}
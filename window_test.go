@@ -0,0 +1,46 @@
+package circuit_breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindow(t *testing.T) {
+	w := NewWindow(10*time.Second, 10)
+	now := time.Unix(1000, 0)
+
+	w.Record(now, true)
+	w.Record(now.Add(time.Second), false)
+	w.Record(now.Add(2*time.Second), false)
+	assert.Equal(t, Counts{3, 1, 2, 0, 2}, w.Counts(now.Add(2*time.Second)))
+
+	// the first bucket falls out of the window
+	assert.Equal(t, Counts{2, 0, 2, 0, 2}, w.Counts(now.Add(10*time.Second)))
+
+	// the whole window expires
+	assert.Equal(t, Counts{0, 0, 0, 0, 0}, w.Counts(now.Add(time.Minute)))
+
+	w.Record(now.Add(time.Minute), true)
+	assert.Equal(t, Counts{1, 1, 0, 1, 0}, w.Counts(now.Add(time.Minute)))
+
+	w.Reset()
+	assert.Equal(t, Counts{0, 0, 0, 0, 0}, w.Counts(now.Add(time.Minute)))
+}
+
+func TestWindowExport(t *testing.T) {
+	w := NewWindow(10*time.Second, 10)
+	now := time.Unix(1000, 0)
+	w.Record(now, false)
+	w.Record(now.Add(time.Second), false)
+
+	other := NewWindow(10*time.Second, 10)
+	assert.True(t, other.Import(w.Export("fast")))
+	assert.Equal(t, Counts{2, 0, 2, 0, 2}, other.Counts(now.Add(time.Second)))
+	assert.Equal(t, Counts{1, 0, 1, 0, 2}, other.Counts(now.Add(10*time.Second)))
+
+	// the windows of another size are not imported
+	assert.False(t, NewWindow(time.Minute, 10).Import(w.Export("fast")))
+	assert.False(t, NewWindow(10*time.Second, 5).Import(w.Export("fast")))
+}